@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"go_appeals/internal/blob"
+	"go_appeals/internal/services"
+)
+
+// newAttachmentStore opens the directory attached files are kept in and
+// reads the limits on them from the environment.
+func newAttachmentStore() (blob.Store, services.AttachmentLimits, error) {
+	limits := services.DefaultAttachmentLimits
+	if value := os.Getenv("ATTACHMENT_MAX_SIZE"); value != "" {
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size <= 0 {
+			return nil, limits, fmt.Errorf("ATTACHMENT_MAX_SIZE must be a positive number of bytes, got %q", value)
+		}
+		limits.MaxSize = size
+	}
+	if value := os.Getenv("ATTACHMENT_MAX_FILES"); value != "" {
+		files, err := strconv.Atoi(value)
+		if err != nil || files <= 0 {
+			return nil, limits, fmt.Errorf("ATTACHMENT_MAX_FILES must be a positive number, got %q", value)
+		}
+		limits.MaxFiles = files
+	}
+	if value := os.Getenv("ATTACHMENT_TYPES"); value != "" {
+		limits.ContentTypes = nil
+		for _, contentType := range strings.Split(value, ",") {
+			if contentType = strings.TrimSpace(contentType); contentType != "" {
+				limits.ContentTypes = append(limits.ContentTypes, contentType)
+			}
+		}
+	}
+
+	dir := os.Getenv("ATTACHMENT_DIR")
+	if dir == "" {
+		dir = "./attachments"
+	}
+	store, err := blob.NewLocal(dir)
+	if err != nil {
+		return nil, limits, err
+	}
+	return store, limits, nil
+}
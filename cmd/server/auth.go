@@ -0,0 +1,146 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go_appeals/internal/access"
+	"go_appeals/internal/auth"
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+	"go_appeals/internal/tenant"
+)
+
+// newAuthenticator accepts API keys from the store and, when configured,
+// JWTs:
+//
+//	AUTH_JWT_SECRET      HS256 shared secret
+//	AUTH_JWT_PUBLIC_KEY  path to a PEM RSA public key or certificate for RS256
+//	AUTH_JWT_ISSUER      required iss claim
+//	AUTH_JWT_AUDIENCE    required aud claim
+func newAuthenticator(store repository.APIKeyStore) (*auth.Authenticator, error) {
+	cfg := auth.JWTConfig{
+		Issuer:     os.Getenv("AUTH_JWT_ISSUER"),
+		Audience:   os.Getenv("AUTH_JWT_AUDIENCE"),
+		HMACSecret: []byte(os.Getenv("AUTH_JWT_SECRET")),
+		Leeway:     30 * time.Second,
+	}
+	if path := os.Getenv("AUTH_JWT_PUBLIC_KEY"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT public key: %w", err)
+		}
+		if cfg.RSAPublicKey, err = auth.ParseRSAPublicKey(data); err != nil {
+			return nil, fmt.Errorf("failed to parse JWT public key %s: %w", path, err)
+		}
+	}
+
+	if len(cfg.HMACSecret) == 0 && cfg.RSAPublicKey == nil {
+		return auth.New(store), nil
+	}
+	return auth.New(store, auth.WithJWT(cfg)), nil
+}
+
+// loadAccessPolicy reads the roles from ACCESS_POLICY, or returns the
+// built-in ones.
+func loadAccessPolicy() (*access.Policy, error) {
+	if path := os.Getenv("ACCESS_POLICY"); path != "" {
+		return access.Load(path)
+	}
+	return access.Default(), nil
+}
+
+// loadTenants reads the tenants from TENANTS_CONFIG, or returns the
+// built-in default tenant.
+func loadTenants() (*tenant.Registry, error) {
+	if path := os.Getenv("TENANTS_CONFIG"); path != "" {
+		return tenant.Load(path)
+	}
+	return tenant.Default(), nil
+}
+
+// runKeys implements the "keys" subcommand:
+//
+//	server keys create -name NAME -subject SUBJECT [-roles admin,operator] [-tenant ID] [-expires 90d]
+//	server keys list
+//	server keys revoke ID
+//
+// Keys created with an empty -tenant act for the default tenant, or, if
+// their roles may switch tenants, whichever one a request names in
+// X-Tenant-ID.
+func runKeys(store repository.APIKeyStore, policy *access.Policy, tenants *tenant.Registry, args []string) error {
+	authenticator := auth.New(store)
+
+	command := "list"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "create":
+		flags := flag.NewFlagSet("keys create", flag.ContinueOnError)
+		name := flags.String("name", "", "what the key is for")
+		subject := flags.String("subject", "", "identity the key acts as")
+		roleList := flags.String("roles", "", "comma-separated roles; the policy's default role when empty")
+		tenantID := flags.String("tenant", models.DefaultTenant, "tenant the key belongs to; none when empty")
+		expires := flags.String("expires", "", "lifetime such as 90d; never expires when empty")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		var roles []models.Role
+		for _, role := range strings.Split(*roleList, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				roles = append(roles, models.Role(role))
+			}
+		}
+		if err := policy.ValidateRoles(roles); err != nil {
+			return err
+		}
+		if *tenantID != "" {
+			if _, err := tenants.Get(*tenantID); err != nil {
+				return err
+			}
+		}
+
+		key, secret, err := authenticator.IssueKey(models.CreateAPIKeyRequest{Name: *name, Subject: *subject, Tenant: *tenantID, Roles: roles, ExpiresIn: *expires})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created API key %s for %s.\n", key.ID, policy.Describe(&models.Principal{Subject: key.Subject, Roles: key.Roles}))
+		fmt.Printf("Key (shown only once): %s\n", secret)
+	case "list":
+		keys, err := authenticator.Keys("")
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			state := "active"
+			switch {
+			case key.RevokedAt != nil:
+				state = "revoked " + key.RevokedAt.Format("2006-01-02 15:04:05")
+			case !key.Usable(time.Now()):
+				state = "expired " + key.ExpiresAt.Format("2006-01-02 15:04:05")
+			}
+			scope := key.Tenant
+			if scope == "" {
+				scope = "*"
+			}
+			fmt.Printf("%-36s %-16s %-20s %-20s %-12s %s\n", key.ID, key.Prefix, key.Name, key.Subject, scope, state)
+		}
+	case "revoke":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: keys revoke ID")
+		}
+		if err := authenticator.RevokeKey("", args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Revoked API key %s.\n", args[0])
+	default:
+		return fmt.Errorf("unknown keys command %q, expected create, list or revoke", command)
+	}
+
+	return nil
+}
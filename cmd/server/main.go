@@ -6,12 +6,19 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"go_appeals/internal/calendar"
 	"go_appeals/internal/handlers"
+	"go_appeals/internal/outbox"
 	"go_appeals/internal/repository"
+	"go_appeals/internal/scheduler"
 	"go_appeals/internal/services"
+	"go_appeals/internal/sla"
+	"go_appeals/internal/webhook"
+	"go_appeals/internal/workflow"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -19,13 +26,11 @@ import (
 )
 
 func main() {
-	app := fiber.New()
-
-	app.Use(recover.New())
-	app.Use(logger.New())
-
-	dbPath := "./appeals.db"
-	repo, err := repository.NewAppealRepository(dbPath)
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		dsn = "./appeals.db"
+	}
+	repo, err := repository.Open(dsn)
 	if err != nil {
 		log.Fatalf("Failed to create repository: %v", err)
 	}
@@ -36,22 +41,219 @@ func main() {
 		log.Println("Database connection closed.")
 	}()
 
-	service := services.NewAppealService(repo)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(repo, os.Args[2:]); err != nil {
+			log.Printf("Migration failed: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := migrateOnStartup(repo); err != nil {
+		log.Printf("Failed to migrate database: %v", err)
+		return
+	}
+
+	accessPolicy, err := loadAccessPolicy()
+	if err != nil {
+		log.Printf("Failed to load access policy: %v", err)
+		return
+	}
+
+	tenants, err := loadTenants()
+	if err != nil {
+		log.Printf("Failed to load tenants: %v", err)
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "keys" {
+		if err := runKeys(repo, accessPolicy, tenants, os.Args[2:]); err != nil {
+			log.Printf("Keys command failed: %v", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	blobs, limits, err := newAttachmentStore()
+	if err != nil {
+		log.Printf("Failed to configure attachments: %v", err)
+		return
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: handlers.ErrorHandler})
+	// Only uploads get room for every file an appeal may have.
+	handlers.UploadBodyLimit(app, int(limits.MaxSize)*limits.MaxFiles+1<<20)
+
+	app.Use(recover.New())
+	app.Use(logger.New())
+
+	authenticator, err := newAuthenticator(repo)
+	if err != nil {
+		log.Printf("Failed to configure authentication: %v", err)
+		return
+	}
+
+	machine := workflow.Default()
+	if path := os.Getenv("WORKFLOW_CONFIG"); path != "" {
+		machine, err = workflow.Load(path)
+		if err != nil {
+			log.Printf("Failed to load workflow: %v", err)
+			return
+		}
+	}
+
+	policy := sla.Default()
+	if path := os.Getenv("SLA_CONFIG"); path != "" {
+		policy, err = sla.Load(path)
+		if err != nil {
+			log.Printf("Failed to load SLA policy: %v", err)
+			return
+		}
+	}
+
+	cal := calendar.Default()
+	if path := os.Getenv("CALENDAR_CONFIG"); path != "" {
+		cal, err = calendar.Load(path)
+		if err != nil {
+			log.Printf("Failed to load business calendar: %v", err)
+			return
+		}
+	}
+
+	var webhookOpts []webhook.Option
+	if os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true" {
+		webhookOpts = append(webhookOpts, webhook.AllowPrivateNetworks())
+	}
+	webhooks := webhook.New(repo, webhookOpts...)
+	if err := webhooks.Start(); err != nil {
+		log.Printf("Failed to start webhook deliveries: %v", err)
+		return
+	}
+
+	// Every change writes its events to the outbox in the same transaction;
+	// the relay passes them on to the sinks.
+	bus := outbox.NewBus(64)
+	sinks := []outbox.Sink{webhooks, bus}
+	if path := os.Getenv("EVENT_LOG"); path != "" {
+		eventLog, err := outbox.OpenLog(path)
+		if err != nil {
+			log.Printf("Failed to open event log: %v", err)
+			return
+		}
+		defer eventLog.Close()
+		sinks = append(sinks, eventLog)
+	}
+	relay := outbox.New(repo, sinks)
+	if err := relay.Start(); err != nil {
+		log.Printf("Failed to start the outbox relay: %v", err)
+		return
+	}
+
+	service := services.NewAppealService(repo, machine,
+		services.WithSLAPolicy(policy), services.WithCalendar(cal), services.WithAccessPolicy(accessPolicy),
+		services.WithTenants(tenants), services.WithAttachments(blobs, limits), services.WithNotifier(relay))
+
+	schedulerOpts := []scheduler.Option{scheduler.WithHandlers(service.Jobs()), scheduler.WithLocation(cal.Location())}
+	var sched *scheduler.Scheduler
+	if path := os.Getenv("SCHEDULER_CONFIG"); path != "" {
+		sched, err = scheduler.Load(path, repo, schedulerOpts...)
+	} else {
+		sched, err = scheduler.Default(repo, schedulerOpts...)
+	}
+	if err != nil {
+		log.Printf("Failed to load scheduler: %v", err)
+		return
+	}
+	// Extra replicas set SCHEDULER_ENABLED=false so that jobs run once;
+	// they still accept manual runs.
+	if os.Getenv("SCHEDULER_ENABLED") != "false" {
+		sched.Start()
+	}
 
 	apiHandlers := &handlers.Handlers{
-		Service: service,
+		Auth:      authenticator,
+		Service:   service,
+		Scheduler: sched,
+		Webhooks:  webhooks,
+		Events:    bus,
+	}
+
+	// Tracking is public, so it comes before authentication; the limit
+	// keeps tokens from being guessed.
+	trackLimit := 30
+	if value := os.Getenv("TRACK_RATE_LIMIT"); value != "" {
+		trackLimit, err = strconv.Atoi(value)
+		if err != nil || trackLimit <= 0 {
+			log.Printf("TRACK_RATE_LIMIT must be a positive number, got %q", value)
+			return
+		}
 	}
+	app.Get("/track/:token", handlers.RateLimit(trackLimit, time.Minute), apiHandlers.TrackAppeal)
+
+	// AUTH_DISABLED=true trusts the X-Actor header instead, for local
+	// development only.
+	if os.Getenv("AUTH_DISABLED") == "true" {
+		log.Println("Authentication is disabled, every endpoint is open.")
+	} else {
+		app.Use(apiHandlers.Authenticate)
+	}
+	app.Use(apiHandlers.ResolveTenant)
+
+	app.Get("/auth/me", apiHandlers.GetMe)
+	app.Post("/api-keys", apiHandlers.CreateAPIKey)
+	app.Get("/api-keys", apiHandlers.GetAPIKeys)
+	app.Delete("/api-keys/:id", apiHandlers.RevokeAPIKey)
 
 	api := app.Group("/appeals")
 	api.Get("/", apiHandlers.GetStartedAppeals)
 	api.Get("/all", apiHandlers.GetAllAppeals)
 	api.Get("/by-dates", apiHandlers.GetAppealsByDates)
+	api.Get("/search", apiHandlers.SearchAppeals)
+	api.Get("/mine", apiHandlers.GetMyAppeals)
+	api.Get("/events", apiHandlers.StreamAppealEvents)
 	api.Post("/cancel-all-in-progress", apiHandlers.CancelAllInProgress)
 	api.Get("/:id", apiHandlers.GetAppealByID)
+	api.Get("/:id/history", apiHandlers.GetAppealHistory)
+	api.Get("/:id/sla", apiHandlers.GetAppealSLA)
+	api.Get("/:id/comments", apiHandlers.GetAppealComments)
+	api.Post("/:id/comments", apiHandlers.CreateAppealComment)
+	api.Patch("/:id/comments/:comment", apiHandlers.EditAppealComment)
+	api.Get("/:id/attachments", apiHandlers.GetAppealAttachments)
+	api.Post("/:id/attachments", apiHandlers.CreateAppealAttachments)
+	api.Get("/:id/attachments/:attachment", apiHandlers.DownloadAppealAttachment)
 	api.Post("/", apiHandlers.CreateAppeal)
 	api.Patch("/:id/start", apiHandlers.StartProcessing)
 	api.Patch("/:id/complete", apiHandlers.CompleteAppeal)
 	api.Patch("/:id/cancel", apiHandlers.CancelAppeal)
+	api.Patch("/:id/assign", apiHandlers.AssignAppeal)
+	api.Patch("/:id/reassign", apiHandlers.ReassignAppeal)
+	api.Patch("/:id/claim", apiHandlers.ClaimAppeal)
+	api.Patch("/:id/hold", apiHandlers.HoldAppeal)
+	api.Patch("/:id/wait", apiHandlers.WaitForRequester)
+	api.Patch("/:id/resume", apiHandlers.ResumeAppeal)
+	api.Patch("/:id/reject", apiHandlers.RejectAppeal)
+	api.Patch("/:id/reopen", apiHandlers.ReopenAppeal)
+
+	app.Get("/requesters/:id", apiHandlers.GetRequester)
+	app.Get("/requesters/:id/appeals", apiHandlers.GetRequesterAppeals)
+
+	app.Get("/workflow", apiHandlers.GetWorkflow)
+	app.Get("/workflow/diagram", apiHandlers.GetWorkflowDiagram)
+	app.Get("/sla", apiHandlers.GetSLAPolicy)
+	app.Get("/tenant", apiHandlers.GetTenant)
+
+	app.Get("/jobs", apiHandlers.GetJobs)
+	app.Post("/jobs/:name/run", apiHandlers.RunJob)
+	app.Get("/jobs/:name/runs", apiHandlers.GetJobRuns)
+	app.Get("/reminders", apiHandlers.GetReminders)
+	app.Get("/reminders/mine", apiHandlers.GetMyReminders)
+
+	app.Post("/webhooks", apiHandlers.CreateWebhook)
+	app.Get("/webhooks", apiHandlers.GetWebhooks)
+	app.Get("/webhooks/:id", apiHandlers.GetWebhook)
+	app.Delete("/webhooks/:id", apiHandlers.DeleteWebhook)
+	app.Get("/webhooks/:id/deliveries", apiHandlers.GetWebhookDeliveries)
+	app.Post("/webhooks/:id/deliveries/:delivery/redeliver", apiHandlers.RedeliverWebhook)
 
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.SendString("Hello, World!")
@@ -78,8 +280,29 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// Event streams never end on their own; closing the bus ends them and
+	// their clients reconnect to another replica or after the restart.
+	bus.Close()
 	if err := app.ShutdownWithContext(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+
+	// Running jobs and webhook deliveries get their own grace period to
+	// finish before their context is cancelled.
+	jobsCtx, cancelJobs := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancelJobs()
+
+	if err := sched.Shutdown(jobsCtx); err != nil {
+		log.Printf("Scheduler forced to stop: %v", err)
+	}
+	// Events not yet relayed stay in the outbox.
+	if err := relay.Shutdown(jobsCtx); err != nil {
+		log.Printf("Outbox relay forced to stop: %v", err)
+	}
+	// Deliveries waiting for a retry stay pending and resume on the next
+	// start.
+	if err := webhooks.Shutdown(jobsCtx); err != nil {
+		log.Printf("Webhook deliveries forced to stop: %v", err)
 	}
 	log.Println("Server gracefully stopped.")
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go_appeals/internal/repository"
+)
+
+// migrateOnStartup applies pending migrations unless AUTO_MIGRATE=false, in
+// which case it refuses to start against an outdated schema.
+func migrateOnStartup(repo repository.AppealStore) error {
+	m, ok := repo.(repository.Migratable)
+	if !ok {
+		return nil
+	}
+	migrator, err := newMigrator(m)
+	if err != nil {
+		return err
+	}
+
+	if os.Getenv("AUTO_MIGRATE") == "false" {
+		pending, err := migrator.Pending()
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migrations, run the migrate subcommand first", len(pending))
+		}
+		return nil
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		return err
+	}
+	log.Printf("Database schema is up to date (%d migrations applied).", len(applied))
+	return nil
+}
+
+// newMigrator returns the migrator of m with the lock TTL from
+// MIGRATION_LOCK_TTL.
+func newMigrator(m repository.Migratable) (*repository.Migrator, error) {
+	migrator := m.Migrator()
+	if value := os.Getenv("MIGRATION_LOCK_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("MIGRATION_LOCK_TTL must be a positive duration, got %q", value)
+		}
+		migrator.LockTTL = ttl
+	}
+	return migrator, nil
+}
+
+// runMigrate implements the "migrate" subcommand:
+//
+//	server migrate [-dry-run] up
+//	server migrate [-dry-run] [-steps N] down
+//	server migrate status
+func runMigrate(repo repository.AppealStore, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "print pending SQL without applying it")
+	steps := flags.Int("steps", 1, "number of migrations to revert with down")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	m, ok := repo.(repository.Migratable)
+	if !ok {
+		return fmt.Errorf("storage backend has no schema to migrate")
+	}
+	migrator, err := newMigrator(m)
+	if err != nil {
+		return err
+	}
+	migrator.DryRun = *dryRun
+
+	command := flags.Arg(0)
+	if command == "" {
+		command = "up"
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up()
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations.")
+		}
+	case "down":
+		if _, err := migrator.Down(*steps); err != nil {
+			return err
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-40s %s\n", status.Name, state)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", command)
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package access
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"go_appeals/internal/models"
+)
+
+//go:embed default.json
+var defaultDefinition []byte
+
+// Permission is something a role may do.
+type Permission string
+
+const (
+	CreateAppeals     Permission = "appeals.create"
+	ReadAppeals       Permission = "appeals.read"
+	ReadOwnAppeals    Permission = "appeals.read_own"
+	ProcessAppeals    Permission = "appeals.process"
+	CompleteAnyAppeal Permission = "appeals.complete_any"
+	AssignAppeals     Permission = "appeals.assign"
+	CancelAllAppeals  Permission = "appeals.cancel_all"
+	InternalNotes     Permission = "comments.internal"
+	ReadRequesters    Permission = "requesters.read"
+	ReadEvents        Permission = "events.read"
+	ReadReminders     Permission = "reminders.read"
+	ManageJobs        Permission = "jobs.manage"
+	ManageWebhooks    Permission = "webhooks.manage"
+	ManageAPIKeys     Permission = "api_keys.manage"
+	SwitchTenants     Permission = "tenants.switch"
+
+	// All grants every permission.
+	All Permission = "*"
+)
+
+// descriptions complete "... may not" in forbidden errors.
+var descriptions = map[Permission]string{
+	CreateAppeals:     "create appeals",
+	ReadAppeals:       "read other people's appeals",
+	ReadOwnAppeals:    "read appeals",
+	ProcessAppeals:    "work on appeals",
+	CompleteAnyAppeal: "complete appeals assigned to someone else",
+	AssignAppeals:     "assign appeals to others",
+	CancelAllAppeals:  "cancel every active appeal",
+	InternalNotes:     "read or write internal notes",
+	ReadRequesters:    "see requesters' contact details",
+	ReadEvents:        "follow appeal events",
+	ReadReminders:     "read everyone's reminders",
+	ManageJobs:        "manage scheduled jobs",
+	ManageWebhooks:    "manage webhooks",
+	ManageAPIKeys:     "manage API keys",
+	SwitchTenants:     "act for other tenants",
+}
+
+// Definition is the JSON form of a policy: the permissions of each role.
+// Callers without a role get DefaultRole, if set.
+type Definition struct {
+	DefaultRole models.Role                  `json:"default_role,omitempty"`
+	Roles       map[models.Role][]Permission `json:"roles"`
+}
+
+// Policy decides what each caller may do from their roles.
+type Policy struct {
+	def    Definition
+	grants map[models.Role]map[Permission]bool
+}
+
+func New(def Definition) (*Policy, error) {
+	p := &Policy{def: def, grants: make(map[models.Role]map[Permission]bool, len(def.Roles))}
+	for role, perms := range def.Roles {
+		p.grants[role] = make(map[Permission]bool, len(perms))
+		for _, perm := range perms {
+			if _, ok := descriptions[perm]; !ok && perm != All {
+				return nil, fmt.Errorf("access policy: role %s has unknown permission %q", role, perm)
+			}
+			p.grants[role][perm] = true
+		}
+	}
+	if def.DefaultRole != "" && p.grants[def.DefaultRole] == nil {
+		return nil, fmt.Errorf("access policy: default role %s is not defined", def.DefaultRole)
+	}
+	return p, nil
+}
+
+func Parse(data []byte) (*Policy, error) {
+	var def Definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("failed to parse access policy: %w", err)
+	}
+	return New(def)
+}
+
+// Load reads a JSON access policy from path.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access policy: %w", err)
+	}
+	return Parse(data)
+}
+
+// Default returns the built-in policy from default.json: requesters file
+// and follow their own appeals, operators work on them, supervisors also
+// assign, complete and bulk-cancel, and admins may do anything.
+func Default() *Policy {
+	p, err := Parse(defaultDefinition)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in access policy: %v", err))
+	}
+	return p
+}
+
+func (p *Policy) Definition() Definition {
+	return p.def
+}
+
+// ValidateRoles rejects roles the policy does not define.
+func (p *Policy) ValidateRoles(roles []models.Role) error {
+	for _, role := range roles {
+		if p.grants[role] == nil {
+			return models.NewError(models.ErrValidation, "unknown_role", "unknown role %q", role)
+		}
+	}
+	return nil
+}
+
+// Roles returns the roles principal acts with, which is the default role
+// when it has none.
+func (p *Policy) Roles(principal *models.Principal) []models.Role {
+	if len(principal.Roles) == 0 && p.def.DefaultRole != "" {
+		return []models.Role{p.def.DefaultRole}
+	}
+	return principal.Roles
+}
+
+// Allows reports whether any of principal's roles grants perm.
+func (p *Policy) Allows(principal *models.Principal, perm Permission) bool {
+	for _, role := range p.Roles(principal) {
+		if grants := p.grants[role]; grants[perm] || grants[All] {
+			return true
+		}
+	}
+	return false
+}
+
+// Check is Allows as an error that explains the refusal.
+func (p *Policy) Check(principal *models.Principal, perm Permission) error {
+	if p.Allows(principal, perm) {
+		return nil
+	}
+	return models.NewError(models.ErrForbidden, "forbidden", "%s may not %s", p.Describe(principal), descriptions[perm])
+}
+
+// Describe names a principal and its roles for error messages, e.g.
+// "jane (requester)".
+func (p *Policy) Describe(principal *models.Principal) string {
+	roles := make([]string, 0, len(principal.Roles))
+	for _, role := range p.Roles(principal) {
+		roles = append(roles, string(role))
+	}
+	sort.Strings(roles)
+	if len(roles) == 0 {
+		return principal.Subject + " (no role)"
+	}
+	return principal.Subject + " (" + strings.Join(roles, ", ") + ")"
+}
@@ -0,0 +1,81 @@
+package access
+
+import (
+	"testing"
+
+	"go_appeals/internal/models"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy := Default()
+	principal := func(roles ...models.Role) *models.Principal {
+		return &models.Principal{Subject: "jane", Roles: roles}
+	}
+
+	tests := []struct {
+		name      string
+		principal *models.Principal
+		perm      Permission
+		allowed   bool
+	}{
+		{"requester creates", principal(models.RoleRequester), CreateAppeals, true},
+		{"requester reads own", principal(models.RoleRequester), ReadOwnAppeals, true},
+		{"requester reads all", principal(models.RoleRequester), ReadAppeals, false},
+		{"no role falls back to requester", principal(), ReadOwnAppeals, true},
+		{"no role processes", principal(), ProcessAppeals, false},
+		{"operator processes", principal(models.RoleOperator), ProcessAppeals, true},
+		{"operator assigns", principal(models.RoleOperator), AssignAppeals, false},
+		{"supervisor assigns", principal(models.RoleSupervisor), AssignAppeals, true},
+		{"requester writes internal notes", principal(models.RoleRequester), InternalNotes, false},
+		{"operator writes internal notes", principal(models.RoleOperator), InternalNotes, true},
+		{"requester reads requesters", principal(models.RoleRequester), ReadRequesters, false},
+		{"operator reads requesters", principal(models.RoleOperator), ReadRequesters, true},
+		{"supervisor manages webhooks", principal(models.RoleSupervisor), ManageWebhooks, false},
+		{"roles add up", principal(models.RoleRequester, models.RoleSupervisor), CancelAllAppeals, true},
+		{"admin manages keys", principal(models.RoleAdmin), ManageAPIKeys, true},
+		{"supervisor switches tenants", principal(models.RoleSupervisor), SwitchTenants, false},
+		{"admin switches tenants", principal(models.RoleAdmin), SwitchTenants, true},
+		{"unknown role", principal("owner"), CreateAppeals, false},
+	}
+	for _, tt := range tests {
+		if allowed := policy.Allows(tt.principal, tt.perm); allowed != tt.allowed {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.allowed, allowed)
+		}
+	}
+
+	err := policy.Check(principal(models.RoleRequester), CancelAllAppeals)
+	if models.ErrorCode(err) != "forbidden" || err.Error() != "jane (requester) may not cancel every active appeal" {
+		t.Errorf("Expected an explained refusal, got %v", err)
+	}
+	if err := policy.ValidateRoles([]models.Role{models.RoleOperator, "owner"}); models.ErrorCode(err) != "unknown_role" {
+		t.Errorf("Expected unknown_role, got %v", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for _, data := range []string{
+		`{"roles": {"operator": ["appeals.fly"]}}`,
+		`{"default_role": "guest", "roles": {"operator": ["appeals.read"]}}`,
+		`{"roles": [`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Expected error for %s", data)
+		}
+	}
+
+	// Без роли по умолчанию вызывающий без ролей не может ничего.
+	policy, err := Parse([]byte(`{"roles": {"auditor": ["appeals.read", "events.read"]}}`))
+	if err != nil {
+		t.Fatalf("Failed to parse policy: %v", err)
+	}
+	if policy.Allows(&models.Principal{Subject: "jane"}, ReadOwnAppeals) {
+		t.Error("Expected no permissions without a role")
+	}
+	if !policy.Allows(&models.Principal{Subject: "jane", Roles: []models.Role{"auditor"}}, ReadEvents) {
+		t.Error("Expected the auditor to read events")
+	}
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+	"go_appeals/internal/sla"
+)
+
+// KeyPrefix starts every API key, which tells keys and JWTs apart.
+const KeyPrefix = "apk_"
+
+// touchInterval limits how often the last use of a key is written.
+const touchInterval = time.Minute
+
+type Option func(a *Authenticator)
+
+// WithJWT accepts bearer tokens as described by cfg, besides API keys.
+func WithJWT(cfg JWTConfig) Option {
+	return func(a *Authenticator) { a.jwt = &cfg }
+}
+
+// Authenticator turns credentials into a Principal and manages API keys.
+type Authenticator struct {
+	store repository.APIKeyStore
+	jwt   *JWTConfig
+	now   func() time.Time
+}
+
+func New(store repository.APIKeyStore, opts ...Option) *Authenticator {
+	a := &Authenticator{store: store, now: time.Now}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Authenticate checks an API key or a JWT.
+func (a *Authenticator) Authenticate(ctx context.Context, credentials string) (*models.Principal, error) {
+	if strings.HasPrefix(credentials, KeyPrefix) {
+		return a.authenticateKey(credentials)
+	}
+	if a.jwt == nil {
+		return nil, models.NewError(models.ErrUnauthorized, "invalid_credentials", "invalid API key")
+	}
+
+	claims, err := a.jwt.verify(credentials, a.now())
+	if err != nil {
+		return nil, err
+	}
+	return &models.Principal{Subject: claims.Subject, Name: claims.Name, Roles: claims.Roles, Tenant: claims.Tenant, Method: models.AuthJWT}, nil
+}
+
+func (a *Authenticator) authenticateKey(secret string) (*models.Principal, error) {
+	key, err := a.store.FindAPIKeyByHash(HashKey(secret))
+	if errors.Is(err, models.ErrNotFound) {
+		return nil, models.NewError(models.ErrUnauthorized, "invalid_credentials", "invalid API key")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := a.now()
+	if !key.Usable(now) {
+		return nil, models.NewError(models.ErrUnauthorized, "invalid_credentials", "API key has been revoked or has expired")
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= touchInterval {
+		if err := a.store.TouchAPIKey(key.ID, now); err != nil {
+			log.Printf("Failed to record use of API key %s: %v", key.ID, err)
+		}
+	}
+
+	return &models.Principal{Subject: key.Subject, Name: key.Name, Roles: key.Roles, Tenant: key.Tenant, Method: models.AuthAPIKey, KeyID: key.ID}, nil
+}
+
+// IssueKey creates an API key and returns it with its secret, which is not
+// stored and cannot be shown again.
+func (a *Authenticator) IssueKey(req models.CreateAPIKeyRequest) (*models.APIKey, string, error) {
+	if err := req.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	key := &models.APIKey{
+		Name:      strings.TrimSpace(req.Name),
+		Subject:   strings.TrimSpace(req.Subject),
+		Tenant:    req.Tenant,
+		Roles:     req.Roles,
+		CreatedAt: a.now(),
+	}
+	if req.ExpiresIn != "" {
+		d, err := sla.ParseDuration(req.ExpiresIn)
+		if err != nil || d <= 0 {
+			return nil, "", models.NewError(models.ErrValidation, "invalid_expires_in", "expires_in must be a positive duration such as 90d")
+		}
+		expires := key.CreatedAt.Add(d)
+		key.ExpiresAt = &expires
+	}
+
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret := KeyPrefix + hex.EncodeToString(random)
+	key.Prefix = secret[:len(KeyPrefix)+8]
+	key.Hash = HashKey(secret)
+
+	if err := a.store.CreateAPIKey(key); err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+// Keys lists the keys of tenant, or every key when tenant is empty.
+func (a *Authenticator) Keys(tenant string) ([]*models.APIKey, error) {
+	keys, err := a.store.ListAPIKeys()
+	if err != nil || tenant == "" {
+		return keys, err
+	}
+	own := make([]*models.APIKey, 0, len(keys))
+	for _, key := range keys {
+		if key.Tenant == tenant {
+			own = append(own, key)
+		}
+	}
+	return own, nil
+}
+
+// RevokeKey stops a key of tenant from authenticating; an empty tenant may
+// revoke any key. Revoking a revoked key does nothing.
+func (a *Authenticator) RevokeKey(tenant, id string) error {
+	key, err := a.store.FindAPIKey(id)
+	if err != nil {
+		return err
+	}
+	if tenant != "" && key.Tenant != tenant {
+		return models.NewError(models.ErrNotFound, "api_key_not_found", "API key %s not found", id)
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	return a.store.RevokeAPIKey(id, a.now())
+}
+
+// HashKey is how API keys are stored. Keys are long and random, so a plain
+// SHA-256 is enough.
+func HashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
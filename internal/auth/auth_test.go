@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+)
+
+var ctx = context.Background()
+
+func segment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func hs256(t *testing.T, secret string, claims map[string]any) string {
+	t.Helper()
+	unsigned := segment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + segment(t, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func rs256(t *testing.T, key *rsa.PrivateKey, claims map[string]any) string {
+	t.Helper()
+	unsigned := segment(t, map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + segment(t, claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign: %v", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWT(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	publicKey, err := ParseRSAPublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("Failed to parse key: %v", err)
+	}
+
+	a := New(repository.NewMemoryAppealRepository(), WithJWT(JWTConfig{
+		Issuer:       "https://sso.example.com",
+		Audience:     "appeals",
+		HMACSecret:   []byte("shared"),
+		RSAPublicKey: publicKey,
+	}))
+	exp := time.Now().Add(time.Hour).Unix()
+	valid := map[string]any{"sub": "jane", "name": "Jane Doe", "iss": "https://sso.example.com", "aud": []string{"crm", "appeals"}, "exp": exp, "roles": []string{"operator"}, "tenant_id": "acme"}
+	with := func(key string, value any) map[string]any {
+		claims := map[string]any{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+	none := segment(t, map[string]string{"alg": "none"}) + "." + segment(t, valid) + "."
+
+	tests := []struct {
+		name  string
+		token string
+		code  string
+	}{
+		{"HS256", hs256(t, "shared", valid), ""},
+		{"RS256", rs256(t, rsaKey, valid), ""},
+		{"wrong secret", hs256(t, "guess", valid), "invalid_token"},
+		{"alg none", none, "invalid_token"},
+		{"expired", hs256(t, "shared", with("exp", time.Now().Add(-time.Hour).Unix())), "token_expired"},
+		{"no expiry", hs256(t, "shared", with("exp", nil)), "invalid_token"},
+		{"not yet valid", hs256(t, "shared", with("nbf", time.Now().Add(time.Hour).Unix())), "invalid_token"},
+		{"wrong issuer", hs256(t, "shared", with("iss", "https://evil.example.com")), "invalid_token"},
+		{"wrong audience", hs256(t, "shared", with("aud", "crm")), "invalid_token"},
+		{"no subject", hs256(t, "shared", with("sub", nil)), "invalid_token"},
+		{"garbage", "not.a.token", "invalid_token"},
+	}
+	for _, tt := range tests {
+		principal, err := a.Authenticate(ctx, tt.token)
+		if tt.code != "" {
+			if models.ErrorCode(err) != tt.code {
+				t.Errorf("%s: expected %s, got %v", tt.name, tt.code, err)
+			}
+			continue
+		}
+		if err != nil || principal.Subject != "jane" || principal.Name != "Jane Doe" || principal.Method != models.AuthJWT ||
+			len(principal.Roles) != 1 || principal.Roles[0] != models.RoleOperator || principal.Tenant != "acme" {
+			t.Errorf("%s: expected jane, got %+v, %v", tt.name, principal, err)
+		}
+	}
+
+	// Без настроенного JWT принимаются только ключи.
+	keysOnly := New(repository.NewMemoryAppealRepository())
+	if _, err := keysOnly.Authenticate(ctx, hs256(t, "shared", valid)); models.ErrorCode(err) != "invalid_credentials" {
+		t.Errorf("Expected tokens to be refused, got %v", err)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	store := repository.NewMemoryAppealRepository()
+	a := New(store)
+
+	if _, _, err := a.IssueKey(models.CreateAPIKeyRequest{Name: "ci"}); models.ErrorCode(err) != "subject_required" {
+		t.Errorf("Expected subject_required, got %v", err)
+	}
+	if _, _, err := a.IssueKey(models.CreateAPIKeyRequest{Name: "ci", Subject: "bot", ExpiresIn: "soon"}); models.ErrorCode(err) != "invalid_expires_in" {
+		t.Errorf("Expected invalid_expires_in, got %v", err)
+	}
+
+	key, secret, err := a.IssueKey(models.CreateAPIKeyRequest{Name: "ci", Subject: "ci-bot", Tenant: "acme", Roles: []models.Role{models.RoleOperator}, ExpiresIn: "90d"})
+	if err != nil {
+		t.Fatalf("Failed to issue key: %v", err)
+	}
+	if len(secret) != len(KeyPrefix)+48 || key.Prefix != secret[:12] || key.Hash == secret || key.ExpiresAt == nil {
+		t.Errorf("Unexpected key %+v for secret %q", key, secret)
+	}
+
+	principal, err := a.Authenticate(ctx, secret)
+	if err != nil || principal.Subject != "ci-bot" || principal.Method != models.AuthAPIKey || principal.KeyID != key.ID ||
+		len(principal.Roles) != 1 || principal.Roles[0] != models.RoleOperator || principal.Tenant != "acme" {
+		t.Fatalf("Expected the key to authenticate, got %+v, %v", principal, err)
+	}
+	if stored, _ := store.FindAPIKey(key.ID); stored.LastUsedAt == nil {
+		t.Errorf("Expected the use to be recorded")
+	}
+	if _, err := a.Authenticate(ctx, secret+"0"); models.ErrorCode(err) != "invalid_credentials" {
+		t.Errorf("Expected an unknown key to be refused, got %v", err)
+	}
+
+	// Ключи другой организации не видны и не отзываются.
+	if keys, err := a.Keys("globex"); err != nil || len(keys) != 0 {
+		t.Errorf("Expected no keys for another tenant, got %+v, %v", keys, err)
+	}
+	if keys, err := a.Keys("acme"); err != nil || len(keys) != 1 {
+		t.Errorf("Expected the tenant's key, got %+v, %v", keys, err)
+	}
+	if err := a.RevokeKey("globex", key.ID); models.ErrorCode(err) != "api_key_not_found" {
+		t.Errorf("Expected api_key_not_found, got %v", err)
+	}
+
+	if err := a.RevokeKey("acme", key.ID); err != nil {
+		t.Fatalf("Failed to revoke key: %v", err)
+	}
+	if err := a.RevokeKey("", key.ID); err != nil {
+		t.Errorf("Expected revoking twice to succeed, got %v", err)
+	}
+	if _, err := a.Authenticate(ctx, secret); models.ErrorCode(err) != "invalid_credentials" {
+		t.Errorf("Expected a revoked key to be refused, got %v", err)
+	}
+	if err := a.RevokeKey("", "missing"); models.ErrorCode(err) != "api_key_not_found" {
+		t.Errorf("Expected api_key_not_found, got %v", err)
+	}
+
+	expiring, secret, err := a.IssueKey(models.CreateAPIKeyRequest{Name: "temp", Subject: "bot", ExpiresIn: "1h"})
+	if err != nil {
+		t.Fatalf("Failed to issue key: %v", err)
+	}
+	a.now = func() time.Time { return expiring.ExpiresAt.Add(time.Second) }
+	if _, err := a.Authenticate(ctx, secret); models.ErrorCode(err) != "invalid_credentials" {
+		t.Errorf("Expected an expired key to be refused, got %v", err)
+	}
+}
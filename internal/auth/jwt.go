@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_appeals/internal/models"
+)
+
+// JWTConfig describes the bearer tokens that are accepted: HS256 tokens
+// signed with HMACSecret and RS256 tokens signed with the private half of
+// RSAPublicKey. Issuer and Audience are checked when set. Tokens must carry
+// sub and exp.
+type JWTConfig struct {
+	Issuer       string
+	Audience     string
+	HMACSecret   []byte
+	RSAPublicKey *rsa.PublicKey
+	// Leeway allows for clock skew when checking exp and nbf.
+	Leeway time.Duration
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+}
+
+type jwtClaims struct {
+	Issuer    string          `json:"iss"`
+	Subject   string          `json:"sub"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+	Name      string          `json:"name"`
+	Roles     []models.Role   `json:"roles"`
+	Tenant    string          `json:"tenant_id"`
+}
+
+func invalidToken(format string, args ...any) *models.Error {
+	return models.NewError(models.ErrUnauthorized, "invalid_token", "invalid bearer token: "+format, args...)
+}
+
+// verify checks a compact JWS and returns its claims.
+func (cfg *JWTConfig) verify(token string, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalidToken("malformed")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, invalidToken("malformed header")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalidToken("malformed signature")
+	}
+
+	switch header.Algorithm {
+	case "HS256":
+		if len(cfg.HMACSecret) == 0 {
+			return nil, invalidToken("HS256 tokens are not accepted")
+		}
+		mac := hmac.New(sha256.New, cfg.HMACSecret)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return nil, invalidToken("bad signature")
+		}
+	case "RS256":
+		if cfg.RSAPublicKey == nil {
+			return nil, invalidToken("RS256 tokens are not accepted")
+		}
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(cfg.RSAPublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, invalidToken("bad signature")
+		}
+	default:
+		return nil, invalidToken("unsupported algorithm %q", header.Algorithm)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, invalidToken("malformed claims")
+	}
+	if claims.Subject == "" {
+		return nil, invalidToken("no subject")
+	}
+	if claims.ExpiresAt == nil {
+		return nil, invalidToken("no expiry")
+	}
+	if now.After(numericDate(*claims.ExpiresAt).Add(cfg.Leeway)) {
+		return nil, models.NewError(models.ErrUnauthorized, "token_expired", "bearer token has expired")
+	}
+	if claims.NotBefore != nil && now.Add(cfg.Leeway).Before(numericDate(*claims.NotBefore)) {
+		return nil, invalidToken("not valid yet")
+	}
+	if cfg.Issuer != "" && claims.Issuer != cfg.Issuer {
+		return nil, invalidToken("wrong issuer")
+	}
+	if cfg.Audience != "" && !hasAudience(claims.Audience, cfg.Audience) {
+		return nil, invalidToken("wrong audience")
+	}
+	return &claims, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func numericDate(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// hasAudience accepts aud as a single string or a list.
+func hasAudience(raw json.RawMessage, want string) bool {
+	var single string
+	if json.Unmarshal(raw, &single) == nil {
+		return single == want
+	}
+	var list []string
+	if json.Unmarshal(raw, &list) == nil {
+		for _, aud := range list {
+			if aud == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ParseRSAPublicKey reads a PEM-encoded RSA public key, in PKIX or PKCS #1
+// form, or the key of a PEM certificate.
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+			key = cert.PublicKey
+		}
+	default:
+		return nil, fmt.Errorf("unexpected PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA public key")
+	}
+	return rsaKey, nil
+}
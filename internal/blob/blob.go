@@ -0,0 +1,168 @@
+// Package blob keeps file contents under their SHA-256, so that a file
+// uploaded twice is stored once.
+package blob
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go_appeals/internal/models"
+)
+
+// Store keeps immutable contents under their key, the hex SHA-256 of the
+// content.
+type Store interface {
+	// Put stores the content of r and returns its key and size. Putting
+	// content that is already stored only returns its key. If r fails,
+	// nothing is stored and its error is returned.
+	Put(r io.Reader) (key string, size int64, err error)
+	// Open returns the content stored under key.
+	Open(key string) (io.ReadSeekCloser, error)
+	// Delete removes the content stored under key. Deleting content that
+	// is not stored is not an error.
+	Delete(key string) error
+}
+
+func notFound(key string) error {
+	return models.NewError(models.ErrNotFound, "blob_not_found", "blob %s not found", key)
+}
+
+// validKey guards paths built from keys.
+func validKey(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
+
+// Local keeps contents as files under a directory, spread over
+// subdirectories by the first two characters of the key.
+type Local struct {
+	dir string
+}
+
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &Local{dir: dir}, nil
+}
+
+func (l *Local) path(key string) string {
+	return filepath.Join(l.dir, key[:2], key)
+}
+
+func (l *Local) Put(r io.Reader) (string, int64, error) {
+	// Written to a temporary file first: the key is only known at the end,
+	// and a reader would never see a half-written blob.
+	tmp, err := os.CreateTemp(l.dir, ".upload-*")
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err != nil {
+		tmp.Close()
+		return "", 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", 0, fmt.Errorf("failed to write blob: %w", err)
+	}
+
+	key := hex.EncodeToString(hash.Sum(nil))
+	path := l.path(key)
+	if _, err := os.Stat(path); err == nil {
+		return key, size, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return "", 0, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", 0, fmt.Errorf("failed to store blob: %w", err)
+	}
+	return key, size, nil
+}
+
+func (l *Local) Open(key string) (io.ReadSeekCloser, error) {
+	if !validKey(key) {
+		return nil, notFound(key)
+	}
+	f, err := os.Open(l.path(key))
+	if os.IsNotExist(err) {
+		return nil, notFound(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+	return f, nil
+}
+
+func (l *Local) Delete(key string) error {
+	if !validKey(key) {
+		return nil
+	}
+	if err := os.Remove(l.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete blob: %w", err)
+	}
+	return nil
+}
+
+// Memory keeps contents in process memory, for tests and throwaway
+// deployments.
+type Memory struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+func NewMemory() *Memory {
+	return &Memory{blobs: make(map[string][]byte)}
+}
+
+func (m *Memory) Put(r io.Reader) (string, int64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", 0, err
+	}
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:])
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.blobs[key]; !ok {
+		m.blobs[key] = data
+	}
+	return key, int64(len(data)), nil
+}
+
+func (m *Memory) Open(key string) (io.ReadSeekCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	data, ok := m.blobs[key]
+	if !ok {
+		return nil, notFound(key)
+	}
+	return nopCloser{bytes.NewReader(data)}, nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.blobs, key)
+	return nil
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error { return nil }
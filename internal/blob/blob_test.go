@@ -0,0 +1,89 @@
+package blob
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go_appeals/internal/models"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestStores(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	local, err := NewLocal(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	for name, store := range map[string]Store{"local": local, "memory": NewMemory()} {
+		key, size, err := store.Put(strings.NewReader("scanned passport"))
+		if err != nil {
+			t.Fatalf("%s: failed to put: %v", name, err)
+		}
+		// sha256("scanned passport")
+		if size != 16 || len(key) != 64 {
+			t.Errorf("%s: unexpected key %q and size %d", name, key, size)
+		}
+		again, _, err := store.Put(strings.NewReader("scanned passport"))
+		if err != nil || again != key {
+			t.Errorf("%s: expected the same key for the same content, got %q, %v", name, again, err)
+		}
+
+		f, err := store.Open(key)
+		if err != nil {
+			t.Fatalf("%s: failed to open: %v", name, err)
+		}
+		if _, err := f.Seek(8, io.SeekStart); err != nil {
+			t.Fatalf("%s: failed to seek: %v", name, err)
+		}
+		data, _ := io.ReadAll(f)
+		f.Close()
+		if string(data) != "passport" {
+			t.Errorf("%s: expected the content from the offset, got %q", name, data)
+		}
+
+		if _, _, err := store.Put(io.MultiReader(strings.NewReader("half"), failingReader{})); err == nil {
+			t.Errorf("%s: expected a failing reader to fail the put", name)
+		}
+		for _, missing := range []string{strings.Repeat("0", 64), "../../etc/passwd"} {
+			if _, err := store.Open(missing); models.ErrorCode(err) != "blob_not_found" {
+				t.Errorf("%s: expected blob_not_found for %q, got %v", name, missing, err)
+			}
+			if err := store.Delete(missing); err != nil {
+				t.Errorf("%s: expected deleting %q to do nothing, got %v", name, missing, err)
+			}
+		}
+
+		draft, _, err := store.Put(strings.NewReader("draft"))
+		if err != nil {
+			t.Fatalf("%s: failed to put: %v", name, err)
+		}
+		if err := store.Delete(draft); err != nil {
+			t.Errorf("%s: failed to delete: %v", name, err)
+		}
+		if _, err := store.Open(draft); models.ErrorCode(err) != "blob_not_found" {
+			t.Errorf("%s: expected the deleted blob to be gone, got %v", name, err)
+		}
+	}
+
+	// Одинаковое содержимое хранится одним файлом, от неудачной записи ничего не остаётся.
+	var files []string
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if len(files) != 1 {
+		t.Errorf("Expected a single file, got %v", files)
+	}
+}
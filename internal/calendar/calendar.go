@@ -0,0 +1,305 @@
+package calendar
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	// Deployments often ship without a zoneinfo database.
+	_ "time/tzdata"
+)
+
+//go:embed default.json
+var defaultDefinition []byte
+
+const dateLayout = "2006-01-02"
+
+// Definition is the JSON form of a calendar. Hours maps lower-case weekday
+// names to working periods such as "09:00-13:00"; days that are missing
+// are days off. HolidaysFile is read relative to the definition file.
+type Definition struct {
+	TimeZone     string              `json:"time_zone"`
+	Hours        map[string][]string `json:"hours"`
+	Holidays     []string            `json:"holidays,omitempty"`
+	HolidaysFile string              `json:"holidays_file,omitempty"`
+}
+
+// period is a working interval in minutes since midnight.
+type period struct {
+	start, end int
+}
+
+// Calendar measures working time: the hours of each weekday, minus
+// holidays, in one time zone.
+type Calendar struct {
+	def      Definition
+	loc      *time.Location
+	hours    [7][]period
+	holidays map[string]bool
+}
+
+func New(def Definition) (*Calendar, error) {
+	c := &Calendar{def: def, holidays: make(map[string]bool)}
+
+	var err error
+	if c.loc, err = time.LoadLocation(def.TimeZone); err != nil {
+		return nil, fmt.Errorf("calendar time zone: %w", err)
+	}
+
+	weekdays := make(map[string]time.Weekday, 7)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		weekdays[strings.ToLower(d.String())] = d
+	}
+	working := false
+	for name, periods := range def.Hours {
+		day, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("calendar hours for unknown weekday %s", name)
+		}
+		for _, value := range periods {
+			p, err := parsePeriod(value)
+			if err != nil {
+				return nil, fmt.Errorf("calendar hours for %s: %w", name, err)
+			}
+			c.hours[day] = append(c.hours[day], p)
+		}
+		sort.Slice(c.hours[day], func(i, j int) bool { return c.hours[day][i].start < c.hours[day][j].start })
+		for i := 1; i < len(c.hours[day]); i++ {
+			if c.hours[day][i].start < c.hours[day][i-1].end {
+				return nil, fmt.Errorf("calendar hours for %s overlap", name)
+			}
+		}
+		working = working || len(c.hours[day]) > 0
+	}
+	if !working {
+		return nil, fmt.Errorf("calendar has no working hours")
+	}
+
+	for _, value := range def.Holidays {
+		if err := c.addHoliday(value); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func Parse(data []byte) (*Calendar, error) {
+	var def Definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("failed to parse calendar definition: %w", err)
+	}
+	return New(def)
+}
+
+// Load reads a JSON calendar definition from path, along with the holidays
+// file it refers to.
+func Load(path string) (*Calendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar definition: %w", err)
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.def.HolidaysFile != "" {
+		holidays := c.def.HolidaysFile
+		if !filepath.IsAbs(holidays) {
+			holidays = filepath.Join(filepath.Dir(path), holidays)
+		}
+		if err := c.LoadHolidays(holidays); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Default returns the built-in calendar from default.json: 9:00–18:00 Moscow
+// time on weekdays, no holidays.
+func Default() *Calendar {
+	c, err := Parse(defaultDefinition)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in calendar: %v", err))
+	}
+	return c
+}
+
+// AlwaysOpen returns a calendar in which all time is working time.
+func AlwaysOpen() *Calendar {
+	hours := make(map[string][]string, 7)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		hours[strings.ToLower(d.String())] = []string{"00:00-24:00"}
+	}
+	c, err := New(Definition{TimeZone: "UTC", Hours: hours})
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// LoadHolidays adds the dates listed in a file, one YYYY-MM-DD per line.
+// Anything after the date is a comment, as are lines starting with #.
+func (c *Calendar) LoadHolidays(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open holidays file: %w", err)
+	}
+	defer f.Close()
+
+	if err := c.readHolidays(f); err != nil {
+		return fmt.Errorf("holidays file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Calendar) readHolidays(r io.Reader) error {
+	lines := bufio.NewScanner(r)
+	for n := 1; lines.Scan(); n++ {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := c.addHoliday(strings.Fields(line)[0]); err != nil {
+			return fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	return lines.Err()
+}
+
+func (c *Calendar) addHoliday(value string) error {
+	day, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return fmt.Errorf("invalid holiday %q, use YYYY-MM-DD", value)
+	}
+	c.holidays[day.Format(dateLayout)] = true
+	return nil
+}
+
+func (c *Calendar) Definition() Definition {
+	return c.def
+}
+
+func (c *Calendar) Location() *time.Location {
+	return c.loc
+}
+
+func (c *Calendar) IsHoliday(t time.Time) bool {
+	return c.holidays[t.In(c.loc).Format(dateLayout)]
+}
+
+// IsWorkingTime reports whether t falls within working hours.
+func (c *Calendar) IsWorkingTime(t time.Time) bool {
+	for _, span := range c.spans(t) {
+		if !t.Before(span[0]) && t.Before(span[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add returns the moment d of working time after t.
+func (c *Calendar) Add(t time.Time, d time.Duration) time.Time {
+	if d <= 0 {
+		return t
+	}
+	for day := t; ; day = nextDay(day, c.loc) {
+		for _, span := range c.spans(day) {
+			if !span[1].After(t) {
+				continue
+			}
+			from := span[0]
+			if from.Before(t) {
+				from = t
+			}
+			available := span[1].Sub(from)
+			if d <= available {
+				return from.Add(d)
+			}
+			d -= available
+		}
+	}
+}
+
+// Elapsed returns the working time between from and to.
+func (c *Calendar) Elapsed(from, to time.Time) time.Duration {
+	var elapsed time.Duration
+	for day := from; day.Before(to); day = nextDay(day, c.loc) {
+		for _, span := range c.spans(day) {
+			start, end := span[0], span[1]
+			if start.Before(from) {
+				start = from
+			}
+			if end.After(to) {
+				end = to
+			}
+			if end.After(start) {
+				elapsed += end.Sub(start)
+			}
+		}
+	}
+	return elapsed
+}
+
+// spans returns the working periods of the day t falls on, as moments.
+func (c *Calendar) spans(t time.Time) [][2]time.Time {
+	t = t.In(c.loc)
+	if c.holidays[t.Format(dateLayout)] {
+		return nil
+	}
+	y, m, d := t.Date()
+	spans := make([][2]time.Time, 0, len(c.hours[t.Weekday()]))
+	for _, p := range c.hours[t.Weekday()] {
+		spans = append(spans, [2]time.Time{
+			time.Date(y, m, d, 0, p.start, 0, 0, c.loc),
+			time.Date(y, m, d, 0, p.end, 0, 0, c.loc),
+		})
+	}
+	return spans
+}
+
+// nextDay returns midnight of the day after t.
+func nextDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+}
+
+func parsePeriod(value string) (period, error) {
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return period{}, fmt.Errorf("invalid period %q, use HH:MM-HH:MM", value)
+	}
+	start, err := parseClock(strings.TrimSpace(from))
+	if err != nil {
+		return period{}, err
+	}
+	end, err := parseClock(strings.TrimSpace(to))
+	if err != nil {
+		return period{}, err
+	}
+	if end <= start {
+		return period{}, fmt.Errorf("invalid period %q, it must end after it starts", value)
+	}
+	return period{start: start, end: end}, nil
+}
+
+// parseClock reads HH:MM as minutes since midnight; 24:00 is the end of the
+// day.
+func parseClock(value string) (int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(value, "%d:%d", &h, &m); err != nil || len(value) != 5 {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", value)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return h*60 + m, nil
+}
@@ -0,0 +1,130 @@
+package calendar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCalendar(t *testing.T) *Calendar {
+	t.Helper()
+	c, err := Parse([]byte(`{
+		"time_zone": "Europe/Moscow",
+		"hours": {
+			"monday": ["09:00-13:00", "14:00-18:00"],
+			"tuesday": ["09:00-18:00"],
+			"wednesday": ["09:00-18:00"],
+			"thursday": ["09:00-18:00"],
+			"friday": ["09:00-16:00"]
+		},
+		"holidays": ["2026-01-01"]
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to parse calendar: %v", err)
+	}
+	return c
+}
+
+func TestAdd(t *testing.T) {
+	t.Parallel()
+
+	c := newTestCalendar(t)
+	msk := c.Location()
+	at := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, msk)
+	}
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		add      time.Duration
+		expected time.Time
+	}{
+		{"WithinDay", at(3, 3, 10, 0), 2 * time.Hour, at(3, 3, 12, 0)},
+		{"OvernightCarry", at(3, 3, 17, 0), 2 * time.Hour, at(3, 4, 10, 0)},
+		{"BeforeOpening", at(3, 3, 6, 0), time.Hour, at(3, 3, 10, 0)},
+		// Пятница короче, а выходные не считаются.
+		{"OverWeekend", at(3, 6, 15, 0), 2 * time.Hour, at(3, 9, 10, 0)},
+		{"LunchBreak", at(3, 2, 12, 30), time.Hour, at(3, 2, 14, 30)},
+		{"Holiday", at(1, 1, 10, 0), time.Hour, at(1, 2, 10, 0)},
+		{"ExactEnd", at(3, 3, 17, 0), time.Hour, at(3, 3, 18, 0)},
+		{"OtherZone", at(3, 3, 10, 0).UTC(), time.Hour, at(3, 3, 11, 0)},
+		{"Zero", at(3, 7, 10, 0), 0, at(3, 7, 10, 0)},
+	}
+	for _, tt := range tests {
+		if got := c.Add(tt.start, tt.add); !got.Equal(tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got.In(msk))
+		}
+		if tt.add > 0 {
+			if got := c.Elapsed(tt.start, tt.expected); got != tt.add {
+				t.Errorf("%s: expected %v elapsed, got %v", tt.name, tt.add, got)
+			}
+		}
+	}
+
+	if got := c.Elapsed(at(3, 2, 0, 0), at(3, 9, 0, 0)); got != 42*time.Hour {
+		t.Errorf("Expected a 42h working week, got %v", got)
+	}
+	if got := c.Elapsed(at(3, 7, 10, 0), at(3, 8, 20, 0)); got != 0 {
+		t.Errorf("Expected no working time over the weekend, got %v", got)
+	}
+	if !c.IsWorkingTime(at(3, 2, 9, 0)) || c.IsWorkingTime(at(3, 2, 13, 30)) || c.IsWorkingTime(at(1, 1, 10, 0)) {
+		t.Errorf("IsWorkingTime disagrees with the configured hours")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	def := `{"time_zone": "UTC", "hours": {"monday": ["09:00-18:00"]}, "holidays_file": "holidays.txt"}`
+	holidays := "# Праздники\n2026-03-09 Перенос\n\n2026-05-01\n"
+	if err := os.WriteFile(filepath.Join(dir, "calendar.json"), []byte(def), 0o644); err != nil {
+		t.Fatalf("Failed to write calendar: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "holidays.txt"), []byte(holidays), 0o644); err != nil {
+		t.Fatalf("Failed to write holidays: %v", err)
+	}
+
+	c, err := Load(filepath.Join(dir, "calendar.json"))
+	if err != nil {
+		t.Fatalf("Failed to load calendar: %v", err)
+	}
+	if !c.IsHoliday(time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC)) || !c.IsHoliday(time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected holidays from the file to be loaded")
+	}
+
+	if err := c.readHolidays(strings.NewReader("2026-13-01\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Expected an error pointing at the bad line, got %v", err)
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	t.Parallel()
+
+	invalid := map[string]Definition{
+		"unknown zone":     {TimeZone: "Mars/Olympus", Hours: map[string][]string{"monday": {"09:00-18:00"}}},
+		"no hours":         {TimeZone: "UTC"},
+		"unknown weekday":  {TimeZone: "UTC", Hours: map[string][]string{"funday": {"09:00-18:00"}}},
+		"backwards period": {TimeZone: "UTC", Hours: map[string][]string{"monday": {"18:00-09:00"}}},
+		"bad clock":        {TimeZone: "UTC", Hours: map[string][]string{"monday": {"9-18"}}},
+		"overlap":          {TimeZone: "UTC", Hours: map[string][]string{"monday": {"09:00-13:00", "12:00-18:00"}}},
+		"bad holiday":      {TimeZone: "UTC", Hours: map[string][]string{"monday": {"09:00-18:00"}}, Holidays: []string{"01.01.2026"}},
+	}
+	for name, def := range invalid {
+		if _, err := New(def); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+
+	if Default().Location().String() != "Europe/Moscow" {
+		t.Errorf("Expected the built-in calendar to use Moscow time")
+	}
+	always := AlwaysOpen()
+	start := time.Date(2026, 3, 7, 23, 0, 0, 0, time.UTC)
+	if got := always.Add(start, 3*time.Hour); !got.Equal(start.Add(3 * time.Hour)) {
+		t.Errorf("Expected AlwaysOpen to count wall-clock time, got %v", got)
+	}
+}
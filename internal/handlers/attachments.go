@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"strconv"
+	"strings"
+
+	"go_appeals/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// attachmentField is the multipart field files are sent in.
+const attachmentField = "attachments"
+
+func (h *Handlers) GetAppealAttachments(c *fiber.Ctx) error {
+	attachments, err := h.Service.GetAttachments(requestContext(c), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"attachments": attachments,
+	})
+}
+
+// CreateAppealAttachments attaches the files of a multipart/form-data
+// request to an appeal.
+func (h *Handlers) CreateAppealAttachments(c *fiber.Ctx) error {
+	uploads, closeUploads, err := multipartUploads(c)
+	if err != nil {
+		return err
+	}
+	defer closeUploads()
+
+	attachments, err := h.Service.AddAttachments(requestContext(c), c.Params("id"), uploads)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"attachments": attachments,
+	})
+}
+
+// DownloadAppealAttachment streams an attached file. A single byte range
+// is honoured, so large files can be resumed; several ranges get the whole
+// file.
+func (h *Handlers) DownloadAppealAttachment(c *fiber.Ctx) error {
+	attachment, content, err := h.Service.OpenAttachment(requestContext(c), c.Params("id"), c.Params("attachment"))
+	if err != nil {
+		return err
+	}
+
+	c.Attachment(attachment.FileName)
+	c.Set(fiber.HeaderContentType, attachment.ContentType)
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderETag, `"`+attachment.SHA256+`"`)
+
+	size := int(attachment.Size)
+	if c.Get(fiber.HeaderRange) == "" {
+		return c.SendStream(content, size)
+	}
+	ranges, err := c.Range(size)
+	if errors.Is(err, fiber.ErrRangeUnsatisfiable) {
+		content.Close()
+		c.Set(fiber.HeaderContentRange, "bytes */"+strconv.Itoa(size))
+		return fiber.NewError(fiber.StatusRequestedRangeNotSatisfiable, "requested range is outside the file")
+	}
+	if err != nil || ranges.Type != "bytes" || len(ranges.Ranges) != 1 {
+		return c.SendStream(content, size)
+	}
+
+	start, end := ranges.Ranges[0].Start, ranges.Ranges[0].End
+	if _, err := content.Seek(int64(start), io.SeekStart); err != nil {
+		content.Close()
+		return err
+	}
+	length := end - start + 1
+	c.Status(fiber.StatusPartialContent)
+	c.Set(fiber.HeaderContentRange, "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(end)+"/"+strconv.Itoa(size))
+	// The stream is closed once it has been sent.
+	return c.SendStream(struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(content, int64(length)), content}, length)
+}
+
+// UploadBodyLimit lets requests that upload attachments have bodies of up
+// to max bytes, while every other request keeps the app's BodyLimit. Bodies
+// are read before routing, so uploads are told apart by their request line
+// and content type.
+func UploadBodyLimit(app *fiber.App, max int) {
+	app.Server().HeaderReceived = func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+		if !header.IsPost() || !strings.HasPrefix(string(header.ContentType()), fiber.MIMEMultipartForm) {
+			return fasthttp.RequestConfig{}
+		}
+		path, _, _ := strings.Cut(strings.ToLower(string(header.RequestURI())), "?")
+		path = strings.TrimSuffix(path, "/")
+		id, uploads := strings.CutSuffix(strings.TrimPrefix(path, "/appeals/"), "/attachments")
+		if path == "/appeals" || (uploads && id != "" && !strings.Contains(id, "/")) {
+			return fasthttp.RequestConfig{MaxRequestBodySize: max}
+		}
+		return fasthttp.RequestConfig{}
+	}
+}
+
+func isMultipart(c *fiber.Ctx) bool {
+	return strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm)
+}
+
+// multipartUploads opens the files of a multipart/form-data request. The
+// returned func closes them.
+func multipartUploads(c *fiber.Ctx) ([]models.AttachmentUpload, func(), error) {
+	if !isMultipart(c) {
+		return nil, nil, badRequest("Expected multipart/form-data")
+	}
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, nil, badRequest("Cannot parse multipart form")
+	}
+
+	var files []multipart.File
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	uploads := make([]models.AttachmentUpload, 0, len(form.File[attachmentField]))
+	for _, header := range form.File[attachmentField] {
+		f, err := header.Open()
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		files = append(files, f)
+		uploads = append(uploads, models.AttachmentUpload{FileName: header.Filename, Content: f})
+	}
+	return uploads, closeAll, nil
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"strings"
+
+	"go_appeals/internal/access"
+	"go_appeals/internal/models"
+	"go_appeals/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HeaderAPIKey carries an API key, as an alternative to a bearer token.
+const HeaderAPIKey = "X-API-Key"
+
+// principalLocal is where Authenticate leaves the caller for requestContext.
+const principalLocal = "principal"
+
+// Authenticate is middleware that lets through only requests with a valid
+// API key or JWT, given as "Authorization: Bearer ..." or in X-API-Key.
+func (h *Handlers) Authenticate(c *fiber.Ctx) error {
+	credentials := c.Get(HeaderAPIKey)
+	if credentials == "" {
+		header := c.Get(fiber.HeaderAuthorization)
+		if scheme, token, ok := strings.Cut(header, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			credentials = strings.TrimSpace(token)
+		}
+	}
+	if credentials == "" {
+		return models.NewError(models.ErrUnauthorized, "credentials_required", "an API key or bearer token is required")
+	}
+
+	principal, err := h.Auth.Authenticate(c.UserContext(), credentials)
+	if err != nil {
+		return err
+	}
+	c.Locals(principalLocal, principal)
+	return c.Next()
+}
+
+// GetMe returns the authenticated caller.
+func (h *Handlers) GetMe(c *fiber.Ctx) error {
+	principal := services.PrincipalFromContext(requestContext(c))
+	if principal == nil {
+		return models.NewError(models.ErrUnauthorized, "credentials_required", "an API key or bearer token is required")
+	}
+	return c.JSON(fiber.Map{
+		"principal": principal,
+	})
+}
+
+// CreateAPIKey issues a key belonging to the request's tenant. The response
+// is the only place its secret is shown.
+func (h *Handlers) CreateAPIKey(c *fiber.Ctx) error {
+	if err := h.authorize(c, access.ManageAPIKeys); err != nil {
+		return err
+	}
+	var req models.CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return badRequest("Cannot parse JSON")
+	}
+
+	if err := h.Service.Access().ValidateRoles(req.Roles); err != nil {
+		return err
+	}
+
+	req.Tenant = tenantOf(c)
+	key, secret, err := h.Auth.IssueKey(req)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"api_key": key,
+		"key":     secret,
+	})
+}
+
+func (h *Handlers) GetAPIKeys(c *fiber.Ctx) error {
+	if err := h.authorize(c, access.ManageAPIKeys); err != nil {
+		return err
+	}
+	keys, err := h.Auth.Keys(tenantOf(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"api_keys": keys,
+	})
+}
+
+func (h *Handlers) RevokeAPIKey(c *fiber.Ctx) error {
+	if err := h.authorize(c, access.ManageAPIKeys); err != nil {
+		return err
+	}
+	if err := h.Auth.RevokeKey(tenantOf(c), c.Params("id")); err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"message": "API key revoked successfully",
+	})
+}
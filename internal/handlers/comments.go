@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"strconv"
+
+	"go_appeals/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetAppealComments returns the thread of an appeal. Internal notes are
+// included only for staff.
+func (h *Handlers) GetAppealComments(c *fiber.Ctx) error {
+	comments, err := h.Service.GetComments(requestContext(c), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"comments": comments,
+	})
+}
+
+func (h *Handlers) CreateAppealComment(c *fiber.Ctx) error {
+	var req models.CreateCommentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return badRequest("Cannot parse JSON")
+	}
+
+	comment, err := h.Service.AddComment(requestContext(c), c.Params("id"), req)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"comment": comment,
+	})
+}
+
+// EditAppealComment changes the text of the caller's own comment.
+func (h *Handlers) EditAppealComment(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("comment"), 10, 64)
+	if err != nil {
+		return models.NewError(models.ErrNotFound, "comment_not_found", "comment %s not found", c.Params("comment"))
+	}
+	var req models.UpdateCommentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return badRequest("Cannot parse JSON")
+	}
+
+	comment, err := h.Service.EditComment(requestContext(c), c.Params("id"), id, req)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"comment": comment,
+	})
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"go_appeals/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 error body. Code is the stable, machine-readable
+// identifier clients should switch on.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+}
+
+// ErrorHandler is the Fiber error handler: handlers return errors and this
+// turns them into problem+json responses.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	problem := problemFor(err)
+	problem.Instance = c.OriginalURL()
+
+	if problem.Status >= fiber.StatusInternalServerError {
+		log.Printf("%s %s: %v", c.Method(), c.OriginalURL(), err)
+	}
+
+	if problem.Status == fiber.StatusUnauthorized {
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="appeals"`)
+	}
+	c.Set(fiber.HeaderContentType, problemContentType)
+	return c.Status(problem.Status).JSON(problem, problemContentType)
+}
+
+func problemFor(err error) Problem {
+	status, code, detail := fiber.StatusInternalServerError, "internal_error", "Internal server error"
+
+	var fiberErr *fiber.Error
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		status, code, detail = fiber.StatusNotFound, "not_found", err.Error()
+	case errors.Is(err, models.ErrInvalidTransition):
+		status, code, detail = fiber.StatusConflict, "invalid_transition", err.Error()
+	case errors.Is(err, models.ErrConflict):
+		status, code, detail = fiber.StatusConflict, "conflict", err.Error()
+	case errors.Is(err, models.ErrValidation):
+		status, code, detail = fiber.StatusUnprocessableEntity, "validation_failed", err.Error()
+	case errors.Is(err, models.ErrUnauthorized):
+		status, code, detail = fiber.StatusUnauthorized, "unauthorized", err.Error()
+	case errors.Is(err, models.ErrForbidden):
+		status, code, detail = fiber.StatusForbidden, "forbidden", err.Error()
+	case errors.Is(err, models.ErrTooLarge):
+		status, code, detail = fiber.StatusRequestEntityTooLarge, "too_large", err.Error()
+	case errors.As(err, &fiberErr):
+		status, detail = fiberErr.Code, fiberErr.Message
+		code = strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+	}
+
+	if domainCode := models.ErrorCode(err); domainCode != "" {
+		code = domainCode
+	}
+
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// badRequest reports a request the server could not parse at all.
+func badRequest(message string) error {
+	return fiber.NewError(fiber.StatusBadRequest, message)
+}
+
+func validationError(code, message string) error {
+	return &models.Error{Kind: models.ErrValidation, Code: code, Message: message}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"go_appeals/internal/access"
+	"go_appeals/internal/models"
+	"go_appeals/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const heartbeatInterval = 15 * time.Second
+
+// StreamAppealEvents handles GET /appeals/events?status=InProgress&assignee=jane,
+// a Server-Sent Events stream of appeal events. Every message carries the
+// event sequence as its id; a client that reconnects with it in
+// Last-Event-ID first receives the events it missed, read from the outbox.
+// Without Last-Event-ID the stream starts with the next event.
+//
+// A client that falls too far behind is disconnected, and catches up the
+// same way when it reconnects.
+func (h *Handlers) StreamAppealEvents(c *fiber.Ctx) error {
+	// Checked up front: once streaming starts the status can no longer
+	// change.
+	if err := h.authorize(c, access.ReadEvents); err != nil {
+		return err
+	}
+	filter := models.EventFilter{Assignee: c.Query("assignee")}
+	for _, s := range splitList(c.Query("status")) {
+		filter.Statuses = append(filter.Statuses, models.AppealStatus(s))
+	}
+
+	resume := c.Get("Last-Event-ID")
+	var after int64
+	if resume != "" {
+		n, err := strconv.ParseInt(resume, 10, 64)
+		if err != nil || n < 0 {
+			return validationError("invalid_last_event_id", "Invalid Last-Event-ID, expected an event sequence")
+		}
+		after = n
+	}
+
+	ctx := requestContext(c)
+	filter.Tenant = services.TenantFromContext(ctx)
+	// Subscribe before reading the outbox, so that an event committed in
+	// between arrives on the bus instead of being lost.
+	events, unsubscribe := h.Events.Subscribe()
+	if resume == "" {
+		last, err := h.Service.LastEventSequence(ctx)
+		if err != nil {
+			unsubscribe()
+			return err
+		}
+		after = last
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+		stream := &eventStream{w: w, filter: filter, last: after}
+
+		for {
+			page, err := h.Service.GetEvents(ctx, stream.last, models.MaxPageSize)
+			if err != nil {
+				log.Printf("Failed to read events after %d: %v", stream.last, err)
+				return
+			}
+			for _, event := range page {
+				if err := stream.send(event); err != nil {
+					return
+				}
+			}
+			if len(page) < models.MaxPageSize {
+				break
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if err := stream.send(&event); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				// Comments keep proxies from closing an idle stream and
+				// tell us when the client has gone.
+				if _, err := w.WriteString(": ping\n\n"); err != nil {
+					return
+				}
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// eventStream writes events in SSE format, skipping those already sent and
+// those the filter rejects.
+type eventStream struct {
+	w      *bufio.Writer
+	filter models.EventFilter
+	last   int64
+}
+
+func (s *eventStream) send(event *models.Event) error {
+	if event.Sequence <= s.last {
+		return nil
+	}
+	s.last = event.Sequence
+	if !s.filter.Matches(event) {
+		return nil
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.w, "id: %d\nevent: %s\ndata: %s\n\n", event.Sequence, event.Type, data)
+	return err
+}
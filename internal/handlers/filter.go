@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"go_appeals/internal/models"
+	"go_appeals/internal/sla"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseAppealFilter reads listing parameters from the query string:
+//
+//	status=New,InProgress  priority=high,urgent  theme=billing  assignee=jane
+//	sla=due_soon|breached  due_within=4h (defaults to the SLA policy's due_soon)
+//	created_from, created_to, updated_from, updated_to (RFC 3339 or YYYY-MM-DD)
+//	sort=created_at|updated_at  order=asc|desc  limit=50  cursor=...
+func parseAppealFilter(c *fiber.Ctx) (models.AppealFilter, error) {
+	filter := models.AppealFilter{
+		Theme:    c.Query("theme"),
+		Assignee: c.Query("assignee"),
+		SortBy:   models.AppealSortField(c.Query("sort")),
+		Cursor:   c.Query("cursor"),
+		SLA:      models.SLAFilter(c.Query("sla")),
+	}
+
+	for _, s := range splitList(c.Query("status")) {
+		filter.Statuses = append(filter.Statuses, models.AppealStatus(s))
+	}
+	for _, p := range splitList(c.Query("priority")) {
+		filter.Priorities = append(filter.Priorities, models.AppealPriority(p))
+	}
+
+	if within := c.Query("due_within"); within != "" {
+		d, err := sla.ParseDuration(within)
+		if err != nil {
+			return filter, validationError("invalid_due_within", "Invalid due_within, use a duration such as 30m, 4h or 2d")
+		}
+		filter.DueWithin = d
+	}
+
+	switch c.Query("order", "asc") {
+	case "asc":
+	case "desc":
+		filter.Descending = true
+	default:
+		return filter, validationError("invalid_order", "Invalid order, use asc or desc")
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return filter, validationError("invalid_limit", "Invalid limit, expected a number")
+		}
+		filter.Limit = n
+	}
+
+	bounds := []struct {
+		param string
+		dest  *time.Time
+		end   bool
+	}{
+		{"created_from", &filter.CreatedFrom, false},
+		{"created_to", &filter.CreatedTo, true},
+		{"updated_from", &filter.UpdatedFrom, false},
+		{"updated_to", &filter.UpdatedTo, true},
+	}
+	for _, bound := range bounds {
+		value := c.Query(bound.param)
+		if value == "" {
+			continue
+		}
+		t, err := parseTimeBound(value, bound.end)
+		if err != nil {
+			return filter, validationError("invalid_date", "Invalid "+bound.param+", use RFC 3339 or YYYY-MM-DD")
+		}
+		*bound.dest = t
+	}
+
+	return filter, nil
+}
+
+// splitList splits a comma-separated query parameter, skipping blanks.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseTimeBound accepts a full timestamp or a bare date. A bare date used as
+// an upper bound covers the whole day.
+func parseTimeBound(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
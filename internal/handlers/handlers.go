@@ -1,70 +1,167 @@
 package handlers
 
 import (
-	"fmt"
+	"context"
+	"strconv"
 
+	"go_appeals/internal/access"
+	"go_appeals/internal/auth"
 	"go_appeals/internal/models"
+	"go_appeals/internal/outbox"
+	"go_appeals/internal/scheduler"
 	"go_appeals/internal/services"
+	"go_appeals/internal/webhook"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type Handlers struct {
-	Service *services.AppealService
+	Auth      *auth.Authenticator
+	Service   *services.AppealService
+	Scheduler *scheduler.Scheduler
+	Webhooks  *webhook.Dispatcher
+	Events    *outbox.Bus
+}
+
+// requestContext carries the caller identity and tenant down to the
+// service, which records the caller in the status history. It is the
+// authenticated principal when Authenticate guards the route, and the
+// X-Actor header otherwise.
+func requestContext(c *fiber.Ctx) context.Context {
+	ctx := c.UserContext()
+	if tenant, ok := c.Locals(tenantLocal).(string); ok {
+		ctx = services.WithTenant(ctx, tenant)
+	}
+	if principal, ok := c.Locals(principalLocal).(*models.Principal); ok {
+		return services.WithPrincipal(ctx, principal)
+	}
+	return services.WithActor(ctx, c.Get("X-Actor"))
+}
+
+// tenantOf is the tenant the request acts for.
+func tenantOf(c *fiber.Ctx) string {
+	return services.TenantFromContext(requestContext(c))
+}
+
+// authorize guards routes that do not go through an AppealService method
+// checking permissions itself.
+func (h *Handlers) authorize(c *fiber.Ctx, perm access.Permission) error {
+	return h.Service.Authorize(requestContext(c), perm)
 }
 
 func (h *Handlers) GetStartedAppeals(c *fiber.Ctx) error {
-	appeals, err := h.Service.GetStartedAppeals()
+	filter, err := parseAppealFilter(c)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return err
 	}
-	return c.JSON(fiber.Map{
-		"appeals": appeals,
-	})
+
+	page, err := h.Service.GetStartedAppeals(requestContext(c), filter)
+	if err != nil {
+		return err
+	}
+	return c.JSON(page)
 }
 
 func (h *Handlers) GetAllAppeals(c *fiber.Ctx) error {
-	appeals, err := h.Service.GetAllAppeals()
+	filter, err := parseAppealFilter(c)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return err
+	}
+
+	page, err := h.Service.GetAllAppeals(requestContext(c), filter)
+	if err != nil {
+		return err
+	}
+	return c.JSON(page)
+}
+
+func (h *Handlers) GetMyAppeals(c *fiber.Ctx) error {
+	filter, err := parseAppealFilter(c)
+	if err != nil {
+		return err
+	}
+
+	page, err := h.Service.GetMyAppeals(requestContext(c), filter)
+	if err != nil {
+		return err
+	}
+	return c.JSON(page)
+}
+
+// SearchAppeals handles GET /appeals/search?q=...&limit=20&offset=0.
+func (h *Handlers) SearchAppeals(c *fiber.Ctx) error {
+	req := models.SearchRequest{Query: c.Query("q")}
+
+	for param, dest := range map[string]*int{"limit": &req.Limit, "offset": &req.Offset} {
+		if value := c.Query(param); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return validationError("invalid_"+param, "Invalid "+param+", expected a number")
+			}
+			*dest = n
+		}
+	}
+
+	results, err := h.Service.SearchAppeals(requestContext(c), req)
+	if err != nil {
+		return err
 	}
 	return c.JSON(fiber.Map{
-		"appeals": appeals,
+		"results": results,
 	})
 }
 
 func (h *Handlers) GetAppealByID(c *fiber.Ctx) error {
-	id := c.Params("id")
-	appeal, err := h.Service.GetAppealByID(id)
+	appeal, err := h.Service.GetAppealByID(requestContext(c), c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return err
 	}
 	return c.JSON(fiber.Map{
 		"appeal": appeal,
 	})
 }
 
+func (h *Handlers) GetAppealHistory(c *fiber.Ctx) error {
+	history, err := h.Service.GetAppealHistory(requestContext(c), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"history": history,
+	})
+}
+
+func (h *Handlers) GetAppealSLA(c *fiber.Ctx) error {
+	report, err := h.Service.GetAppealSLA(requestContext(c), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"sla": report,
+	})
+}
+
+// CreateAppeal takes the appeal as JSON, or as multipart/form-data when
+// files are attached to it.
 func (h *Handlers) CreateAppeal(c *fiber.Ctx) error {
 	var req models.CreateAppealRequest
 
+	if isMultipart(c) {
+		uploads, closeUploads, err := multipartUploads(c)
+		if err != nil {
+			return err
+		}
+		defer closeUploads()
+		req.Attachments = uploads
+	}
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Cannot parse JSON",
-		})
+		return badRequest("Cannot parse JSON")
 	}
 
-	appeal, err := h.Service.CreateAppeal(req)
+	appeal, err := h.Service.CreateAppeal(requestContext(c), req)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
@@ -74,18 +171,9 @@ func (h *Handlers) CreateAppeal(c *fiber.Ctx) error {
 }
 
 func (h *Handlers) StartProcessing(c *fiber.Ctx) error {
-	id := c.Params("id")
-
-	appeal, err := h.Service.StartProcessing(id)
+	appeal, err := h.Service.StartProcessing(requestContext(c), c.Params("id"))
 	if err != nil {
-		if err.Error() == fmt.Sprintf("appeal with ID %s not found", id) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return err
 	}
 
 	return c.JSON(fiber.Map{
@@ -95,25 +183,14 @@ func (h *Handlers) StartProcessing(c *fiber.Ctx) error {
 }
 
 func (h *Handlers) CompleteAppeal(c *fiber.Ctx) error {
-	id := c.Params("id")
-
 	var req models.UpdateAppealSolutionRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Cannot parse JSON",
-		})
+		return badRequest("Cannot parse JSON")
 	}
 
-	appeal, err := h.Service.CompleteAppeal(id, req)
+	appeal, err := h.Service.CompleteAppeal(requestContext(c), c.Params("id"), req)
 	if err != nil {
-		if err.Error() == fmt.Sprintf("appeal with ID %s not found", id) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return err
 	}
 
 	return c.JSON(fiber.Map{
@@ -123,35 +200,121 @@ func (h *Handlers) CompleteAppeal(c *fiber.Ctx) error {
 }
 
 func (h *Handlers) CancelAppeal(c *fiber.Ctx) error {
-	id := c.Params("id")
+	var req models.UpdateAppealCancelRequest
+	if err := c.BodyParser(&req); err != nil {
+		return badRequest("Cannot parse JSON")
+	}
 
-	err := h.Service.CancelAppeal(id)
+	appeal, err := h.Service.CancelAppeal(requestContext(c), c.Params("id"), req)
 	if err != nil {
-		if err.Error() == fmt.Sprintf("appeal with ID %s not found", id) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return err
 	}
 
 	return c.JSON(fiber.Map{
 		"message": "Appeal canceled successfully",
-		"id":      id,
+		"id":      appeal.ID,
+		"appeal":  appeal,
+	})
+}
+
+func (h *Handlers) AssignAppeal(c *fiber.Ctx) error {
+	return h.assignment(c, h.Service.AssignAppeal, "Appeal assigned successfully")
+}
+
+func (h *Handlers) ReassignAppeal(c *fiber.Ctx) error {
+	return h.assignment(c, h.Service.ReassignAppeal, "Appeal reassigned successfully")
+}
+
+func (h *Handlers) ClaimAppeal(c *fiber.Ctx) error {
+	appeal, err := h.Service.ClaimAppeal(requestContext(c), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Appeal claimed successfully",
+		"appeal":  appeal,
+	})
+}
+
+func (h *Handlers) assignment(c *fiber.Ctx, assign func(context.Context, string, models.AssignAppealRequest) (*models.Appeal, error), message string) error {
+	var req models.AssignAppealRequest
+	if err := c.BodyParser(&req); err != nil {
+		return badRequest("Cannot parse JSON")
+	}
+
+	appeal, err := assign(requestContext(c), c.Params("id"), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": message,
+		"appeal":  appeal,
+	})
+}
+
+func (h *Handlers) HoldAppeal(c *fiber.Ctx) error {
+	return h.reasonTransition(c, h.Service.HoldAppeal, "Appeal put on hold successfully")
+}
+
+func (h *Handlers) WaitForRequester(c *fiber.Ctx) error {
+	return h.reasonTransition(c, h.Service.WaitForRequester, "Appeal is waiting for the requester")
+}
+
+func (h *Handlers) RejectAppeal(c *fiber.Ctx) error {
+	return h.reasonTransition(c, h.Service.RejectAppeal, "Appeal rejected successfully")
+}
+
+func (h *Handlers) ReopenAppeal(c *fiber.Ctx) error {
+	return h.reasonTransition(c, h.Service.ReopenAppeal, "Appeal reopened successfully")
+}
+
+func (h *Handlers) ResumeAppeal(c *fiber.Ctx) error {
+	appeal, err := h.Service.ResumeAppeal(requestContext(c), c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Appeal resumed successfully",
+		"appeal":  appeal,
+	})
+}
+
+// reasonTransition handles the PATCH endpoints whose body is just a required
+// {"reason": "..."}.
+func (h *Handlers) reasonTransition(c *fiber.Ctx, transition func(context.Context, string, models.UpdateAppealReasonRequest) (*models.Appeal, error), message string) error {
+	var req models.UpdateAppealReasonRequest
+	if err := c.BodyParser(&req); err != nil {
+		return badRequest("Cannot parse JSON")
+	}
+
+	appeal, err := transition(requestContext(c), c.Params("id"), req)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(fiber.Map{
+		"message": message,
+		"appeal":  appeal,
 	})
 }
 
 func (h *Handlers) CancelAllInProgress(c *fiber.Ctx) error {
-	if err := h.Service.CancelAllInProgress(); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	var req models.UpdateAppealCancelRequest
+	if err := c.BodyParser(&req); err != nil {
+		return badRequest("Cannot parse JSON")
+	}
+
+	cancelled, err := h.Service.CancelAllInProgress(requestContext(c), req)
+	if err != nil {
+		return err
 	}
 
 	return c.JSON(fiber.Map{
-		"message": "All in progress appeals canceled successfully",
+		"message":   "All in progress appeals canceled successfully",
+		"cancelled": cancelled,
 	})
 }
 
@@ -162,35 +325,57 @@ func (h *Handlers) GetAppealsByDates(c *fiber.Ctx) error {
 	endStr := c.Query("endDate")
 
 	if startStr == "" || endStr == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "startDate and endDate are required",
-		})
+		return validationError("dates_required", "startDate and endDate are required")
 	}
 
 	start, err := time.Parse(layout, startStr)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid startDate format, use YYYY-MM-DD",
-		})
+		return validationError("invalid_date", "Invalid startDate format, use YYYY-MM-DD")
 	}
 
 	end, err := time.Parse(layout, endStr)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid endDate format, use YYYY-MM-DD",
-		})
+		return validationError("invalid_date", "Invalid endDate format, use YYYY-MM-DD")
 	}
 
 	end = end.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
 
-	appeals, err := h.Service.GetAppealsByDates(start, end)
+	appeals, err := h.Service.GetAppealsByDates(requestContext(c), start, end)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return err
 	}
 
 	return c.JSON(fiber.Map{
 		"appeals": appeals,
 	})
 }
+
+func (h *Handlers) GetWorkflow(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"workflow": h.Service.Workflow().Definition(),
+	})
+}
+
+func (h *Handlers) GetSLAPolicy(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"sla":      h.Service.SLAPolicy(requestContext(c)).Definition(),
+		"calendar": h.Service.Calendar().Definition(),
+	})
+}
+
+// GetWorkflowDiagram renders the appeal workflow; ?format=mermaid (default)
+// or ?format=dot for Graphviz.
+func (h *Handlers) GetWorkflowDiagram(c *fiber.Ctx) error {
+	machine := h.Service.Workflow()
+
+	switch c.Query("format", "mermaid") {
+	case "mermaid":
+		c.Set(fiber.HeaderContentType, "text/vnd.mermaid; charset=utf-8")
+		return c.SendString(machine.Mermaid())
+	case "dot", "graphviz":
+		c.Set(fiber.HeaderContentType, "text/vnd.graphviz; charset=utf-8")
+		return c.SendString(machine.Graphviz())
+	default:
+		return validationError("invalid_format", "Invalid format, use mermaid or dot")
+	}
+}
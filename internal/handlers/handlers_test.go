@@ -0,0 +1,875 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go_appeals/internal/auth"
+	"go_appeals/internal/models"
+	"go_appeals/internal/outbox"
+	"go_appeals/internal/repository"
+	"go_appeals/internal/scheduler"
+	"go_appeals/internal/services"
+	"go_appeals/internal/tenant"
+	"go_appeals/internal/webhook"
+	"go_appeals/internal/workflow"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func newTestApp(t *testing.T) *fiber.App {
+	h := &Handlers{
+		Service: services.NewAppealService(repository.NewMemoryAppealRepository(), workflow.Default()),
+	}
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Post("/appeals", h.CreateAppeal)
+	app.Get("/appeals/all", h.GetAllAppeals)
+	app.Get("/appeals/:id", h.GetAppealByID)
+	app.Patch("/appeals/:id/complete", h.CompleteAppeal)
+	app.Patch("/appeals/:id/cancel", h.CancelAppeal)
+	return app
+}
+
+func doRequest(t *testing.T, app *fiber.App, method, path, body string) (int, string, map[string]any) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Request %s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to decode response %q: %v", data, err)
+	}
+	return resp.StatusCode, resp.Header.Get(fiber.HeaderContentType), decoded
+}
+
+func TestErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+
+	status, _, body := doRequest(t, app, "POST", "/appeals", `{"theme": "Printer", "message": "Out of toner"}`)
+	if status != fiber.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %v", status, body)
+	}
+	id := body["appeal"].(map[string]any)["id"].(string)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"NotFound", "GET", "/appeals/missing", "", fiber.StatusNotFound, "appeal_not_found"},
+		{"InvalidTransition", "PATCH", "/appeals/" + id + "/complete", `{"solution": "Refilled"}`, fiber.StatusConflict, "invalid_transition"},
+		{"Validation", "PATCH", "/appeals/" + id + "/cancel", `{"reason": " "}`, fiber.StatusUnprocessableEntity, "reason_required"},
+		{"MalformedBody", "PATCH", "/appeals/" + id + "/cancel", `{"reason":`, fiber.StatusBadRequest, "bad_request"},
+		{"UnknownRoute", "GET", "/nowhere", "", fiber.StatusNotFound, "not_found"},
+	}
+
+	for _, tt := range tests {
+		status, contentType, problem := doRequest(t, app, tt.method, tt.path, tt.body)
+		if status != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, status)
+		}
+		if !strings.HasPrefix(contentType, "application/problem+json") {
+			t.Errorf("%s: expected problem+json, got %s", tt.name, contentType)
+		}
+		if problem["code"] != tt.code {
+			t.Errorf("%s: expected code %s, got %v", tt.name, tt.code, problem["code"])
+		}
+		if int(problem["status"].(float64)) != tt.status || problem["title"] == "" || problem["instance"] != tt.path {
+			t.Errorf("%s: incomplete problem body %v", tt.name, problem)
+		}
+	}
+}
+
+func TestAppealByNumber(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+
+	_, _, body := doRequest(t, app, "POST", "/appeals", `{"theme": "Printer", "message": "Out of toner"}`)
+	created := body["appeal"].(map[string]any)
+	id, number := created["id"].(string), created["number"].(string)
+
+	status, _, body := doRequest(t, app, "GET", "/appeals/"+number, "")
+	if status != fiber.StatusOK || body["appeal"].(map[string]any)["id"] != id {
+		t.Fatalf("Expected the appeal by its number, got %d: %v", status, body)
+	}
+	// В ответе всегда настоящий идентификатор, даже если обращались по номеру.
+	status, _, body = doRequest(t, app, "PATCH", "/appeals/"+number+"/cancel", `{"reason": "Duplicate"}`)
+	if status != fiber.StatusOK || body["id"] != id {
+		t.Errorf("Expected the appeal to be cancelled by its number, got %d: %v", status, body)
+	}
+}
+
+func TestListAppealsQuery(t *testing.T) {
+	t.Parallel()
+
+	app := newTestApp(t)
+	for _, theme := range []string{"billing", "billing", "delivery"} {
+		if status, _, body := doRequest(t, app, "POST", "/appeals", `{"theme": "`+theme+`", "message": "Help"}`); status != fiber.StatusCreated {
+			t.Fatalf("Expected 201, got %d: %v", status, body)
+		}
+	}
+
+	status, _, page := doRequest(t, app, "GET", "/appeals/all?theme=billing&status=New&order=desc&limit=1&created_from=2000-01-01", "")
+	if status != fiber.StatusOK {
+		t.Fatalf("Expected 200, got %d: %v", status, page)
+	}
+	if len(page["appeals"].([]any)) != 1 || page["next_cursor"] == nil {
+		t.Fatalf("Expected one appeal and a cursor, got %v", page)
+	}
+
+	status, _, page = doRequest(t, app, "GET", "/appeals/all?theme=billing&status=New&order=desc&limit=1&created_from=2000-01-01&cursor="+page["next_cursor"].(string), "")
+	if status != fiber.StatusOK {
+		t.Fatalf("Expected 200, got %d: %v", status, page)
+	}
+	if len(page["appeals"].([]any)) != 1 || page["next_cursor"] != nil {
+		t.Errorf("Expected the last billing appeal without a cursor, got %v", page)
+	}
+
+	status, _, page = doRequest(t, app, "GET", "/appeals/all?priority=normal&sla=due_soon&due_within=7d", "")
+	if status != fiber.StatusOK || len(page["appeals"].([]any)) != 3 {
+		t.Errorf("Expected all three appeals to be due within a week, got %d %v", status, page)
+	}
+
+	for query, code := range map[string]string{
+		"order=sideways":               "invalid_order",
+		"limit=many":                   "invalid_limit",
+		"limit=1000":                   "invalid_limit",
+		"sort=theme":                   "invalid_sort",
+		"created_to=monday":            "invalid_date",
+		"cursor=bm90LWpzb24":           "invalid_cursor",
+		"priority=critical":            "invalid_priority",
+		"sla=overdue":                  "invalid_sla",
+		"sla=due_soon&due_within=soon": "invalid_due_within",
+	} {
+		status, _, problem := doRequest(t, app, "GET", "/appeals/all?"+query, "")
+		if status != fiber.StatusUnprocessableEntity || problem["code"] != code {
+			t.Errorf("%s: expected 422 %s, got %d %v", query, code, status, problem["code"])
+		}
+	}
+}
+
+func TestJobsEndpoints(t *testing.T) {
+	t.Parallel()
+
+	repo := repository.NewMemoryAppealRepository()
+	service := services.NewAppealService(repo, workflow.Default())
+	sched, err := scheduler.Default(repo, scheduler.WithHandlers(service.Jobs()))
+	if err != nil {
+		t.Fatalf("Failed to create scheduler: %v", err)
+	}
+	t.Cleanup(func() { sched.Shutdown(context.Background()) })
+	h := &Handlers{Service: service, Scheduler: sched}
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Get("/jobs", h.GetJobs)
+	app.Post("/jobs/:name/run", h.RunJob)
+	app.Get("/jobs/:name/runs", h.GetJobRuns)
+	app.Get("/reminders", h.GetReminders)
+
+	status, _, body := doRequest(t, app, "GET", "/jobs", "")
+	if status != fiber.StatusOK || len(body["jobs"].([]any)) != 3 {
+		t.Fatalf("Expected the three default jobs, got %d %v", status, body)
+	}
+
+	status, _, body = doRequest(t, app, "POST", "/jobs/escalate_breaches/run", "")
+	if status != fiber.StatusAccepted || body["run"].(map[string]any)["triggered_by"] != "manual" {
+		t.Fatalf("Expected 202 with a manual run, got %d %v", status, body)
+	}
+
+	status, _, body = doRequest(t, app, "GET", "/jobs/escalate_breaches/runs?limit=10", "")
+	if status != fiber.StatusOK || len(body["runs"].([]any)) != 1 {
+		t.Errorf("Expected one run in the history, got %d %v", status, body)
+	}
+
+	for path, code := range map[string]string{
+		"/jobs/missing/runs":                   "job_not_found",
+		"/jobs/escalate_breaches/runs?limit=x": "invalid_limit",
+		"/reminders?limit=1000":                "invalid_limit",
+	} {
+		status, _, problem := doRequest(t, app, "GET", path, "")
+		if problem["code"] != code {
+			t.Errorf("%s: expected %s, got %d %v", path, code, status, problem)
+		}
+	}
+}
+
+func TestWebhookEndpoints(t *testing.T) {
+	t.Parallel()
+
+	repo := repository.NewMemoryAppealRepository()
+	h := &Handlers{
+		Service:  services.NewAppealService(repo, workflow.Default()),
+		Webhooks: webhook.New(repo),
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Post("/webhooks", h.CreateWebhook)
+	app.Get("/webhooks", h.GetWebhooks)
+	app.Delete("/webhooks/:id", h.DeleteWebhook)
+	app.Get("/webhooks/:id/deliveries", h.GetWebhookDeliveries)
+	app.Post("/webhooks/:id/deliveries/:delivery/redeliver", h.RedeliverWebhook)
+
+	if status, _, problem := doRequest(t, app, "POST", "/webhooks", `{"url": "crm"}`); status != fiber.StatusUnprocessableEntity || problem["code"] != "invalid_url" {
+		t.Errorf("Expected 422 invalid_url, got %d %v", status, problem)
+	}
+
+	status, _, body := doRequest(t, app, "POST", "/webhooks", `{"url": "https://crm.example.com/hook", "events": ["appeal.created"]}`)
+	if status != fiber.StatusCreated {
+		t.Fatalf("Expected 201, got %d %v", status, body)
+	}
+	created := body["webhook"].(map[string]any)
+	if created["secret"] == nil {
+		t.Errorf("Expected the secret in the creation response, got %v", created)
+	}
+	id := created["id"].(string)
+
+	status, _, body = doRequest(t, app, "GET", "/webhooks", "")
+	if listed := body["webhooks"].([]any); status != fiber.StatusOK || len(listed) != 1 || listed[0].(map[string]any)["secret"] != nil {
+		t.Errorf("Expected one webhook without its secret, got %d %v", status, body)
+	}
+
+	status, _, body = doRequest(t, app, "GET", "/webhooks/"+id+"/deliveries", "")
+	if status != fiber.StatusOK || len(body["deliveries"].([]any)) != 0 {
+		t.Errorf("Expected an empty delivery log, got %d %v", status, body)
+	}
+	if status, _, problem := doRequest(t, app, "POST", "/webhooks/"+id+"/deliveries/first/redeliver", ""); status != fiber.StatusNotFound || problem["code"] != "delivery_not_found" {
+		t.Errorf("Expected 404 delivery_not_found, got %d %v", status, problem)
+	}
+
+	if status, _, body := doRequest(t, app, "DELETE", "/webhooks/"+id, ""); status != fiber.StatusOK {
+		t.Errorf("Expected 200, got %d %v", status, body)
+	}
+	if status, _, problem := doRequest(t, app, "DELETE", "/webhooks/"+id, ""); status != fiber.StatusNotFound || problem["code"] != "webhook_not_found" {
+		t.Errorf("Expected 404 webhook_not_found, got %d %v", status, problem)
+	}
+}
+
+// sseMessage is one message of an event stream.
+type sseMessage struct {
+	id, event, data string
+}
+
+func readMessage(t *testing.T, r *bufio.Reader) sseMessage {
+	t.Helper()
+	var msg sseMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Stream ended early: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && msg.id != "":
+			return msg
+		case strings.HasPrefix(line, "id: "):
+			msg.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			msg.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			msg.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestAppealEventStream(t *testing.T) {
+	t.Parallel()
+
+	repo := repository.NewMemoryAppealRepository()
+	bus := outbox.NewBus(16)
+	relay := outbox.New(repo, []outbox.Sink{bus}, outbox.WithPollInterval(10*time.Millisecond))
+	if err := relay.Start(); err != nil {
+		t.Fatalf("Failed to start relay: %v", err)
+	}
+	t.Cleanup(func() { relay.Shutdown(context.Background()) })
+
+	ctx := services.WithActor(context.Background(), "jane")
+	service := services.NewAppealService(repo, workflow.Default(), services.WithNotifier(relay))
+	h := &Handlers{Service: service, Events: bus}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Get("/appeals/events", h.StreamAppealEvents)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() {
+		bus.Close()
+		app.Shutdown()
+	})
+
+	req := httptest.NewRequest("GET", "/appeals/events", nil)
+	req.Header.Set("Last-Event-ID", "soon")
+	if resp, err := app.Test(req); err != nil || resp.StatusCode != fiber.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a bad Last-Event-ID, got %v, %v", resp, err)
+	}
+
+	appeal, err := service.CreateAppeal(ctx, models.CreateAppealRequest{Theme: "Printer", Message: "Out of toner"})
+	if err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+	if _, err := service.StartProcessing(ctx, appeal.ID); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if _, err := service.CreateAppeal(ctx, models.CreateAppealRequest{Theme: "Chair", Message: "Broken"}); err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+
+	// Клиент переподключается после первого события: пропущенное приходит из
+	// outbox, новое из шины, чужие статусы отфильтрованы.
+	stream, err := http.NewRequest("GET", "http://"+ln.Addr().String()+"/appeals/events?status=InProgress", nil)
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	stream.Header.Set("Last-Event-ID", "1")
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(stream)
+	if err != nil {
+		t.Fatalf("Failed to open the stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != fiber.StatusOK || resp.Header.Get(fiber.HeaderContentType) != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get(fiber.HeaderContentType))
+	}
+	r := bufio.NewReader(resp.Body)
+
+	if msg := readMessage(t, r); msg.id != "2" || msg.event != "appeal.started" {
+		t.Errorf("Expected the missed start, got %+v", msg)
+	}
+
+	if _, err := service.CompleteAppeal(ctx, appeal.ID, models.UpdateAppealSolutionRequest{Solution: "Refilled"}); err != nil {
+		t.Fatalf("Failed to complete appeal: %v", err)
+	}
+	msg := readMessage(t, r)
+	var event models.Event
+	if err := json.Unmarshal([]byte(msg.data), &event); err != nil {
+		t.Fatalf("Expected an event as data, got %q", msg.data)
+	}
+	if msg.id != "4" || msg.event != "appeal.completed" || event.AppealID != appeal.ID || event.Appeal.Status != models.StatusCompleted {
+		t.Errorf("Expected the live completion, got %+v %+v", msg, event)
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	t.Parallel()
+
+	repo := repository.NewMemoryAppealRepository()
+	h := &Handlers{
+		Auth:    auth.New(repo),
+		Service: services.NewAppealService(repo, workflow.Default()),
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(h.Authenticate)
+	app.Use(h.ResolveTenant)
+	app.Get("/auth/me", h.GetMe)
+	app.Post("/api-keys", h.CreateAPIKey)
+	app.Get("/api-keys", h.GetAPIKeys)
+	app.Delete("/api-keys/:id", h.RevokeAPIKey)
+	app.Post("/appeals", h.CreateAppeal)
+	app.Get("/appeals/:id/history", h.GetAppealHistory)
+
+	_, secret, err := h.Auth.IssueKey(models.CreateAPIKeyRequest{Name: "bootstrap", Subject: "admin", Tenant: models.DefaultTenant, Roles: []models.Role{models.RoleAdmin}})
+	if err != nil {
+		t.Fatalf("Failed to issue key: %v", err)
+	}
+	call := func(method, path, body string, headers map[string]string) (*http.Response, map[string]any) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Request %s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var decoded map[string]any
+		json.NewDecoder(resp.Body).Decode(&decoded)
+		return resp, decoded
+	}
+
+	for name, headers := range map[string]map[string]string{
+		"no credentials": nil,
+		"wrong key":      {HeaderAPIKey: "apk_wrong"},
+		"basic auth":     {fiber.HeaderAuthorization: "Basic YWRtaW46YWRtaW4="},
+	} {
+		resp, problem := call("GET", "/auth/me", "", headers)
+		if resp.StatusCode != fiber.StatusUnauthorized || resp.Header.Get(fiber.HeaderWWWAuthenticate) == "" || problem["code"] == "" {
+			t.Errorf("%s: expected 401 with a challenge, got %d %v", name, resp.StatusCode, problem)
+		}
+	}
+
+	bearer := map[string]string{fiber.HeaderAuthorization: "Bearer " + secret}
+	resp, body := call("GET", "/auth/me", "", bearer)
+	if resp.StatusCode != fiber.StatusOK || body["principal"].(map[string]any)["subject"] != "admin" {
+		t.Fatalf("Expected the key's subject, got %d %v", resp.StatusCode, body)
+	}
+
+	resp, body = call("POST", "/api-keys", `{"name": "crm", "subject": "crm-sync"}`, bearer)
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected 201, got %d %v", resp.StatusCode, body)
+	}
+	issued := body["key"].(string)
+	keyID := body["api_key"].(map[string]any)["id"].(string)
+	if _, ok := body["api_key"].(map[string]any)["hash"]; ok {
+		t.Errorf("Expected the hash to stay private, got %v", body)
+	}
+
+	// Аутентифицированный вызывающий не может представиться другим через X-Actor.
+	resp, body = call("POST", "/appeals", `{"theme": "Printer", "message": "Out of toner"}`, map[string]string{HeaderAPIKey: issued, "X-Actor": "mallory"})
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatalf("Expected 201, got %d %v", resp.StatusCode, body)
+	}
+	_, history := call("GET", "/appeals/"+body["appeal"].(map[string]any)["id"].(string)+"/history", "", bearer)
+	if entries := history["history"].([]any); entries[0].(map[string]any)["actor"] != "crm-sync" {
+		t.Errorf("Expected the key's subject as the actor, got %v", entries)
+	}
+
+	if resp, _ := call("DELETE", "/api-keys/"+keyID, "", bearer); resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected the key to be revoked, got %d", resp.StatusCode)
+	}
+	if resp, problem := call("GET", "/auth/me", "", map[string]string{HeaderAPIKey: issued}); resp.StatusCode != fiber.StatusUnauthorized || problem["code"] != "invalid_credentials" {
+		t.Errorf("Expected a revoked key to be refused, got %d %v", resp.StatusCode, problem)
+	}
+	_, body = call("GET", "/api-keys", "", bearer)
+	if keys := body["api_keys"].([]any); len(keys) != 2 || keys[1].(map[string]any)["revoked_at"] == nil {
+		t.Errorf("Expected both keys, the second revoked, got %v", keys)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	t.Parallel()
+
+	repo := repository.NewMemoryAppealRepository()
+	h := &Handlers{
+		Auth:     auth.New(repo),
+		Service:  services.NewAppealService(repo, workflow.Default()),
+		Webhooks: webhook.New(repo),
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(h.Authenticate)
+	app.Post("/api-keys", h.CreateAPIKey)
+	app.Get("/webhooks", h.GetWebhooks)
+	app.Post("/appeals", h.CreateAppeal)
+	app.Get("/appeals/all", h.GetAllAppeals)
+	app.Get("/appeals/:id", h.GetAppealByID)
+	app.Patch("/appeals/:id/start", h.StartProcessing)
+	app.Patch("/appeals/:id/claim", h.ClaimAppeal)
+	app.Patch("/appeals/:id/complete", h.CompleteAppeal)
+	app.Get("/appeals/:id/comments", h.GetAppealComments)
+	app.Post("/appeals/:id/comments", h.CreateAppealComment)
+	app.Patch("/appeals/:id/comments/:comment", h.EditAppealComment)
+	app.Get("/requesters/:id", h.GetRequester)
+	app.Get("/requesters/:id/appeals", h.GetRequesterAppeals)
+
+	keys := map[string]string{}
+	for subject, role := range map[string]models.Role{
+		"jane": models.RoleRequester, "john": models.RoleRequester, "olga": models.RoleOperator,
+		"oleg": models.RoleOperator, "sam": models.RoleSupervisor, "root": models.RoleAdmin,
+	} {
+		_, secret, err := h.Auth.IssueKey(models.CreateAPIKeyRequest{Name: subject, Subject: subject, Roles: []models.Role{role}})
+		if err != nil {
+			t.Fatalf("Failed to issue a key: %v", err)
+		}
+		keys[subject] = secret
+	}
+	call := func(who, method, path, body string) (int, map[string]any) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(HeaderAPIKey, keys[who])
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Request %s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var decoded map[string]any
+		json.NewDecoder(resp.Body).Decode(&decoded)
+		return resp.StatusCode, decoded
+	}
+
+	status, body := call("jane", "POST", "/appeals", `{"theme": "Printer", "message": "Out of toner", "requester": {"name": "Jane Doe", "email": "jane@example.com"}}`)
+	if status != fiber.StatusCreated || body["appeal"].(map[string]any)["created_by"] != "jane" {
+		t.Fatalf("Expected jane's appeal, got %d %v", status, body)
+	}
+	id := body["appeal"].(map[string]any)["id"].(string)
+	requesterID := body["appeal"].(map[string]any)["requester_id"].(string)
+	if status, _ := call("john", "POST", "/appeals", `{"theme": "Scanner", "message": "Jammed"}`); status != fiber.StatusCreated {
+		t.Fatalf("Expected 201, got %d", status)
+	}
+
+	tests := []struct {
+		name   string
+		who    string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"requester reads own appeal", "jane", "GET", "/appeals/" + id, "", fiber.StatusOK, ""},
+		{"requester reads someone else's appeal", "john", "GET", "/appeals/" + id, "", fiber.StatusForbidden, "not_your_appeal"},
+		{"requester starts processing", "jane", "PATCH", "/appeals/" + id + "/start", "", fiber.StatusForbidden, "forbidden"},
+		{"operator starts processing", "olga", "PATCH", "/appeals/" + id + "/start", "", fiber.StatusOK, ""},
+		{"operator claims", "olga", "PATCH", "/appeals/" + id + "/claim", "", fiber.StatusOK, ""},
+		{"other operator completes", "oleg", "PATCH", "/appeals/" + id + "/complete", `{"solution": "Refilled"}`, fiber.StatusForbidden, "not_assignee"},
+		{"operator manages webhooks", "olga", "GET", "/webhooks", "", fiber.StatusForbidden, "forbidden"},
+		{"supervisor manages API keys", "sam", "POST", "/api-keys", `{"name": "x", "subject": "x"}`, fiber.StatusForbidden, "forbidden"},
+		{"admin issues a key with an unknown role", "root", "POST", "/api-keys", `{"name": "x", "subject": "x", "roles": ["owner"]}`, fiber.StatusUnprocessableEntity, "unknown_role"},
+		{"supervisor completes someone else's appeal", "sam", "PATCH", "/appeals/" + id + "/complete", `{"solution": "Refilled"}`, fiber.StatusOK, ""},
+		{"admin manages webhooks", "root", "GET", "/webhooks", "", fiber.StatusOK, ""},
+		{"requester replies", "jane", "POST", "/appeals/" + id + "/comments", `{"body": "Any news?"}`, fiber.StatusCreated, ""},
+		{"requester writes an internal note", "jane", "POST", "/appeals/" + id + "/comments", `{"body": "Hm", "visibility": "internal"}`, fiber.StatusForbidden, "forbidden"},
+		{"operator writes an internal note", "olga", "POST", "/appeals/" + id + "/comments", `{"body": "Vendor is slow", "visibility": "internal"}`, fiber.StatusCreated, ""},
+		{"requester comments on someone else's appeal", "john", "POST", "/appeals/" + id + "/comments", `{"body": "Me too"}`, fiber.StatusForbidden, "not_your_appeal"},
+		{"edit a comment that is not a number", "jane", "PATCH", "/appeals/" + id + "/comments/first", `{"body": "Hello?"}`, fiber.StatusNotFound, "comment_not_found"},
+		{"requester lists a requester's appeals", "jane", "GET", "/requesters/" + requesterID + "/appeals", "", fiber.StatusForbidden, "forbidden"},
+		{"operator lists a requester's appeals", "olga", "GET", "/requesters/" + requesterID + "/appeals", "", fiber.StatusOK, ""},
+		{"operator reads a requester", "olga", "GET", "/requesters/" + requesterID, "", fiber.StatusOK, ""},
+		{"operator reads an unknown requester", "olga", "GET", "/requesters/missing", "", fiber.StatusNotFound, "requester_not_found"},
+	}
+	for _, tt := range tests {
+		status, body := call(tt.who, tt.method, tt.path, tt.body)
+		if status != tt.status || (tt.code != "" && body["code"] != tt.code) {
+			t.Errorf("%s: expected %d %s, got %d %v", tt.name, tt.status, tt.code, status, body)
+		}
+	}
+
+	// Внутренние заметки видны только сотрудникам.
+	for who, want := range map[string]int{"jane": 1, "olga": 2} {
+		if _, body := call(who, "GET", "/appeals/"+id+"/comments", ""); len(body["comments"].([]any)) != want {
+			t.Errorf("Expected %s to see %d comments, got %v", who, want, body["comments"])
+		}
+	}
+
+	// Заявитель видит в списке только свои обращения.
+	for who, want := range map[string]int{"jane": 1, "john": 1, "olga": 2} {
+		if _, body := call(who, "GET", "/appeals/all", ""); len(body["appeals"].([]any)) != want {
+			t.Errorf("Expected %s to see %d appeals, got %v", who, want, body["appeals"])
+		}
+	}
+}
+
+func TestTenants(t *testing.T) {
+	t.Parallel()
+
+	tenants, err := tenant.New(tenant.Definition{Tenants: []tenant.Tenant{
+		{ID: models.DefaultTenant, Name: "Default"},
+		{ID: "acme", Name: "Acme", Themes: []string{"Printer"}},
+		{ID: "globex", Name: "Globex"},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to build tenants: %v", err)
+	}
+	repo := repository.NewMemoryAppealRepository()
+	service := services.NewAppealService(repo, workflow.Default(), services.WithTenants(tenants))
+	sched, err := scheduler.Default(repo, scheduler.WithHandlers(service.Jobs()))
+	if err != nil {
+		t.Fatalf("Failed to create scheduler: %v", err)
+	}
+	t.Cleanup(func() { sched.Shutdown(context.Background()) })
+	h := &Handlers{
+		Auth:      auth.New(repo),
+		Service:   service,
+		Scheduler: sched,
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Use(h.Authenticate)
+	app.Use(h.ResolveTenant)
+	app.Get("/tenant", h.GetTenant)
+	app.Post("/jobs/:name/run", h.RunJob)
+	app.Get("/jobs/:name/runs", h.GetJobRuns)
+	app.Post("/appeals", h.CreateAppeal)
+	app.Get("/appeals/all", h.GetAllAppeals)
+	app.Get("/appeals/:id", h.GetAppealByID)
+
+	keys := map[string]string{}
+	for who, key := range map[string]struct {
+		org  string
+		role models.Role
+	}{
+		"acme":    {"acme", models.RoleAdmin},
+		"support": {"", models.RoleAdmin},
+		"legacy":  {"", models.RoleOperator},
+	} {
+		_, secret, err := h.Auth.IssueKey(models.CreateAPIKeyRequest{Name: who, Subject: who, Tenant: key.org, Roles: []models.Role{key.role}})
+		if err != nil {
+			t.Fatalf("Failed to issue a key: %v", err)
+		}
+		keys[who] = secret
+	}
+	call := func(who, org, method, path, body string) (int, map[string]any) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set(HeaderAPIKey, keys[who])
+		if org != "" {
+			req.Header.Set(HeaderTenant, org)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Request %s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		var decoded map[string]any
+		json.NewDecoder(resp.Body).Decode(&decoded)
+		return resp.StatusCode, decoded
+	}
+
+	status, body := call("acme", "", "POST", "/appeals", `{"theme": "Printer", "message": "Out of toner"}`)
+	if status != fiber.StatusCreated || body["appeal"].(map[string]any)["tenant_id"] != "acme" {
+		t.Fatalf("Expected an appeal of acme, got %d %v", status, body)
+	}
+	id := body["appeal"].(map[string]any)["id"].(string)
+
+	tests := []struct {
+		name   string
+		who    string
+		org    string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"theme the tenant does not offer", "acme", "", "POST", "/appeals", `{"theme": "Scanner", "message": "Jammed"}`, fiber.StatusUnprocessableEntity, "unknown_theme"},
+		{"key of another tenant", "acme", "globex", "GET", "/appeals/all", "", fiber.StatusForbidden, "tenant_mismatch"},
+		{"same tenant named explicitly", "acme", "acme", "GET", "/appeals/" + id, "", fiber.StatusOK, ""},
+		{"appeal of another tenant", "support", "globex", "GET", "/appeals/" + id, "", fiber.StatusNotFound, "appeal_not_found"},
+		{"cross-tenant key", "support", "acme", "GET", "/appeals/" + id, "", fiber.StatusOK, ""},
+		{"unknown tenant", "support", "initech", "GET", "/appeals/all", "", fiber.StatusNotFound, "tenant_not_found"},
+		{"key without a tenant naming another", "legacy", "acme", "GET", "/appeals/" + id, "", fiber.StatusForbidden, "forbidden"},
+		{"key without a tenant naming the default", "legacy", models.DefaultTenant, "GET", "/appeals/all", "", fiber.StatusOK, ""},
+		{"key without a tenant", "legacy", "", "GET", "/appeals/" + id, "", fiber.StatusNotFound, "appeal_not_found"},
+		// Задачи обходят все организации, поэтому админ одной организации их не запускает.
+		{"tenant admin running a job", "acme", "", "POST", "/jobs/escalate_breaches/run", "", fiber.StatusForbidden, "tenant_mismatch"},
+		{"tenant admin reading job runs", "acme", "", "GET", "/jobs/escalate_breaches/runs", "", fiber.StatusForbidden, "tenant_mismatch"},
+		{"operator running a job", "legacy", "", "POST", "/jobs/escalate_breaches/run", "", fiber.StatusForbidden, "forbidden"},
+		{"cross-tenant admin running a job", "support", "", "POST", "/jobs/escalate_breaches/run", "", fiber.StatusAccepted, ""},
+		{"cross-tenant admin reading job runs", "support", "", "GET", "/jobs/escalate_breaches/runs", "", fiber.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		status, body := call(tt.who, tt.org, tt.method, tt.path, tt.body)
+		if status != tt.status || (tt.code != "" && body["code"] != tt.code) {
+			t.Errorf("%s: expected %d %s, got %d %v", tt.name, tt.status, tt.code, status, body)
+		}
+	}
+
+	// Без заголовка ключ без организации работает в организации по умолчанию.
+	for org, want := range map[string]int{"": 0, "globex": 0, "acme": 1} {
+		if _, body := call("support", org, "GET", "/appeals/all", ""); len(body["appeals"].([]any)) != want {
+			t.Errorf("Expected %d appeals in %q, got %v", want, org, body["appeals"])
+		}
+	}
+	if _, body := call("acme", "", "GET", "/tenant", ""); body["tenant"].(map[string]any)["name"] != "Acme" {
+		t.Errorf("Expected the caller's tenant, got %v", body)
+	}
+}
+
+func TestAttachmentEndpoints(t *testing.T) {
+	t.Parallel()
+
+	h := &Handlers{
+		Service: services.NewAppealService(repository.NewMemoryAppealRepository(), workflow.Default()),
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler, BodyLimit: 1 << 10})
+	UploadBodyLimit(app, 64<<10)
+	app.Post("/appeals", h.CreateAppeal)
+	app.Get("/appeals/:id", h.GetAppealByID)
+	app.Get("/appeals/:id/attachments", h.GetAppealAttachments)
+	app.Post("/appeals/:id/attachments", h.CreateAppealAttachments)
+	app.Get("/appeals/:id/attachments/:attachment", h.DownloadAppealAttachment)
+
+	// Больше общего лимита тела, но в пределах лимита для загрузок.
+	pdf := []byte("%PDF-1.7\n" + strings.Repeat("receipt ", 512))
+	multipartRequest := func(path string, fields map[string]string, files map[string][]byte) *http.Request {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		for name, value := range fields {
+			w.WriteField(name, value)
+		}
+		for name, content := range files {
+			part, _ := w.CreateFormFile("attachments", name)
+			part.Write(content)
+		}
+		w.Close()
+		req := httptest.NewRequest("POST", path, &body)
+		req.Header.Set(fiber.HeaderContentType, w.FormDataContentType())
+		return req
+	}
+	send := func(req *http.Request) (*http.Response, []byte) {
+		t.Helper()
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("Request %s %s failed: %v", req.Method, req.URL, err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp, data
+	}
+
+	resp, data := send(multipartRequest("/appeals", map[string]string{"theme": "Billing", "message": "Charged twice"}, map[string][]byte{"receipt.pdf": pdf}))
+	var created struct {
+		Appeal models.Appeal `json:"appeal"`
+	}
+	json.Unmarshal(data, &created)
+	if resp.StatusCode != fiber.StatusCreated || created.Appeal.Theme != "Billing" || len(created.Appeal.Attachments) != 1 {
+		t.Fatalf("Expected the appeal with its attachment, got %d %s", resp.StatusCode, data)
+	}
+	id, attachment := created.Appeal.ID, created.Appeal.Attachments[0]
+
+	status, _, body := doRequest(t, app, "GET", "/appeals/"+id, "")
+	if attachments, _ := body["appeal"].(map[string]any)["attachments"].([]any); status != fiber.StatusOK || len(attachments) != 1 {
+		t.Errorf("Expected the attachment metadata with the appeal, got %d %v", status, body)
+	}
+
+	resp, data = send(multipartRequest("/appeals/"+id+"/attachments", nil, map[string][]byte{"script.sh": []byte("#!/bin/sh\nrm -rf /\n")}))
+	if resp.StatusCode != fiber.StatusUnprocessableEntity || !strings.Contains(string(data), "unsupported_attachment_type") {
+		t.Errorf("Expected 422 unsupported_attachment_type, got %d %s", resp.StatusCode, data)
+	}
+	resp, data = send(multipartRequest("/appeals/"+id+"/attachments", nil, map[string][]byte{"copy.pdf": pdf}))
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Errorf("Expected 201, got %d %s", resp.StatusCode, data)
+	}
+	status, _, body = doRequest(t, app, "GET", "/appeals/"+id+"/attachments", "")
+	if status != fiber.StatusOK || len(body["attachments"].([]any)) != 2 {
+		t.Errorf("Expected both attachments, got %d %v", status, body)
+	}
+
+	download := "/appeals/" + id + "/attachments/" + attachment.ID
+	tests := []struct {
+		name         string
+		rangeHeader  string
+		status       int
+		contentRange string
+		body         []byte
+	}{
+		{"Whole", "", fiber.StatusOK, "", pdf},
+		{"Prefix", "bytes=0-7", fiber.StatusPartialContent, "bytes 0-7/" + strconv.Itoa(len(pdf)), pdf[:8]},
+		{"Suffix", "bytes=-8", fiber.StatusPartialContent, "bytes " + strconv.Itoa(len(pdf)-8) + "-" + strconv.Itoa(len(pdf)-1) + "/" + strconv.Itoa(len(pdf)), pdf[len(pdf)-8:]},
+		{"Unsatisfiable", "bytes=100000-", fiber.StatusRequestedRangeNotSatisfiable, "bytes */" + strconv.Itoa(len(pdf)), nil},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", download, nil)
+		if tt.rangeHeader != "" {
+			req.Header.Set(fiber.HeaderRange, tt.rangeHeader)
+		}
+		resp, data := send(req)
+		if resp.StatusCode != tt.status || resp.Header.Get(fiber.HeaderContentRange) != tt.contentRange {
+			t.Errorf("%s: expected %d with range %q, got %d with %q", tt.name, tt.status, tt.contentRange, resp.StatusCode, resp.Header.Get(fiber.HeaderContentRange))
+			continue
+		}
+		if tt.body != nil && !bytes.Equal(data, tt.body) {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.body, data)
+		}
+		if tt.status == fiber.StatusOK && (resp.Header.Get(fiber.HeaderContentType) != "application/pdf" ||
+			resp.Header.Get(fiber.HeaderAcceptRanges) != "bytes" || !strings.Contains(resp.Header.Get(fiber.HeaderContentDisposition), "receipt.pdf")) {
+			t.Errorf("%s: unexpected headers %v", tt.name, resp.Header)
+		}
+	}
+
+	if status, _, problem := doRequest(t, app, "GET", "/appeals/"+id+"/attachments/missing", ""); status != fiber.StatusNotFound || problem["code"] != "attachment_not_found" {
+		t.Errorf("Expected 404 attachment_not_found, got %d %v", status, problem)
+	}
+
+	// Остальные запросы ограничены общим лимитом.
+	large := httptest.NewRequest("POST", "/appeals", strings.NewReader(`{"theme": "Billing", "message": "`+strings.Repeat("x", 4<<10)+`"}`))
+	large.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	// fasthttp отвечает 413 и закрывает соединение, app.Test видит это как ошибку.
+	if resp, err := app.Test(large); err == nil && resp.StatusCode != fiber.StatusRequestEntityTooLarge ||
+		err != nil && !strings.Contains(err.Error(), "body size exceeds") {
+		t.Errorf("Expected a large JSON body to be refused, got %v, %v", resp, err)
+	}
+}
+
+func TestTrackAppeal(t *testing.T) {
+	t.Parallel()
+
+	repo := repository.NewMemoryAppealRepository()
+	h := &Handlers{
+		Auth:    auth.New(repo),
+		Service: services.NewAppealService(repo, workflow.Default()),
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Get("/track/:token", RateLimit(3, time.Minute), h.TrackAppeal)
+	app.Use(h.Authenticate)
+	app.Post("/appeals", h.CreateAppeal)
+
+	_, secret, err := h.Auth.IssueKey(models.CreateAPIKeyRequest{Name: "jane", Subject: "jane", Roles: []models.Role{models.RoleRequester}})
+	if err != nil {
+		t.Fatalf("Failed to issue a key: %v", err)
+	}
+	req := httptest.NewRequest("POST", "/appeals", strings.NewReader(`{"theme": "Printer", "message": "Out of toner", "requester": {"email": "jane@example.com"}}`))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	req.Header.Set(HeaderAPIKey, secret)
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	var created struct {
+		Appeal models.Appeal `json:"appeal"`
+	}
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusCreated || created.Appeal.TrackingToken == "" || created.Appeal.Number == "" {
+		t.Fatalf("Expected a number and a tracking token, got %d %+v", resp.StatusCode, created.Appeal)
+	}
+
+	// Без ключа: номер, статус и даты, но ни текста, ни заявителя.
+	resp, err = app.Test(httptest.NewRequest("GET", "/track/"+created.Appeal.TrackingToken, nil))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	var body map[string]map[string]any
+	json.Unmarshal(data, &body)
+	tracked := body["appeal"]
+	if resp.StatusCode != fiber.StatusOK || tracked["number"] != created.Appeal.Number || tracked["status"] != string(models.StatusNew) {
+		t.Errorf("Expected the tracked appeal, got %d %s", resp.StatusCode, data)
+	}
+	for _, leak := range []string{"Out of toner", "jane", created.Appeal.ID} {
+		if strings.Contains(string(data), leak) {
+			t.Errorf("Expected %q not to be shown, got %s", leak, data)
+		}
+	}
+	if resp.Header.Get(fiber.HeaderCacheControl) != "no-store" {
+		t.Errorf("Expected the page not to be cached, got %v", resp.Header)
+	}
+
+	if status, _, problem := doRequest(t, app, "GET", "/track/guess", ""); status != fiber.StatusNotFound || problem["code"] != "tracking_token_not_found" {
+		t.Errorf("Expected 404 tracking_token_not_found, got %d %v", status, problem)
+	}
+	// Третий запрос с адреса за минуту был последним разрешённым.
+	if status, _, problem := doRequest(t, app, "GET", "/track/"+created.Appeal.TrackingToken, ""); status != fiber.StatusOK {
+		t.Errorf("Expected 200 within the limit, got %d %v", status, problem)
+	}
+	if status, _, problem := doRequest(t, app, "GET", "/track/"+created.Appeal.TrackingToken, ""); status != fiber.StatusTooManyRequests || problem["code"] != "too_many_requests" {
+		t.Errorf("Expected 429 too_many_requests, got %d %v", status, problem)
+	}
+}
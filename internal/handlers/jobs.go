@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"strconv"
+
+	"go_appeals/internal/access"
+	"go_appeals/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *Handlers) GetJobs(c *fiber.Ctx) error {
+	if err := h.authorizeJobs(c); err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"jobs": h.Scheduler.Jobs(),
+	})
+}
+
+// RunJob starts a job right away and answers before it finishes; poll
+// GET /jobs/:name/runs for the outcome.
+func (h *Handlers) RunJob(c *fiber.Ctx) error {
+	if err := h.authorizeJobs(c); err != nil {
+		return err
+	}
+	run, err := h.Scheduler.Trigger(c.Params("name"))
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"run": run,
+	})
+}
+
+func (h *Handlers) GetJobRuns(c *fiber.Ctx) error {
+	if err := h.authorizeJobs(c); err != nil {
+		return err
+	}
+	limit, err := parseLimit(c)
+	if err != nil {
+		return err
+	}
+
+	runs, err := h.Scheduler.Runs(c.Params("name"), limit)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"runs": runs,
+	})
+}
+
+// authorizeJobs lets through callers who may manage jobs for every tenant:
+// a run goes through all of them, and so does the run history. A caller
+// bound to a tenant never may, whatever its roles.
+func (h *Handlers) authorizeJobs(c *fiber.Ctx) error {
+	if err := h.authorize(c, access.ManageJobs); err != nil {
+		return err
+	}
+	principal, ok := c.Locals(principalLocal).(*models.Principal)
+	if !ok {
+		return nil
+	}
+	if principal.Tenant != "" {
+		return models.NewError(models.ErrForbidden, "tenant_mismatch", "jobs run for every tenant; %s belongs to tenant %s", principal.Subject, principal.Tenant)
+	}
+	return h.Service.Access().Check(principal, access.SwitchTenants)
+}
+
+// GetReminders handles GET /reminders?recipient=jane&appeal_id=...&limit=50.
+func (h *Handlers) GetReminders(c *fiber.Ctx) error {
+	filter, err := parseReminderFilter(c)
+	if err != nil {
+		return err
+	}
+
+	reminders, err := h.Service.GetReminders(requestContext(c), filter)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"reminders": reminders,
+	})
+}
+
+func (h *Handlers) GetMyReminders(c *fiber.Ctx) error {
+	filter, err := parseReminderFilter(c)
+	if err != nil {
+		return err
+	}
+
+	reminders, err := h.Service.GetMyReminders(requestContext(c), filter)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"reminders": reminders,
+	})
+}
+
+func parseReminderFilter(c *fiber.Ctx) (models.ReminderFilter, error) {
+	limit, err := parseLimit(c)
+	return models.ReminderFilter{
+		Recipient: c.Query("recipient"),
+		AppealID:  c.Query("appeal_id"),
+		Limit:     limit,
+	}, err
+}
+
+func parseLimit(c *fiber.Ctx) (int, error) {
+	value := c.Query("limit")
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, validationError("invalid_limit", "Invalid limit, expected a number")
+	}
+	return n, nil
+}
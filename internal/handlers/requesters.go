@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *Handlers) GetRequester(c *fiber.Ctx) error {
+	requester, err := h.Service.GetRequester(requestContext(c), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"requester": requester,
+	})
+}
+
+// GetRequesterAppeals lists the appeals of a requester; it accepts the same
+// parameters as the other listings.
+func (h *Handlers) GetRequesterAppeals(c *fiber.Ctx) error {
+	filter, err := parseAppealFilter(c)
+	if err != nil {
+		return err
+	}
+
+	page, err := h.Service.GetRequesterAppeals(requestContext(c), c.Params("id"), filter)
+	if err != nil {
+		return err
+	}
+	return c.JSON(page)
+}
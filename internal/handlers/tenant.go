@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"go_appeals/internal/access"
+	"go_appeals/internal/models"
+	"go_appeals/internal/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// HeaderTenant names the organization a request acts for.
+const HeaderTenant = "X-Tenant-ID"
+
+// tenantLocal is where ResolveTenant leaves the tenant for requestContext.
+const tenantLocal = "tenant"
+
+// ResolveTenant is middleware that decides which tenant a request acts for.
+// A caller whose key or token belongs to a tenant always acts for it, and
+// naming another one in X-Tenant-ID is refused. Other callers act for the
+// default tenant; only those allowed to switch tenants may choose another
+// with X-Tenant-ID. Without authentication the header is trusted. It must
+// come after Authenticate.
+func (h *Handlers) ResolveTenant(c *fiber.Ctx) error {
+	id := c.Get(HeaderTenant)
+	if principal, ok := c.Locals(principalLocal).(*models.Principal); ok {
+		home := principal.Tenant
+		if home == "" {
+			home = models.DefaultTenant
+		}
+		switch {
+		case id == "" || id == home:
+			id = home
+		case principal.Tenant != "":
+			return models.NewError(models.ErrForbidden, "tenant_mismatch", "%s belongs to tenant %s, not %s", principal.Subject, principal.Tenant, id)
+		default:
+			if err := h.Service.Access().Check(principal, access.SwitchTenants); err != nil {
+				return err
+			}
+		}
+	}
+	if id == "" {
+		id = models.DefaultTenant
+	}
+
+	if _, err := h.Service.Tenants().Get(id); err != nil {
+		return err
+	}
+	c.Locals(tenantLocal, id)
+	return c.Next()
+}
+
+// GetTenant returns the configuration of the tenant the request acts for.
+func (h *Handlers) GetTenant(c *fiber.Ctx) error {
+	t, err := h.Service.Tenants().Get(services.TenantFromContext(requestContext(c)))
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"tenant": t,
+	})
+}
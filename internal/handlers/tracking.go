@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+)
+
+// TrackAppeal is the public status page of an appeal, found by the
+// tracking token given when it was filed. It needs no credentials, so it
+// shows only what models.TrackedAppeal allows.
+func (h *Handlers) TrackAppeal(c *fiber.Ctx) error {
+	// The token is in the URL; keep it out of caches and referrers.
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+
+	appeal, err := h.Service.TrackAppeal(requestContext(c), c.Params("token"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"appeal": appeal,
+	})
+}
+
+// RateLimit is middleware that lets each client IP make at most max
+// requests per window. Failed requests count too, so tokens cannot be
+// guessed quickly.
+func RateLimit(max int, window time.Duration) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:        max,
+		Expiration: window,
+		LimitReached: func(c *fiber.Ctx) error {
+			return fiber.NewError(fiber.StatusTooManyRequests, "too many requests, try again later")
+		},
+	})
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"strconv"
+
+	"go_appeals/internal/access"
+	"go_appeals/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CreateWebhook subscribes a URL to events. The response is the only place
+// the signing secret is shown.
+func (h *Handlers) CreateWebhook(c *fiber.Ctx) error {
+	if err := h.authorize(c, access.ManageWebhooks); err != nil {
+		return err
+	}
+	var req models.CreateWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return badRequest("Cannot parse JSON")
+	}
+
+	sub, err := h.Webhooks.CreateSubscription(tenantOf(c), req)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"webhook": sub,
+	})
+}
+
+func (h *Handlers) GetWebhooks(c *fiber.Ctx) error {
+	if err := h.authorize(c, access.ManageWebhooks); err != nil {
+		return err
+	}
+	subs, err := h.Webhooks.Subscriptions(tenantOf(c))
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"webhooks": subs,
+	})
+}
+
+func (h *Handlers) GetWebhook(c *fiber.Ctx) error {
+	if err := h.authorize(c, access.ManageWebhooks); err != nil {
+		return err
+	}
+	sub, err := h.Webhooks.Subscription(tenantOf(c), c.Params("id"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"webhook": sub,
+	})
+}
+
+func (h *Handlers) DeleteWebhook(c *fiber.Ctx) error {
+	if err := h.authorize(c, access.ManageWebhooks); err != nil {
+		return err
+	}
+	if err := h.Webhooks.DeleteSubscription(tenantOf(c), c.Params("id")); err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"message": "Webhook deleted successfully",
+	})
+}
+
+func (h *Handlers) GetWebhookDeliveries(c *fiber.Ctx) error {
+	if err := h.authorize(c, access.ManageWebhooks); err != nil {
+		return err
+	}
+	limit, err := parseLimit(c)
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := h.Webhooks.Deliveries(tenantOf(c), c.Params("id"), limit)
+	if err != nil {
+		return err
+	}
+	return c.JSON(fiber.Map{
+		"deliveries": deliveries,
+	})
+}
+
+// RedeliverWebhook queues a finished delivery again; the new delivery is
+// attempted in the background.
+func (h *Handlers) RedeliverWebhook(c *fiber.Ctx) error {
+	if err := h.authorize(c, access.ManageWebhooks); err != nil {
+		return err
+	}
+	id, err := strconv.ParseInt(c.Params("delivery"), 10, 64)
+	if err != nil {
+		return models.NewError(models.ErrNotFound, "delivery_not_found", "delivery %s not found", c.Params("delivery"))
+	}
+
+	delivery, err := h.Webhooks.Redeliver(tenantOf(c), c.Params("id"), id)
+	if err != nil {
+		return err
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"delivery": delivery,
+	})
+}
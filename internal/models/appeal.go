@@ -1,6 +1,11 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
 
 type AppealStatus string
 
@@ -9,30 +14,162 @@ const (
 	StatusInProgress AppealStatus = "InProgress"
 	StatusCompleted  AppealStatus = "Completed"
 	StatusCancelled  AppealStatus = "Cancelled"
+
+	StatusOnHold              AppealStatus = "OnHold"
+	StatusWaitingForRequester AppealStatus = "WaitingForRequester"
+	StatusRejected            AppealStatus = "Rejected"
+	StatusReopened            AppealStatus = "Reopened"
 )
 
+// DefaultTenant owns the records stored without a tenant, among them every
+// record from before tenants were introduced.
+const DefaultTenant = "default"
+
 type Appeal struct {
 	ID           string       `json:"id"`
+	Number       string       `json:"number,omitempty"`
+	TenantID     string       `json:"tenant_id"`
 	Theme        string       `json:"theme"`
 	Message      string       `json:"message"`
 	Status       AppealStatus `json:"status"`
 	Solution     string       `json:"solution,omitempty"`
-	CanselReason string       `json:"cansel_reason,omitempty"`
+	CancelReason string       `json:"cancel_reason,omitempty"`
+	Assignee     string       `json:"assignee,omitempty"`
+	CreatedBy    string       `json:"created_by,omitempty"`
 	CreatedAt    time.Time    `json:"created_at"`
 	UpdatedAt    time.Time    `json:"updated_at"`
+
+	Priority        AppealPriority `json:"priority"`
+	ResponseDueAt   *time.Time     `json:"response_due_at,omitempty"`
+	ResolutionDueAt *time.Time     `json:"resolution_due_at,omitempty"`
+	RespondedAt     *time.Time     `json:"responded_at,omitempty"`
+	ResolvedAt      *time.Time     `json:"resolved_at,omitempty"`
+	// SLAPausedAt is set while the SLA clock is stopped; SLAPausedFor is
+	// the working time it has stood still so far.
+	SLAPausedAt  *time.Time    `json:"sla_paused_at,omitempty"`
+	SLAPausedFor time.Duration `json:"-"`
+	EscalatedAt  *time.Time    `json:"escalated_at,omitempty"`
+
+	// Attachments and Requester are only filled in when an appeal is
+	// created or fetched by ID.
+	Attachments []*Attachment `json:"attachments,omitempty"`
+	RequesterID string        `json:"requester_id,omitempty"`
+	Requester   *Requester    `json:"requester,omitempty"`
+
+	// TrackingToken lets the requester follow the appeal without an
+	// account. Only its hash is stored, so it is only known right after the
+	// appeal is created.
+	TrackingToken     string `json:"tracking_token,omitempty"`
+	TrackingTokenHash string `json:"-"`
+}
+
+// appealJSON is Appeal with the cancellation reason also under its old,
+// misspelt name cansel_reason, which is deprecated but still read by
+// clients written before it was corrected.
+type appealJSON struct {
+	plainAppeal
+	CanselReason string `json:"cansel_reason,omitempty"`
+}
+
+type plainAppeal Appeal
+
+func (a Appeal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(appealJSON{plainAppeal(a), a.CancelReason})
+}
+
+// UnmarshalJSON also accepts the old name, as in events stored before the
+// rename.
+func (a *Appeal) UnmarshalJSON(data []byte) error {
+	var decoded appealJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*a = Appeal(decoded.plainAppeal)
+	if a.CancelReason == "" {
+		a.CancelReason = decoded.CanselReason
+	}
+	return nil
+}
+
+// StatusHistoryEntry records a single status transition of an appeal.
+// FromStatus is empty for the entry written when the appeal is created.
+type StatusHistoryEntry struct {
+	ID         int64        `json:"id"`
+	TenantID   string       `json:"-"`
+	AppealID   string       `json:"appeal_id"`
+	FromStatus AppealStatus `json:"from_status,omitempty"`
+	ToStatus   AppealStatus `json:"to_status"`
+	Actor      string       `json:"actor,omitempty"`
+	Reason     string       `json:"reason,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
 }
 
 type CreateAppealRequest struct {
-	Theme   string `json:"theme" validate:"required,min=1"`
-	Message string `json:"message" validate:"required,min=1"`
+	Theme    string         `json:"theme" form:"theme" validate:"required,min=1"`
+	Message  string         `json:"message" form:"message" validate:"required,min=1"`
+	Priority AppealPriority `json:"priority,omitempty" form:"priority"`
+
+	// Requester says who files the appeal, so they can be answered.
+	Requester *RequesterDetails `json:"requester,omitempty" form:"requester"`
+
+	// Attachments are files sent along with a multipart request.
+	Attachments []AttachmentUpload `json:"-" form:"-"`
 }
 
 type UpdateAppealSolutionRequest struct {
 	Solution string `json:"solution" validate:"required,min=1"`
 }
 
+const MaxCancelReasonLength = 1000
+
 type UpdateAppealCancelRequest struct {
-	Reason string `json:"reason" validate:"required,min=1"`
+	Reason string `json:"reason" validate:"required,min=1,max=1000"`
+}
+
+func (r UpdateAppealCancelRequest) Validate() error {
+	reason := strings.TrimSpace(r.Reason)
+	if reason == "" {
+		return NewError(ErrValidation, "reason_required", "cancellation reason is required")
+	}
+	if utf8.RuneCountInString(reason) > MaxCancelReasonLength {
+		return NewError(ErrValidation, "reason_too_long", "cancellation reason must not exceed %d characters", MaxCancelReasonLength)
+	}
+	return nil
+}
+
+// UpdateAppealReasonRequest is the body of transitions that must be
+// explained: hold, wait, reject and reopen. The reason ends up in the
+// status history.
+type UpdateAppealReasonRequest struct {
+	Reason string `json:"reason" validate:"required,min=1,max=1000"`
+}
+
+func (r UpdateAppealReasonRequest) Validate() error {
+	reason := strings.TrimSpace(r.Reason)
+	if reason == "" {
+		return NewError(ErrValidation, "reason_required", "reason is required")
+	}
+	if utf8.RuneCountInString(reason) > MaxCancelReasonLength {
+		return NewError(ErrValidation, "reason_too_long", "reason must not exceed %d characters", MaxCancelReasonLength)
+	}
+	return nil
+}
+
+const MaxAssigneeLength = 255
+
+type AssignAppealRequest struct {
+	Assignee string `json:"assignee" validate:"required,min=1,max=255"`
+}
+
+func (r AssignAppealRequest) Validate() error {
+	assignee := strings.TrimSpace(r.Assignee)
+	if assignee == "" {
+		return NewError(ErrValidation, "assignee_required", "assignee is required")
+	}
+	if utf8.RuneCountInString(assignee) > MaxAssigneeLength {
+		return NewError(ErrValidation, "assignee_too_long", "assignee must not exceed %d characters", MaxAssigneeLength)
+	}
+	return nil
 }
 
 type FilterDatesRequest struct {
@@ -57,14 +194,18 @@ func (a *Appeal) IsCancelled() bool {
 	return a.Status == StatusCancelled
 }
 
-func (a *Appeal) CanStartProcessing() bool {
-	return a.Status == StatusNew || a.Status == StatusCancelled
+func (a *Appeal) IsOnHold() bool {
+	return a.Status == StatusOnHold
 }
 
-func (a *Appeal) CanComplete() bool {
-	return a.Status == StatusInProgress
+func (a *Appeal) IsWaitingForRequester() bool {
+	return a.Status == StatusWaitingForRequester
+}
+
+func (a *Appeal) IsRejected() bool {
+	return a.Status == StatusRejected
 }
 
-func (a *Appeal) CanCancel() bool {
-	return a.Status == StatusNew || a.Status == StatusInProgress
-}
\ No newline at end of file
+func (a *Appeal) IsReopened() bool {
+	return a.Status == StatusReopened
+}
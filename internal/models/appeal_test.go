@@ -1,6 +1,8 @@
 package models
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 )
@@ -64,83 +66,28 @@ func TestAppealStatusMethods(t *testing.T) {
 			t.Errorf("Expected appeal not to be cancelled, got %s", appeal.Status)
 		}
 	})
-}
-
-func TestAppealCanMethods(t *testing.T) {
-	t.Parallel()
-
-	appeal := &Appeal{
-		ID:        "test-id",
-		Theme:     "Test Theme",
-		Message:   "Test Message",
-		Status:    StatusNew,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-
-	t.Run("CanStartProcessing", func(t *testing.T) {
-		appeal.Status = StatusNew
-		if !appeal.CanStartProcessing() {
-			t.Error("Expected appeal with StatusNew to be able to start processing")
-		}
-
-		appeal.Status = StatusCancelled
-		if !appeal.CanStartProcessing() {
-			t.Error("Expected appeal with StatusCancelled to be able to start processing")
-		}
-
-		appeal.Status = StatusInProgress
-		if appeal.CanStartProcessing() {
-			t.Error("Expected appeal with StatusInProgress not to be able to start processing")
-		}
-
-		appeal.Status = StatusCompleted
-		if appeal.CanStartProcessing() {
-			t.Error("Expected appeal with StatusCompleted not to be able to start processing")
-		}
-	})
 
-	t.Run("CanComplete", func(t *testing.T) {
-		appeal.Status = StatusInProgress
-		if !appeal.CanComplete() {
-			t.Error("Expected appeal with StatusInProgress to be able to complete")
+	t.Run("PausedAndReopened", func(t *testing.T) {
+		checks := []struct {
+			status AppealStatus
+			is     func() bool
+		}{
+			{StatusOnHold, appeal.IsOnHold},
+			{StatusWaitingForRequester, appeal.IsWaitingForRequester},
+			{StatusRejected, appeal.IsRejected},
+			{StatusReopened, appeal.IsReopened},
 		}
 
-		appeal.Status = StatusNew
-		if appeal.CanComplete() {
-			t.Error("Expected appeal with StatusNew not to be able to complete")
-		}
+		for _, check := range checks {
+			appeal.Status = check.status
+			if !check.is() {
+				t.Errorf("Expected status check to match %s", check.status)
+			}
 
-		appeal.Status = StatusCompleted
-		if appeal.CanComplete() {
-			t.Error("Expected appeal with StatusCompleted not to be able to complete")
-		}
-
-		appeal.Status = StatusCancelled
-		if appeal.CanComplete() {
-			t.Error("Expected appeal with StatusCancelled not to be able to complete")
-		}
-	})
-
-	t.Run("CanCancel", func(t *testing.T) {
-		appeal.Status = StatusNew
-		if !appeal.CanCancel() {
-			t.Error("Expected appeal with StatusNew to be able to cancel")
-		}
-
-		appeal.Status = StatusInProgress
-		if !appeal.CanCancel() {
-			t.Error("Expected appeal with StatusInProgress to be able to cancel")
-		}
-
-		appeal.Status = StatusCompleted
-		if appeal.CanCancel() {
-			t.Error("Expected appeal with StatusCompleted not to be able to cancel")
-		}
-
-		appeal.Status = StatusCancelled
-		if appeal.CanCancel() {
-			t.Error("Expected appeal with StatusCancelled not to be able to cancel")
+			appeal.Status = StatusInProgress
+			if check.is() {
+				t.Errorf("Expected status check for %s not to match %s", check.status, appeal.Status)
+			}
 		}
 	})
 }
@@ -196,6 +143,66 @@ func TestUpdateAppealCancelRequest(t *testing.T) {
 	if req.Reason != "Cancellation reason" {
 		t.Errorf("Expected Reason to be 'Cancellation reason', got %s", req.Reason)
 	}
+
+	if err := req.Validate(); err != nil {
+		t.Errorf("Expected valid reason, got %v", err)
+	}
+
+	for _, reason := range []string{"", "   ", strings.Repeat("я", MaxCancelReasonLength+1)} {
+		if err := (UpdateAppealCancelRequest{Reason: reason}).Validate(); err == nil {
+			t.Errorf("Expected reason of length %d to be rejected", len(reason))
+		}
+	}
+}
+
+func TestAppealCancelReasonJSON(t *testing.T) {
+	t.Parallel()
+
+	data, err := json.Marshal(&Appeal{ID: "1", Status: StatusCancelled, CancelReason: "Duplicate"})
+	if err != nil {
+		t.Fatalf("Failed to marshal appeal: %v", err)
+	}
+	var fields map[string]any
+	json.Unmarshal(data, &fields)
+	// Старое имя с опечаткой пока отдаётся для совместимости.
+	if fields["cancel_reason"] != "Duplicate" || fields["cansel_reason"] != "Duplicate" || fields["id"] != "1" {
+		t.Errorf("Expected the reason under both names, got %s", data)
+	}
+
+	var decoded Appeal
+	if err := json.Unmarshal([]byte(`{"id": "1", "cansel_reason": "Old event"}`), &decoded); err != nil || decoded.CancelReason != "Old event" {
+		t.Errorf("Expected the old name to be read, got %+v, %v", decoded, err)
+	}
+	if data, _ := json.Marshal(&Appeal{ID: "2"}); strings.Contains(string(data), "reason") {
+		t.Errorf("Expected no reason when there is none, got %s", data)
+	}
+}
+
+func TestUpdateAppealReasonRequest(t *testing.T) {
+	t.Parallel()
+
+	if err := (UpdateAppealReasonRequest{Reason: "Need a scan of the contract"}).Validate(); err != nil {
+		t.Errorf("Expected valid reason, got %v", err)
+	}
+
+	for _, reason := range []string{"", "\t", strings.Repeat("я", MaxCancelReasonLength+1)} {
+		if err := (UpdateAppealReasonRequest{Reason: reason}).Validate(); err == nil {
+			t.Errorf("Expected reason of length %d to be rejected", len(reason))
+		}
+	}
+}
+
+func TestAssignAppealRequest(t *testing.T) {
+	t.Parallel()
+
+	if err := (AssignAppealRequest{Assignee: "operator@example.com"}).Validate(); err != nil {
+		t.Errorf("Expected valid assignee, got %v", err)
+	}
+	for _, assignee := range []string{"", "  ", strings.Repeat("a", MaxAssigneeLength+1)} {
+		if err := (AssignAppealRequest{Assignee: assignee}).Validate(); err == nil {
+			t.Errorf("Expected assignee of length %d to be rejected", len(assignee))
+		}
+	}
 }
 
 func TestFilterDatesRequest(t *testing.T) {
@@ -217,4 +224,3 @@ func TestFilterDatesRequest(t *testing.T) {
 		t.Errorf("Expected EndDate to be '2023-12-31', got %s", req.EndDate)
 	}
 }
-
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"io"
+	"time"
+)
+
+// Attachment describes a file attached to an appeal. The content itself is
+// kept in blob storage under SHA256.
+type Attachment struct {
+	ID          string    `json:"id"`
+	TenantID    string    `json:"-"`
+	AppealID    string    `json:"appeal_id"`
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	UploadedBy  string    `json:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AttachmentUpload is a file as it is received, before it is stored.
+type AttachmentUpload struct {
+	FileName string
+	Content  io.Reader
+}
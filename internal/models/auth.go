@@ -0,0 +1,84 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Authentication methods a Principal can come from.
+const (
+	AuthAPIKey = "api_key"
+	AuthJWT    = "jwt"
+)
+
+// Role is a set of permissions granted to a caller by the access policy.
+type Role string
+
+const (
+	RoleRequester  Role = "requester"
+	RoleOperator   Role = "operator"
+	RoleSupervisor Role = "supervisor"
+	RoleAdmin      Role = "admin"
+)
+
+// Principal is the authenticated caller. Subject identifies them in status
+// history, assignments and reminders. A caller bound to a tenant only works
+// with its records; one without a tenant picks one per request.
+type Principal struct {
+	Subject string `json:"subject"`
+	Name    string `json:"name,omitempty"`
+	Tenant  string `json:"tenant_id,omitempty"`
+	Roles   []Role `json:"roles"`
+	Method  string `json:"method"`
+	// KeyID is the API key the caller used, if any.
+	KeyID string `json:"key_id,omitempty"`
+}
+
+// APIKey is a stored API key. Only a hash of the secret is kept; the secret
+// itself is shown once, when the key is issued. Prefix is the start of the
+// secret, enough to recognise a key in a list.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Subject    string     `json:"subject"`
+	Tenant     string     `json:"tenant_id,omitempty"`
+	Roles      []Role     `json:"roles"`
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// Usable reports whether the key may still authenticate at now.
+func (k *APIKey) Usable(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// CreateAPIKeyRequest issues a key acting as Subject with Roles in Tenant.
+// ExpiresIn is a duration such as 90d; without it the key does not expire.
+// Tenant is never read from a request body: keys issued over the API belong
+// to the tenant of the request.
+type CreateAPIKeyRequest struct {
+	Name      string `json:"name"`
+	Subject   string `json:"subject"`
+	Tenant    string `json:"-"`
+	Roles     []Role `json:"roles"`
+	ExpiresIn string `json:"expires_in"`
+}
+
+func (r CreateAPIKeyRequest) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return NewError(ErrValidation, "name_required", "name is required")
+	}
+	if strings.TrimSpace(r.Subject) == "" {
+		return NewError(ErrValidation, "subject_required", "subject is required")
+	}
+	for _, role := range r.Roles {
+		if strings.TrimSpace(string(role)) == "" || strings.Contains(string(role), ",") {
+			return NewError(ErrValidation, "invalid_role", "role names must not be empty or contain commas")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type CommentVisibility string
+
+const (
+	// CommentPublic is a reply the requester sees.
+	CommentPublic CommentVisibility = "public"
+	// CommentInternal is a note only staff see.
+	CommentInternal CommentVisibility = "internal"
+)
+
+const MaxCommentLength = 10000
+
+// Comment is a message in the thread of an appeal. Edits keeps the earlier
+// versions of Body, oldest first; EditedAt is set once it has been edited.
+type Comment struct {
+	ID         int64             `json:"id"`
+	TenantID   string            `json:"-"`
+	AppealID   string            `json:"appeal_id"`
+	Visibility CommentVisibility `json:"visibility"`
+	Author     string            `json:"author"`
+	Body       string            `json:"body"`
+	CreatedAt  time.Time         `json:"created_at"`
+	EditedAt   *time.Time        `json:"edited_at,omitempty"`
+	Edits      []*CommentEdit    `json:"edits,omitempty"`
+}
+
+// CommentEdit is a version of a comment that was replaced: Body is the text
+// before EditedBy changed it at EditedAt.
+type CommentEdit struct {
+	ID        int64     `json:"-"`
+	CommentID int64     `json:"-"`
+	Body      string    `json:"body"`
+	EditedBy  string    `json:"edited_by"`
+	EditedAt  time.Time `json:"edited_at"`
+}
+
+// CreateCommentRequest adds a comment, a public reply unless Visibility
+// says internal.
+type CreateCommentRequest struct {
+	Body       string            `json:"body"`
+	Visibility CommentVisibility `json:"visibility"`
+}
+
+func (r CreateCommentRequest) Validate() error {
+	switch r.Visibility {
+	case "", CommentPublic, CommentInternal:
+	default:
+		return NewError(ErrValidation, "invalid_visibility", "visibility must be %s or %s", CommentPublic, CommentInternal)
+	}
+	return validateCommentBody(r.Body)
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body"`
+}
+
+func (r UpdateCommentRequest) Validate() error {
+	return validateCommentBody(r.Body)
+}
+
+func validateCommentBody(body string) error {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return NewError(ErrValidation, "body_required", "comment body is required")
+	}
+	if utf8.RuneCountInString(body) > MaxCommentLength {
+		return NewError(ErrValidation, "body_too_long", "comment body must not exceed %d characters", MaxCommentLength)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error kinds shared by the repository, service and HTTP layers. Check them
+// with errors.Is; the HTTP layer maps each kind to a status code.
+var (
+	ErrNotFound          = errors.New("not found")
+	ErrInvalidTransition = errors.New("invalid transition")
+	ErrValidation        = errors.New("validation failed")
+	ErrConflict          = errors.New("conflict")
+	ErrUnauthorized      = errors.New("unauthorized")
+	ErrForbidden         = errors.New("forbidden")
+	ErrTooLarge          = errors.New("too large")
+)
+
+// Error is a domain error with a machine-readable code such as
+// "appeal_not_found". Message is meant for humans and may change.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+}
+
+func NewError(kind error, code, format string, args ...any) *Error {
+	return &Error{Kind: kind, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// ErrorCode returns the code of the first *Error in err's chain, or "" if
+// there is none.
+func ErrorCode(err error) string {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Code
+	}
+	return ""
+}
+
+func AppealNotFound(id string) *Error {
+	return NewError(ErrNotFound, "appeal_not_found", "appeal with ID %s not found", id)
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	t.Parallel()
+
+	err := AppealNotFound("42")
+	if err.Error() != "appeal with ID 42 not found" {
+		t.Errorf("Unexpected message: %s", err.Error())
+	}
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrValidation) {
+		t.Errorf("Expected error to be ErrNotFound only")
+	}
+
+	wrapped := fmt.Errorf("loading appeal: %w", err)
+	if !errors.Is(wrapped, ErrNotFound) {
+		t.Errorf("Expected wrapped error to keep its kind")
+	}
+	if code := ErrorCode(wrapped); code != "appeal_not_found" {
+		t.Errorf("Expected code appeal_not_found, got %q", code)
+	}
+
+	if code := ErrorCode(errors.New("boom")); code != "" {
+		t.Errorf("Expected no code for a plain error, got %q", code)
+	}
+
+	if err := (UpdateAppealCancelRequest{}).Validate(); !errors.Is(err, ErrValidation) || ErrorCode(err) != "reason_required" {
+		t.Errorf("Expected reason_required validation error, got %v", err)
+	}
+}
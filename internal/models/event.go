@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Event types raised outside the workflow. Transitions raise the event
+// named in the workflow definition, e.g. appeal.started.
+const (
+	EventAppealCreated   = "appeal.created"
+	EventAppealAssigned  = "appeal.assigned"
+	EventAppealEscalated = "appeal.escalated"
+)
+
+// Event is something that happened to an appeal, with the appeal as it was
+// right after. FromStatus is set for status changes. ID is unique per event
+// and lets receivers drop duplicates; Sequence orders the events as they
+// were committed.
+type Event struct {
+	ID         string       `json:"id"`
+	Sequence   int64        `json:"sequence,omitempty"`
+	TenantID   string       `json:"tenant_id"`
+	Type       string       `json:"type"`
+	AppealID   string       `json:"appeal_id"`
+	FromStatus AppealStatus `json:"from_status,omitempty"`
+	Actor      string       `json:"actor,omitempty"`
+	Appeal     *Appeal      `json:"appeal"`
+	OccurredAt time.Time    `json:"occurred_at"`
+}
+
+// EventFilter narrows a stream of events. A status matches events that move
+// the appeal into or out of it, so a view of that status can both add and
+// drop the appeal. An empty Tenant matches events of every tenant.
+type EventFilter struct {
+	Tenant   string
+	Statuses []AppealStatus
+	Assignee string
+}
+
+func (f EventFilter) Matches(event *Event) bool {
+	if f.Tenant != "" && event.TenantID != f.Tenant {
+		return false
+	}
+	if event.Appeal == nil {
+		return len(f.Statuses) == 0 && f.Assignee == ""
+	}
+	if f.Assignee != "" && event.Appeal.Assignee != f.Assignee {
+		return false
+	}
+	if len(f.Statuses) == 0 {
+		return true
+	}
+	for _, status := range f.Statuses {
+		if status == event.Appeal.Status || status == event.FromStatus {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package models
+
+import "testing"
+
+func TestEventFilter(t *testing.T) {
+	t.Parallel()
+
+	completed := &Event{
+		Type:       "appeal.completed",
+		FromStatus: StatusInProgress,
+		Appeal:     &Appeal{Status: StatusCompleted, Assignee: "jane"},
+	}
+
+	tests := []struct {
+		name   string
+		filter EventFilter
+		want   bool
+	}{
+		{"no filter", EventFilter{}, true},
+		{"new status", EventFilter{Statuses: []AppealStatus{StatusCompleted}}, true},
+		{"previous status", EventFilter{Statuses: []AppealStatus{StatusNew, StatusInProgress}}, true},
+		{"other status", EventFilter{Statuses: []AppealStatus{StatusNew}}, false},
+		{"assignee", EventFilter{Assignee: "jane"}, true},
+		{"other assignee", EventFilter{Assignee: "bob"}, false},
+		{"status and other assignee", EventFilter{Statuses: []AppealStatus{StatusCompleted}, Assignee: "bob"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Matches(completed); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
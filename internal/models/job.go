@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+type JobRunStatus string
+
+const (
+	JobRunning   JobRunStatus = "running"
+	JobSucceeded JobRunStatus = "succeeded"
+	JobFailed    JobRunStatus = "failed"
+)
+
+type JobTrigger string
+
+const (
+	TriggerSchedule JobTrigger = "schedule"
+	TriggerManual   JobTrigger = "manual"
+)
+
+// JobRun records one execution of a scheduled job. Result is the job's own
+// summary of what it did.
+type JobRun struct {
+	ID          int64        `json:"id"`
+	Job         string       `json:"job"`
+	TriggeredBy JobTrigger   `json:"triggered_by"`
+	Status      JobRunStatus `json:"status"`
+	Result      string       `json:"result,omitempty"`
+	Error       string       `json:"error,omitempty"`
+	StartedAt   time.Time    `json:"started_at"`
+	FinishedAt  *time.Time   `json:"finished_at,omitempty"`
+}
+
+type ReminderKind string
+
+const (
+	ReminderResponseDue     ReminderKind = "response_due"
+	ReminderResolutionDue   ReminderKind = "resolution_due"
+	ReminderRequesterSilent ReminderKind = "requester_silent"
+	ReminderEscalated       ReminderKind = "escalated"
+)
+
+// Reminder tells Recipient, or the whole team when it is empty, that
+// something about an appeal needs attention by DueAt. There is at most one
+// reminder of each kind per appeal and due time.
+type Reminder struct {
+	ID        int64        `json:"id"`
+	TenantID  string       `json:"-"`
+	AppealID  string       `json:"appeal_id"`
+	Kind      ReminderKind `json:"kind"`
+	Recipient string       `json:"recipient,omitempty"`
+	Message   string       `json:"message"`
+	DueAt     time.Time    `json:"due_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// ReminderFilter selects reminders, newest first. Empty fields match
+// anything.
+type ReminderFilter struct {
+	Recipient string
+	AppealID  string
+	Limit     int
+}
+
+func (f *ReminderFilter) Normalize() error {
+	if f.Limit == 0 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit < 0 || f.Limit > MaxPageSize {
+		return NewError(ErrValidation, "invalid_limit", "limit must be between 1 and %d", MaxPageSize)
+	}
+	return nil
+}
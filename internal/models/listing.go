@@ -0,0 +1,144 @@
+package models
+
+import "time"
+
+type AppealSortField string
+
+const (
+	SortByCreatedAt AppealSortField = "created_at"
+	SortByUpdatedAt AppealSortField = "updated_at"
+)
+
+// SLAFilter selects appeals by the state of their deadlines.
+type SLAFilter string
+
+const (
+	SLADueSoon  SLAFilter = "due_soon"
+	SLABreached SLAFilter = "breached"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// AppealFilter selects a page of appeals. Zero values mean "no constraint";
+// time bounds are inclusive. Cursor is the NextCursor of the previous page
+// and must be used with the same filter and sort.
+//
+// SLA filters are evaluated as of AsOf, which defaults to the current time:
+// SLADueSoon keeps appeals with an unmet deadline within DueWithin from then,
+// SLABreached keeps appeals that missed either deadline.
+type AppealFilter struct {
+	Statuses    []AppealStatus
+	Priorities  []AppealPriority
+	Theme       string
+	Assignee    string
+	CreatedBy   string
+	RequesterID string
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	UpdatedFrom time.Time
+	UpdatedTo   time.Time
+	SortBy      AppealSortField
+	Descending  bool
+	Limit       int
+	Cursor      string
+	SLA         SLAFilter
+	DueWithin   time.Duration
+	AsOf        time.Time
+}
+
+type AppealPage struct {
+	Appeals    []*Appeal `json:"appeals"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// Normalize fills in the default sort and page size and rejects filters the
+// stores cannot serve.
+func (f *AppealFilter) Normalize() error {
+	switch f.SortBy {
+	case "":
+		f.SortBy = SortByCreatedAt
+	case SortByCreatedAt, SortByUpdatedAt:
+	default:
+		return NewError(ErrValidation, "invalid_sort", "cannot sort by %q, use created_at or updated_at", f.SortBy)
+	}
+
+	if f.Limit == 0 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit < 0 || f.Limit > MaxPageSize {
+		return NewError(ErrValidation, "invalid_limit", "limit must be between 1 and %d", MaxPageSize)
+	}
+
+	if !f.CreatedFrom.IsZero() && !f.CreatedTo.IsZero() && f.CreatedFrom.After(f.CreatedTo) {
+		return NewError(ErrValidation, "invalid_range", "created_from must not be after created_to")
+	}
+	if !f.UpdatedFrom.IsZero() && !f.UpdatedTo.IsZero() && f.UpdatedFrom.After(f.UpdatedTo) {
+		return NewError(ErrValidation, "invalid_range", "updated_from must not be after updated_to")
+	}
+
+	for _, priority := range f.Priorities {
+		if !priority.Valid() {
+			return NewError(ErrValidation, "invalid_priority", "unknown priority %q, use low, normal, high or urgent", priority)
+		}
+	}
+
+	switch f.SLA {
+	case "":
+	case SLADueSoon:
+		if f.DueWithin <= 0 {
+			return NewError(ErrValidation, "invalid_due_within", "due_within must be a positive duration")
+		}
+	case SLABreached:
+	default:
+		return NewError(ErrValidation, "invalid_sla", "unknown sla filter %q, use due_soon or breached", f.SLA)
+	}
+	if f.SLA != "" && f.AsOf.IsZero() {
+		f.AsOf = time.Now()
+	}
+
+	return nil
+}
+
+// SortValue returns the timestamp the appeal is ordered by under field.
+func (a *Appeal) SortValue(field AppealSortField) time.Time {
+	if field == SortByUpdatedAt {
+		return a.UpdatedAt
+	}
+	return a.CreatedAt
+}
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+type SearchRequest struct {
+	Query  string
+	Limit  int
+	Offset int
+}
+
+func (r *SearchRequest) Normalize() error {
+	if r.Limit == 0 {
+		r.Limit = DefaultSearchLimit
+	}
+	if r.Limit < 0 || r.Limit > MaxSearchLimit {
+		return NewError(ErrValidation, "invalid_limit", "limit must be between 1 and %d", MaxSearchLimit)
+	}
+	if r.Offset < 0 {
+		return NewError(ErrValidation, "invalid_offset", "offset must not be negative")
+	}
+	return nil
+}
+
+// SearchResult is an appeal matching a search. Rank orders results within
+// one response; Highlights maps field names to snippets with the matching
+// words wrapped in <mark>.
+type SearchResult struct {
+	Appeal     *Appeal           `json:"appeal"`
+	Rank       float64           `json:"rank"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"net/mail"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ContactChannel is how a requester prefers to be answered.
+type ContactChannel string
+
+const (
+	ChannelEmail ContactChannel = "email"
+	ChannelPhone ContactChannel = "phone"
+	ChannelPost  ContactChannel = "post"
+)
+
+// Requester is the person who files appeals. Within a tenant there is one
+// requester per email address and per phone number, both kept normalized.
+// Subject is the caller who gave the details when filing for themselves;
+// nobody else without access to contact details may change them.
+type Requester struct {
+	ID               string         `json:"id"`
+	TenantID         string         `json:"-"`
+	Name             string         `json:"name,omitempty"`
+	Email            string         `json:"email,omitempty"`
+	Phone            string         `json:"phone,omitempty"`
+	Address          string         `json:"address,omitempty"`
+	PreferredChannel ContactChannel `json:"preferred_channel,omitempty"`
+	Subject          string         `json:"-"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+}
+
+// Redacted returns a copy that identifies the requester without giving away
+// how to reach them: the name is cut to the first name and initials, the
+// email and phone are masked and the address is left out.
+func (r *Requester) Redacted() *Requester {
+	redacted := *r
+	redacted.Address = ""
+
+	words := strings.Fields(r.Name)
+	for i := 1; i < len(words); i++ {
+		words[i] = string([]rune(words[i])[:1]) + "."
+	}
+	redacted.Name = strings.Join(words, " ")
+
+	if local, domain, ok := strings.Cut(r.Email, "@"); ok && local != "" {
+		redacted.Email = string([]rune(local)[:1]) + "***@" + domain
+	}
+	if n := len(r.Phone); n > 2 {
+		redacted.Phone = strings.Repeat("*", n-2) + r.Phone[n-2:]
+	}
+	return &redacted
+}
+
+// RequesterDetails are the contact details given with a new appeal.
+type RequesterDetails struct {
+	Name             string         `json:"name" form:"name"`
+	Email            string         `json:"email" form:"email"`
+	Phone            string         `json:"phone" form:"phone"`
+	Address          string         `json:"address" form:"address"`
+	PreferredChannel ContactChannel `json:"preferred_channel" form:"preferred_channel"`
+}
+
+// Normalize trims the details and brings the email and phone to the form
+// requesters are matched by.
+func (d *RequesterDetails) Normalize() {
+	d.Name = strings.Join(strings.Fields(d.Name), " ")
+	d.Address = strings.TrimSpace(d.Address)
+	d.Email = NormalizeEmail(d.Email)
+	d.Phone = NormalizePhone(d.Phone)
+}
+
+// Validate checks normalized details. A preferred channel, if given, must
+// come with the contact it needs.
+func (d RequesterDetails) Validate() error {
+	if d.Email == "" && d.Phone == "" {
+		return NewError(ErrValidation, "contact_required", "requester email or phone is required")
+	}
+	if d.Email != "" {
+		if addr, err := mail.ParseAddress(d.Email); err != nil || addr.Address != d.Email {
+			return NewError(ErrValidation, "invalid_email", "%q is not an email address", d.Email)
+		}
+	}
+	if d.Phone != "" {
+		if digits := len(strings.TrimPrefix(d.Phone, "+")); digits < 7 || digits > 15 {
+			return NewError(ErrValidation, "invalid_phone", "phone number must have 7 to 15 digits")
+		}
+	}
+
+	var reachable bool
+	switch d.PreferredChannel {
+	case "":
+		reachable = true
+	case ChannelEmail:
+		reachable = d.Email != ""
+	case ChannelPhone:
+		reachable = d.Phone != ""
+	case ChannelPost:
+		reachable = d.Address != ""
+	default:
+		return NewError(ErrValidation, "invalid_channel", "preferred channel must be %s, %s or %s", ChannelEmail, ChannelPhone, ChannelPost)
+	}
+	if !reachable {
+		return NewError(ErrValidation, "channel_unreachable", "preferred channel %s needs a contact to reach the requester", d.PreferredChannel)
+	}
+	return nil
+}
+
+// NormalizeEmail lowercases an email address, so that addresses differing
+// only in case match.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// NormalizePhone keeps only the digits of a phone number and a leading
+// plus, so that "+7 (912) 345-67-89" and "+79123456789" match.
+func NormalizePhone(phone string) string {
+	phone = strings.TrimSpace(phone)
+	var b strings.Builder
+	if strings.HasPrefix(phone, "+") {
+		b.WriteByte('+')
+	}
+	for _, r := range phone {
+		if unicode.IsDigit(r) && r < unicode.MaxASCII {
+			b.WriteRune(r)
+		}
+	}
+	if b.String() == "+" {
+		return ""
+	}
+	return b.String()
+}
@@ -0,0 +1,43 @@
+package models
+
+import "testing"
+
+func TestNormalizeContacts(t *testing.T) {
+	t.Parallel()
+
+	phones := map[string]string{
+		"+7 (912) 345-67-89": "+79123456789",
+		"8 912 345 67 89":    "89123456789",
+		" +44 20 7946 0958 ": "+442079460958",
+		"+":                  "",
+		"call me":            "",
+	}
+	for in, want := range phones {
+		if got := NormalizePhone(in); got != want {
+			t.Errorf("NormalizePhone(%q): expected %q, got %q", in, want, got)
+		}
+	}
+	if got := NormalizeEmail("  Jane.Doe@Example.COM "); got != "jane.doe@example.com" {
+		t.Errorf("Expected a lowercased email, got %q", got)
+	}
+}
+
+func TestRequesterRedacted(t *testing.T) {
+	t.Parallel()
+
+	requester := &Requester{
+		ID:      "r1",
+		Name:    "Анна Мария Иванова",
+		Email:   "anna@example.com",
+		Phone:   "+79123456789",
+		Address: "1 Main St",
+	}
+	redacted := requester.Redacted()
+	if redacted.ID != "r1" || redacted.Name != "Анна М. И." || redacted.Email != "a***@example.com" ||
+		redacted.Phone != "**********89" || redacted.Address != "" {
+		t.Errorf("Unexpected redaction %+v", redacted)
+	}
+	if requester.Address != "1 Main St" {
+		t.Errorf("Expected the original to be left alone, got %+v", requester)
+	}
+}
@@ -0,0 +1,106 @@
+package models
+
+import "time"
+
+type AppealPriority string
+
+const (
+	PriorityLow    AppealPriority = "low"
+	PriorityNormal AppealPriority = "normal"
+	PriorityHigh   AppealPriority = "high"
+	PriorityUrgent AppealPriority = "urgent"
+)
+
+// Escalated returns the next priority up; urgent stays urgent.
+func (p AppealPriority) Escalated() AppealPriority {
+	switch p {
+	case PriorityLow:
+		return PriorityNormal
+	case PriorityNormal, "":
+		return PriorityHigh
+	}
+	return PriorityUrgent
+}
+
+func (p AppealPriority) Valid() bool {
+	switch p {
+	case PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent:
+		return true
+	}
+	return false
+}
+
+// ParsePriority maps an empty priority to PriorityNormal and rejects unknown
+// ones.
+func ParsePriority(p AppealPriority) (AppealPriority, error) {
+	if p == "" {
+		return PriorityNormal, nil
+	}
+	if !p.Valid() {
+		return "", NewError(ErrValidation, "invalid_priority", "unknown priority %q, use low, normal, high or urgent", p)
+	}
+	return p, nil
+}
+
+// AppealSLA is the SLA state of an appeal at a given moment. HandlingTime
+// is the working time spent on the appeal so far, with the time the SLA
+// clock was stopped left out.
+type AppealSLA struct {
+	AppealID           string         `json:"appeal_id"`
+	Priority           AppealPriority `json:"priority"`
+	ResponseDueAt      *time.Time     `json:"response_due_at,omitempty"`
+	ResolutionDueAt    *time.Time     `json:"resolution_due_at,omitempty"`
+	RespondedAt        *time.Time     `json:"responded_at,omitempty"`
+	ResolvedAt         *time.Time     `json:"resolved_at,omitempty"`
+	ClockStopped       bool           `json:"clock_stopped"`
+	PausedSeconds      int64          `json:"paused_seconds"`
+	HandlingSeconds    int64          `json:"handling_seconds"`
+	ResponseBreached   bool           `json:"response_breached"`
+	ResolutionBreached bool           `json:"resolution_breached"`
+}
+
+// ResponseBreached reports whether the appeal was answered after its
+// response deadline or is still unanswered past it at now. While the SLA
+// clock is stopped, now is the time it was stopped.
+func (a *Appeal) ResponseBreached(now time.Time) bool {
+	return deadlineBreached(a.ResponseDueAt, a.RespondedAt, a.SLAPausedAt, now)
+}
+
+// ResolutionBreached is ResponseBreached for the resolution deadline.
+func (a *Appeal) ResolutionBreached(now time.Time) bool {
+	return deadlineBreached(a.ResolutionDueAt, a.ResolvedAt, a.SLAPausedAt, now)
+}
+
+func (a *Appeal) SLABreached(now time.Time) bool {
+	return a.ResponseBreached(now) || a.ResolutionBreached(now)
+}
+
+// DueWithin reports whether a deadline the appeal has not met yet falls
+// between now and now+window. Nothing is due while the SLA clock is stopped.
+func (a *Appeal) DueWithin(now time.Time, window time.Duration) bool {
+	if a.SLAPausedAt != nil {
+		return false
+	}
+	return deadlineDue(a.ResponseDueAt, a.RespondedAt, now, window) ||
+		deadlineDue(a.ResolutionDueAt, a.ResolvedAt, now, window)
+}
+
+func deadlineBreached(due, met, paused *time.Time, now time.Time) bool {
+	if due == nil {
+		return false
+	}
+	switch {
+	case met != nil:
+		now = *met
+	case paused != nil:
+		now = *paused
+	}
+	return now.After(*due)
+}
+
+func deadlineDue(due, met *time.Time, now time.Time, window time.Duration) bool {
+	if due == nil || met != nil {
+		return false
+	}
+	return !due.Before(now) && !due.After(now.Add(window))
+}
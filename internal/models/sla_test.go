@@ -0,0 +1,57 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAppealSLA(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d)
+		return &v
+	}
+
+	tests := []struct {
+		name     string
+		appeal   Appeal
+		breached bool
+		dueSoon  bool
+	}{
+		{"NoDeadlines", Appeal{}, false, false},
+		{"Overdue", Appeal{ResponseDueAt: at(-time.Minute)}, true, false},
+		{"DueSoon", Appeal{ResponseDueAt: at(time.Hour)}, false, true},
+		{"DueLater", Appeal{ResolutionDueAt: at(3 * time.Hour)}, false, false},
+		{"AnsweredInTime", Appeal{ResponseDueAt: at(-time.Hour), RespondedAt: at(-2 * time.Hour)}, false, false},
+		{"ResolvedLate", Appeal{ResolutionDueAt: at(-2 * time.Hour), ResolvedAt: at(-time.Hour)}, true, false},
+		{"ResolvedBeforeDue", Appeal{ResolutionDueAt: at(time.Hour), ResolvedAt: at(-time.Hour)}, false, false},
+		// Часы SLA остановлены до наступления срока.
+		{"PausedInTime", Appeal{ResolutionDueAt: at(-time.Hour), SLAPausedAt: at(-2 * time.Hour)}, false, false},
+		{"PausedLate", Appeal{ResolutionDueAt: at(-2 * time.Hour), SLAPausedAt: at(-time.Hour)}, true, false},
+		{"PausedDueSoon", Appeal{ResolutionDueAt: at(time.Hour), SLAPausedAt: at(-time.Hour)}, false, false},
+	}
+	for _, tt := range tests {
+		if got := tt.appeal.SLABreached(now); got != tt.breached {
+			t.Errorf("%s: expected breached=%v, got %v", tt.name, tt.breached, got)
+		}
+		if got := tt.appeal.DueWithin(now, 2*time.Hour); got != tt.dueSoon {
+			t.Errorf("%s: expected due soon=%v, got %v", tt.name, tt.dueSoon, got)
+		}
+	}
+}
+
+func TestParsePriority(t *testing.T) {
+	t.Parallel()
+
+	if p, err := ParsePriority(""); err != nil || p != PriorityNormal {
+		t.Errorf("Expected empty priority to default to normal, got %q, %v", p, err)
+	}
+	if p, err := ParsePriority(PriorityUrgent); err != nil || p != PriorityUrgent {
+		t.Errorf("Expected urgent to be accepted, got %q, %v", p, err)
+	}
+	if _, err := ParsePriority("critical"); ErrorCode(err) != "invalid_priority" {
+		t.Errorf("Expected invalid_priority, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// TrackedAppeal is what anyone holding an appeal's tracking token may see:
+// where it stands and what the requester has been told, nothing about who
+// filed it or who works on it.
+type TrackedAppeal struct {
+	Number      string          `json:"number,omitempty"`
+	Status      AppealStatus    `json:"status"`
+	Solution    string          `json:"solution,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	RespondedAt *time.Time      `json:"responded_at,omitempty"`
+	ResolvedAt  *time.Time      `json:"resolved_at,omitempty"`
+	Replies     []*TrackedReply `json:"replies"`
+}
+
+// TrackedReply is a public comment without its author.
+type TrackedReply struct {
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+}
+
+// Track returns the public view of appeal and its public comments.
+func Track(appeal *Appeal, comments []*Comment) *TrackedAppeal {
+	tracked := &TrackedAppeal{
+		Number:      appeal.Number,
+		Status:      appeal.Status,
+		Solution:    appeal.Solution,
+		CreatedAt:   appeal.CreatedAt,
+		UpdatedAt:   appeal.UpdatedAt,
+		RespondedAt: appeal.RespondedAt,
+		ResolvedAt:  appeal.ResolvedAt,
+		Replies:     make([]*TrackedReply, 0, len(comments)),
+	}
+	for _, comment := range comments {
+		if comment.Visibility != CommentPublic {
+			continue
+		}
+		tracked.Replies = append(tracked.Replies, &TrackedReply{
+			Body:      comment.Body,
+			CreatedAt: comment.CreatedAt,
+			EditedAt:  comment.EditedAt,
+		})
+	}
+	return tracked
+}
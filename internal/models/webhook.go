@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WebhookSubscription receives the events listed in Events, or every event
+// when the list is empty, signed with Secret. Only events of its tenant are
+// sent.
+type WebhookSubscription struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events,omitempty"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (s *WebhookSubscription) Matches(eventType string) bool {
+	if !s.Active {
+		return false
+	}
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, event := range s.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+func (r CreateWebhookRequest) Validate() error {
+	u, err := url.Parse(strings.TrimSpace(r.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return NewError(ErrValidation, "invalid_url", "url must be an absolute http or https URL")
+	}
+	for _, event := range r.Events {
+		if strings.TrimSpace(event) == "" {
+			return NewError(ErrValidation, "invalid_event", "event names must not be empty")
+		}
+	}
+	return nil
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one event sent to one subscription, with the outcome of
+// the latest attempt. A pending delivery is retried at NextAttemptAt.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	FinishedAt     *time.Time      `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,220 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+)
+
+const (
+	DefaultPollInterval = time.Second
+	DefaultBatchSize    = 100
+	maxBackoff          = time.Minute
+)
+
+// Sink receives the events of the outbox. An event may be published more
+// than once, after a failure or a restart, so sinks and whoever consumes
+// them should recognise repeats by the event ID.
+type Sink interface {
+	// Name identifies the sink's position in the outbox; it must not change
+	// between runs.
+	Name() string
+	Publish(ctx context.Context, event models.Event) error
+}
+
+type Option func(r *Relay)
+
+// WithPollInterval sets how often the outbox is checked for events that
+// arrived without a Notify, such as those written by another process.
+func WithPollInterval(d time.Duration) Option {
+	return func(r *Relay) { r.poll = d }
+}
+
+// WithBatchSize sets how many events are read from the outbox at a time.
+func WithBatchSize(n int) Option {
+	return func(r *Relay) { r.batch = n }
+}
+
+type worker struct {
+	sink   Sink
+	offset int64
+	wake   chan struct{}
+}
+
+// Relay publishes the events of the outbox to its sinks, in order and
+// independently of each other. The position of every sink is stored after
+// each batch, so a restart continues where the last run stopped. A sink that
+// fails is retried with the same event until it succeeds.
+type Relay struct {
+	store   repository.Store
+	workers []*worker
+	poll    time.Duration
+	batch   int
+
+	mu       sync.Mutex
+	started  bool
+	stopped  bool
+	stop     chan struct{}
+	running  sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopOnce sync.Once
+}
+
+func New(store repository.Store, sinks []Sink, opts ...Option) *Relay {
+	r := &Relay{
+		store: store,
+		poll:  DefaultPollInterval,
+		batch: DefaultBatchSize,
+		stop:  make(chan struct{}),
+	}
+	for _, sink := range sinks {
+		r.workers = append(r.workers, &worker{sink: sink, wake: make(chan struct{}, 1)})
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	return r
+}
+
+// Start begins relaying. A sink seen for the first time starts after the
+// latest event instead of receiving the whole history.
+func (r *Relay) Start() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.started || r.stopped {
+		return nil
+	}
+
+	for _, w := range r.workers {
+		offset, ok, err := r.store.EventOffset(w.sink.Name())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if offset, err = r.store.LastEventSequence(); err != nil {
+				return err
+			}
+			if err := r.store.SetEventOffset(w.sink.Name(), offset); err != nil {
+				return err
+			}
+		}
+		w.offset = offset
+	}
+
+	r.started = true
+	for _, w := range r.workers {
+		r.running.Add(1)
+		go r.run(w)
+	}
+	return nil
+}
+
+// Shutdown stops relaying and waits for the batches in progress. If ctx
+// expires first, the sinks' contexts are cancelled and ctx.Err() is
+// returned.
+func (r *Relay) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	r.stopped = true
+	r.mu.Unlock()
+	r.stopOnce.Do(func() { close(r.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		r.running.Wait()
+		close(done)
+	}()
+
+	defer r.cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Notify makes the sinks check the outbox now rather than at the next poll.
+func (r *Relay) Notify() {
+	for _, w := range r.workers {
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (r *Relay) run(w *worker) {
+	defer r.running.Done()
+
+	failures := 0
+	for {
+		wait := r.poll
+		full, err := r.relay(w)
+		switch {
+		case err != nil:
+			failures++
+			wait = r.delay(failures)
+			log.Printf("Outbox sink %s failed, retrying in %s: %v", w.sink.Name(), wait, err)
+		case full:
+			failures = 0
+			wait = 0
+		default:
+			failures = 0
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-r.stop:
+			timer.Stop()
+			return
+		case <-w.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// relay publishes one batch and stores how far it got. It reports whether
+// the batch was full, meaning more events are probably waiting.
+func (r *Relay) relay(w *worker) (bool, error) {
+	events, err := r.store.ListEvents(w.offset, r.batch)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the outbox: %w", err)
+	}
+
+	offset := w.offset
+	for _, event := range events {
+		if err = w.sink.Publish(r.ctx, *event); err != nil {
+			err = fmt.Errorf("event %d (%s): %w", event.Sequence, event.ID, err)
+			break
+		}
+		offset = event.Sequence
+	}
+
+	if offset != w.offset {
+		if err := r.store.SetEventOffset(w.sink.Name(), offset); err != nil {
+			return false, fmt.Errorf("failed to store outbox offset: %w", err)
+		}
+		w.offset = offset
+	}
+	return err == nil && len(events) == r.batch, err
+}
+
+// delay is the wait after the given number of consecutive failures.
+func (r *Relay) delay(failures int) time.Duration {
+	delay := r.poll
+	for i := 1; i < failures && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
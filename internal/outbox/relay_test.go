@@ -0,0 +1,214 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+)
+
+// recordingSink keeps what it got and fails while failures is positive.
+type recordingSink struct {
+	name     string
+	mu       sync.Mutex
+	failures int
+	events   []models.Event
+}
+
+func (s *recordingSink) Name() string {
+	return s.name
+}
+
+func (s *recordingSink) Publish(ctx context.Context, event models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("sink unavailable")
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *recordingSink) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []string
+	for _, event := range s.events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func addEvents(t *testing.T, store repository.Store, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		if err := store.AddEvent(&models.Event{ID: id, Type: models.EventAppealCreated, AppealID: "a1"}); err != nil {
+			t.Fatalf("Failed to add event: %v", err)
+		}
+	}
+}
+
+func waitFor(t *testing.T, sink *recordingSink, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if ids := sink.received(); len(ids) >= n {
+			return ids
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Sink %s got %v, expected %d events", sink.name, sink.received(), n)
+	return nil
+}
+
+func startRelay(t *testing.T, store repository.Store, sinks ...Sink) *Relay {
+	t.Helper()
+	relay := New(store, sinks, WithPollInterval(10*time.Millisecond), WithBatchSize(2))
+	if err := relay.Start(); err != nil {
+		t.Fatalf("Failed to start relay: %v", err)
+	}
+	t.Cleanup(func() { relay.Shutdown(context.Background()) })
+	return relay
+}
+
+func TestRelay(t *testing.T) {
+	t.Parallel()
+
+	store := repository.NewMemoryAppealRepository()
+	addEvents(t, store, "old")
+
+	steady := &recordingSink{name: "steady"}
+	flaky := &recordingSink{name: "flaky", failures: 2}
+	relay := startRelay(t, store, steady, flaky)
+
+	addEvents(t, store, "e1", "e2", "e3")
+	relay.Notify()
+
+	// Новый приёмник не получает историю, сбойный получает всё по порядку.
+	for _, sink := range []*recordingSink{steady, flaky} {
+		ids := waitFor(t, sink, 3)
+		if len(ids) != 3 || ids[0] != "e1" || ids[1] != "e2" || ids[2] != "e3" {
+			t.Errorf("Sink %s: expected e1 e2 e3, got %v", sink.name, ids)
+		}
+	}
+
+	if err := relay.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+	last, _ := store.LastEventSequence()
+	for _, name := range []string{"steady", "flaky"} {
+		if seq, ok, err := store.EventOffset(name); err != nil || !ok || seq != last {
+			t.Errorf("Expected %s to be stored at %d, got %d, %v, %v", name, last, seq, ok, err)
+		}
+	}
+}
+
+func TestRelayResumes(t *testing.T) {
+	t.Parallel()
+
+	store := repository.NewMemoryAppealRepository()
+	first := &recordingSink{name: "log"}
+	relay := startRelay(t, store, first)
+	addEvents(t, store, "e1")
+	relay.Notify()
+	waitFor(t, first, 1)
+	if err := relay.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+
+	// События, записанные пока никто не слушал, доходят после перезапуска.
+	addEvents(t, store, "e2", "e3")
+	second := &recordingSink{name: "log"}
+	startRelay(t, store, second)
+
+	ids := waitFor(t, second, 2)
+	if len(ids) != 2 || ids[0] != "e2" || ids[1] != "e3" {
+		t.Errorf("Expected the missed events only, got %v", ids)
+	}
+}
+
+func TestBus(t *testing.T) {
+	t.Parallel()
+
+	bus := NewBus(1)
+	events, unsubscribe := bus.Subscribe()
+	slow, _ := bus.Subscribe()
+
+	if err := bus.Publish(context.Background(), models.Event{ID: "e1"}); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	if event := <-events; event.ID != "e1" {
+		t.Errorf("Expected e1, got %+v", event)
+	}
+	bus.Publish(context.Background(), models.Event{ID: "e2"})
+
+	// Отставший подписчик отключается, а не блокирует остальных.
+	<-slow
+	if _, ok := <-slow; ok {
+		t.Errorf("Expected the subscriber that fell behind to be dropped")
+	}
+	if event := <-events; event.ID != "e2" {
+		t.Errorf("Expected e2, got %+v", event)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	if _, ok := <-events; ok {
+		t.Errorf("Expected the channel to be closed on unsubscribe")
+	}
+
+	open, _ := bus.Subscribe()
+	bus.Close()
+	if _, ok := <-open; ok {
+		t.Errorf("Expected Close to end subscriptions")
+	}
+	late, _ := bus.Subscribe()
+	if _, ok := <-late; ok {
+		t.Errorf("Expected no subscriptions after Close")
+	}
+}
+
+func TestLogSink(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := OpenLog(path)
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	for _, id := range []string{"e1", "e2"} {
+		if err := sink.Publish(context.Background(), models.Event{ID: id, Type: models.EventAppealCreated, Sequence: 7}); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Failed to close log: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	defer file.Close()
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event models.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Expected a JSON line, got %q", scanner.Text())
+		}
+		ids = append(ids, event.ID)
+	}
+	if len(ids) != 2 || ids[0] != "e1" || ids[1] != "e2" {
+		t.Errorf("Expected one line per event, got %v", ids)
+	}
+}
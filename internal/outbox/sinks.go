@@ -0,0 +1,115 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"go_appeals/internal/models"
+)
+
+// LogSink appends every event to a file as a line of JSON.
+type LogSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// OpenLog opens path for appending, creating it if needed.
+func OpenLog(path string) (*LogSink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	return &LogSink{file: file}, nil
+}
+
+func (l *LogSink) Name() string {
+	return "log"
+}
+
+func (l *LogSink) Publish(ctx context.Context, event models.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+func (l *LogSink) Close() error {
+	return l.file.Close()
+}
+
+// Bus hands events to subscribers inside the process. It never blocks the
+// relay: a subscriber that falls more than its buffer behind is dropped,
+// and its channel closed, so it can catch up from the store instead.
+type Bus struct {
+	mu     sync.Mutex
+	subs   map[chan models.Event]struct{}
+	buffer int
+	closed bool
+}
+
+func NewBus(buffer int) *Bus {
+	return &Bus{subs: make(map[chan models.Event]struct{}), buffer: buffer}
+}
+
+func (b *Bus) Name() string {
+	return "bus"
+}
+
+func (b *Bus) Publish(ctx context.Context, event models.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// Subscribe returns a channel of the events published from now on and a
+// function that ends the subscription. After Close the channel comes
+// closed.
+func (b *Bus) Subscribe() (<-chan models.Event, func()) {
+	ch := make(chan models.Event, b.buffer)
+
+	b.mu.Lock()
+	if b.closed {
+		close(ch)
+	} else {
+		b.subs[ch] = struct{}{}
+	}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close ends every subscription and refuses new ones.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_appeals/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const apiKeyColumns = "id, name, subject, tenant_id, roles, prefix, key_hash, created_at, expires_at, revoked_at, last_used_at"
+
+func apiKeyNotFound(id string) *models.Error {
+	return models.NewError(models.ErrNotFound, "api_key_not_found", "API key %s not found", id)
+}
+
+func (r *AppealRepository) CreateAPIKey(key *models.APIKey) error {
+	key.ID = uuid.New().String()
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+
+	_, err := r.conn().Exec(r.rebind(
+		"INSERT INTO api_keys ("+apiKeyColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		key.ID,
+		key.Name,
+		key.Subject,
+		key.Tenant,
+		joinRoles(key.Roles),
+		key.Prefix,
+		key.Hash,
+		key.CreatedAt.UTC(),
+		nullTime(key.ExpiresAt),
+		nullTime(key.RevokedAt),
+		nullTime(key.LastUsedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert API key: %w", err)
+	}
+	return nil
+}
+
+func (r *AppealRepository) FindAPIKey(id string) (*models.APIKey, error) {
+	return r.findAPIKey("id", id)
+}
+
+func (r *AppealRepository) FindAPIKeyByHash(hash string) (*models.APIKey, error) {
+	return r.findAPIKey("key_hash", hash)
+}
+
+func (r *AppealRepository) findAPIKey(column, value string) (*models.APIKey, error) {
+	key, err := scanAPIKey(r.conn().QueryRow(r.rebind(
+		"SELECT "+apiKeyColumns+" FROM api_keys WHERE "+column+" = ?"), value))
+	if err == sql.ErrNoRows {
+		if column == "id" {
+			return nil, apiKeyNotFound(value)
+		}
+		return nil, models.NewError(models.ErrNotFound, "api_key_not_found", "API key not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find API key: %w", err)
+	}
+	return key, nil
+}
+
+func (r *AppealRepository) ListAPIKeys() ([]*models.APIKey, error) {
+	rows, err := r.conn().Query("SELECT " + apiKeyColumns + " FROM api_keys ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]*models.APIKey, 0)
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan API key row: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *AppealRepository) RevokeAPIKey(id string, at time.Time) error {
+	return r.setAPIKeyTime("revoked_at", id, at)
+}
+
+func (r *AppealRepository) TouchAPIKey(id string, at time.Time) error {
+	return r.setAPIKeyTime("last_used_at", id, at)
+}
+
+func (r *AppealRepository) setAPIKeyTime(column, id string, at time.Time) error {
+	result, err := r.conn().Exec(r.rebind("UPDATE api_keys SET "+column+" = ? WHERE id = ?"), at.UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return apiKeyNotFound(id)
+	}
+	return nil
+}
+
+func scanAPIKey(row scanner) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	var roles string
+	err := row.Scan(
+		&key.ID,
+		&key.Name,
+		&key.Subject,
+		&key.Tenant,
+		&roles,
+		&key.Prefix,
+		&key.Hash,
+		&key.CreatedAt,
+		&key.ExpiresAt,
+		&key.RevokedAt,
+		&key.LastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if roles != "" {
+		for _, role := range strings.Split(roles, ",") {
+			key.Roles = append(key.Roles, models.Role(role))
+		}
+	}
+	return key, nil
+}
+
+func joinRoles(roles []models.Role) string {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role)
+	}
+	return strings.Join(names, ",")
+}
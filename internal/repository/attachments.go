@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go_appeals/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const attachmentColumns = "id, tenant_id, appeal_id, file_name, content_type, size, sha256, uploaded_by, created_at"
+
+func (r *AppealRepository) AddAttachment(attachment *models.Attachment) error {
+	attachment.ID = uuid.New().String()
+	if attachment.CreatedAt.IsZero() {
+		attachment.CreatedAt = time.Now()
+	}
+	attachment.CreatedAt = attachment.CreatedAt.UTC()
+	attachment.TenantID = r.tenantOf(attachment.TenantID)
+
+	_, err := r.conn().Exec(r.rebind(
+		"INSERT INTO appeal_attachments ("+attachmentColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		attachment.ID,
+		attachment.TenantID,
+		attachment.AppealID,
+		attachment.FileName,
+		attachment.ContentType,
+		attachment.Size,
+		attachment.SHA256,
+		attachment.UploadedBy,
+		attachment.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert attachment: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AppealRepository) FindAttachment(id string) (*models.Attachment, error) {
+	where, args := r.scope("tenant_id", []string{"id = ?"}, []any{id})
+	attachment, err := scanAttachment(r.conn().QueryRow(r.rebind(
+		"SELECT "+attachmentColumns+" FROM appeal_attachments"+whereClause(where)), args...))
+	if err == sql.ErrNoRows {
+		return nil, models.NewError(models.ErrNotFound, "attachment_not_found", "attachment %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+func (r *AppealRepository) ListAttachments(appealID string) ([]*models.Attachment, error) {
+	where, args := r.scope("tenant_id", []string{"appeal_id = ?"}, []any{appealID})
+	rows, err := r.conn().Query(r.rebind(
+		"SELECT "+attachmentColumns+" FROM appeal_attachments"+whereClause(where)+" ORDER BY created_at, id"), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := make([]*models.Attachment, 0)
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment row: %w", err)
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+// ContentInUse is not scoped to a tenant: contents are shared by hash
+// across tenants.
+func (r *AppealRepository) ContentInUse(sha256 string) (bool, error) {
+	var count int
+	err := r.conn().QueryRow(r.rebind(
+		"SELECT COUNT(*) FROM appeal_attachments WHERE sha256 = ?"), sha256).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to count attachments: %w", err)
+	}
+	return count > 0, nil
+}
+
+func scanAttachment(row scanner) (*models.Attachment, error) {
+	attachment := &models.Attachment{}
+	err := row.Scan(
+		&attachment.ID,
+		&attachment.TenantID,
+		&attachment.AppealID,
+		&attachment.FileName,
+		&attachment.ContentType,
+		&attachment.Size,
+		&attachment.SHA256,
+		&attachment.UploadedBy,
+		&attachment.CreatedAt,
+	)
+	return attachment, err
+}
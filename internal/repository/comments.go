@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go_appeals/internal/models"
+)
+
+func commentNotFound(id int64) error {
+	return models.NewError(models.ErrNotFound, "comment_not_found", "comment %d not found", id)
+}
+
+const commentColumns = "id, tenant_id, appeal_id, visibility, author, body, created_at, edited_at"
+
+func (r *AppealRepository) AddComment(comment *models.Comment) error {
+	if comment.CreatedAt.IsZero() {
+		comment.CreatedAt = time.Now()
+	}
+	comment.CreatedAt = comment.CreatedAt.UTC()
+	comment.TenantID = r.tenantOf(comment.TenantID)
+
+	err := r.conn().QueryRow(r.rebind(
+		"INSERT INTO appeal_comments (tenant_id, appeal_id, visibility, author, body, created_at) VALUES (?, ?, ?, ?, ?, ?) RETURNING id"),
+		comment.TenantID,
+		comment.AppealID,
+		comment.Visibility,
+		comment.Author,
+		comment.Body,
+		comment.CreatedAt,
+	).Scan(&comment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert comment: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AppealRepository) FindComment(id int64) (*models.Comment, error) {
+	where, args := r.scope("tenant_id", []string{"id = ?"}, []any{id})
+	comment, err := scanComment(r.conn().QueryRow(r.rebind(
+		"SELECT "+commentColumns+" FROM appeal_comments"+whereClause(where)), args...))
+	if err == sql.ErrNoRows {
+		return nil, commentNotFound(id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find comment: %w", err)
+	}
+
+	edits, err := r.queryCommentEdits("WHERE comment_id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	comment.Edits = edits[comment.ID]
+	return comment, nil
+}
+
+func (r *AppealRepository) EditComment(comment *models.Comment, edit *models.CommentEdit) error {
+	if edit.EditedAt.IsZero() {
+		edit.EditedAt = time.Now()
+	}
+	edit.EditedAt = edit.EditedAt.UTC()
+	edit.CommentID = comment.ID
+
+	return r.atomically(func(tx *AppealRepository) error {
+		where, args := tx.scope("tenant_id", []string{"id = ?"}, []any{comment.ID})
+		result, err := tx.conn().Exec(tx.rebind(
+			"UPDATE appeal_comments SET body = ?, edited_at = ?"+whereClause(where)),
+			append([]any{comment.Body, nullTime(comment.EditedAt)}, args...)...)
+		if err != nil {
+			return fmt.Errorf("failed to update comment: %w", err)
+		}
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+			return commentNotFound(comment.ID)
+		}
+
+		err = tx.conn().QueryRow(tx.rebind(
+			"INSERT INTO appeal_comment_edits (comment_id, body, edited_by, edited_at) VALUES (?, ?, ?, ?) RETURNING id"),
+			edit.CommentID,
+			edit.Body,
+			edit.EditedBy,
+			edit.EditedAt,
+		).Scan(&edit.ID)
+		if err != nil {
+			return fmt.Errorf("failed to insert comment edit: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *AppealRepository) ListComments(appealID string, internal bool) ([]*models.Comment, error) {
+	where, args := r.scope("tenant_id", []string{"appeal_id = ?"}, []any{appealID})
+	if !internal {
+		where = append(where, "visibility = ?")
+		args = append(args, models.CommentPublic)
+	}
+	rows, err := r.conn().Query(r.rebind(
+		"SELECT "+commentColumns+" FROM appeal_comments"+whereClause(where)+" ORDER BY id"), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := make([]*models.Comment, 0)
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment row: %w", err)
+		}
+		comments = append(comments, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	edits, err := r.queryCommentEdits(
+		"WHERE comment_id IN (SELECT id FROM appeal_comments WHERE appeal_id = ?)", appealID)
+	if err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		comment.Edits = edits[comment.ID]
+	}
+	return comments, nil
+}
+
+// queryCommentEdits returns the edits matching where, by comment, oldest
+// first.
+func (r *AppealRepository) queryCommentEdits(where string, args ...any) (map[int64][]*models.CommentEdit, error) {
+	rows, err := r.conn().Query(r.rebind(
+		"SELECT id, comment_id, body, edited_by, edited_at FROM appeal_comment_edits "+where+" ORDER BY id"), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comment edits: %w", err)
+	}
+	defer rows.Close()
+
+	edits := make(map[int64][]*models.CommentEdit)
+	for rows.Next() {
+		edit := &models.CommentEdit{}
+		if err := rows.Scan(&edit.ID, &edit.CommentID, &edit.Body, &edit.EditedBy, &edit.EditedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment edit row: %w", err)
+		}
+		edits[edit.CommentID] = append(edits[edit.CommentID], edit)
+	}
+	return edits, rows.Err()
+}
+
+func scanComment(row scanner) (*models.Comment, error) {
+	comment := &models.Comment{}
+	err := row.Scan(
+		&comment.ID,
+		&comment.TenantID,
+		&comment.AppealID,
+		&comment.Visibility,
+		&comment.Author,
+		&comment.Body,
+		&comment.CreatedAt,
+		&comment.EditedAt,
+	)
+	return comment, err
+}
@@ -0,0 +1,1605 @@
+package repository
+
+import (
+	"errors"
+	"go_appeals/internal/models"
+	"go_appeals/internal/search"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runAppealStoreConformance runs the same scenarios against every Store
+// backend. newStore must return an empty store and register its own cleanup.
+func runAppealStoreConformance(t *testing.T, newStore func(t *testing.T) Store) {
+	scenarios := []struct {
+		name string
+		run  func(t *testing.T, repo Store)
+	}{
+		{"Save", testSave},
+		{"GetAll", testGetAll},
+		{"FindByID", testFindByID},
+		{"Update", testUpdate},
+		{"SelectAppealsByDates", testSelectAppealsByDates},
+		{"CancelInProgressAppeals", testCancelInProgressAppeals},
+		{"FindByStatus", testFindByStatus},
+		{"List", testList},
+		{"Search", testSearch},
+		{"Assignee", testAssignee},
+		{"CreatedBy", testCreatedBy},
+		{"Tenants", testTenants},
+		{"SLA", testSLA},
+		{"Reminders", testReminders},
+		{"JobRuns", testJobRuns},
+		{"Webhooks", testWebhooks},
+		{"Outbox", testOutbox},
+		{"APIKeys", testAPIKeys},
+		{"History", testHistory},
+		{"Comments", testComments},
+		{"Attachments", testAttachments},
+		{"Requesters", testRequesters},
+		{"Tracking", testTracking},
+		{"WithTx", testWithTx},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Parallel()
+			scenario.run(t, newStore(t))
+		})
+	}
+}
+
+func testSelectAppealsByDates(t *testing.T, repo Store) {
+	startDate := time.Date(2025, 10, 27, 0, 0, 0, 0, time.UTC)  // Начало дня
+	endDate := time.Date(2025, 10, 27, 23, 59, 59, 0, time.UTC) // Конец дня
+
+	appeals := []*models.Appeal{
+		{
+			Theme:        "Test theme",
+			Message:      "Test message",
+			Status:       "open",
+			Solution:     "Test solution",
+			CancelReason: "Test reason",
+			CreatedAt:    time.Date(2025, 10, 27, 12, 0, 0, 0, time.UTC), // Середина дня
+			UpdatedAt:    time.Date(2025, 10, 27, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			Theme:        "Test theme",
+			Message:      "Test message",
+			Status:       "open",
+			Solution:     "Test solution",
+			CancelReason: "Test reason",
+			CreatedAt:    time.Date(2025, 10, 27, 18, 0, 0, 0, time.UTC), // Вечер дня
+			UpdatedAt:    time.Date(2025, 10, 27, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			Theme:        "Test theme",
+			Message:      "Test message",
+			Status:       "open",
+			Solution:     "Test solution",
+			CancelReason: "Test reason",
+			CreatedAt:    time.Date(2025, 10, 28, 9, 0, 0, 0, time.UTC), // Следующий день
+			UpdatedAt:    time.Date(2025, 10, 28, 9, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, appeal := range appeals {
+		_, err := repo.Save(appeal)
+		if err != nil {
+			t.Errorf("Failed to save appeal: %v", err)
+		}
+	}
+
+	selectedAppeals, err := repo.SelectAppealsByDates(startDate, endDate)
+	if err != nil {
+		t.Errorf("Failed to select appeals by dates: %v", err)
+	}
+
+	expected := appeals[:2]
+	if len(selectedAppeals) != len(expected) {
+		t.Errorf("Expected %d appeals, got %d", len(expected), len(selectedAppeals))
+	}
+
+	if len(selectedAppeals) == len(expected) {
+		for i, appeal := range expected {
+			if selectedAppeals[i].ID != appeal.ID {
+				t.Errorf("Expected appeal ID %s, got %s", appeal.ID, selectedAppeals[i].ID)
+			}
+		}
+	}
+}
+
+// cancelWith cancels appeals the way the default workflow does.
+func cancelWith(reason string) func(appeal *models.Appeal) error {
+	return func(appeal *models.Appeal) error {
+		appeal.Status = models.StatusCancelled
+		appeal.CancelReason = reason
+		return nil
+	}
+}
+
+func testCancelInProgressAppeals(t *testing.T, repo Store) {
+	appeals := []*models.Appeal{
+		{
+			Theme:        "Test theme",
+			Message:      "Test message",
+			Status:       models.StatusNew,
+			Solution:     "Test solution",
+			CancelReason: "Test reason",
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		},
+		{
+			Theme:        "Test theme",
+			Message:      "Test message",
+			Status:       models.StatusInProgress,
+			Solution:     "Test solution",
+			CancelReason: "Test reason",
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		},
+		{
+			Theme:        "Test theme",
+			Message:      "Test message",
+			Status:       models.StatusCompleted,
+			Solution:     "Test solution",
+			CancelReason: "Test reason",
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		},
+		{
+			Theme:        "Test theme",
+			Message:      "Test message",
+			Status:       models.StatusCancelled,
+			Solution:     "Test solution",
+			CancelReason: "Test reason",
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		},
+	}
+
+	savedAppeals := make([]*models.Appeal, len(appeals))
+	for i, appeal := range appeals {
+		saved, err := repo.Save(appeal)
+		if err != nil {
+			t.Fatalf("Failed to save appeal: %v", err)
+		}
+		savedAppeals[i] = saved
+	}
+	inProgress := []models.AppealStatus{models.StatusNew, models.StatusInProgress}
+
+	// Ошибка на любой заявке откатывает всю отмену.
+	failure := errors.New("guard failed")
+	_, err := repo.CancelInProgressAppeals(inProgress, func(appeal *models.Appeal) error {
+		if appeal.Status == models.StatusInProgress {
+			return failure
+		}
+		return cancelWith("Service shutdown")(appeal)
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected the cancel error, got %v", err)
+	}
+	if found, err := repo.FindByID(savedAppeals[0].ID); err != nil || found.Status != models.StatusNew {
+		t.Errorf("Expected the failed cancellation to be undone, got %+v, %v", found, err)
+	}
+
+	cancelled, err := repo.CancelInProgressAppeals(inProgress, cancelWith("Service shutdown"))
+	if err != nil {
+		t.Errorf("Failed to cancel in-progress appeals: %v", err)
+	}
+	if len(cancelled) != 2 {
+		t.Errorf("Expected 2 appeals reported as cancelled, got %d", len(cancelled))
+	}
+	for _, appeal := range cancelled {
+		if appeal.Status != models.StatusCancelled || appeal.CancelReason != "Service shutdown" {
+			t.Errorf("Expected the cancelled appeal as saved, got %+v", appeal)
+		}
+	}
+
+	expected := []struct {
+		status models.AppealStatus
+		reason string
+	}{
+		{models.StatusCancelled, "Service shutdown"},
+		{models.StatusCancelled, "Service shutdown"},
+		{models.StatusCompleted, "Test reason"},
+		{models.StatusCancelled, "Test reason"},
+	}
+	for i, saved := range savedAppeals {
+		found, err := repo.FindByID(saved.ID)
+		if err != nil {
+			t.Errorf("Failed to find appeal with original ID %s: %v", saved.ID, err)
+			continue
+		}
+		if found.Status != expected[i].status {
+			t.Errorf("Expected appeal %s to be %s, got %s", saved.ID, expected[i].status, found.Status)
+		}
+		if found.CancelReason != expected[i].reason {
+			t.Errorf("Expected appeal %s to have reason %q, got %q", saved.ID, expected[i].reason, found.CancelReason)
+		}
+	}
+
+	if cancelled, err := repo.CancelInProgressAppeals(nil, cancelWith("Nothing")); err != nil || len(cancelled) != 0 {
+		t.Errorf("Expected nothing to be cancelled without statuses, got %+v, %v", cancelled, err)
+	}
+}
+
+func testSave(t *testing.T, repo Store) {
+	appeal := &models.Appeal{
+		Theme:        "Test theme",
+		Message:      "Test message",
+		Status:       models.StatusNew,
+		Solution:     "Test solution",
+		CancelReason: "Test reason",
+	}
+
+	savedAppeal, err := repo.Save(appeal)
+	if err != nil {
+		t.Fatalf("Failed to save appeal: %v", err)
+	}
+
+	if savedAppeal.ID == "" {
+		t.Errorf("Expected saved appeal to have a non-empty ID, got %s", savedAppeal.ID)
+	}
+	if savedAppeal.CreatedAt.IsZero() || savedAppeal.UpdatedAt.IsZero() {
+		t.Errorf("Expected saved appeal to have timestamps set")
+	}
+}
+
+func testGetAll(t *testing.T, repo Store) {
+	appeals := []*models.Appeal{
+		{
+			Theme:        "Test theme 1",
+			Message:      "Test message 1",
+			Status:       models.StatusNew,
+			Solution:     "Test solution 1",
+			CancelReason: "Test reason 1",
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		},
+		{
+			Theme:        "Test theme 2",
+			Message:      "Test message 2",
+			Status:       models.StatusNew,
+			Solution:     "Test solution 2",
+			CancelReason: "Test reason 2",
+			CreatedAt:    time.Now(),
+			UpdatedAt:    time.Now(),
+		},
+	}
+
+	for _, appeal := range appeals {
+		_, err := repo.Save(appeal)
+		if err != nil {
+			t.Errorf("Failed to save appeal: %v", err)
+		}
+	}
+
+	savedAppeals, err := repo.GetAll()
+	if err != nil {
+		t.Errorf("Failed to get all appeals: %v", err)
+	}
+
+	if len(savedAppeals) != len(appeals) {
+		t.Errorf("Expected %d appeals, got %d", len(appeals), len(savedAppeals))
+	}
+}
+
+func testFindByID(t *testing.T, repo Store) {
+	appeal := &models.Appeal{
+		Theme:        "Test theme",
+		Message:      "Test message",
+		Status:       models.StatusNew,
+		Solution:     "Test solution",
+		CancelReason: "Test reason",
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	savedAppeal, err := repo.Save(appeal)
+	if err != nil {
+		t.Fatalf("Failed to save appeal: %v", err)
+	}
+
+	foundAppeal, err := repo.FindByID(savedAppeal.ID)
+	if err != nil {
+		t.Fatalf("Failed to find appeal by ID: %v", err)
+	}
+
+	if foundAppeal.ID != savedAppeal.ID {
+		t.Errorf("Expected appeal with ID %s, got %s", savedAppeal.ID, foundAppeal.ID)
+	}
+	if foundAppeal.Theme != savedAppeal.Theme || foundAppeal.Solution != savedAppeal.Solution {
+		t.Errorf("Expected stored fields to round-trip, got %+v", foundAppeal)
+	}
+
+	if _, err := repo.FindByID("non-existent"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when finding non-existent appeal, got %v", err)
+	}
+}
+
+func testUpdate(t *testing.T, repo Store) {
+	originalAppeal := &models.Appeal{
+		Theme:        "Original theme",
+		Message:      "Original message",
+		Status:       models.StatusNew,
+		Solution:     "Original solution",
+		CancelReason: "Original reason",
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	savedAppeal, err := repo.Save(originalAppeal)
+	if err != nil {
+		t.Fatalf("Failed to save appeal: %v", err)
+	}
+
+	updatedAppeal := &models.Appeal{
+		ID:           savedAppeal.ID,
+		Theme:        "Updated theme",
+		Message:      "Updated message",
+		Status:       models.StatusInProgress,
+		Solution:     "Updated solution",
+		CancelReason: "Updated reason",
+		CreatedAt:    savedAppeal.CreatedAt,
+		UpdatedAt:    time.Now(),
+	}
+
+	returnedAppeal, err := repo.Update(updatedAppeal)
+	if err != nil {
+		t.Fatalf("Failed to update appeal: %v", err)
+	}
+
+	if returnedAppeal.Theme != "Updated theme" {
+		t.Errorf("Expected theme %s, got %s", "Updated theme", returnedAppeal.Theme)
+	}
+	if returnedAppeal.Status != models.StatusInProgress {
+		t.Errorf("Expected status %s, got %s", models.StatusInProgress, returnedAppeal.Status)
+	}
+
+	foundAppeal, err := repo.FindByID(savedAppeal.ID)
+	if err != nil {
+		t.Fatalf("Failed to find appeal by ID: %v", err)
+	}
+
+	if foundAppeal.Theme != "Updated theme" {
+		t.Errorf("Expected theme %s in database, got %s", "Updated theme", foundAppeal.Theme)
+	}
+	if foundAppeal.Status != models.StatusInProgress {
+		t.Errorf("Expected status %s in database, got %s", models.StatusInProgress, foundAppeal.Status)
+	}
+
+	if foundAppeal.UpdatedAt.Equal(savedAppeal.UpdatedAt) {
+		t.Errorf("Expected UpdatedAt to be different after update")
+	}
+
+	nonExistentAppeal := &models.Appeal{
+		ID:           "non-existent",
+		Theme:        "Test theme",
+		Message:      "Test message",
+		Status:       models.StatusNew,
+		Solution:     "Test solution",
+		CancelReason: "Test reason",
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	_, err = repo.Update(nonExistentAppeal)
+	if !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when updating non-existent appeal, got %v", err)
+	}
+}
+
+func testFindByStatus(t *testing.T, repo Store) {
+	for _, status := range []models.AppealStatus{models.StatusNew, models.StatusInProgress, models.StatusCompleted, models.StatusNew} {
+		if _, err := repo.Save(&models.Appeal{Theme: "Theme", Message: "Message", Status: status}); err != nil {
+			t.Fatalf("Failed to save appeal: %v", err)
+		}
+	}
+
+	found, err := repo.FindByStatus(models.StatusNew, models.StatusCompleted)
+	if err != nil {
+		t.Fatalf("Failed to find appeals by status: %v", err)
+	}
+	if len(found) != 3 {
+		t.Errorf("Expected 3 appeals, got %d", len(found))
+	}
+	for _, appeal := range found {
+		if appeal.Status == models.StatusInProgress {
+			t.Errorf("Expected in-progress appeal to be excluded")
+		}
+	}
+
+	none, err := repo.FindByStatus()
+	if err != nil {
+		t.Fatalf("Failed to find appeals without statuses: %v", err)
+	}
+	if len(none) != 0 {
+		t.Errorf("Expected no appeals for an empty status list, got %d", len(none))
+	}
+}
+
+func testHistory(t *testing.T, repo Store) {
+	appeal, err := repo.Save(&models.Appeal{Theme: "Theme", Message: "Message", Status: models.StatusNew})
+	if err != nil {
+		t.Fatalf("Failed to save appeal: %v", err)
+	}
+	other, err := repo.Save(&models.Appeal{Theme: "Theme", Message: "Message", Status: models.StatusNew})
+	if err != nil {
+		t.Fatalf("Failed to save appeal: %v", err)
+	}
+
+	entries := []*models.StatusHistoryEntry{
+		{AppealID: appeal.ID, ToStatus: models.StatusNew, Actor: "requester"},
+		{AppealID: other.ID, ToStatus: models.StatusNew},
+		{AppealID: appeal.ID, FromStatus: models.StatusNew, ToStatus: models.StatusInProgress, Actor: "operator"},
+		{AppealID: appeal.ID, FromStatus: models.StatusInProgress, ToStatus: models.StatusCancelled, Actor: "operator", Reason: "Duplicate"},
+	}
+	for _, entry := range entries {
+		if err := repo.AddHistory(entry); err != nil {
+			t.Fatalf("Failed to add history: %v", err)
+		}
+		if entry.ID == 0 {
+			t.Errorf("Expected history entry to get an ID")
+		}
+	}
+
+	history, err := repo.GetHistory(appeal.ID)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 history entries, got %d", len(history))
+	}
+
+	last := history[2]
+	if last.FromStatus != models.StatusInProgress || last.ToStatus != models.StatusCancelled ||
+		last.Actor != "operator" || last.Reason != "Duplicate" || last.CreatedAt.IsZero() {
+		t.Errorf("Expected last entry to round-trip, got %+v", last)
+	}
+	if history[0].ID >= history[1].ID || history[1].ID >= history[2].ID {
+		t.Errorf("Expected history ordered by ID, got %d, %d, %d", history[0].ID, history[1].ID, history[2].ID)
+	}
+}
+
+func testWithTx(t *testing.T, repo Store) {
+	var committed *models.Appeal
+	err := repo.WithTx(func(tx AppealStore) error {
+		var err error
+		committed, err = tx.Save(&models.Appeal{Theme: "Committed", Message: "Message", Status: models.StatusNew})
+		if err != nil {
+			return err
+		}
+		return tx.AddHistory(&models.StatusHistoryEntry{AppealID: committed.ID, ToStatus: models.StatusNew})
+	})
+	if err != nil {
+		t.Fatalf("Failed to run transaction: %v", err)
+	}
+
+	rollback := errors.New("rollback")
+	var discarded *models.Appeal
+	err = repo.WithTx(func(tx AppealStore) error {
+		var err error
+		discarded, err = tx.Save(&models.Appeal{Theme: "Discarded", Message: "Message", Status: models.StatusNew})
+		if err != nil {
+			return err
+		}
+
+		committed.Status = models.StatusInProgress
+		if _, err := tx.Update(committed); err != nil {
+			return err
+		}
+		if err := tx.AddHistory(&models.StatusHistoryEntry{AppealID: committed.ID, FromStatus: models.StatusNew, ToStatus: models.StatusInProgress}); err != nil {
+			return err
+		}
+
+		// Reads inside the transaction see its own writes.
+		found, err := tx.FindByID(committed.ID)
+		if err != nil {
+			return err
+		}
+		if found.Status != models.StatusInProgress {
+			t.Errorf("Expected transaction to see its own update, got %s", found.Status)
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Expected rollback error, got %v", err)
+	}
+
+	if _, err := repo.FindByID(discarded.ID); err == nil {
+		t.Errorf("Expected appeal saved in a rolled back transaction to be gone")
+	}
+
+	found, err := repo.FindByID(committed.ID)
+	if err != nil {
+		t.Fatalf("Failed to find committed appeal: %v", err)
+	}
+	if found.Status != models.StatusNew {
+		t.Errorf("Expected rolled back update to be discarded, got %s", found.Status)
+	}
+
+	history, err := repo.GetHistory(committed.ID)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 1 {
+		t.Errorf("Expected only the committed history entry, got %d", len(history))
+	}
+}
+
+func testList(t *testing.T, repo Store) {
+	base := time.Date(2025, 11, 3, 9, 0, 0, 0, time.UTC)
+	// Два обращения с одинаковым created_at проверяют сортировку по id.
+	seeds := []struct {
+		theme   string
+		status  models.AppealStatus
+		created time.Duration
+		updated time.Duration
+	}{
+		{"billing", models.StatusNew, 0, 50 * time.Minute},
+		{"billing", models.StatusInProgress, 10 * time.Minute, 20 * time.Minute},
+		{"delivery", models.StatusNew, 20 * time.Minute, 40 * time.Minute},
+		{"billing", models.StatusCompleted, 20 * time.Minute, 30 * time.Minute},
+		{"delivery", models.StatusInProgress, 30 * time.Minute, 10 * time.Minute},
+		{"billing", models.StatusNew, 40 * time.Minute, 60 * time.Minute},
+	}
+	for _, seed := range seeds {
+		_, err := repo.Save(&models.Appeal{
+			Theme:     seed.theme,
+			Message:   "Test message",
+			Status:    seed.status,
+			CreatedAt: base.Add(seed.created),
+			UpdatedAt: base.Add(seed.updated),
+		})
+		if err != nil {
+			t.Fatalf("Failed to save appeal: %v", err)
+		}
+	}
+
+	all, err := repo.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get all appeals: %v", err)
+	}
+
+	collect := func(filter models.AppealFilter) []*models.Appeal {
+		t.Helper()
+		var appeals []*models.Appeal
+		for pages := 0; ; pages++ {
+			if pages > len(seeds) {
+				t.Fatalf("Pagination did not terminate")
+			}
+			page, err := repo.List(filter)
+			if err != nil {
+				t.Fatalf("Failed to list appeals: %v", err)
+			}
+			if len(page.Appeals) > filter.Limit && filter.Limit > 0 {
+				t.Fatalf("Expected at most %d appeals per page, got %d", filter.Limit, len(page.Appeals))
+			}
+			appeals = append(appeals, page.Appeals...)
+			if page.NextCursor == "" {
+				return appeals
+			}
+			filter.Cursor = page.NextCursor
+		}
+	}
+
+	isSorted := func(appeals []*models.Appeal, field models.AppealSortField, desc bool) bool {
+		for i := 1; i < len(appeals); i++ {
+			prev, cur := appeals[i-1], appeals[i]
+			pv, cv := prev.SortValue(field), cur.SortValue(field)
+			ordered := pv.Before(cv) || (pv.Equal(cv) && prev.ID < cur.ID)
+			if desc {
+				ordered = pv.After(cv) || (pv.Equal(cv) && prev.ID > cur.ID)
+			}
+			if !ordered {
+				return false
+			}
+		}
+		return true
+	}
+
+	t.Run("PagesCoverEverything", func(t *testing.T) {
+		for _, field := range []models.AppealSortField{models.SortByCreatedAt, models.SortByUpdatedAt} {
+			for _, desc := range []bool{false, true} {
+				appeals := collect(models.AppealFilter{SortBy: field, Descending: desc, Limit: 2})
+				if len(appeals) != len(all) {
+					t.Errorf("%s desc=%v: expected %d appeals across pages, got %d", field, desc, len(all), len(appeals))
+				}
+				if !isSorted(appeals, field, desc) {
+					t.Errorf("%s desc=%v: appeals are not in keyset order", field, desc)
+				}
+			}
+		}
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			filter   models.AppealFilter
+			expected int
+		}{
+			{"Statuses", models.AppealFilter{Statuses: []models.AppealStatus{models.StatusNew, models.StatusInProgress}}, 5},
+			{"Theme", models.AppealFilter{Theme: "delivery"}, 2},
+			{"ThemeAndStatus", models.AppealFilter{Theme: "billing", Statuses: []models.AppealStatus{models.StatusNew}}, 2},
+			{"CreatedRange", models.AppealFilter{CreatedFrom: base.Add(10 * time.Minute), CreatedTo: base.Add(30 * time.Minute)}, 4},
+			{"UpdatedFrom", models.AppealFilter{UpdatedFrom: base.Add(40 * time.Minute)}, 3},
+			{"UpdatedTo", models.AppealFilter{UpdatedTo: base.Add(20 * time.Minute), Limit: 1}, 2},
+			{"OtherZone", models.AppealFilter{CreatedFrom: base.Add(40 * time.Minute).In(time.FixedZone("MSK", 3*60*60))}, 1},
+		}
+
+		for _, tt := range tests {
+			appeals := collect(tt.filter)
+			if len(appeals) != tt.expected {
+				t.Errorf("%s: expected %d appeals, got %d", tt.name, tt.expected, len(appeals))
+			}
+		}
+	})
+
+	t.Run("InvalidRequests", func(t *testing.T) {
+		page, err := repo.List(models.AppealFilter{Limit: 1})
+		if err != nil {
+			t.Fatalf("Failed to list appeals: %v", err)
+		}
+
+		invalid := []models.AppealFilter{
+			{Cursor: "not a cursor"},
+			{Cursor: page.NextCursor, Limit: 1, Descending: true},
+			{Cursor: page.NextCursor, Limit: 1, SortBy: models.SortByUpdatedAt},
+			{SortBy: "theme"},
+			{Limit: models.MaxPageSize + 1},
+			{CreatedFrom: base.Add(time.Hour), CreatedTo: base},
+		}
+		for i, filter := range invalid {
+			if _, err := repo.List(filter); !errors.Is(err, models.ErrValidation) {
+				t.Errorf("Filter %d: expected validation error, got %v", i, err)
+			}
+		}
+	})
+}
+
+func testSearch(t *testing.T, repo Store) {
+	base := time.Date(2025, 11, 10, 9, 0, 0, 0, time.UTC)
+	appeals := []*models.Appeal{
+		{Theme: "Яма на улице Ленина", Message: "Огромная яма у дома 5", CreatedAt: base},
+		{Theme: "Освещение", Message: "Не горят фонари на улице Ленина", CreatedAt: base.Add(time.Minute)},
+		{Theme: "Parcel delivery", Message: "The courier never delivered my parcel", CreatedAt: base.Add(2 * time.Minute)},
+		{Theme: "Шум", Message: "Соседи шумят по ночам", CreatedAt: base.Add(3 * time.Minute)},
+	}
+	for _, appeal := range appeals {
+		appeal.Status = models.StatusNew
+		if _, err := repo.Save(appeal); err != nil {
+			t.Fatalf("Failed to save appeal: %v", err)
+		}
+	}
+
+	find := func(query string, limit, offset int) []*models.SearchResult {
+		t.Helper()
+		q, err := search.Parse(query)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", query, err)
+		}
+		results, err := repo.Search(q, limit, offset)
+		if err != nil {
+			t.Fatalf("Failed to search %q: %v", query, err)
+		}
+		return results
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"ямы", []string{appeals[0].ID}},
+		{`"улицы Ленина"`, []string{appeals[0].ID, appeals[1].ID}},
+		{`"Ленина улица"`, nil},
+		{"deliveries", []string{appeals[2].ID}},
+		{"cour*", []string{appeals[2].ID}},
+		{"фонар* ленина", []string{appeals[1].ID}},
+		{"шум трамвай", nil},
+	}
+	for _, tt := range tests {
+		results := find(tt.query, 10, 0)
+		if len(results) != len(tt.expected) {
+			t.Errorf("%q: expected %d results, got %d", tt.query, len(tt.expected), len(results))
+			continue
+		}
+		for i, id := range tt.expected {
+			if results[i].Appeal.ID != id {
+				t.Errorf("%q: expected result %d to be %s, got %s", tt.query, i, id, results[i].Appeal.ID)
+			}
+			if results[i].Rank <= 0 {
+				t.Errorf("%q: expected a positive rank, got %v", tt.query, results[i].Rank)
+			}
+		}
+	}
+
+	if page := find(`"улицы Ленина"`, 1, 1); len(page) != 1 || page[0].Appeal.ID != appeals[1].ID {
+		t.Errorf("Expected the second phrase match on the second page")
+	}
+	if page := find(`"улицы Ленина"`, 10, 5); len(page) != 0 {
+		t.Errorf("Expected no results past the end, got %d", len(page))
+	}
+
+	appeals[3].Solution = "Проведена беседа с соседями"
+	if _, err := repo.Update(appeals[3]); err != nil {
+		t.Fatalf("Failed to update appeal: %v", err)
+	}
+	if results := find("беседы", 10, 0); len(results) != 1 || results[0].Appeal.ID != appeals[3].ID {
+		t.Errorf("Expected updated solution to be searchable, got %d results", len(results))
+	}
+}
+
+func testAssignee(t *testing.T, repo Store) {
+	var saved []*models.Appeal
+	for _, assignee := range []string{"anna", "boris", ""} {
+		appeal, err := repo.Save(&models.Appeal{Theme: "Theme", Message: "Message", Status: models.StatusNew, Assignee: assignee})
+		if err != nil {
+			t.Fatalf("Failed to save appeal: %v", err)
+		}
+		saved = append(saved, appeal)
+	}
+
+	found, err := repo.FindByID(saved[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to find appeal: %v", err)
+	}
+	if found.Assignee != "anna" {
+		t.Errorf("Expected assignee to round-trip, got %q", found.Assignee)
+	}
+
+	saved[2].Assignee = "anna"
+	if _, err := repo.Update(saved[2]); err != nil {
+		t.Fatalf("Failed to update appeal: %v", err)
+	}
+
+	page, err := repo.List(models.AppealFilter{Assignee: "anna"})
+	if err != nil {
+		t.Fatalf("Failed to list appeals: %v", err)
+	}
+	if len(page.Appeals) != 2 {
+		t.Fatalf("Expected anna's two appeals, got %d", len(page.Appeals))
+	}
+	for _, appeal := range page.Appeals {
+		if appeal.ID != saved[0].ID && appeal.ID != saved[2].ID {
+			t.Errorf("Unexpected appeal %s in anna's list", appeal.ID)
+		}
+	}
+}
+
+func testCreatedBy(t *testing.T, repo Store) {
+	for _, owner := range []string{"jane", "john", "jane"} {
+		if _, err := repo.Save(&models.Appeal{Theme: "Theme", Message: "Message", Status: models.StatusNew, CreatedBy: owner}); err != nil {
+			t.Fatalf("Failed to save appeal: %v", err)
+		}
+	}
+
+	page, err := repo.List(models.AppealFilter{CreatedBy: "jane"})
+	if err != nil {
+		t.Fatalf("Failed to list appeals: %v", err)
+	}
+	if len(page.Appeals) != 2 {
+		t.Fatalf("Expected jane's two appeals, got %d", len(page.Appeals))
+	}
+	for _, appeal := range page.Appeals {
+		if appeal.CreatedBy != "jane" {
+			t.Errorf("Unexpected appeal by %q in jane's list", appeal.CreatedBy)
+		}
+	}
+}
+
+func testComments(t *testing.T, repo Store) {
+	appeal, err := repo.Save(&models.Appeal{Theme: "Theme", Message: "Message", Status: models.StatusNew})
+	if err != nil {
+		t.Fatalf("Failed to save appeal: %v", err)
+	}
+	other, err := repo.Save(&models.Appeal{Theme: "Theme", Message: "Message", Status: models.StatusNew})
+	if err != nil {
+		t.Fatalf("Failed to save appeal: %v", err)
+	}
+
+	comments := []*models.Comment{
+		{AppealID: appeal.ID, Visibility: models.CommentPublic, Author: "jane", Body: "Any news?"},
+		{AppealID: appeal.ID, Visibility: models.CommentInternal, Author: "olga", Body: "Waiting for the vendor"},
+		{AppealID: other.ID, Visibility: models.CommentPublic, Author: "john", Body: "Thanks"},
+	}
+	for _, comment := range comments {
+		if err := repo.AddComment(comment); err != nil {
+			t.Fatalf("Failed to add comment: %v", err)
+		}
+		if comment.ID == 0 {
+			t.Errorf("Expected an ID for %+v", comment)
+		}
+	}
+
+	public, err := repo.ListComments(appeal.ID, false)
+	if err != nil || len(public) != 1 || public[0].Author != "jane" {
+		t.Fatalf("Expected only the public reply, got %+v, %v", public, err)
+	}
+	thread, err := repo.ListComments(appeal.ID, true)
+	if err != nil || len(thread) != 2 || thread[0].ID != comments[0].ID || thread[1].Visibility != models.CommentInternal {
+		t.Fatalf("Expected the whole thread in order, got %+v, %v", thread, err)
+	}
+
+	comment := comments[0]
+	for i, body := range []string{"Any news on this?", "Any news on this, please?"} {
+		edit := &models.CommentEdit{Body: comment.Body, EditedBy: "jane"}
+		edited := time.Now()
+		comment.Body, comment.EditedAt = body, &edited
+		if err := repo.EditComment(comment, edit); err != nil {
+			t.Fatalf("Edit %d: failed: %v", i, err)
+		}
+		if edit.ID == 0 || edit.CommentID != comment.ID {
+			t.Errorf("Edit %d: unexpected %+v", i, edit)
+		}
+	}
+	// Откаченная правка не сохраняется.
+	rollback := errors.New("rollback")
+	err = repo.WithTx(func(tx AppealStore) error {
+		changed := *comment
+		changed.Body = "Discarded"
+		if err := tx.EditComment(&changed, &models.CommentEdit{Body: comment.Body, EditedBy: "jane"}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Expected the rollback error, got %v", err)
+	}
+
+	found, err := repo.FindComment(comment.ID)
+	if err != nil {
+		t.Fatalf("Failed to find comment: %v", err)
+	}
+	if found.Body != "Any news on this, please?" || found.EditedAt == nil || len(found.Edits) != 2 ||
+		found.Edits[0].Body != "Any news?" || found.Edits[1].Body != "Any news on this?" || found.Edits[0].EditedBy != "jane" {
+		t.Errorf("Expected the latest body with both earlier versions, got %+v", found)
+	}
+	if thread, _ := repo.ListComments(appeal.ID, true); len(thread[0].Edits) != 2 || len(thread[1].Edits) != 0 {
+		t.Errorf("Expected the edits in the thread, got %+v", thread)
+	}
+
+	if _, err := repo.FindComment(999); models.ErrorCode(err) != "comment_not_found" {
+		t.Errorf("Expected comment_not_found, got %v", err)
+	}
+	if err := repo.EditComment(&models.Comment{ID: 999, Body: "x"}, &models.CommentEdit{Body: "y"}); models.ErrorCode(err) != "comment_not_found" {
+		t.Errorf("Expected comment_not_found, got %v", err)
+	}
+	if _, err := repo.ForTenant("acme").FindComment(comment.ID); models.ErrorCode(err) != "comment_not_found" {
+		t.Errorf("Expected another tenant's comment to be invisible, got %v", err)
+	}
+}
+
+func testAttachments(t *testing.T, repo Store) {
+	appeal, err := repo.Save(&models.Appeal{Theme: "Theme", Message: "Message", Status: models.StatusNew})
+	if err != nil {
+		t.Fatalf("Failed to save appeal: %v", err)
+	}
+	created := time.Date(2025, 10, 27, 12, 0, 0, 0, time.UTC)
+
+	sha := strings.Repeat("ab", 32)
+	attachments := []*models.Attachment{
+		{AppealID: appeal.ID, FileName: "receipt.pdf", ContentType: "application/pdf", Size: 2048, SHA256: sha, UploadedBy: "jane", CreatedAt: created},
+		// Тот же файл под другим именем хранится одним блобом, но это отдельное вложение.
+		{AppealID: appeal.ID, FileName: "receipt-copy.pdf", ContentType: "application/pdf", Size: 2048, SHA256: sha, UploadedBy: "jane", CreatedAt: created.Add(time.Minute)},
+	}
+	for _, attachment := range attachments {
+		if err := repo.AddAttachment(attachment); err != nil {
+			t.Fatalf("Failed to add attachment: %v", err)
+		}
+		if attachment.ID == "" || attachment.TenantID != models.DefaultTenant {
+			t.Errorf("Expected an ID and the default tenant, got %+v", attachment)
+		}
+	}
+
+	found, err := repo.FindAttachment(attachments[0].ID)
+	if err != nil {
+		t.Fatalf("Failed to find attachment: %v", err)
+	}
+	if found.FileName != "receipt.pdf" || found.Size != 2048 || found.SHA256 != sha || found.AppealID != appeal.ID ||
+		found.UploadedBy != "jane" || !found.CreatedAt.Equal(created) {
+		t.Errorf("Unexpected attachment %+v", found)
+	}
+
+	list, err := repo.ListAttachments(appeal.ID)
+	if err != nil || len(list) != 2 || list[0].ID != attachments[0].ID || list[1].FileName != "receipt-copy.pdf" {
+		t.Fatalf("Expected both attachments in order, got %+v, %v", list, err)
+	}
+	if list, _ := repo.ListAttachments("missing"); len(list) != 0 {
+		t.Errorf("Expected no attachments, got %+v", list)
+	}
+
+	rollback := errors.New("rollback")
+	err = repo.WithTx(func(tx AppealStore) error {
+		if err := tx.AddAttachment(&models.Attachment{AppealID: appeal.ID, FileName: "discarded.png", ContentType: "image/png", SHA256: sha}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Expected the rollback error, got %v", err)
+	}
+	if list, _ := repo.ListAttachments(appeal.ID); len(list) != 2 {
+		t.Errorf("Expected the rolled back attachment to be gone, got %+v", list)
+	}
+
+	if _, err := repo.FindAttachment("missing"); models.ErrorCode(err) != "attachment_not_found" {
+		t.Errorf("Expected attachment_not_found, got %v", err)
+	}
+	if _, err := repo.ForTenant("acme").FindAttachment(found.ID); models.ErrorCode(err) != "attachment_not_found" {
+		t.Errorf("Expected another tenant's attachment to be invisible, got %v", err)
+	}
+	if list, _ := repo.ForTenant("acme").ListAttachments(appeal.ID); len(list) != 0 {
+		t.Errorf("Expected no attachments for another tenant, got %+v", list)
+	}
+
+	// Содержимое общее для всех организаций, поэтому проверка его не ограничивает.
+	for key, want := range map[string]bool{sha: true, strings.Repeat("cd", 32): false} {
+		if inUse, err := repo.ForTenant("acme").ContentInUse(key); err != nil || inUse != want {
+			t.Errorf("Expected content %s in use to be %v, got %v, %v", key, want, inUse, err)
+		}
+	}
+}
+
+func testRequesters(t *testing.T, repo Store) {
+	jane := &models.Requester{Name: "Jane Doe", Email: "jane@example.com", Phone: "+79123456789", PreferredChannel: models.ChannelEmail, Subject: "jane"}
+	john := &models.Requester{Name: "John Roe", Phone: "+79000000000", PreferredChannel: models.ChannelPhone}
+	for _, requester := range []*models.Requester{jane, john} {
+		if err := repo.AddRequester(requester); err != nil {
+			t.Fatalf("Failed to add requester: %v", err)
+		}
+		if requester.ID == "" || requester.TenantID != models.DefaultTenant {
+			t.Errorf("Expected an ID and the default tenant, got %+v", requester)
+		}
+	}
+
+	// Почта и телефон уникальны в пределах организации, но не между ними.
+	if err := repo.AddRequester(&models.Requester{Email: "jane@example.com"}); models.ErrorCode(err) != "requester_exists" {
+		t.Errorf("Expected requester_exists for a taken email, got %v", err)
+	}
+	if err := repo.AddRequester(&models.Requester{Phone: "+79000000000"}); models.ErrorCode(err) != "requester_exists" {
+		t.Errorf("Expected requester_exists for a taken phone, got %v", err)
+	}
+	if err := repo.ForTenant("acme").AddRequester(&models.Requester{Email: "jane@example.com"}); err != nil {
+		t.Errorf("Expected another tenant to have its own requesters, got %v", err)
+	}
+
+	tests := []struct {
+		name, email, phone string
+		want               string
+	}{
+		{"Email", "jane@example.com", "", jane.ID},
+		{"Phone", "", "+79000000000", john.ID},
+		{"EmailFirst", "jane@example.com", "+79000000000", jane.ID},
+		{"FallbackToPhone", "nobody@example.com", "+79000000000", john.ID},
+	}
+	for _, tt := range tests {
+		found, err := repo.ForTenant(models.DefaultTenant).FindRequesterByContact(tt.email, tt.phone)
+		if err != nil || found.ID != tt.want {
+			t.Errorf("%s: expected %s, got %+v, %v", tt.name, tt.want, found, err)
+		}
+	}
+	if found, err := repo.FindRequester(jane.ID); err != nil || found.Subject != "jane" {
+		t.Errorf("Expected the requester's subject to be stored, got %+v, %v", found, err)
+	}
+	if _, err := repo.FindRequesterByContact("", ""); models.ErrorCode(err) != "requester_not_found" {
+		t.Errorf("Expected empty contacts to match nothing, got %v", err)
+	}
+
+	john.Email, john.Address = "john@example.com", "1 Main St"
+	if err := repo.UpdateRequester(john); err != nil {
+		t.Fatalf("Failed to update requester: %v", err)
+	}
+	found, err := repo.FindRequester(john.ID)
+	if err != nil || found.Email != "john@example.com" || found.Address != "1 Main St" || found.Name != "John Roe" {
+		t.Errorf("Expected the updated requester, got %+v, %v", found, err)
+	}
+	john.Email = "jane@example.com"
+	if err := repo.UpdateRequester(john); models.ErrorCode(err) != "requester_exists" {
+		t.Errorf("Expected requester_exists when taking another requester's email, got %v", err)
+	}
+	if err := repo.UpdateRequester(&models.Requester{ID: "missing"}); models.ErrorCode(err) != "requester_not_found" {
+		t.Errorf("Expected requester_not_found, got %v", err)
+	}
+	if _, err := repo.ForTenant("acme").FindRequester(jane.ID); models.ErrorCode(err) != "requester_not_found" {
+		t.Errorf("Expected another tenant's requester to be invisible, got %v", err)
+	}
+
+	for _, message := range []string{"Out of toner", "Paper jam"} {
+		if _, err := repo.Save(&models.Appeal{Theme: "Printer", Message: message, Status: models.StatusNew, RequesterID: jane.ID}); err != nil {
+			t.Fatalf("Failed to save appeal: %v", err)
+		}
+	}
+	if _, err := repo.Save(&models.Appeal{Theme: "Printer", Message: "Smudges", Status: models.StatusNew, RequesterID: john.ID}); err != nil {
+		t.Fatalf("Failed to save appeal: %v", err)
+	}
+	page, err := repo.List(models.AppealFilter{RequesterID: jane.ID})
+	if err != nil || len(page.Appeals) != 2 || page.Appeals[0].RequesterID != jane.ID {
+		t.Errorf("Expected Jane's two appeals, got %+v, %v", page, err)
+	}
+}
+
+func testTracking(t *testing.T, repo Store) {
+	acme := repo.ForTenant("acme")
+	saved, err := acme.Save(&models.Appeal{Theme: "Printer", Message: "Out of toner", Status: models.StatusNew, Number: "2026-000001", TrackingTokenHash: "hash-1"})
+	if err != nil {
+		t.Fatalf("Failed to save appeal: %v", err)
+	}
+
+	found, err := repo.FindByTrackingHash("hash-1")
+	if err != nil || found.ID != saved.ID || found.Number != "2026-000001" || found.TrackingTokenHash != "hash-1" {
+		t.Errorf("Expected the appeal by its tracking hash, got %+v, %v", found, err)
+	}
+	if found, err := repo.FindByID(saved.ID); err != nil || found.Number != "2026-000001" {
+		t.Errorf("Expected the number to be stored, got %+v, %v", found, err)
+	}
+	for _, tt := range []struct {
+		name  string
+		store AppealStore
+		hash  string
+	}{
+		{"Unknown", repo, "hash-2"},
+		{"Empty", repo, ""},
+		{"OtherTenant", repo.ForTenant("globex"), "hash-1"},
+	} {
+		if _, err := tt.store.FindByTrackingHash(tt.hash); models.ErrorCode(err) != "tracking_token_not_found" {
+			t.Errorf("%s: expected tracking_token_not_found, got %v", tt.name, err)
+		}
+	}
+
+	if found, err := acme.FindByNumber("2026-000001"); err != nil || found.ID != saved.ID {
+		t.Errorf("Expected the appeal by its number, got %+v, %v", found, err)
+	}
+	for _, number := range []string{"2026-000002", ""} {
+		if _, err := acme.FindByNumber(number); models.ErrorCode(err) != "appeal_not_found" {
+			t.Errorf("Expected appeal_not_found for %q, got %v", number, err)
+		}
+	}
+	if _, err := repo.ForTenant("globex").FindByNumber("2026-000001"); models.ErrorCode(err) != "appeal_not_found" {
+		t.Errorf("Expected another tenant's number to be hidden, got %v", err)
+	}
+	// Номера уникальны только внутри арендатора.
+	if _, err := acme.Save(&models.Appeal{Theme: "Printer", Message: "Jammed", Status: models.StatusNew, Number: "2026-000001"}); models.ErrorCode(err) != "number_taken" {
+		t.Errorf("Expected number_taken, got %v", err)
+	}
+	if _, err := repo.ForTenant("globex").Save(&models.Appeal{Theme: "Printer", Message: "Jammed", Status: models.StatusNew, Number: "2026-000001"}); err != nil {
+		t.Errorf("Expected another tenant to have its own numbers, got %v", err)
+	}
+
+	next := func(store AppealStore, series string, want int64) {
+		t.Helper()
+		if n, err := store.NextNumber(series); err != nil || n != want {
+			t.Errorf("Expected %d in %s, got %d, %v", want, series, n, err)
+		}
+	}
+	next(acme, "2026", 1)
+	next(acme, "2026", 2)
+	next(acme, "2027", 1)
+	next(repo.ForTenant("globex"), "2026", 1)
+
+	// Номер из откаченной транзакции выдаётся снова.
+	rollback := errors.New("rollback")
+	err = acme.WithTx(func(tx AppealStore) error {
+		if n, err := tx.NextNumber("2026"); err != nil || n != 3 {
+			t.Errorf("Expected 3 inside the transaction, got %d, %v", n, err)
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Expected the rollback error, got %v", err)
+	}
+	next(acme, "2026", 3)
+}
+
+func testTenants(t *testing.T, repo Store) {
+	acme, globex := repo.ForTenant("acme"), repo.ForTenant("globex")
+	created := time.Date(2025, 10, 27, 12, 0, 0, 0, time.UTC)
+
+	var acmeAppeals []*models.Appeal
+	for _, message := range []string{"Printer out of toner", "Toner smudges"} {
+		appeal, err := acme.Save(&models.Appeal{Theme: "Printer", Message: message, Status: models.StatusInProgress, CreatedAt: created})
+		if err != nil {
+			t.Fatalf("Failed to save appeal: %v", err)
+		}
+		if appeal.TenantID != "acme" {
+			t.Errorf("Expected the appeal to belong to acme, got %q", appeal.TenantID)
+		}
+		acmeAppeals = append(acmeAppeals, appeal)
+	}
+	if _, err := globex.Save(&models.Appeal{Theme: "Printer", Message: "No toner left", Status: models.StatusInProgress, CreatedAt: created}); err != nil {
+		t.Fatalf("Failed to save appeal: %v", err)
+	}
+	// Без привязки к организации обращение попадает в организацию по умолчанию.
+	unbound, err := repo.Save(&models.Appeal{Theme: "Printer", Message: "Toner", Status: models.StatusNew, CreatedAt: created})
+	if err != nil {
+		t.Fatalf("Failed to save appeal: %v", err)
+	}
+	if unbound.TenantID != models.DefaultTenant {
+		t.Errorf("Expected the default tenant, got %q", unbound.TenantID)
+	}
+
+	counts := func(name string, list func(store AppealStore) (int, error)) {
+		t.Helper()
+		for store, want := range map[AppealStore]int{acme: 2, globex: 1, repo: 4} {
+			got, err := list(store)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if got != want {
+				t.Errorf("%s: expected %d appeals, got %d", name, want, got)
+			}
+		}
+	}
+	counts("GetAll", func(store AppealStore) (int, error) {
+		appeals, err := store.GetAll()
+		return len(appeals), err
+	})
+	counts("SelectAppealsByDates", func(store AppealStore) (int, error) {
+		appeals, err := store.SelectAppealsByDates(created.Add(-time.Hour), created.Add(time.Hour))
+		return len(appeals), err
+	})
+	counts("List", func(store AppealStore) (int, error) {
+		page, err := store.List(models.AppealFilter{})
+		return len(page.Appeals), err
+	})
+	counts("Search", func(store AppealStore) (int, error) {
+		q, err := search.Parse("toner")
+		if err != nil {
+			return 0, err
+		}
+		results, err := store.Search(q, 10, 0)
+		return len(results), err
+	})
+
+	id := acmeAppeals[0].ID
+	if _, err := globex.FindByID(id); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected another tenant's appeal to be invisible, got %v", err)
+	}
+	if _, err := globex.Update(acmeAppeals[0]); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected another tenant's appeal to be unchangeable, got %v", err)
+	}
+	if found, err := acme.FindByID(id); err != nil || found.TenantID != "acme" {
+		t.Errorf("Expected acme's appeal, got %+v, %v", found, err)
+	}
+
+	if err := acme.AddHistory(&models.StatusHistoryEntry{AppealID: id, ToStatus: models.StatusInProgress}); err != nil {
+		t.Fatalf("Failed to add history: %v", err)
+	}
+	if history, err := globex.GetHistory(id); err != nil || len(history) != 0 {
+		t.Errorf("Expected no history for another tenant, got %d, %v", len(history), err)
+	}
+	if _, err := acme.AddReminder(&models.Reminder{AppealID: id, Kind: models.ReminderResponseDue, Message: "Reminder", DueAt: created}); err != nil {
+		t.Fatalf("Failed to add reminder: %v", err)
+	}
+	if reminders, err := globex.ListReminders(models.ReminderFilter{}); err != nil || len(reminders) != 0 {
+		t.Errorf("Expected no reminders for another tenant, got %d, %v", len(reminders), err)
+	}
+
+	err = acme.WithTx(func(tx AppealStore) error {
+		return tx.AddEvent(&models.Event{ID: "acme-1", Type: "appeal.created", AppealID: id, OccurredAt: created})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add event: %v", err)
+	}
+	if events, err := globex.ListEvents(0, 10); err != nil || len(events) != 0 {
+		t.Errorf("Expected no events for another tenant, got %d, %v", len(events), err)
+	}
+	if last, err := globex.LastEventSequence(); err != nil || last != 0 {
+		t.Errorf("Expected no last event for another tenant, got %d, %v", last, err)
+	}
+	if events, err := repo.ListEvents(0, 10); err != nil || len(events) != 1 || events[0].TenantID != "acme" {
+		t.Errorf("Expected acme's event, got %+v, %v", events, err)
+	}
+
+	cancelled, err := globex.CancelInProgressAppeals([]models.AppealStatus{models.StatusInProgress}, cancelWith("Closing"))
+	if err != nil || len(cancelled) != 1 || cancelled[0].TenantID != "globex" {
+		t.Fatalf("Expected only globex's appeal to be cancelled, got %+v, %v", cancelled, err)
+	}
+	for _, appeal := range acmeAppeals {
+		if found, err := acme.FindByID(appeal.ID); err != nil || found.Status != models.StatusInProgress {
+			t.Errorf("Expected acme's appeal to stay in progress, got %+v, %v", found, err)
+		}
+	}
+}
+
+func testSLA(t *testing.T, repo Store) {
+	now := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		v := now.Add(d).In(time.FixedZone("MSK", 3*60*60))
+		return &v
+	}
+
+	appeals := map[string]*models.Appeal{
+		// Ответ просрочен, обращение ещё без ответа.
+		"unanswered": {Priority: models.PriorityUrgent, ResponseDueAt: at(-time.Hour), ResolutionDueAt: at(4 * time.Hour)},
+		// Ответ дан вовремя, срок решения наступает через час.
+		"due": {Priority: models.PriorityHigh, ResponseDueAt: at(-2 * time.Hour), RespondedAt: at(-3 * time.Hour), ResolutionDueAt: at(time.Hour)},
+		// Решено позже срока.
+		"late": {Priority: models.PriorityNormal, ResponseDueAt: at(-48 * time.Hour), RespondedAt: at(-49 * time.Hour), ResolutionDueAt: at(-24 * time.Hour), ResolvedAt: at(-time.Hour), EscalatedAt: at(-12 * time.Hour)},
+		// Решено вовремя, срок решения уже не важен.
+		"resolved":  {Priority: models.PriorityLow, ResponseDueAt: at(-48 * time.Hour), RespondedAt: at(-49 * time.Hour), ResolutionDueAt: at(2 * time.Hour), ResolvedAt: at(-time.Hour)},
+		"untracked": {Priority: models.PriorityNormal},
+		// Часы остановлены до срока: ни просрочки, ни «скоро срок».
+		"paused": {Priority: models.PriorityNormal, ResponseDueAt: at(-48 * time.Hour), RespondedAt: at(-49 * time.Hour), ResolutionDueAt: at(-time.Hour), SLAPausedAt: at(-2 * time.Hour), SLAPausedFor: 90 * time.Minute},
+	}
+	names := make(map[string]string)
+	for name, appeal := range appeals {
+		appeal.Theme, appeal.Message, appeal.Status = "Theme", "Message", models.StatusInProgress
+		if _, err := repo.Save(appeal); err != nil {
+			t.Fatalf("Failed to save appeal: %v", err)
+		}
+		names[appeal.ID] = name
+	}
+
+	found, err := repo.FindByID(appeals["late"].ID)
+	if err != nil {
+		t.Fatalf("Failed to find appeal: %v", err)
+	}
+	if found.Priority != models.PriorityNormal || found.ResolvedAt == nil || !found.ResolvedAt.Equal(*appeals["late"].ResolvedAt) ||
+		found.EscalatedAt == nil || !found.EscalatedAt.Equal(*appeals["late"].EscalatedAt) {
+		t.Errorf("Expected priority and SLA timestamps to round-trip, got %+v", found)
+	}
+	found, err = repo.FindByID(appeals["paused"].ID)
+	if err != nil {
+		t.Fatalf("Failed to find appeal: %v", err)
+	}
+	if found.SLAPausedAt == nil || !found.SLAPausedAt.Equal(*appeals["paused"].SLAPausedAt) || found.SLAPausedFor != 90*time.Minute {
+		t.Errorf("Expected the stopped SLA clock to round-trip, got %v / %v", found.SLAPausedAt, found.SLAPausedFor)
+	}
+	found, err = repo.FindByID(appeals["untracked"].ID)
+	if err != nil {
+		t.Fatalf("Failed to find appeal: %v", err)
+	}
+	if found.ResponseDueAt != nil || found.ResolvedAt != nil {
+		t.Errorf("Expected appeal without deadlines to stay without them, got %+v", found)
+	}
+
+	tests := []struct {
+		name     string
+		filter   models.AppealFilter
+		expected []string
+	}{
+		{"Breached", models.AppealFilter{SLA: models.SLABreached, AsOf: now}, []string{"late", "unanswered"}},
+		{"DueSoon", models.AppealFilter{SLA: models.SLADueSoon, DueWithin: 2 * time.Hour, AsOf: now}, []string{"due"}},
+		{"DueLater", models.AppealFilter{SLA: models.SLADueSoon, DueWithin: 5 * time.Hour, AsOf: now}, []string{"due", "unanswered"}},
+		{"Priorities", models.AppealFilter{Priorities: []models.AppealPriority{models.PriorityUrgent, models.PriorityLow}}, []string{"resolved", "unanswered"}},
+	}
+	for _, tt := range tests {
+		page, err := repo.List(tt.filter)
+		if err != nil {
+			t.Fatalf("%s: failed to list appeals: %v", tt.name, err)
+		}
+		var got []string
+		for _, appeal := range page.Appeals {
+			got = append(got, names[appeal.ID])
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+
+	invalid := []models.AppealFilter{
+		{SLA: "overdue"},
+		{SLA: models.SLADueSoon},
+		{Priorities: []models.AppealPriority{"critical"}},
+	}
+	for i, filter := range invalid {
+		if _, err := repo.List(filter); !errors.Is(err, models.ErrValidation) {
+			t.Errorf("Filter %d: expected validation error, got %v", i, err)
+		}
+	}
+}
+
+func testReminders(t *testing.T, repo Store) {
+	appeal, err := repo.Save(&models.Appeal{Theme: "Theme", Message: "Message", Status: models.StatusInProgress})
+	if err != nil {
+		t.Fatalf("Failed to save appeal: %v", err)
+	}
+
+	due := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	reminders := []struct {
+		reminder models.Reminder
+		added    bool
+	}{
+		{models.Reminder{Kind: models.ReminderResponseDue, Recipient: "anna", DueAt: due}, true},
+		{models.Reminder{Kind: models.ReminderResolutionDue, Recipient: "anna", DueAt: due}, true},
+		// Повторное напоминание о том же сроке не создаётся.
+		{models.Reminder{Kind: models.ReminderResponseDue, Recipient: "anna", DueAt: due.In(time.FixedZone("MSK", 3*60*60))}, false},
+		{models.Reminder{Kind: models.ReminderResponseDue, DueAt: due.Add(time.Hour)}, true},
+	}
+	for i, tt := range reminders {
+		tt.reminder.AppealID = appeal.ID
+		tt.reminder.Message = "Reminder"
+		added, err := repo.AddReminder(&tt.reminder)
+		if err != nil {
+			t.Fatalf("Reminder %d: failed to add: %v", i, err)
+		}
+		if added != tt.added {
+			t.Errorf("Reminder %d: expected added=%v, got %v", i, tt.added, added)
+		}
+		if added && tt.reminder.ID == 0 {
+			t.Errorf("Reminder %d: expected an ID", i)
+		}
+	}
+
+	anna, err := repo.ListReminders(models.ReminderFilter{Recipient: "anna"})
+	if err != nil {
+		t.Fatalf("Failed to list reminders: %v", err)
+	}
+	if len(anna) != 2 || anna[0].Kind != models.ReminderResolutionDue || !anna[1].DueAt.Equal(due) {
+		t.Errorf("Expected anna's two reminders newest first, got %+v", anna)
+	}
+
+	all, err := repo.ListReminders(models.ReminderFilter{AppealID: appeal.ID, Limit: 2})
+	if err != nil {
+		t.Fatalf("Failed to list reminders: %v", err)
+	}
+	if len(all) != 2 || all[0].Recipient != "" {
+		t.Errorf("Expected the latest two reminders, got %+v", all)
+	}
+
+	if _, err := repo.ListReminders(models.ReminderFilter{Limit: -1}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected validation error for a negative limit, got %v", err)
+	}
+}
+
+func testJobRuns(t *testing.T, repo Store) {
+	started := time.Date(2025, 12, 1, 3, 0, 0, 0, time.UTC)
+	var runs []*models.JobRun
+	for i, job := range []string{"cancel_stale", "remind_due_soon", "cancel_stale"} {
+		run := &models.JobRun{Job: job, TriggeredBy: models.TriggerSchedule, Status: models.JobRunning, StartedAt: started.Add(time.Duration(i) * time.Minute)}
+		if err := repo.StartJobRun(run); err != nil {
+			t.Fatalf("Failed to start job run: %v", err)
+		}
+		runs = append(runs, run)
+	}
+
+	finished := started.Add(5 * time.Minute)
+	runs[0].Status, runs[0].Result, runs[0].FinishedAt = models.JobSucceeded, "cancelled 3 appeals", &finished
+	if err := repo.FinishJobRun(runs[0]); err != nil {
+		t.Fatalf("Failed to finish job run: %v", err)
+	}
+	if err := repo.FinishJobRun(&models.JobRun{ID: 999, Status: models.JobFailed}); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected not found error for an unknown run, got %v", err)
+	}
+
+	history, err := repo.ListJobRuns("cancel_stale", 10)
+	if err != nil {
+		t.Fatalf("Failed to list job runs: %v", err)
+	}
+	if len(history) != 2 || history[0].ID != runs[2].ID {
+		t.Fatalf("Expected two cancel_stale runs newest first, got %+v", history)
+	}
+	last := history[1]
+	if last.Status != models.JobSucceeded || last.Result != "cancelled 3 appeals" || last.FinishedAt == nil || !last.FinishedAt.Equal(finished) {
+		t.Errorf("Expected the finished run to round-trip, got %+v", last)
+	}
+	if history[0].FinishedAt != nil || history[0].Status != models.JobRunning {
+		t.Errorf("Expected the running run to have no finish time, got %+v", history[0])
+	}
+
+	limited, err := repo.ListJobRuns("cancel_stale", 1)
+	if err != nil {
+		t.Fatalf("Failed to list job runs: %v", err)
+	}
+	if len(limited) != 1 {
+		t.Errorf("Expected the limit to apply, got %d runs", len(limited))
+	}
+}
+
+func testOutbox(t *testing.T, repo Store) {
+	if last, err := repo.LastEventSequence(); err != nil || last != 0 {
+		t.Fatalf("Expected an empty outbox, got %d, %v", last, err)
+	}
+
+	occurred := time.Date(2025, 12, 1, 9, 0, 0, 0, time.UTC)
+	first := &models.Event{ID: "e1", Type: "appeal.created", AppealID: "a1", Actor: "jane", Appeal: &models.Appeal{ID: "a1", Theme: "Printer"}, OccurredAt: occurred}
+	if err := repo.AddEvent(first); err != nil {
+		t.Fatalf("Failed to add event: %v", err)
+	}
+	err := repo.WithTx(func(tx AppealStore) error {
+		return tx.AddEvent(&models.Event{ID: "e2", Type: "appeal.started", AppealID: "a1", FromStatus: models.StatusNew, OccurredAt: occurred})
+	})
+	if err != nil {
+		t.Fatalf("Failed to add event in a transaction: %v", err)
+	}
+	// Откаченная транзакция не оставляет событий.
+	rollback := errors.New("rollback")
+	err = repo.WithTx(func(tx AppealStore) error {
+		if err := tx.AddEvent(&models.Event{ID: "discarded", Type: "appeal.completed", AppealID: "a1"}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Expected the rollback error, got %v", err)
+	}
+	if err := repo.AddEvent(&models.Event{ID: "e3", Type: "appeal.assigned", AppealID: "a2"}); err != nil {
+		t.Fatalf("Failed to add event: %v", err)
+	}
+
+	events, err := repo.ListEvents(0, 10)
+	if err != nil {
+		t.Fatalf("Failed to list events: %v", err)
+	}
+	if len(events) != 3 || events[0].ID != "e1" || events[1].ID != "e2" || events[2].ID != "e3" {
+		t.Fatalf("Expected the committed events in order, got %+v", events)
+	}
+	if events[0].Sequence != first.Sequence || events[0].Sequence >= events[1].Sequence || events[1].Sequence >= events[2].Sequence {
+		t.Errorf("Expected increasing sequences, got %d %d %d", events[0].Sequence, events[1].Sequence, events[2].Sequence)
+	}
+	if events[0].Actor != "jane" || events[0].Appeal == nil || events[0].Appeal.Theme != "Printer" || !events[0].OccurredAt.Equal(occurred) || events[1].FromStatus != models.StatusNew {
+		t.Errorf("Expected events to round-trip, got %+v", events[:2])
+	}
+
+	page, err := repo.ListEvents(events[0].Sequence, 1)
+	if err != nil || len(page) != 1 || page[0].ID != "e2" {
+		t.Errorf("Expected the page after the first event, got %+v, %v", page, err)
+	}
+	if last, err := repo.LastEventSequence(); err != nil || last != events[2].Sequence {
+		t.Errorf("Expected the last sequence %d, got %d, %v", events[2].Sequence, last, err)
+	}
+
+	if _, ok, err := repo.EventOffset("webhooks"); err != nil || ok {
+		t.Errorf("Expected no offset for a new sink, got %v, %v", ok, err)
+	}
+	for _, seq := range []int64{events[0].Sequence, events[1].Sequence} {
+		if err := repo.SetEventOffset("webhooks", seq); err != nil {
+			t.Fatalf("Failed to set offset: %v", err)
+		}
+	}
+	if seq, ok, err := repo.EventOffset("webhooks"); err != nil || !ok || seq != events[1].Sequence {
+		t.Errorf("Expected the latest offset, got %d, %v, %v", seq, ok, err)
+	}
+	if _, ok, _ := repo.EventOffset("log"); ok {
+		t.Errorf("Expected offsets to be kept per sink")
+	}
+}
+
+func testAPIKeys(t *testing.T, repo Store) {
+	created := time.Date(2025, 12, 1, 9, 0, 0, 0, time.UTC)
+	expires := created.Add(90 * 24 * time.Hour)
+	key := &models.APIKey{Name: "ci", Subject: "ci-bot", Roles: []models.Role{models.RoleOperator, models.RoleSupervisor}, Prefix: "apk_0123abcd", Hash: "hash-1", CreatedAt: created, ExpiresAt: &expires}
+	if err := repo.CreateAPIKey(key); err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+	if err := repo.CreateAPIKey(&models.APIKey{Name: "crm", Subject: "crm", Prefix: "apk_4567ef01", Hash: "hash-2", CreatedAt: created.Add(time.Minute)}); err != nil {
+		t.Fatalf("Failed to create API key: %v", err)
+	}
+
+	found, err := repo.FindAPIKeyByHash("hash-1")
+	if err != nil {
+		t.Fatalf("Failed to find API key: %v", err)
+	}
+	if found.ID != key.ID || found.Name != "ci" || found.Subject != "ci-bot" || found.Prefix != "apk_0123abcd" ||
+		len(found.Roles) != 2 || found.Roles[1] != models.RoleSupervisor || found.ExpiresAt == nil || !found.ExpiresAt.Equal(expires) || found.RevokedAt != nil {
+		t.Errorf("Expected the key to round-trip, got %+v", found)
+	}
+	if _, err := repo.FindAPIKeyByHash("missing"); models.ErrorCode(err) != "api_key_not_found" {
+		t.Errorf("Expected api_key_not_found, got %v", err)
+	}
+
+	used := created.Add(time.Hour)
+	if err := repo.TouchAPIKey(key.ID, used); err != nil {
+		t.Fatalf("Failed to touch API key: %v", err)
+	}
+	if err := repo.RevokeAPIKey(key.ID, used.Add(time.Hour)); err != nil {
+		t.Fatalf("Failed to revoke API key: %v", err)
+	}
+	if err := repo.RevokeAPIKey("missing", used); models.ErrorCode(err) != "api_key_not_found" {
+		t.Errorf("Expected api_key_not_found, got %v", err)
+	}
+
+	found, err = repo.FindAPIKey(key.ID)
+	if err != nil {
+		t.Fatalf("Failed to find API key: %v", err)
+	}
+	if found.LastUsedAt == nil || !found.LastUsedAt.Equal(used) || found.RevokedAt == nil || !found.RevokedAt.Equal(used.Add(time.Hour)) {
+		t.Errorf("Expected the use and revocation to be stored, got %+v", found)
+	}
+
+	keys, err := repo.ListAPIKeys()
+	if err != nil {
+		t.Fatalf("Failed to list API keys: %v", err)
+	}
+	if len(keys) != 2 || keys[0].ID != key.ID || keys[1].Name != "crm" || len(keys[1].Roles) != 0 {
+		t.Errorf("Expected both keys oldest first, got %+v", keys)
+	}
+}
+
+func testWebhooks(t *testing.T, repo Store) {
+	created := time.Date(2025, 12, 1, 9, 0, 0, 0, time.UTC)
+	sub := &models.WebhookSubscription{URL: "https://crm.example.com/hooks", Secret: "s3cret", Events: []string{"appeal.created", "appeal.completed"}, Active: true, CreatedAt: created}
+	if err := repo.CreateWebhook(sub); err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	other := &models.WebhookSubscription{URL: "https://dashboard.example.com", Secret: "other", Active: true, CreatedAt: created.Add(time.Minute)}
+	if err := repo.CreateWebhook(other); err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	found, err := repo.FindWebhook(sub.ID)
+	if err != nil {
+		t.Fatalf("Failed to find webhook: %v", err)
+	}
+	if found.URL != sub.URL || found.Secret != "s3cret" || len(found.Events) != 2 || found.Events[1] != "appeal.completed" || !found.Active || !found.CreatedAt.Equal(created) {
+		t.Errorf("Expected the webhook to round-trip, got %+v", found)
+	}
+	if _, err := repo.FindWebhook("missing"); models.ErrorCode(err) != "webhook_not_found" {
+		t.Errorf("Expected webhook_not_found, got %v", err)
+	}
+
+	subs, err := repo.ListWebhooks()
+	if err != nil {
+		t.Fatalf("Failed to list webhooks: %v", err)
+	}
+	if len(subs) != 2 || subs[0].ID != sub.ID || subs[1].Events != nil {
+		t.Errorf("Expected both webhooks oldest first, got %+v", subs)
+	}
+
+	payload := []byte(`{"id":"e1","type":"appeal.created"}`)
+	var deliveries []*models.WebhookDelivery
+	for _, target := range []*models.WebhookSubscription{sub, sub, other} {
+		delivery := &models.WebhookDelivery{SubscriptionID: target.ID, EventID: "e1", EventType: "appeal.created", Payload: payload, Status: models.DeliveryPending, CreatedAt: created}
+		if err := repo.AddDelivery(delivery); err != nil {
+			t.Fatalf("Failed to add delivery: %v", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	finished := created.Add(time.Second)
+	first := deliveries[0]
+	first.Status, first.Attempts, first.ResponseStatus, first.FinishedAt = models.DeliverySucceeded, 2, 204, &finished
+	if err := repo.UpdateDelivery(first); err != nil {
+		t.Fatalf("Failed to update delivery: %v", err)
+	}
+	retry := created.Add(time.Minute)
+	deliveries[1].Attempts, deliveries[1].Error, deliveries[1].NextAttemptAt = 1, "connection refused", &retry
+	if err := repo.UpdateDelivery(deliveries[1]); err != nil {
+		t.Fatalf("Failed to update delivery: %v", err)
+	}
+	if err := repo.UpdateDelivery(&models.WebhookDelivery{ID: 999}); models.ErrorCode(err) != "delivery_not_found" {
+		t.Errorf("Expected delivery_not_found, got %v", err)
+	}
+
+	stored, err := repo.FindDelivery(first.ID)
+	if err != nil {
+		t.Fatalf("Failed to find delivery: %v", err)
+	}
+	if string(stored.Payload) != string(payload) || stored.Status != models.DeliverySucceeded || stored.Attempts != 2 || stored.ResponseStatus != 204 || !stored.FinishedAt.Equal(finished) || stored.NextAttemptAt != nil {
+		t.Errorf("Expected the delivery to round-trip, got %+v", stored)
+	}
+
+	log, err := repo.ListDeliveries(sub.ID, 10)
+	if err != nil {
+		t.Fatalf("Failed to list deliveries: %v", err)
+	}
+	if len(log) != 2 || log[0].ID != deliveries[1].ID || log[0].Error != "connection refused" || !log[0].NextAttemptAt.Equal(retry) {
+		t.Errorf("Expected the subscription's deliveries newest first, got %+v", log)
+	}
+
+	pending, err := repo.PendingDeliveries()
+	if err != nil {
+		t.Fatalf("Failed to list pending deliveries: %v", err)
+	}
+	if len(pending) != 2 || pending[0].ID != deliveries[1].ID || pending[1].ID != deliveries[2].ID {
+		t.Errorf("Expected the two pending deliveries oldest first, got %+v", pending)
+	}
+
+	// Удаление подписки уносит с собой её журнал доставок.
+	if err := repo.DeleteWebhook(sub.ID); err != nil {
+		t.Fatalf("Failed to delete webhook: %v", err)
+	}
+	if err := repo.DeleteWebhook(sub.ID); models.ErrorCode(err) != "webhook_not_found" {
+		t.Errorf("Expected webhook_not_found, got %v", err)
+	}
+	if _, err := repo.FindDelivery(first.ID); models.ErrorCode(err) != "delivery_not_found" {
+		t.Errorf("Expected the deliveries to be deleted, got %v", err)
+	}
+	if pending, _ := repo.PendingDeliveries(); len(pending) != 1 {
+		t.Errorf("Expected only the other subscription's delivery to remain, got %d", len(pending))
+	}
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"go_appeals/internal/models"
+)
+
+// cursor is the keyset position after the last appeal of a page. It is
+// handed to clients base64-encoded and treated as opaque by them.
+type cursor struct {
+	SortBy     models.AppealSortField `json:"s"`
+	Descending bool                   `json:"d,omitempty"`
+	Value      time.Time              `json:"v"`
+	ID         string                 `json:"i"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns nil for the first page. The cursor must have been
+// issued for the same sort as filter.
+func decodeCursor(filter models.AppealFilter) (*cursor, error) {
+	if filter.Cursor == "" {
+		return nil, nil
+	}
+
+	invalid := models.NewError(models.ErrValidation, "invalid_cursor", "invalid cursor")
+
+	data, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, invalid
+	}
+	if c.SortBy != filter.SortBy || c.Descending != filter.Descending {
+		return nil, invalid
+	}
+
+	c.Value = c.Value.UTC()
+	return &c, nil
+}
+
+// newPage trims the extra row fetched to detect whether another page exists.
+func newPage(appeals []*models.Appeal, filter models.AppealFilter) *models.AppealPage {
+	page := &models.AppealPage{Appeals: appeals}
+	if len(appeals) <= filter.Limit {
+		return page
+	}
+
+	page.Appeals = appeals[:filter.Limit]
+	last := page.Appeals[len(page.Appeals)-1]
+	page.NextCursor = encodeCursor(cursor{
+		SortBy:     filter.SortBy,
+		Descending: filter.Descending,
+		Value:      last.SortValue(filter.SortBy).UTC(),
+		ID:         last.ID,
+	})
+	return page
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go_appeals/internal/models"
+)
+
+// postgresOutboxLockKey serialises outbox writers on PostgreSQL. Sequence
+// values are handed out before commit, so without it a transaction could
+// commit an event below one a reader has already passed.
+const postgresOutboxLockKey = 727362
+
+func (r *AppealRepository) AddEvent(event *models.Event) error {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	event.TenantID = r.tenantOf(event.TenantID)
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	return r.atomically(func(tx *AppealRepository) error {
+		if tx.dialect == dialectPostgres {
+			if _, err := tx.conn().Exec("SELECT pg_advisory_xact_lock($1)", postgresOutboxLockKey); err != nil {
+				return fmt.Errorf("failed to lock the outbox: %w", err)
+			}
+		}
+
+		err := tx.conn().QueryRow(r.rebind(
+			"INSERT INTO appeal_events (event_id, tenant_id, type, appeal_id, payload, created_at) VALUES (?, ?, ?, ?, ?, ?) RETURNING id"),
+			event.ID,
+			event.TenantID,
+			event.Type,
+			event.AppealID,
+			string(payload),
+			event.OccurredAt.UTC(),
+		).Scan(&event.Sequence)
+		if err != nil {
+			return fmt.Errorf("failed to insert event: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *AppealRepository) ListEvents(after int64, limit int) ([]*models.Event, error) {
+	where, args := r.scope("tenant_id", []string{"id > ?"}, []any{after})
+	rows, err := r.conn().Query(r.rebind(
+		"SELECT id, payload FROM appeal_events"+whereClause(where)+" ORDER BY id LIMIT ?"),
+		append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*models.Event, 0)
+	for rows.Next() {
+		var seq int64
+		var payload []byte
+		if err := rows.Scan(&seq, &payload); err != nil {
+			return nil, fmt.Errorf("failed to scan event row: %w", err)
+		}
+		event := &models.Event{}
+		if err := json.Unmarshal(payload, event); err != nil {
+			return nil, fmt.Errorf("failed to decode event %d: %w", seq, err)
+		}
+		event.Sequence = seq
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+func (r *AppealRepository) LastEventSequence() (int64, error) {
+	var seq int64
+	where, args := r.scope("tenant_id", nil, nil)
+	if err := r.conn().QueryRow(r.rebind("SELECT COALESCE(MAX(id), 0) FROM appeal_events"+whereClause(where)), args...).Scan(&seq); err != nil {
+		return 0, fmt.Errorf("failed to query the last event: %w", err)
+	}
+	return seq, nil
+}
+
+func (r *AppealRepository) EventOffset(sink string) (int64, bool, error) {
+	var seq int64
+	err := r.conn().QueryRow(r.rebind("SELECT last_event FROM outbox_offsets WHERE sink = ?"), sink).Scan(&seq)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to query outbox offset: %w", err)
+	}
+	return seq, true, nil
+}
+
+func (r *AppealRepository) SetEventOffset(sink string, seq int64) error {
+	_, err := r.conn().Exec(r.rebind(
+		"INSERT INTO outbox_offsets (sink, last_event) VALUES (?, ?) ON CONFLICT (sink) DO UPDATE SET last_event = excluded.last_event"),
+		sink, seq)
+	if err != nil {
+		return fmt.Errorf("failed to store outbox offset: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go_appeals/internal/models"
+)
+
+func (r *AppealRepository) StartJobRun(run *models.JobRun) error {
+	if run.StartedAt.IsZero() {
+		run.StartedAt = time.Now()
+	}
+
+	err := r.conn().QueryRow(r.rebind(
+		"INSERT INTO job_runs (job, triggered_by, status, result, error, started_at) VALUES (?, ?, ?, ?, ?, ?) RETURNING id"),
+		run.Job,
+		run.TriggeredBy,
+		run.Status,
+		run.Result,
+		run.Error,
+		run.StartedAt.UTC(),
+	).Scan(&run.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert job run: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AppealRepository) FinishJobRun(run *models.JobRun) error {
+	result, err := r.conn().Exec(r.rebind(
+		"UPDATE job_runs SET status = ?, result = ?, error = ?, finished_at = ? WHERE id = ?"),
+		run.Status,
+		run.Result,
+		run.Error,
+		nullTime(run.FinishedAt),
+		run.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update job run: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return models.NewError(models.ErrNotFound, "job_run_not_found", "job run %d not found", run.ID)
+	}
+	return nil
+}
+
+func (r *AppealRepository) ListJobRuns(job string, limit int) ([]*models.JobRun, error) {
+	rows, err := r.conn().Query(r.rebind(
+		"SELECT id, job, triggered_by, status, result, error, started_at, finished_at FROM job_runs WHERE job = ? ORDER BY id DESC LIMIT ?"),
+		job, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := make([]*models.JobRun, 0)
+	for rows.Next() {
+		run := &models.JobRun{}
+		err := rows.Scan(
+			&run.ID,
+			&run.Job,
+			&run.TriggeredBy,
+			&run.Status,
+			&run.Result,
+			&run.Error,
+			&run.StartedAt,
+			&run.FinishedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job run row: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
+
+func (r *AppealRepository) AddReminder(reminder *models.Reminder) (bool, error) {
+	if reminder.CreatedAt.IsZero() {
+		reminder.CreatedAt = time.Now()
+	}
+
+	err := r.conn().QueryRow(r.rebind(
+		"INSERT INTO appeal_reminders (tenant_id, appeal_id, kind, recipient, message, due_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?) "+
+			"ON CONFLICT (appeal_id, kind, due_at) DO NOTHING RETURNING id"),
+		r.tenantOf(reminder.TenantID),
+		reminder.AppealID,
+		reminder.Kind,
+		reminder.Recipient,
+		reminder.Message,
+		reminder.DueAt.UTC(),
+		reminder.CreatedAt.UTC(),
+	).Scan(&reminder.ID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to insert reminder: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *AppealRepository) ListReminders(filter models.ReminderFilter) ([]*models.Reminder, error) {
+	if err := filter.Normalize(); err != nil {
+		return nil, err
+	}
+
+	where, args := r.scope("tenant_id", nil, nil)
+	if filter.Recipient != "" {
+		where = append(where, "recipient = ?")
+		args = append(args, filter.Recipient)
+	}
+	if filter.AppealID != "" {
+		where = append(where, "appeal_id = ?")
+		args = append(args, filter.AppealID)
+	}
+
+	query := "SELECT id, tenant_id, appeal_id, kind, recipient, message, due_at, created_at FROM appeal_reminders" +
+		whereClause(where) + " ORDER BY id DESC LIMIT ?"
+	args = append(args, filter.Limit)
+
+	rows, err := r.conn().Query(r.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reminders: %w", err)
+	}
+	defer rows.Close()
+
+	reminders := make([]*models.Reminder, 0)
+	for rows.Next() {
+		reminder := &models.Reminder{}
+		err := rows.Scan(
+			&reminder.ID,
+			&reminder.TenantID,
+			&reminder.AppealID,
+			&reminder.Kind,
+			&reminder.Recipient,
+			&reminder.Message,
+			&reminder.DueAt,
+			&reminder.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reminder row: %w", err)
+		}
+		reminders = append(reminders, reminder)
+	}
+
+	return reminders, rows.Err()
+}
@@ -0,0 +1,936 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go_appeals/internal/models"
+	"go_appeals/internal/search"
+
+	"github.com/google/uuid"
+)
+
+// MemoryAppealRepository keeps appeals in process memory. It is safe for
+// concurrent use and is meant for tests and throwaway deployments.
+type MemoryAppealRepository struct {
+	mu    *sync.RWMutex
+	state *memoryState
+	// inTx is set on the view handed to WithTx callbacks, which already
+	// hold the write lock.
+	inTx bool
+	// tenant is set on the views returned by ForTenant.
+	tenant string
+}
+
+type memoryState struct {
+	appeals        map[string]*models.Appeal
+	order          []string
+	history        []*models.StatusHistoryEntry
+	nextHistoryID  int64
+	reminders      []*models.Reminder
+	nextReminderID int64
+	jobRuns        []*models.JobRun
+	webhooks       []*models.WebhookSubscription
+	deliveries     []*models.WebhookDelivery
+	events         []*models.Event
+	offsets        map[string]int64
+	apiKeys        []*models.APIKey
+	comments       []*models.Comment
+	commentEdits   []*models.CommentEdit
+	attachments    []*models.Attachment
+	requesters     []*models.Requester
+	sequences      map[string]int64
+}
+
+func NewMemoryAppealRepository() *MemoryAppealRepository {
+	return &MemoryAppealRepository{
+		mu: &sync.RWMutex{},
+		state: &memoryState{
+			appeals:   make(map[string]*models.Appeal),
+			offsets:   make(map[string]int64),
+			sequences: make(map[string]int64),
+		},
+	}
+}
+
+func (s *memoryState) clone() *memoryState {
+	c := &memoryState{
+		appeals:        make(map[string]*models.Appeal, len(s.appeals)),
+		order:          append([]string(nil), s.order...),
+		history:        append([]*models.StatusHistoryEntry(nil), s.history...),
+		nextHistoryID:  s.nextHistoryID,
+		reminders:      append([]*models.Reminder(nil), s.reminders...),
+		nextReminderID: s.nextReminderID,
+		jobRuns:        append([]*models.JobRun(nil), s.jobRuns...),
+		webhooks:       append([]*models.WebhookSubscription(nil), s.webhooks...),
+		deliveries:     append([]*models.WebhookDelivery(nil), s.deliveries...),
+		events:         append([]*models.Event(nil), s.events...),
+		apiKeys:        append([]*models.APIKey(nil), s.apiKeys...),
+		comments:       append([]*models.Comment(nil), s.comments...),
+		commentEdits:   append([]*models.CommentEdit(nil), s.commentEdits...),
+		attachments:    append([]*models.Attachment(nil), s.attachments...),
+		requesters:     append([]*models.Requester(nil), s.requesters...),
+		offsets:        make(map[string]int64, len(s.offsets)),
+		sequences:      make(map[string]int64, len(s.sequences)),
+	}
+	for sink, seq := range s.offsets {
+		c.offsets[sink] = seq
+	}
+	for series, last := range s.sequences {
+		c.sequences[series] = last
+	}
+	for id, appeal := range s.appeals {
+		copied := *appeal
+		c.appeals[id] = &copied
+	}
+	return c
+}
+
+func (r *MemoryAppealRepository) lock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.mu.Lock()
+	return r.mu.Unlock
+}
+
+func (r *MemoryAppealRepository) rlock() func() {
+	if r.inTx {
+		return func() {}
+	}
+	r.mu.RLock()
+	return r.mu.RUnlock
+}
+
+// WithTx runs fn against a private copy of the data and swaps it in only if
+// fn succeeds. Other callers are blocked for the duration.
+func (r *MemoryAppealRepository) WithTx(fn func(tx AppealStore) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx := &MemoryAppealRepository{mu: r.mu, state: r.state.clone(), inTx: true, tenant: r.tenant}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	*r.state = *tx.state
+	return nil
+}
+
+// ForTenant returns a view of the repository that reads only the records of
+// tenant and stores new ones under it.
+func (r *MemoryAppealRepository) ForTenant(tenant string) AppealStore {
+	scoped := *r
+	scoped.tenant = tenant
+	return &scoped
+}
+
+// sees reports whether a record of tenant is visible through r.
+func (r *MemoryAppealRepository) sees(tenant string) bool {
+	return r.tenant == "" || r.tenant == tenant
+}
+
+func (r *MemoryAppealRepository) tenantOf(tenant string) string {
+	switch {
+	case r.tenant != "":
+		return r.tenant
+	case tenant != "":
+		return tenant
+	}
+	return models.DefaultTenant
+}
+
+func (r *MemoryAppealRepository) Save(appeal *models.Appeal) (*models.Appeal, error) {
+	now := time.Now()
+	appeal.ID = uuid.New().String()
+	appeal.TenantID = r.tenantOf(appeal.TenantID)
+	if appeal.CreatedAt.IsZero() {
+		appeal.CreatedAt = now
+	}
+	if appeal.UpdatedAt.IsZero() {
+		appeal.UpdatedAt = now
+	}
+
+	defer r.lock()()
+
+	for _, existing := range r.state.appeals {
+		if appeal.Number != "" && existing.Number == appeal.Number && existing.TenantID == appeal.TenantID {
+			return nil, numberTaken(appeal.Number)
+		}
+	}
+	stored := *appeal
+	r.state.appeals[appeal.ID] = &stored
+	r.state.order = append(r.state.order, appeal.ID)
+
+	return appeal, nil
+}
+
+func (r *MemoryAppealRepository) Update(appeal *models.Appeal) (*models.Appeal, error) {
+	defer r.lock()()
+
+	existing, ok := r.state.appeals[appeal.ID]
+	if !ok || !r.sees(existing.TenantID) {
+		return nil, models.AppealNotFound(appeal.ID)
+	}
+
+	appeal.UpdatedAt = time.Now()
+	appeal.CreatedAt = existing.CreatedAt
+	appeal.TenantID = existing.TenantID
+
+	stored := *appeal
+	r.state.appeals[appeal.ID] = &stored
+
+	return appeal, nil
+}
+
+func (r *MemoryAppealRepository) FindByID(id string) (*models.Appeal, error) {
+	defer r.rlock()()
+
+	stored, ok := r.state.appeals[id]
+	if !ok || !r.sees(stored.TenantID) {
+		return nil, models.AppealNotFound(id)
+	}
+
+	appeal := *stored
+	return &appeal, nil
+}
+
+func (r *MemoryAppealRepository) FindByStatus(statuses ...models.AppealStatus) ([]*models.Appeal, error) {
+	return r.filter(func(a *models.Appeal) bool {
+		return containsStatus(statuses, a.Status)
+	}), nil
+}
+
+func (r *MemoryAppealRepository) CancelInProgressAppeals(statuses []models.AppealStatus, cancel func(appeal *models.Appeal) error) ([]*models.Appeal, error) {
+	return cancelInProgress(r, statuses, cancel)
+}
+
+func (r *MemoryAppealRepository) GetAll() ([]*models.Appeal, error) {
+	return r.filter(func(*models.Appeal) bool { return true }), nil
+}
+
+func (r *MemoryAppealRepository) SelectAppealsByDates(start, end time.Time) ([]*models.Appeal, error) {
+	return r.filter(func(a *models.Appeal) bool {
+		return !a.CreatedAt.Before(start) && !a.CreatedAt.After(end)
+	}), nil
+}
+
+func (r *MemoryAppealRepository) List(filter models.AppealFilter) (*models.AppealPage, error) {
+	if err := filter.Normalize(); err != nil {
+		return nil, err
+	}
+	after, err := decodeCursor(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// less orders (value, id) positions the same way the SQL backends do.
+	less := func(v1 time.Time, id1 string, v2 time.Time, id2 string) bool {
+		if filter.Descending {
+			return v1.After(v2) || (v1.Equal(v2) && id1 > id2)
+		}
+		return v1.Before(v2) || (v1.Equal(v2) && id1 < id2)
+	}
+
+	appeals := r.filter(func(a *models.Appeal) bool {
+		switch {
+		case len(filter.Statuses) > 0 && !containsStatus(filter.Statuses, a.Status),
+			len(filter.Priorities) > 0 && !containsPriority(filter.Priorities, a.Priority),
+			filter.Theme != "" && a.Theme != filter.Theme,
+			filter.Assignee != "" && a.Assignee != filter.Assignee,
+			filter.CreatedBy != "" && a.CreatedBy != filter.CreatedBy,
+			filter.RequesterID != "" && a.RequesterID != filter.RequesterID,
+			!filter.CreatedFrom.IsZero() && a.CreatedAt.Before(filter.CreatedFrom),
+			!filter.CreatedTo.IsZero() && a.CreatedAt.After(filter.CreatedTo),
+			!filter.UpdatedFrom.IsZero() && a.UpdatedAt.Before(filter.UpdatedFrom),
+			!filter.UpdatedTo.IsZero() && a.UpdatedAt.After(filter.UpdatedTo),
+			filter.SLA == models.SLADueSoon && !a.DueWithin(filter.AsOf, filter.DueWithin),
+			filter.SLA == models.SLABreached && !a.SLABreached(filter.AsOf):
+			return false
+		}
+		return after == nil || less(after.Value, after.ID, a.SortValue(filter.SortBy), a.ID)
+	})
+
+	sort.Slice(appeals, func(i, j int) bool {
+		return less(appeals[i].SortValue(filter.SortBy), appeals[i].ID, appeals[j].SortValue(filter.SortBy), appeals[j].ID)
+	})
+	if len(appeals) > filter.Limit+1 {
+		appeals = appeals[:filter.Limit+1]
+	}
+
+	return newPage(appeals, filter), nil
+}
+
+func (r *MemoryAppealRepository) Search(q search.Query, limit, offset int) ([]*models.SearchResult, error) {
+	results := make([]*models.SearchResult, 0)
+	for _, appeal := range r.filter(func(*models.Appeal) bool { return true }) {
+		if rank, ok := search.Score(search.NewDocument(appeal), q); ok {
+			results = append(results, &models.SearchResult{Appeal: appeal, Rank: rank})
+		}
+	}
+	return pageSearchResults(results, limit, offset), nil
+}
+
+func (r *MemoryAppealRepository) AddHistory(entry *models.StatusHistoryEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	entry.TenantID = r.tenantOf(entry.TenantID)
+
+	defer r.lock()()
+
+	r.state.nextHistoryID++
+	entry.ID = r.state.nextHistoryID
+
+	stored := *entry
+	r.state.history = append(r.state.history, &stored)
+
+	return nil
+}
+
+func (r *MemoryAppealRepository) GetHistory(appealID string) ([]*models.StatusHistoryEntry, error) {
+	defer r.rlock()()
+
+	history := make([]*models.StatusHistoryEntry, 0)
+	for _, stored := range r.state.history {
+		if stored.AppealID == appealID && r.sees(stored.TenantID) {
+			entry := *stored
+			history = append(history, &entry)
+		}
+	}
+
+	return history, nil
+}
+
+func (r *MemoryAppealRepository) AddReminder(reminder *models.Reminder) (bool, error) {
+	if reminder.CreatedAt.IsZero() {
+		reminder.CreatedAt = time.Now()
+	}
+	reminder.TenantID = r.tenantOf(reminder.TenantID)
+
+	defer r.lock()()
+
+	for _, stored := range r.state.reminders {
+		if stored.AppealID == reminder.AppealID && stored.Kind == reminder.Kind && stored.DueAt.Equal(reminder.DueAt) {
+			return false, nil
+		}
+	}
+
+	r.state.nextReminderID++
+	reminder.ID = r.state.nextReminderID
+
+	stored := *reminder
+	r.state.reminders = append(r.state.reminders, &stored)
+
+	return true, nil
+}
+
+func (r *MemoryAppealRepository) ListReminders(filter models.ReminderFilter) ([]*models.Reminder, error) {
+	if err := filter.Normalize(); err != nil {
+		return nil, err
+	}
+
+	defer r.rlock()()
+
+	reminders := make([]*models.Reminder, 0)
+	for i := len(r.state.reminders) - 1; i >= 0 && len(reminders) < filter.Limit; i-- {
+		stored := r.state.reminders[i]
+		if !r.sees(stored.TenantID) ||
+			(filter.Recipient != "" && stored.Recipient != filter.Recipient) ||
+			(filter.AppealID != "" && stored.AppealID != filter.AppealID) {
+			continue
+		}
+		reminder := *stored
+		reminders = append(reminders, &reminder)
+	}
+
+	return reminders, nil
+}
+
+func (r *MemoryAppealRepository) StartJobRun(run *models.JobRun) error {
+	if run.StartedAt.IsZero() {
+		run.StartedAt = time.Now()
+	}
+
+	defer r.lock()()
+
+	run.ID = int64(len(r.state.jobRuns)) + 1
+	stored := *run
+	r.state.jobRuns = append(r.state.jobRuns, &stored)
+
+	return nil
+}
+
+func (r *MemoryAppealRepository) FinishJobRun(run *models.JobRun) error {
+	defer r.lock()()
+
+	if run.ID < 1 || run.ID > int64(len(r.state.jobRuns)) {
+		return models.NewError(models.ErrNotFound, "job_run_not_found", "job run %d not found", run.ID)
+	}
+	stored := *run
+	r.state.jobRuns[run.ID-1] = &stored
+
+	return nil
+}
+
+func (r *MemoryAppealRepository) ListJobRuns(job string, limit int) ([]*models.JobRun, error) {
+	defer r.rlock()()
+
+	runs := make([]*models.JobRun, 0)
+	for i := len(r.state.jobRuns) - 1; i >= 0 && len(runs) < limit; i-- {
+		if r.state.jobRuns[i].Job == job {
+			run := *r.state.jobRuns[i]
+			runs = append(runs, &run)
+		}
+	}
+
+	return runs, nil
+}
+
+func (r *MemoryAppealRepository) AddEvent(event *models.Event) error {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	event.TenantID = r.tenantOf(event.TenantID)
+
+	defer r.lock()()
+
+	event.Sequence = int64(len(r.state.events)) + 1
+	stored := *event
+	r.state.events = append(r.state.events, &stored)
+
+	return nil
+}
+
+func (r *MemoryAppealRepository) ListEvents(after int64, limit int) ([]*models.Event, error) {
+	defer r.rlock()()
+
+	events := make([]*models.Event, 0)
+	for i := after; i >= 0 && i < int64(len(r.state.events)) && len(events) < limit; i++ {
+		if !r.sees(r.state.events[i].TenantID) {
+			continue
+		}
+		event := *r.state.events[i]
+		events = append(events, &event)
+	}
+	return events, nil
+}
+
+func (r *MemoryAppealRepository) LastEventSequence() (int64, error) {
+	defer r.rlock()()
+
+	for i := len(r.state.events) - 1; i >= 0; i-- {
+		if r.sees(r.state.events[i].TenantID) {
+			return r.state.events[i].Sequence, nil
+		}
+	}
+	return 0, nil
+}
+
+func (r *MemoryAppealRepository) AddComment(comment *models.Comment) error {
+	if comment.CreatedAt.IsZero() {
+		comment.CreatedAt = time.Now()
+	}
+	comment.TenantID = r.tenantOf(comment.TenantID)
+
+	defer r.lock()()
+
+	comment.ID = int64(len(r.state.comments)) + 1
+	stored := *comment
+	stored.Edits = nil
+	r.state.comments = append(r.state.comments, &stored)
+
+	return nil
+}
+
+func (r *MemoryAppealRepository) FindComment(id int64) (*models.Comment, error) {
+	defer r.rlock()()
+
+	if id < 1 || id > int64(len(r.state.comments)) || !r.sees(r.state.comments[id-1].TenantID) {
+		return nil, models.NewError(models.ErrNotFound, "comment_not_found", "comment %d not found", id)
+	}
+	return r.withEdits(r.state.comments[id-1]), nil
+}
+
+func (r *MemoryAppealRepository) EditComment(comment *models.Comment, edit *models.CommentEdit) error {
+	if edit.EditedAt.IsZero() {
+		edit.EditedAt = time.Now()
+	}
+	edit.CommentID = comment.ID
+
+	defer r.lock()()
+
+	if comment.ID < 1 || comment.ID > int64(len(r.state.comments)) || !r.sees(r.state.comments[comment.ID-1].TenantID) {
+		return models.NewError(models.ErrNotFound, "comment_not_found", "comment %d not found", comment.ID)
+	}
+	updated := *r.state.comments[comment.ID-1]
+	updated.Body = comment.Body
+	updated.EditedAt = comment.EditedAt
+	r.state.comments[comment.ID-1] = &updated
+
+	edit.ID = int64(len(r.state.commentEdits)) + 1
+	stored := *edit
+	r.state.commentEdits = append(r.state.commentEdits, &stored)
+
+	return nil
+}
+
+func (r *MemoryAppealRepository) ListComments(appealID string, internal bool) ([]*models.Comment, error) {
+	defer r.rlock()()
+
+	comments := make([]*models.Comment, 0)
+	for _, stored := range r.state.comments {
+		if stored.AppealID != appealID || !r.sees(stored.TenantID) ||
+			(!internal && stored.Visibility != models.CommentPublic) {
+			continue
+		}
+		comments = append(comments, r.withEdits(stored))
+	}
+
+	return comments, nil
+}
+
+// withEdits copies a stored comment together with its edits. The caller
+// holds the lock.
+func (r *MemoryAppealRepository) withEdits(stored *models.Comment) *models.Comment {
+	comment := *stored
+	for _, edit := range r.state.commentEdits {
+		if edit.CommentID == comment.ID {
+			copied := *edit
+			comment.Edits = append(comment.Edits, &copied)
+		}
+	}
+	return &comment
+}
+
+func (r *MemoryAppealRepository) AddAttachment(attachment *models.Attachment) error {
+	if attachment.CreatedAt.IsZero() {
+		attachment.CreatedAt = time.Now()
+	}
+	attachment.TenantID = r.tenantOf(attachment.TenantID)
+
+	defer r.lock()()
+
+	attachment.ID = uuid.New().String()
+	stored := *attachment
+	r.state.attachments = append(r.state.attachments, &stored)
+
+	return nil
+}
+
+func (r *MemoryAppealRepository) FindAttachment(id string) (*models.Attachment, error) {
+	defer r.rlock()()
+
+	for _, stored := range r.state.attachments {
+		if stored.ID == id && r.sees(stored.TenantID) {
+			attachment := *stored
+			return &attachment, nil
+		}
+	}
+	return nil, models.NewError(models.ErrNotFound, "attachment_not_found", "attachment %s not found", id)
+}
+
+func (r *MemoryAppealRepository) ListAttachments(appealID string) ([]*models.Attachment, error) {
+	defer r.rlock()()
+
+	attachments := make([]*models.Attachment, 0)
+	for _, stored := range r.state.attachments {
+		if stored.AppealID == appealID && r.sees(stored.TenantID) {
+			attachment := *stored
+			attachments = append(attachments, &attachment)
+		}
+	}
+
+	return attachments, nil
+}
+
+func (r *MemoryAppealRepository) ContentInUse(sha256 string) (bool, error) {
+	defer r.rlock()()
+
+	for _, stored := range r.state.attachments {
+		if stored.SHA256 == sha256 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MemoryAppealRepository) AddRequester(requester *models.Requester) error {
+	now := time.Now()
+	if requester.CreatedAt.IsZero() {
+		requester.CreatedAt = now
+	}
+	if requester.UpdatedAt.IsZero() {
+		requester.UpdatedAt = now
+	}
+	requester.TenantID = r.tenantOf(requester.TenantID)
+
+	defer r.lock()()
+
+	if r.contactTaken(requester) {
+		return models.NewError(models.ErrConflict, "requester_exists", "a requester with this email or phone already exists")
+	}
+	requester.ID = uuid.New().String()
+	stored := *requester
+	r.state.requesters = append(r.state.requesters, &stored)
+
+	return nil
+}
+
+func (r *MemoryAppealRepository) UpdateRequester(requester *models.Requester) error {
+	requester.UpdatedAt = time.Now()
+
+	defer r.lock()()
+
+	for i, stored := range r.state.requesters {
+		if stored.ID != requester.ID || !r.sees(stored.TenantID) {
+			continue
+		}
+		requester.TenantID, requester.CreatedAt = stored.TenantID, stored.CreatedAt
+		if r.contactTaken(requester) {
+			return models.NewError(models.ErrConflict, "requester_exists", "a requester with this email or phone already exists")
+		}
+		updated := *requester
+		r.state.requesters[i] = &updated
+		return nil
+	}
+	return models.NewError(models.ErrNotFound, "requester_not_found", "requester %s not found", requester.ID)
+}
+
+// contactTaken reports whether another requester of the same tenant has
+// the email or phone of requester. The caller holds the lock.
+func (r *MemoryAppealRepository) contactTaken(requester *models.Requester) bool {
+	for _, stored := range r.state.requesters {
+		if stored.ID == requester.ID || stored.TenantID != requester.TenantID {
+			continue
+		}
+		if (requester.Email != "" && stored.Email == requester.Email) ||
+			(requester.Phone != "" && stored.Phone == requester.Phone) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryAppealRepository) FindRequester(id string) (*models.Requester, error) {
+	defer r.rlock()()
+
+	for _, stored := range r.state.requesters {
+		if stored.ID == id && r.sees(stored.TenantID) {
+			requester := *stored
+			return &requester, nil
+		}
+	}
+	return nil, models.NewError(models.ErrNotFound, "requester_not_found", "requester %s not found", id)
+}
+
+func (r *MemoryAppealRepository) FindRequesterByContact(email, phone string) (*models.Requester, error) {
+	defer r.rlock()()
+
+	for _, matches := range []func(*models.Requester) bool{
+		func(stored *models.Requester) bool { return email != "" && stored.Email == email },
+		func(stored *models.Requester) bool { return phone != "" && stored.Phone == phone },
+	} {
+		for _, stored := range r.state.requesters {
+			if matches(stored) && r.sees(stored.TenantID) {
+				requester := *stored
+				return &requester, nil
+			}
+		}
+	}
+	return nil, models.NewError(models.ErrNotFound, "requester_not_found", "no requester with these contact details")
+}
+
+func (r *MemoryAppealRepository) FindByTrackingHash(hash string) (*models.Appeal, error) {
+	defer r.rlock()()
+
+	for _, id := range r.state.order {
+		appeal := r.state.appeals[id]
+		if hash != "" && appeal.TrackingTokenHash == hash && r.sees(appeal.TenantID) {
+			copied := *appeal
+			return &copied, nil
+		}
+	}
+	return nil, models.NewError(models.ErrNotFound, "tracking_token_not_found", "no appeal with this tracking token")
+}
+
+func (r *MemoryAppealRepository) FindByNumber(number string) (*models.Appeal, error) {
+	defer r.rlock()()
+
+	for _, id := range r.state.order {
+		appeal := r.state.appeals[id]
+		if number != "" && appeal.Number == number && r.sees(appeal.TenantID) {
+			copied := *appeal
+			return &copied, nil
+		}
+	}
+	return nil, models.AppealNotFound(number)
+}
+
+func (r *MemoryAppealRepository) NextNumber(series string) (int64, error) {
+	key := r.tenantOf("") + "/" + series
+
+	defer r.lock()()
+
+	r.state.sequences[key]++
+	return r.state.sequences[key], nil
+}
+
+func (r *MemoryAppealRepository) EventOffset(sink string) (int64, bool, error) {
+	defer r.rlock()()
+
+	seq, ok := r.state.offsets[sink]
+	return seq, ok, nil
+}
+
+func (r *MemoryAppealRepository) SetEventOffset(sink string, seq int64) error {
+	defer r.lock()()
+
+	r.state.offsets[sink] = seq
+	return nil
+}
+
+func (r *MemoryAppealRepository) CreateWebhook(sub *models.WebhookSubscription) error {
+	sub.ID = uuid.New().String()
+	if sub.CreatedAt.IsZero() {
+		sub.CreatedAt = time.Now()
+	}
+	sub.TenantID = r.tenantOf(sub.TenantID)
+
+	defer r.lock()()
+
+	stored := *sub
+	stored.Events = append([]string(nil), sub.Events...)
+	r.state.webhooks = append(r.state.webhooks, &stored)
+
+	return nil
+}
+
+func (r *MemoryAppealRepository) FindWebhook(id string) (*models.WebhookSubscription, error) {
+	defer r.rlock()()
+
+	for _, stored := range r.state.webhooks {
+		if stored.ID == id {
+			sub := *stored
+			return &sub, nil
+		}
+	}
+	return nil, webhookNotFound(id)
+}
+
+func (r *MemoryAppealRepository) ListWebhooks() ([]*models.WebhookSubscription, error) {
+	defer r.rlock()()
+
+	subs := make([]*models.WebhookSubscription, 0, len(r.state.webhooks))
+	for _, stored := range r.state.webhooks {
+		sub := *stored
+		subs = append(subs, &sub)
+	}
+	return subs, nil
+}
+
+func (r *MemoryAppealRepository) DeleteWebhook(id string) error {
+	defer r.lock()()
+
+	for i, stored := range r.state.webhooks {
+		if stored.ID != id {
+			continue
+		}
+		r.state.webhooks = append(r.state.webhooks[:i:i], r.state.webhooks[i+1:]...)
+		// Delivery IDs are indexes, so deleted deliveries are only blanked out.
+		for j, delivery := range r.state.deliveries {
+			if delivery != nil && delivery.SubscriptionID == id {
+				r.state.deliveries[j] = nil
+			}
+		}
+		return nil
+	}
+	return webhookNotFound(id)
+}
+
+func (r *MemoryAppealRepository) AddDelivery(delivery *models.WebhookDelivery) error {
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+
+	defer r.lock()()
+
+	delivery.ID = int64(len(r.state.deliveries)) + 1
+	stored := *delivery
+	r.state.deliveries = append(r.state.deliveries, &stored)
+
+	return nil
+}
+
+func (r *MemoryAppealRepository) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	defer r.lock()()
+
+	if delivery.ID < 1 || delivery.ID > int64(len(r.state.deliveries)) || r.state.deliveries[delivery.ID-1] == nil {
+		return deliveryNotFound(delivery.ID)
+	}
+	stored := *r.state.deliveries[delivery.ID-1]
+	stored.Status = delivery.Status
+	stored.Attempts = delivery.Attempts
+	stored.ResponseStatus = delivery.ResponseStatus
+	stored.Error = delivery.Error
+	stored.NextAttemptAt = delivery.NextAttemptAt
+	stored.FinishedAt = delivery.FinishedAt
+	r.state.deliveries[delivery.ID-1] = &stored
+
+	return nil
+}
+
+func (r *MemoryAppealRepository) FindDelivery(id int64) (*models.WebhookDelivery, error) {
+	defer r.rlock()()
+
+	if id < 1 || id > int64(len(r.state.deliveries)) || r.state.deliveries[id-1] == nil {
+		return nil, deliveryNotFound(id)
+	}
+	delivery := *r.state.deliveries[id-1]
+	return &delivery, nil
+}
+
+func (r *MemoryAppealRepository) ListDeliveries(subscriptionID string, limit int) ([]*models.WebhookDelivery, error) {
+	defer r.rlock()()
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for i := len(r.state.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if stored := r.state.deliveries[i]; stored != nil && stored.SubscriptionID == subscriptionID {
+			delivery := *stored
+			deliveries = append(deliveries, &delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+func (r *MemoryAppealRepository) PendingDeliveries() ([]*models.WebhookDelivery, error) {
+	defer r.rlock()()
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for _, stored := range r.state.deliveries {
+		if stored != nil && stored.Status == models.DeliveryPending {
+			delivery := *stored
+			deliveries = append(deliveries, &delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+func (r *MemoryAppealRepository) Close() error {
+	return nil
+}
+
+func (r *MemoryAppealRepository) filter(keep func(*models.Appeal) bool) []*models.Appeal {
+	defer r.rlock()()
+
+	appeals := make([]*models.Appeal, 0, len(r.state.order))
+	for _, id := range r.state.order {
+		stored := r.state.appeals[id]
+		if !r.sees(stored.TenantID) || !keep(stored) {
+			continue
+		}
+		appeal := *stored
+		appeals = append(appeals, &appeal)
+	}
+
+	sort.SliceStable(appeals, func(i, j int) bool {
+		return appeals[i].CreatedAt.Before(appeals[j].CreatedAt)
+	})
+
+	return appeals
+}
+
+func containsStatus(statuses []models.AppealStatus, status models.AppealStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPriority(priorities []models.AppealPriority, priority models.AppealPriority) bool {
+	for _, p := range priorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryAppealRepository) CreateAPIKey(key *models.APIKey) error {
+	key.ID = uuid.New().String()
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+
+	defer r.lock()()
+
+	stored := *key
+	stored.Roles = append([]models.Role(nil), key.Roles...)
+	r.state.apiKeys = append(r.state.apiKeys, &stored)
+	return nil
+}
+
+func (r *MemoryAppealRepository) FindAPIKey(id string) (*models.APIKey, error) {
+	defer r.rlock()()
+
+	for _, stored := range r.state.apiKeys {
+		if stored.ID == id {
+			key := *stored
+			return &key, nil
+		}
+	}
+	return nil, apiKeyNotFound(id)
+}
+
+func (r *MemoryAppealRepository) FindAPIKeyByHash(hash string) (*models.APIKey, error) {
+	defer r.rlock()()
+
+	for _, stored := range r.state.apiKeys {
+		if stored.Hash == hash {
+			key := *stored
+			return &key, nil
+		}
+	}
+	return nil, models.NewError(models.ErrNotFound, "api_key_not_found", "API key not found")
+}
+
+func (r *MemoryAppealRepository) ListAPIKeys() ([]*models.APIKey, error) {
+	defer r.rlock()()
+
+	keys := make([]*models.APIKey, 0, len(r.state.apiKeys))
+	for _, stored := range r.state.apiKeys {
+		key := *stored
+		keys = append(keys, &key)
+	}
+	return keys, nil
+}
+
+func (r *MemoryAppealRepository) RevokeAPIKey(id string, at time.Time) error {
+	return r.updateAPIKey(id, func(key *models.APIKey) { key.RevokedAt = &at })
+}
+
+func (r *MemoryAppealRepository) TouchAPIKey(id string, at time.Time) error {
+	return r.updateAPIKey(id, func(key *models.APIKey) { key.LastUsedAt = &at })
+}
+
+func (r *MemoryAppealRepository) updateAPIKey(id string, update func(key *models.APIKey)) error {
+	defer r.lock()()
+
+	for i, stored := range r.state.apiKeys {
+		if stored.ID == id {
+			key := *stored
+			update(&key)
+			r.state.apiKeys[i] = &key
+			return nil
+		}
+	}
+	return apiKeyNotFound(id)
+}
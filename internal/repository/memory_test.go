@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+
+	"go_appeals/internal/models"
+)
+
+func TestMemoryAppealRepositoryConformance(t *testing.T) {
+	t.Parallel()
+
+	runAppealStoreConformance(t, func(t *testing.T) Store {
+		return NewMemoryAppealRepository()
+	})
+}
+
+func TestMemoryAppealRepositoryConcurrentAccess(t *testing.T) {
+	t.Parallel()
+
+	repo := NewMemoryAppealRepository()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			saved, err := repo.Save(&models.Appeal{Theme: "Theme", Message: "Message", Status: models.StatusNew})
+			if err != nil {
+				t.Errorf("Failed to save appeal: %v", err)
+				return
+			}
+			saved.Status = models.StatusInProgress
+			if _, err := repo.Update(saved); err != nil {
+				t.Errorf("Failed to update appeal: %v", err)
+			}
+			if _, err := repo.GetAll(); err != nil {
+				t.Errorf("Failed to get all appeals: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	appeals, err := repo.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get all appeals: %v", err)
+	}
+	if len(appeals) != 50 {
+		t.Errorf("Expected 50 appeals, got %d", len(appeals))
+	}
+}
+
+func TestMemoryAppealRepositoryReturnsCopies(t *testing.T) {
+	t.Parallel()
+
+	repo := NewMemoryAppealRepository()
+	saved, err := repo.Save(&models.Appeal{Theme: "Theme", Message: "Message", Status: models.StatusNew})
+	if err != nil {
+		t.Fatalf("Failed to save appeal: %v", err)
+	}
+
+	saved.Status = models.StatusCompleted
+	found, err := repo.FindByID(saved.ID)
+	if err != nil {
+		t.Fatalf("Failed to find appeal: %v", err)
+	}
+	if found.Status != models.StatusNew {
+		t.Errorf("Expected stored appeal to be unaffected by caller mutation, got %s", found.Status)
+	}
+}
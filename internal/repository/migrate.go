@@ -0,0 +1,479 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// postgresMigrationLockKey is the pg_advisory_lock key shared by every server
+// migrating the same database.
+const postgresMigrationLockKey = 727361
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies the numbered up/down scripts embedded under migrations/
+// and records them in schema_migrations. In DryRun mode it only prints the
+// SQL it would run to Out.
+//
+// On SQLite a lock not refreshed for LockTTL is taken to belong to a process
+// that died holding it and is taken over. The holder refreshes it after
+// every migration, so LockTTL must outlast the slowest one.
+type Migrator struct {
+	db         *sql.DB
+	dialect    dialect
+	migrations []Migration
+	lockOwner  string
+
+	DryRun      bool
+	Out         io.Writer
+	LockTimeout time.Duration
+	LockTTL     time.Duration
+}
+
+// Migratable is implemented by stores that keep a schema.
+type Migratable interface {
+	Migrator() *Migrator
+}
+
+var _ Migratable = (*AppealRepository)(nil)
+
+func (r *AppealRepository) Migrator() *Migrator {
+	return &Migrator{
+		db:          r.db,
+		dialect:     r.dialect,
+		Out:         os.Stdout,
+		LockTimeout: 30 * time.Second,
+		LockTTL:     10 * time.Minute,
+	}
+}
+
+func (m *Migrator) Migrations() ([]Migration, error) {
+	if m.migrations != nil {
+		return m.migrations, nil
+	}
+
+	dir := "migrations/sqlite"
+	if m.dialect == dialectPostgres {
+		dir = "migrations/postgres"
+	}
+
+	migrations, err := loadMigrations(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+	m.migrations = migrations
+	return migrations, nil
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		prefix, _, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s must be named NNNN_name.%s.sql", name, direction)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s has invalid version: %w", name, err)
+		}
+
+		body, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: base}
+			byVersion[version] = migration
+		} else if migration.Name != base {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", migration.Name, base, version)
+		}
+		if direction == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %s needs both up and down scripts", migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in order and returns the ones applied
+// (or, in dry-run mode, the ones that would be).
+func (m *Migrator) Up() ([]Migration, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	err = m.withLock(func(conn *sql.Conn) error {
+		done, err := m.appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if err := m.run(conn, migration, migration.Up, true); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+
+		if m.DryRun {
+			return nil
+		}
+		return m.syncSearchIndex(conn)
+	})
+
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	err = m.withLock(func(conn *sql.Conn) error {
+		done, err := m.appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			if err := m.run(conn, migration, migration.Down, false); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	migrations, err := m.Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := m.db.Conn(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	done, err := m.appliedVersions(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		appliedAt, ok := done[migration.Version]
+		statuses = append(statuses, MigrationStatus{
+			Migration: migration,
+			Applied:   ok,
+			AppliedAt: appliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations Up would apply.
+func (m *Migrator) Pending() ([]Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, status.Migration)
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) run(conn *sql.Conn, migration Migration, script string, up bool) error {
+	direction := "down"
+	if up {
+		direction = "up"
+	}
+
+	if m.DryRun {
+		fmt.Fprintf(m.Out, "-- %s (%s)\n%s\n", migration.Name, direction, strings.TrimSpace(script))
+		return nil
+	}
+
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %s: %w", migration.Name, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("failed to run migration %s %s: %w", migration.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, m.dialect.rebind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+			migration.Version, migration.Name, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, m.dialect.rebind("DELETE FROM schema_migrations WHERE version = ?"), migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", migration.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", migration.Name, err)
+	}
+
+	log.Printf("Migration %s %s applied.", migration.Name, direction)
+	m.refreshLock(conn)
+	return nil
+}
+
+func (m *Migrator) appliedVersions(conn *sql.Conn) (map[int]time.Time, error) {
+	ctx := context.Background()
+	done := make(map[int]time.Time)
+
+	exists, err := m.tableExists(conn, "schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return done, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan schema_migrations row: %w", err)
+		}
+		done[version] = appliedAt
+	}
+	return done, rows.Err()
+}
+
+func (m *Migrator) tableExists(conn *sql.Conn, table string) (bool, error) {
+	query := "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	if m.dialect == dialectPostgres {
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1"
+	}
+
+	var count int
+	if err := conn.QueryRowContext(context.Background(), query, table).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to look up table %s: %w", table, err)
+	}
+	return count > 0, nil
+}
+
+func (m *Migrator) ensureMigrationsTable(conn *sql.Conn) error {
+	schema := `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+		id INTEGER PRIMARY KEY CHECK (id = 1),
+		owner TEXT NOT NULL,
+		locked_at DATETIME NOT NULL
+	);
+	`
+	if m.dialect == dialectPostgres {
+		schema = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	);
+	`
+	}
+
+	if _, err := conn.ExecContext(context.Background(), schema); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// withLock runs fn on a dedicated connection while holding the migration
+// lock: a session advisory lock on PostgreSQL, a single-row lock table on
+// SQLite. Dry runs only read, so they skip both the lock and table creation.
+func (m *Migrator) withLock(fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if m.DryRun {
+		return fn(conn)
+	}
+
+	if err := m.ensureMigrationsTable(conn); err != nil {
+		return err
+	}
+
+	unlock, err := m.lock(conn)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return fn(conn)
+}
+
+var errMigrationLocked = errors.New("migration lock is held by another process")
+
+func (m *Migrator) lock(conn *sql.Conn) (func(), error) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.LockTimeout)
+	defer cancel()
+
+	if m.dialect == dialectPostgres {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", postgresMigrationLockKey); err != nil {
+			return nil, fmt.Errorf("%w: %v", errMigrationLocked, err)
+		}
+		return func() {
+			if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", postgresMigrationLockKey); err != nil {
+				log.Printf("Error releasing migration lock: %v", err)
+			}
+		}, nil
+	}
+
+	owner := uuid.New().String()
+	for {
+		now := time.Now().UTC()
+		_, err := conn.ExecContext(ctx,
+			"INSERT INTO schema_migrations_lock (id, owner, locked_at) VALUES (1, ?, ?)", owner, now)
+		if err == nil {
+			break
+		}
+		if !strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if m.LockTTL > 0 {
+			result, err := conn.ExecContext(ctx,
+				"UPDATE schema_migrations_lock SET owner = ?, locked_at = ? WHERE id = 1 AND locked_at < ?",
+				owner, now, now.Add(-m.LockTTL))
+			if err != nil {
+				return nil, fmt.Errorf("failed to take over migration lock: %w", err)
+			}
+			if taken, _ := result.RowsAffected(); taken == 1 {
+				log.Printf("Took over a migration lock not refreshed for %s.", m.LockTTL)
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			var holder string
+			var since time.Time
+			_ = conn.QueryRowContext(context.Background(),
+				"SELECT owner, locked_at FROM schema_migrations_lock WHERE id = 1").Scan(&holder, &since)
+			return nil, fmt.Errorf("%w (%s since %s)", errMigrationLocked, holder, since.Format(time.RFC3339))
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	m.lockOwner = owner
+	return func() {
+		m.lockOwner = ""
+		if _, err := conn.ExecContext(context.Background(),
+			"DELETE FROM schema_migrations_lock WHERE id = 1 AND owner = ?", owner); err != nil {
+			log.Printf("Error releasing migration lock: %v", err)
+		}
+	}, nil
+}
+
+// refreshLock tells other processes that the holder of the SQLite
+// migration lock is still alive.
+func (m *Migrator) refreshLock(conn *sql.Conn) {
+	if m.lockOwner == "" {
+		return
+	}
+	if _, err := conn.ExecContext(context.Background(),
+		"UPDATE schema_migrations_lock SET locked_at = ? WHERE id = 1 AND owner = ?", time.Now().UTC(), m.lockOwner); err != nil {
+		log.Printf("Error refreshing migration lock: %v", err)
+	}
+}
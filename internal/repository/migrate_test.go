@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go_appeals/internal/search"
+)
+
+func newUnmigratedTestRepository(t *testing.T) *AppealRepository {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open in-memory database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	return &AppealRepository{db: db}
+}
+
+func TestMigrateUp(t *testing.T) {
+	t.Parallel()
+
+	repo := newUnmigratedTestRepository(t)
+	migrator := repo.Migrator()
+
+	migrations, err := migrator.Migrations()
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Errorf("Expected %d migrations applied, got %d", len(migrations), len(applied))
+	}
+
+	applied, err = migrator.Up()
+	if err != nil {
+		t.Fatalf("Failed to re-run migrations: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("Expected second run to apply nothing, got %d", len(applied))
+	}
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("Failed to get migration status: %v", err)
+	}
+	for _, status := range statuses {
+		if !status.Applied || status.AppliedAt.IsZero() {
+			t.Errorf("Expected migration %s to be applied", status.Name)
+		}
+	}
+}
+
+func TestMigrateExistingDatabase(t *testing.T) {
+	t.Parallel()
+
+	repo := newUnmigratedTestRepository(t)
+
+	// Databases created before migrations existed already have the table.
+	_, err := repo.db.Exec(`
+	CREATE TABLE appeals (
+		id TEXT PRIMARY KEY,
+		theme TEXT NOT NULL,
+		message TEXT NOT NULL,
+		status TEXT NOT NULL,
+		solution TEXT,
+		cansel_reason TEXT,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+	INSERT INTO appeals VALUES ('legacy', 'Theme', 'Message', 'Cancelled', '', 'Old reason', '2025-01-01 10:00:00+00:00', '2025-01-01 10:00:00+00:00');
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create legacy schema: %v", err)
+	}
+
+	if _, err := repo.Migrator().Up(); err != nil {
+		t.Fatalf("Failed to migrate legacy database: %v", err)
+	}
+
+	appeal, err := repo.FindByID("legacy")
+	if err != nil {
+		t.Fatalf("Failed to find legacy appeal: %v", err)
+	}
+	if appeal.CancelReason != "Old reason" {
+		t.Errorf("Expected cancellation reason to survive migration, got %q", appeal.CancelReason)
+	}
+
+	// Обращения, сохранённые до появления поиска, индексируются при миграции.
+	results, err := repo.Search(search.Query{Clauses: []search.Clause{{Terms: []string{search.Stem("messages")}}}}, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].Appeal.ID != "legacy" {
+		t.Errorf("Expected the legacy appeal to be searchable, got %d results", len(results))
+	}
+}
+
+func TestMigrateDown(t *testing.T) {
+	t.Parallel()
+
+	repo := newUnmigratedTestRepository(t)
+	migrator := repo.Migrator()
+
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	reverted, err := migrator.Down(1)
+	if err != nil {
+		t.Fatalf("Failed to revert migration: %v", err)
+	}
+	if len(reverted) != 1 {
+		t.Fatalf("Expected 1 migration reverted, got %d", len(reverted))
+	}
+
+	pending, err := migrator.Pending()
+	if err != nil {
+		t.Fatalf("Failed to get pending migrations: %v", err)
+	}
+	if len(pending) != 1 || pending[0].Version != reverted[0].Version {
+		t.Errorf("Expected reverted migration to be pending again, got %+v", pending)
+	}
+
+	migrations, _ := migrator.Migrations()
+	if _, err := migrator.Down(len(migrations)); err != nil {
+		t.Fatalf("Failed to revert all migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Failed to re-apply migrations: %v", err)
+	}
+}
+
+func TestMigrateDryRun(t *testing.T) {
+	t.Parallel()
+
+	repo := newUnmigratedTestRepository(t)
+
+	var out bytes.Buffer
+	migrator := repo.Migrator()
+	migrator.DryRun = true
+	migrator.Out = &out
+
+	planned, err := migrator.Up()
+	if err != nil {
+		t.Fatalf("Failed to plan migrations: %v", err)
+	}
+	if len(planned) == 0 {
+		t.Fatalf("Expected pending migrations in dry run")
+	}
+	if !strings.Contains(out.String(), "CREATE TABLE IF NOT EXISTS appeals") {
+		t.Errorf("Expected dry run to print pending SQL, got %q", out.String())
+	}
+
+	exists, err := repo.Migrator().Status()
+	if err != nil {
+		t.Fatalf("Failed to get migration status: %v", err)
+	}
+	for _, status := range exists {
+		if status.Applied {
+			t.Errorf("Expected dry run not to apply %s", status.Name)
+		}
+	}
+
+	var count int
+	if err := repo.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'appeals'").Scan(&count); err != nil {
+		t.Fatalf("Failed to query sqlite_master: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected dry run not to create the appeals table")
+	}
+}
+
+func TestMigrateLock(t *testing.T) {
+	t.Parallel()
+
+	repo := newUnmigratedTestRepository(t)
+	migrator := repo.Migrator()
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	if _, err := repo.db.Exec(
+		"INSERT INTO schema_migrations_lock (id, owner, locked_at) VALUES (1, 'other-server', ?)", time.Now().UTC()); err != nil {
+		t.Fatalf("Failed to take migration lock: %v", err)
+	}
+
+	migrator.LockTimeout = 200 * time.Millisecond
+	if _, err := migrator.Up(); !errors.Is(err, errMigrationLocked) {
+		t.Errorf("Expected migration lock error, got %v", err)
+	}
+
+	if _, err := repo.db.Exec("DELETE FROM schema_migrations_lock"); err != nil {
+		t.Fatalf("Failed to release migration lock: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Errorf("Expected migrations to run once the lock is released, got %v", err)
+	}
+}
+
+func TestMigrateStaleLock(t *testing.T) {
+	t.Parallel()
+
+	repo := newUnmigratedTestRepository(t)
+	migrator := repo.Migrator()
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	// Процесс упал, не сняв блокировку.
+	if _, err := repo.db.Exec(
+		"INSERT INTO schema_migrations_lock (id, owner, locked_at) VALUES (1, 'crashed-server', ?)", time.Now().Add(-time.Hour).UTC()); err != nil {
+		t.Fatalf("Failed to take migration lock: %v", err)
+	}
+
+	migrator.LockTimeout, migrator.LockTTL = 200*time.Millisecond, 2*time.Hour
+	if _, err := migrator.Up(); !errors.Is(err, errMigrationLocked) {
+		t.Errorf("Expected a lock younger than the TTL to be respected, got %v", err)
+	}
+
+	migrator.LockTTL = 30 * time.Minute
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Expected the stale lock to be taken over, got %v", err)
+	}
+	var held int
+	if err := repo.db.QueryRow("SELECT COUNT(*) FROM schema_migrations_lock").Scan(&held); err != nil || held != 0 {
+		t.Errorf("Expected the lock to be released, got %d, %v", held, err)
+	}
+}
+
+func TestLoadMigrations(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"m/0002_second.up.sql":   {Data: []byte("SELECT 2;")},
+		"m/0002_second.down.sql": {Data: []byte("SELECT -2;")},
+		"m/0001_first.up.sql":    {Data: []byte("SELECT 1;")},
+		"m/0001_first.down.sql":  {Data: []byte("SELECT -1;")},
+		"m/README.md":            {Data: []byte("ignored")},
+	}
+
+	migrations, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Name != "0001_first" || migrations[1].Version != 2 {
+		t.Errorf("Expected migrations ordered by version, got %+v", migrations)
+	}
+
+	fsys["m/0003_missing_down.up.sql"] = &fstest.MapFile{Data: []byte("SELECT 3;")}
+	if _, err := loadMigrations(fsys, "m"); err == nil {
+		t.Errorf("Expected error for migration without a down script")
+	}
+}
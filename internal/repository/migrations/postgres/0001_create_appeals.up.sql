@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS appeals (
+	id TEXT PRIMARY KEY,
+	theme TEXT NOT NULL,
+	message TEXT NOT NULL,
+	status TEXT NOT NULL,
+	solution TEXT NOT NULL DEFAULT '',
+	cansel_reason TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
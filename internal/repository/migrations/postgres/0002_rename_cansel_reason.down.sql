@@ -0,0 +1 @@
+ALTER TABLE appeals RENAME COLUMN cancel_reason TO cansel_reason;
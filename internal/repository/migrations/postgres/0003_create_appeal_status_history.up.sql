@@ -0,0 +1,11 @@
+CREATE TABLE appeal_status_history (
+	id BIGSERIAL PRIMARY KEY,
+	appeal_id TEXT NOT NULL REFERENCES appeals (id) ON DELETE CASCADE,
+	from_status TEXT NOT NULL DEFAULT '',
+	to_status TEXT NOT NULL,
+	actor TEXT NOT NULL DEFAULT '',
+	reason TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_appeal_status_history_appeal_id ON appeal_status_history (appeal_id, id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_appeals_theme_created_at;
+DROP INDEX IF EXISTS idx_appeals_status_updated_at;
+DROP INDEX IF EXISTS idx_appeals_status_created_at;
+DROP INDEX IF EXISTS idx_appeals_updated_at;
+DROP INDEX IF EXISTS idx_appeals_created_at;
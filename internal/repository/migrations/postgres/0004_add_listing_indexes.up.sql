@@ -0,0 +1,5 @@
+CREATE INDEX idx_appeals_created_at ON appeals (created_at, id);
+CREATE INDEX idx_appeals_updated_at ON appeals (updated_at, id);
+CREATE INDEX idx_appeals_status_created_at ON appeals (status, created_at, id);
+CREATE INDEX idx_appeals_status_updated_at ON appeals (status, updated_at, id);
+CREATE INDEX idx_appeals_theme_created_at ON appeals (theme, created_at, id);
@@ -0,0 +1,19 @@
+-- Analyzed (stemmed) copy of the searchable appeal fields, written by the
+-- application on every save. Stemming is done in Go, so the 'simple' text
+-- search configuration only splits the stored terms.
+CREATE TABLE appeal_search (
+	id BIGSERIAL PRIMARY KEY,
+	appeal_id TEXT NOT NULL UNIQUE REFERENCES appeals (id) ON DELETE CASCADE,
+	theme TEXT NOT NULL DEFAULT '',
+	message TEXT NOT NULL DEFAULT '',
+	solution TEXT NOT NULL DEFAULT '',
+	words TEXT NOT NULL DEFAULT '',
+	document TSVECTOR GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', theme), 'A') ||
+		setweight(to_tsvector('simple', message), 'B') ||
+		setweight(to_tsvector('simple', solution), 'B') ||
+		setweight(to_tsvector('simple', words), 'D')
+	) STORED
+);
+
+CREATE INDEX idx_appeal_search_document ON appeal_search USING GIN (document);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_appeals_assignee;
+ALTER TABLE appeals DROP COLUMN assignee;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_appeals_resolution_due;
+DROP INDEX IF EXISTS idx_appeals_response_due;
+DROP INDEX IF EXISTS idx_appeals_priority;
+ALTER TABLE appeals DROP COLUMN resolved_at;
+ALTER TABLE appeals DROP COLUMN responded_at;
+ALTER TABLE appeals DROP COLUMN resolution_due_at;
+ALTER TABLE appeals DROP COLUMN response_due_at;
+ALTER TABLE appeals DROP COLUMN priority;
@@ -0,0 +1,9 @@
+ALTER TABLE appeals ADD COLUMN priority TEXT NOT NULL DEFAULT 'normal';
+ALTER TABLE appeals ADD COLUMN response_due_at TIMESTAMPTZ;
+ALTER TABLE appeals ADD COLUMN resolution_due_at TIMESTAMPTZ;
+ALTER TABLE appeals ADD COLUMN responded_at TIMESTAMPTZ;
+ALTER TABLE appeals ADD COLUMN resolved_at TIMESTAMPTZ;
+
+CREATE INDEX idx_appeals_priority ON appeals (priority, created_at, id);
+CREATE INDEX idx_appeals_response_due ON appeals (response_due_at) WHERE responded_at IS NULL;
+CREATE INDEX idx_appeals_resolution_due ON appeals (resolution_due_at) WHERE resolved_at IS NULL;
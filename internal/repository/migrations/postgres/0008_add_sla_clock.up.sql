@@ -0,0 +1,3 @@
+ALTER TABLE appeals ADD COLUMN sla_paused_at TIMESTAMPTZ;
+-- Working time the SLA clock has been stopped, in nanoseconds.
+ALTER TABLE appeals ADD COLUMN sla_paused_for BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS appeal_reminders;
+DROP TABLE IF EXISTS job_runs;
+ALTER TABLE appeals DROP COLUMN escalated_at;
@@ -0,0 +1,27 @@
+ALTER TABLE appeals ADD COLUMN escalated_at TIMESTAMPTZ;
+
+CREATE TABLE job_runs (
+	id BIGSERIAL PRIMARY KEY,
+	job TEXT NOT NULL,
+	triggered_by TEXT NOT NULL,
+	status TEXT NOT NULL,
+	result TEXT NOT NULL DEFAULT '',
+	error TEXT NOT NULL DEFAULT '',
+	started_at TIMESTAMPTZ NOT NULL,
+	finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_job_runs_job ON job_runs (job, id);
+
+CREATE TABLE appeal_reminders (
+	id BIGSERIAL PRIMARY KEY,
+	appeal_id TEXT NOT NULL REFERENCES appeals (id) ON DELETE CASCADE,
+	kind TEXT NOT NULL,
+	recipient TEXT NOT NULL DEFAULT '',
+	message TEXT NOT NULL,
+	due_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	UNIQUE (appeal_id, kind, due_at)
+);
+
+CREATE INDEX idx_appeal_reminders_recipient ON appeal_reminders (recipient, id);
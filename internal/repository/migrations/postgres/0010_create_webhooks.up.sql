@@ -0,0 +1,26 @@
+CREATE TABLE webhook_subscriptions (
+	id TEXT PRIMARY KEY,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	events TEXT NOT NULL DEFAULT '',
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+	id BIGSERIAL PRIMARY KEY,
+	subscription_id TEXT NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+	event_id TEXT NOT NULL,
+	event_type TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	response_status INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL,
+	next_attempt_at TIMESTAMPTZ,
+	finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id, id);
+CREATE INDEX idx_webhook_deliveries_status ON webhook_deliveries (status, id);
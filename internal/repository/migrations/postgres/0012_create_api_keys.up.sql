@@ -0,0 +1,11 @@
+CREATE TABLE api_keys (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	key_hash TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMPTZ NOT NULL,
+	expires_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ,
+	last_used_at TIMESTAMPTZ
+);
@@ -0,0 +1,3 @@
+ALTER TABLE api_keys DROP COLUMN roles;
+DROP INDEX IF EXISTS idx_appeals_created_by;
+ALTER TABLE appeals DROP COLUMN created_by;
@@ -0,0 +1,16 @@
+-- Everything stored before tenants existed belongs to the default tenant.
+ALTER TABLE appeals ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+
+CREATE INDEX idx_appeals_tenant ON appeals (tenant_id, created_at, id);
+
+ALTER TABLE appeal_status_history ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+
+ALTER TABLE appeal_reminders ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+
+ALTER TABLE appeal_events ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+
+CREATE INDEX idx_appeal_events_tenant ON appeal_events (tenant_id, id);
+
+ALTER TABLE webhook_subscriptions ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+
+ALTER TABLE api_keys ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
@@ -0,0 +1,22 @@
+CREATE TABLE appeal_comments (
+	id BIGSERIAL PRIMARY KEY,
+	tenant_id TEXT NOT NULL DEFAULT 'default',
+	appeal_id TEXT NOT NULL REFERENCES appeals (id) ON DELETE CASCADE,
+	visibility TEXT NOT NULL DEFAULT 'public',
+	author TEXT NOT NULL,
+	body TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	edited_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_appeal_comments_appeal_id ON appeal_comments (appeal_id, id);
+
+CREATE TABLE appeal_comment_edits (
+	id BIGSERIAL PRIMARY KEY,
+	comment_id BIGINT NOT NULL REFERENCES appeal_comments (id) ON DELETE CASCADE,
+	body TEXT NOT NULL,
+	edited_by TEXT NOT NULL,
+	edited_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_appeal_comment_edits_comment_id ON appeal_comment_edits (comment_id, id);
@@ -0,0 +1,20 @@
+CREATE TABLE requesters (
+	id TEXT PRIMARY KEY,
+	tenant_id TEXT NOT NULL DEFAULT 'default',
+	name TEXT NOT NULL DEFAULT '',
+	email TEXT NOT NULL DEFAULT '',
+	phone TEXT NOT NULL DEFAULT '',
+	address TEXT NOT NULL DEFAULT '',
+	preferred_channel TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+-- Requesters are matched by normalized email and phone, either of which may
+-- be missing.
+CREATE UNIQUE INDEX idx_requesters_email ON requesters (tenant_id, email) WHERE email <> '';
+CREATE UNIQUE INDEX idx_requesters_phone ON requesters (tenant_id, phone) WHERE phone <> '';
+
+ALTER TABLE appeals ADD COLUMN requester_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_appeals_requester ON appeals (requester_id, created_at, id);
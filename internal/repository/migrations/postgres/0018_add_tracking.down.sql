@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS appeal_number_sequences;
+DROP INDEX IF EXISTS idx_appeals_tracking_token;
+DROP INDEX IF EXISTS idx_appeals_number;
+ALTER TABLE appeals DROP COLUMN tracking_token_hash;
+ALTER TABLE appeals DROP COLUMN number;
@@ -0,0 +1,2 @@
+-- The caller who gave the details of a requester they filed for themselves.
+ALTER TABLE requesters ADD COLUMN subject TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS appeals (
+	id TEXT PRIMARY KEY,
+	theme TEXT NOT NULL,
+	message TEXT NOT NULL,
+	status TEXT NOT NULL,
+	solution TEXT,
+	cansel_reason TEXT,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
@@ -0,0 +1 @@
+ALTER TABLE appeals RENAME COLUMN cansel_reason TO cancel_reason;
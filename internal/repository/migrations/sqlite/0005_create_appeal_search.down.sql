@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS appeal_search_fts;
+DROP TABLE IF EXISTS appeal_search;
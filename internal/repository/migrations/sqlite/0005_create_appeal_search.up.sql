@@ -0,0 +1,11 @@
+-- Analyzed (stemmed) copy of the searchable appeal fields, written by the
+-- application on every save. When SQLite is built with FTS5 the migrator
+-- also maintains the appeal_search_fts index over this table.
+CREATE TABLE appeal_search (
+	id INTEGER PRIMARY KEY,
+	appeal_id TEXT NOT NULL UNIQUE REFERENCES appeals (id) ON DELETE CASCADE,
+	theme TEXT NOT NULL DEFAULT '',
+	message TEXT NOT NULL DEFAULT '',
+	solution TEXT NOT NULL DEFAULT '',
+	words TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,3 @@
+ALTER TABLE appeals ADD COLUMN assignee TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_appeals_assignee ON appeals (assignee, status, created_at, id);
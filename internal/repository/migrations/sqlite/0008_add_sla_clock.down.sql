@@ -0,0 +1,2 @@
+ALTER TABLE appeals DROP COLUMN sla_paused_for;
+ALTER TABLE appeals DROP COLUMN sla_paused_at;
@@ -0,0 +1,27 @@
+ALTER TABLE appeals ADD COLUMN escalated_at DATETIME;
+
+CREATE TABLE job_runs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	job TEXT NOT NULL,
+	triggered_by TEXT NOT NULL,
+	status TEXT NOT NULL,
+	result TEXT NOT NULL DEFAULT '',
+	error TEXT NOT NULL DEFAULT '',
+	started_at DATETIME NOT NULL,
+	finished_at DATETIME
+);
+
+CREATE INDEX idx_job_runs_job ON job_runs (job, id);
+
+CREATE TABLE appeal_reminders (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	appeal_id TEXT NOT NULL REFERENCES appeals (id) ON DELETE CASCADE,
+	kind TEXT NOT NULL,
+	recipient TEXT NOT NULL DEFAULT '',
+	message TEXT NOT NULL,
+	due_at DATETIME NOT NULL,
+	created_at DATETIME NOT NULL,
+	UNIQUE (appeal_id, kind, due_at)
+);
+
+CREATE INDEX idx_appeal_reminders_recipient ON appeal_reminders (recipient, id);
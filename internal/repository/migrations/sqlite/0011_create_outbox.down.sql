@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS outbox_offsets;
+DROP TABLE IF EXISTS appeal_events;
@@ -0,0 +1,15 @@
+CREATE TABLE appeal_events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	event_id TEXT NOT NULL UNIQUE,
+	type TEXT NOT NULL,
+	appeal_id TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_appeal_events_appeal ON appeal_events (appeal_id, id);
+
+CREATE TABLE outbox_offsets (
+	sink TEXT PRIMARY KEY,
+	last_event INTEGER NOT NULL
+);
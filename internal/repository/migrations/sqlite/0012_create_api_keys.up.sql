@@ -0,0 +1,11 @@
+CREATE TABLE api_keys (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	key_hash TEXT NOT NULL UNIQUE,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME,
+	revoked_at DATETIME,
+	last_used_at DATETIME
+);
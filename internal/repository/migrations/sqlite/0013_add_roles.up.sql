@@ -0,0 +1,5 @@
+ALTER TABLE appeals ADD COLUMN created_by TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_appeals_created_by ON appeals (created_by, created_at, id);
+
+ALTER TABLE api_keys ADD COLUMN roles TEXT NOT NULL DEFAULT '';
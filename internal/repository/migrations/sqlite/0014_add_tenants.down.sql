@@ -0,0 +1,8 @@
+ALTER TABLE api_keys DROP COLUMN tenant_id;
+ALTER TABLE webhook_subscriptions DROP COLUMN tenant_id;
+DROP INDEX IF EXISTS idx_appeal_events_tenant;
+ALTER TABLE appeal_events DROP COLUMN tenant_id;
+ALTER TABLE appeal_reminders DROP COLUMN tenant_id;
+ALTER TABLE appeal_status_history DROP COLUMN tenant_id;
+DROP INDEX IF EXISTS idx_appeals_tenant;
+ALTER TABLE appeals DROP COLUMN tenant_id;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS appeal_comment_edits;
+DROP TABLE IF EXISTS appeal_comments;
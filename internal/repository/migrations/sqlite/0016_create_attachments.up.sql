@@ -0,0 +1,13 @@
+CREATE TABLE appeal_attachments (
+	id TEXT PRIMARY KEY,
+	tenant_id TEXT NOT NULL DEFAULT 'default',
+	appeal_id TEXT NOT NULL REFERENCES appeals (id) ON DELETE CASCADE,
+	file_name TEXT NOT NULL,
+	content_type TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	sha256 TEXT NOT NULL,
+	uploaded_by TEXT NOT NULL,
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_appeal_attachments_appeal_id ON appeal_attachments (appeal_id, created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_appeals_requester;
+ALTER TABLE appeals DROP COLUMN requester_id;
+DROP TABLE IF EXISTS requesters;
@@ -0,0 +1,14 @@
+ALTER TABLE appeals ADD COLUMN number TEXT NOT NULL DEFAULT '';
+ALTER TABLE appeals ADD COLUMN tracking_token_hash TEXT NOT NULL DEFAULT '';
+
+-- Appeals filed before numbering existed have neither.
+CREATE UNIQUE INDEX idx_appeals_number ON appeals (tenant_id, number) WHERE number <> '';
+CREATE UNIQUE INDEX idx_appeals_tracking_token ON appeals (tracking_token_hash) WHERE tracking_token_hash <> '';
+
+-- The last registration number given out in each series, e.g. a year.
+CREATE TABLE appeal_number_sequences (
+	tenant_id TEXT NOT NULL,
+	series TEXT NOT NULL,
+	last_number INTEGER NOT NULL,
+	PRIMARY KEY (tenant_id, series)
+);
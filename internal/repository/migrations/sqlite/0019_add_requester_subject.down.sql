@@ -0,0 +1 @@
+ALTER TABLE requesters DROP COLUMN subject;
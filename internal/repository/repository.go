@@ -3,7 +3,7 @@ package repository
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"strings"
 	"time"
 
 	"go_appeals/internal/models"
@@ -12,12 +12,27 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+const appealColumns = "id, theme, message, status, solution, cancel_reason, assignee, created_at, updated_at, " +
+	"priority, response_due_at, resolution_due_at, responded_at, resolved_at, sla_paused_at, sla_paused_for, escalated_at, created_by, tenant_id, requester_id, number, tracking_token_hash"
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Prepare(query string) (*sql.Stmt, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
 type AppealRepository struct {
-	db *sql.DB
+	db      *sql.DB
+	tx      *sql.Tx
+	dialect dialect
+	// tenant is set on the views returned by ForTenant.
+	tenant string
 }
 
 func NewAppealRepository(dbPath string) (*AppealRepository, error) {
-	db, err := sql.Open("sqlite3", dbPath)
+	db, err := sql.Open("sqlite3", sqliteDSN(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -27,113 +42,215 @@ func NewAppealRepository(dbPath string) (*AppealRepository, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	repo := &AppealRepository{db: db}
+	return &AppealRepository{db: db}, nil
+}
 
-	if err := repo.InitSchema(); err != nil {
-		return nil, fmt.Errorf("failed to initialize database schema: %w", err)
+// sqliteDSN makes transactions take the write lock when they begin, and
+// makes everything wait for a lock instead of failing at once. A deferred
+// transaction that reads before it writes cannot wait: it fails with
+// "database is locked" as soon as another one has written first. Options
+// already in the path are left alone.
+func sqliteDSN(path string) string {
+	for _, option := range []string{"_txlock=immediate", "_busy_timeout=5000"} {
+		name, _, _ := strings.Cut(option, "=")
+		if strings.Contains(path, name+"=") {
+			continue
+		}
+		if strings.Contains(path, "?") {
+			path += "&" + option
+		} else {
+			path += "?" + option
+		}
 	}
+	return path
+}
 
-	return repo, nil
+// conn returns the transaction the repository is bound to, if any.
+func (r *AppealRepository) conn() queryer {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.db
 }
 
-func (r *AppealRepository) InitSchema() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS appeals (
-		id TEXT PRIMARY KEY,
-		theme TEXT NOT NULL,
-		message TEXT NOT NULL,
-		status TEXT NOT NULL,
-		solution TEXT,
-		cansel_reason TEXT,
-		created_at DATETIME NOT NULL,
-		updated_at DATETIME NOT NULL
-	);
-	`
-	_, err := r.db.Exec(schema)
+func (r *AppealRepository) WithTx(fn func(tx AppealStore) error) error {
+	if r.tx != nil {
+		return fn(r)
+	}
+
+	tx, err := r.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to create appeals table: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&AppealRepository{db: r.db, tx: tx, dialect: r.dialect, tenant: r.tenant}); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
-	log.Println("Appeals table initialized or already exists.")
 	return nil
 }
 
+// ForTenant returns a view of the repository that reads only the records of
+// tenant and stores new ones under it. The repository itself sees every
+// tenant.
+func (r *AppealRepository) ForTenant(tenant string) AppealStore {
+	scoped := *r
+	scoped.tenant = tenant
+	return &scoped
+}
+
+// scope adds the condition restricting column to the bound tenant, if any.
+func (r *AppealRepository) scope(column string, conds []string, args []any) ([]string, []any) {
+	if r.tenant == "" {
+		return conds, args
+	}
+	return append(conds, column+" = ?"), append(args, r.tenant)
+}
+
+// tenantOf is the tenant a new record is stored under: the bound one, else
+// the record's own, else the default.
+func (r *AppealRepository) tenantOf(tenant string) string {
+	switch {
+	case r.tenant != "":
+		return r.tenant
+	case tenant != "":
+		return tenant
+	}
+	return models.DefaultTenant
+}
+
+// atomically runs fn in the current transaction, or in a new one.
+func (r *AppealRepository) atomically(fn func(tx *AppealRepository) error) error {
+	return r.WithTx(func(tx AppealStore) error {
+		return fn(tx.(*AppealRepository))
+	})
+}
+
 func (r *AppealRepository) Save(appeal *models.Appeal) (*models.Appeal, error) {
+	now := time.Now().UTC()
 	appeal.ID = uuid.New().String()
-	appeal.CreatedAt = time.Now()
-	appeal.UpdatedAt = time.Now()
+	if appeal.CreatedAt.IsZero() {
+		appeal.CreatedAt = now
+	}
+	if appeal.UpdatedAt.IsZero() {
+		appeal.UpdatedAt = now
+	}
+	// SQLite stores timestamps as text, so keyset comparisons only work if
+	// every row is written in the same zone.
+	appeal.CreatedAt = appeal.CreatedAt.UTC()
+	appeal.UpdatedAt = appeal.UpdatedAt.UTC()
+	appeal.TenantID = r.tenantOf(appeal.TenantID)
+
+	err := r.atomically(func(tx *AppealRepository) error {
+		stmt, err := tx.conn().Prepare(tx.rebind(
+			"INSERT INTO appeals (" + appealColumns + ") VALUES (" + placeholders(22) + ")"))
+		if err != nil {
+			return fmt.Errorf("failed to prepare save statement: %w", err)
+		}
+		defer stmt.Close()
+
+		_, err = stmt.Exec(
+			appeal.ID,
+			appeal.Theme,
+			appeal.Message,
+			appeal.Status,
+			appeal.Solution,
+			appeal.CancelReason,
+			appeal.Assignee,
+			appeal.CreatedAt,
+			appeal.UpdatedAt,
+			appeal.Priority,
+			nullTime(appeal.ResponseDueAt),
+			nullTime(appeal.ResolutionDueAt),
+			nullTime(appeal.RespondedAt),
+			nullTime(appeal.ResolvedAt),
+			nullTime(appeal.SLAPausedAt),
+			int64(appeal.SLAPausedFor),
+			nullTime(appeal.EscalatedAt),
+			appeal.CreatedBy,
+			appeal.TenantID,
+			appeal.RequesterID,
+			appeal.Number,
+			appeal.TrackingTokenHash,
+		)
+		if isUniqueViolation(err) {
+			return numberTaken(appeal.Number)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to execute save statement: %w", err)
+		}
 
-	stmt, err := r.db.Prepare(
-		"INSERT INTO appeals (id, theme, message, status, solution, cansel_reason, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)")
+		return tx.indexAppeal(appeal)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare save statement: %w", err)
-	}
-	defer stmt.Close()
-
-	_, err = stmt.Exec(
-		appeal.ID,
-		appeal.Theme,
-		appeal.Message,
-		appeal.Status,
-		appeal.Solution,
-		appeal.CanselReason,
-		appeal.CreatedAt,
-		appeal.UpdatedAt,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute save statement: %w", err)
+		return nil, err
 	}
 
 	return appeal, nil
 }
 
 func (r *AppealRepository) Update(appeal *models.Appeal) (*models.Appeal, error) {
-	appeal.UpdatedAt = time.Now()
+	appeal.UpdatedAt = time.Now().UTC()
+
+	err := r.atomically(func(tx *AppealRepository) error {
+		where, scopeArgs := tx.scope("tenant_id", []string{"id = ?"}, []any{appeal.ID})
+		stmt, err := tx.conn().Prepare(tx.rebind(
+			"UPDATE appeals SET theme=?, message=?, status=?, solution=?, cancel_reason=?, assignee=?, updated_at=?, " +
+				"priority=?, response_due_at=?, resolution_due_at=?, responded_at=?, resolved_at=?, sla_paused_at=?, sla_paused_for=?, escalated_at=?" +
+				whereClause(where)))
+		if err != nil {
+			return fmt.Errorf("failed to prepare update statement: %w", err)
+		}
+		defer stmt.Close()
+
+		args := []any{
+			appeal.Theme,
+			appeal.Message,
+			appeal.Status,
+			appeal.Solution,
+			appeal.CancelReason,
+			appeal.Assignee,
+			appeal.UpdatedAt,
+			appeal.Priority,
+			nullTime(appeal.ResponseDueAt),
+			nullTime(appeal.ResolutionDueAt),
+			nullTime(appeal.RespondedAt),
+			nullTime(appeal.ResolvedAt),
+			nullTime(appeal.SLAPausedAt),
+			int64(appeal.SLAPausedFor),
+			nullTime(appeal.EscalatedAt),
+		}
+		result, err := stmt.Exec(append(args, scopeArgs...)...)
+		if err != nil {
+			return fmt.Errorf("failed to execute update statement: %w", err)
+		}
 
-	stmt, err := r.db.Prepare(
-		"UPDATE appeals SET theme=?, message=?, status=?, solution=?, cansel_reason=?, updated_at=? WHERE id=?")
-	if err != nil {
-		return nil, fmt.Errorf("failed to prepare update statement: %w", err)
-	}
-	defer stmt.Close()
-
-	result, err := stmt.Exec(
-		appeal.Theme,
-		appeal.Message,
-		appeal.Status,
-		appeal.Solution,
-		appeal.CanselReason,
-		appeal.UpdatedAt,
-		appeal.ID,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute update statement: %w", err)
-	}
+		rowsAffected, _ := result.RowsAffected()
+		if rowsAffected == 0 {
+			return models.AppealNotFound(appeal.ID)
+		}
 
-	rowsAffected, _ := result.RowsAffected()
-	if rowsAffected == 0 {
-		return nil, fmt.Errorf("appeal with ID %s not found for update", appeal.ID)
+		return tx.indexAppeal(appeal)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return appeal, nil
 }
 
 func (r *AppealRepository) FindByID(id string) (*models.Appeal, error) {
-	row := r.db.QueryRow(
-		"SELECT id, theme, message, status, solution, cansel_reason, created_at, updated_at FROM appeals WHERE id = ?", id)
+	where, args := r.scope("tenant_id", []string{"id = ?"}, []any{id})
+	row := r.conn().QueryRow(r.rebind(
+		"SELECT "+appealColumns+" FROM appeals"+whereClause(where)), args...)
 
-	appeal := &models.Appeal{}
-	err := row.Scan(
-		&appeal.ID,
-		&appeal.Theme,
-		&appeal.Message,
-		&appeal.Status,
-		&appeal.Solution,
-		&appeal.CanselReason,
-		&appeal.CreatedAt,
-		&appeal.UpdatedAt,
-	)
+	appeal, err := scanAppeal(row)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("appeal with ID %s not found", id)
+		return nil, models.AppealNotFound(id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan appeal: %w", err)
@@ -143,55 +260,195 @@ func (r *AppealRepository) FindByID(id string) (*models.Appeal, error) {
 }
 
 func (r *AppealRepository) GetAll() ([]*models.Appeal, error) {
-	rows, err := r.db.Query(
-		"SELECT id, theme, message, status, solution, cansel_reason, created_at, updated_at FROM appeals")
+	where, args := r.scope("tenant_id", nil, nil)
+	return r.queryAppeals("SELECT "+appealColumns+" FROM appeals"+whereClause(where)+" ORDER BY created_at, id", args...)
+}
+
+func (r *AppealRepository) FindByStatus(statuses ...models.AppealStatus) ([]*models.Appeal, error) {
+	if len(statuses) == 0 {
+		return make([]*models.Appeal, 0), nil
+	}
+
+	args := make([]any, len(statuses))
+	for i, status := range statuses {
+		args[i] = status
+	}
+	where, args := r.scope("tenant_id", []string{"status IN (" + placeholders(len(statuses)) + ")"}, args)
+
+	return r.queryAppeals(
+		"SELECT "+appealColumns+" FROM appeals"+whereClause(where)+" ORDER BY created_at, id",
+		args...)
+}
+
+func (r *AppealRepository) CancelInProgressAppeals(statuses []models.AppealStatus, cancel func(appeal *models.Appeal) error) ([]*models.Appeal, error) {
+	return cancelInProgress(r, statuses, cancel)
+}
+
+func (r *AppealRepository) SelectAppealsByDates(start, end time.Time) ([]*models.Appeal, error) {
+	where, args := r.scope("tenant_id", []string{"created_at BETWEEN ? AND ?"}, []any{start.UTC(), end.UTC()})
+	return r.queryAppeals(
+		"SELECT "+appealColumns+" FROM appeals"+whereClause(where)+" ORDER BY created_at",
+		args...)
+}
+
+func (r *AppealRepository) List(filter models.AppealFilter) (*models.AppealPage, error) {
+	if err := filter.Normalize(); err != nil {
+		return nil, err
+	}
+	after, err := decodeCursor(filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query appeals: %w", err)
+		return nil, err
+	}
+
+	where, args := r.scope("tenant_id", nil, nil)
+
+	if len(filter.Statuses) > 0 {
+		where = append(where, "status IN ("+placeholders(len(filter.Statuses))+")")
+		for _, status := range filter.Statuses {
+			args = append(args, status)
+		}
+	}
+	if len(filter.Priorities) > 0 {
+		where = append(where, "priority IN ("+placeholders(len(filter.Priorities))+")")
+		for _, priority := range filter.Priorities {
+			args = append(args, priority)
+		}
+	}
+	if filter.Theme != "" {
+		where = append(where, "theme = ?")
+		args = append(args, filter.Theme)
+	}
+	if filter.Assignee != "" {
+		where = append(where, "assignee = ?")
+		args = append(args, filter.Assignee)
+	}
+	if filter.CreatedBy != "" {
+		where = append(where, "created_by = ?")
+		args = append(args, filter.CreatedBy)
+	}
+	if filter.RequesterID != "" {
+		where = append(where, "requester_id = ?")
+		args = append(args, filter.RequesterID)
+	}
+	for _, bound := range []struct {
+		cond  string
+		value time.Time
+	}{
+		{"created_at >= ?", filter.CreatedFrom},
+		{"created_at <= ?", filter.CreatedTo},
+		{"updated_at >= ?", filter.UpdatedFrom},
+		{"updated_at <= ?", filter.UpdatedTo},
+	} {
+		if !bound.value.IsZero() {
+			where = append(where, bound.cond)
+			args = append(args, bound.value.UTC())
+		}
+	}
+
+	asOf := filter.AsOf.UTC()
+	// A stopped SLA clock reads the time it was stopped, see
+	// models.Appeal.SLABreached.
+	switch filter.SLA {
+	case models.SLADueSoon:
+		until := asOf.Add(filter.DueWithin)
+		where = append(where, "sla_paused_at IS NULL AND ((responded_at IS NULL AND response_due_at BETWEEN ? AND ?)"+
+			" OR (resolved_at IS NULL AND resolution_due_at BETWEEN ? AND ?))")
+		args = append(args, asOf, until, asOf, until)
+	case models.SLABreached:
+		where = append(where, "(COALESCE(responded_at, sla_paused_at, ?) > response_due_at"+
+			" OR COALESCE(resolved_at, sla_paused_at, ?) > resolution_due_at)")
+		args = append(args, asOf, asOf)
+	}
+
+	// SortBy is one of the validated column names, never user input.
+	column := string(filter.SortBy)
+	op, direction := ">", "ASC"
+	if filter.Descending {
+		op, direction = "<", "DESC"
+	}
+	if after != nil {
+		where = append(where, "("+column+", id) "+op+" (?, ?)")
+		args = append(args, after.Value, after.ID)
+	}
+
+	query := "SELECT " + appealColumns + " FROM appeals" + whereClause(where)
+	query += " ORDER BY " + column + " " + direction + ", id " + direction + " LIMIT ?"
+	args = append(args, filter.Limit+1)
+
+	appeals, err := r.queryAppeals(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(appeals, filter), nil
+}
+
+func (r *AppealRepository) AddHistory(entry *models.StatusHistoryEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	err := r.conn().QueryRow(r.rebind(
+		"INSERT INTO appeal_status_history (tenant_id, appeal_id, from_status, to_status, actor, reason, created_at) VALUES (?, ?, ?, ?, ?, ?, ?) RETURNING id"),
+		r.tenantOf(entry.TenantID),
+		entry.AppealID,
+		entry.FromStatus,
+		entry.ToStatus,
+		entry.Actor,
+		entry.Reason,
+		entry.CreatedAt,
+	).Scan(&entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert status history: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AppealRepository) GetHistory(appealID string) ([]*models.StatusHistoryEntry, error) {
+	where, args := r.scope("tenant_id", []string{"appeal_id = ?"}, []any{appealID})
+	rows, err := r.conn().Query(r.rebind(
+		"SELECT id, tenant_id, appeal_id, from_status, to_status, actor, reason, created_at FROM appeal_status_history"+whereClause(where)+" ORDER BY id"),
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query status history: %w", err)
 	}
 	defer rows.Close()
 
-	appeals := make([]*models.Appeal, 0)
+	history := make([]*models.StatusHistoryEntry, 0)
 	for rows.Next() {
-		appeal := &models.Appeal{}
+		entry := &models.StatusHistoryEntry{}
 		err := rows.Scan(
-			&appeal.ID,
-			&appeal.Theme,
-			&appeal.Message,
-			&appeal.Status,
-			&appeal.Solution,
-			&appeal.CanselReason,
-			&appeal.CreatedAt,
-			&appeal.UpdatedAt,
+			&entry.ID,
+			&entry.TenantID,
+			&entry.AppealID,
+			&entry.FromStatus,
+			&entry.ToStatus,
+			&entry.Actor,
+			&entry.Reason,
+			&entry.CreatedAt,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan appeal row: %w", err)
+			return nil, fmt.Errorf("failed to scan status history row: %w", err)
 		}
-		appeals = append(appeals, appeal)
+		history = append(history, entry)
 	}
 
-	return appeals, nil
+	return history, rows.Err()
 }
 
-func (r *AppealRepository) CancelInProgressAppeals() error {
-	stmt, err := r.db.Prepare(
-		"UPDATE appeals SET status = ?, updated_at = ? WHERE status IN (?, ?)")
-	if err != nil {
-		return fmt.Errorf("failed to prepare cancel statement: %w", err)
-	}
-	defer stmt.Close()
-
-	_, err = stmt.Exec(models.StatusCancelled, time.Now(), models.StatusNew, models.StatusInProgress)
-	if err != nil {
-		return fmt.Errorf("failed to execute cancel statement: %w", err)
+func (r *AppealRepository) Close() error {
+	if r.tx != nil {
+		return nil
 	}
+	return r.db.Close()
+}
 
-	return nil
+func (r *AppealRepository) rebind(query string) string {
+	return r.dialect.rebind(query)
 }
 
-func (r *AppealRepository) SelectAppealsByDates(start, end time.Time) ([]*models.Appeal, error) {
-	rows, err := r.db.Query(
-		"SELECT id, theme, message, status, solution, cansel_reason, created_at, updated_at FROM appeals WHERE created_at BETWEEN ? AND ?",
-		start, end)
+func (r *AppealRepository) queryAppeals(query string, args ...any) ([]*models.Appeal, error) {
+	rows, err := r.conn().Query(r.rebind(query), args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query appeals: %w", err)
 	}
@@ -199,26 +456,72 @@ func (r *AppealRepository) SelectAppealsByDates(start, end time.Time) ([]*models
 
 	appeals := make([]*models.Appeal, 0)
 	for rows.Next() {
-		appeal := &models.Appeal{}
-		err := rows.Scan(
-			&appeal.ID,
-			&appeal.Theme,
-			&appeal.Message,
-			&appeal.Status,
-			&appeal.Solution,
-			&appeal.CanselReason,
-			&appeal.CreatedAt,
-			&appeal.UpdatedAt,
-		)
+		appeal, err := scanAppeal(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan appeal row: %w", err)
 		}
 		appeals = append(appeals, appeal)
 	}
 
-	return appeals, nil
+	return appeals, rows.Err()
 }
 
-func (r *AppealRepository) Close() error {
-	return r.db.Close()
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanAppeal reads the appealColumns of a row followed by any extra columns
+// into extra.
+func scanAppeal(row scanner, extra ...any) (*models.Appeal, error) {
+	appeal := &models.Appeal{}
+	dest := append([]any{
+		&appeal.ID,
+		&appeal.Theme,
+		&appeal.Message,
+		&appeal.Status,
+		&appeal.Solution,
+		&appeal.CancelReason,
+		&appeal.Assignee,
+		&appeal.CreatedAt,
+		&appeal.UpdatedAt,
+		&appeal.Priority,
+		&appeal.ResponseDueAt,
+		&appeal.ResolutionDueAt,
+		&appeal.RespondedAt,
+		&appeal.ResolvedAt,
+		&appeal.SLAPausedAt,
+		&appeal.SLAPausedFor,
+		&appeal.EscalatedAt,
+		&appeal.CreatedBy,
+		&appeal.TenantID,
+		&appeal.RequesterID,
+		&appeal.Number,
+		&appeal.TrackingTokenHash,
+	}, extra...)
+	err := row.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	return appeal, nil
+}
+
+// nullTime stores an optional timestamp as NULL or, like every other
+// timestamp, in UTC.
+func nullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// whereClause joins conditions into a WHERE clause, or nothing.
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
 }
@@ -2,9 +2,11 @@ package repository
 
 import (
 	"database/sql"
-	"go_appeals/internal/models"
+	"path/filepath"
+	"sync"
 	"testing"
-	"time"
+
+	"go_appeals/internal/models"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -14,10 +16,12 @@ func newTestRepository(t *testing.T) (*AppealRepository, func()) {
 	if err != nil {
 		t.Fatalf("Failed to open in-memory database: %v", err)
 	}
+	// Every new connection to :memory: opens a separate empty database.
+	db.SetMaxOpenConns(1)
 
 	repo := &AppealRepository{db: db}
-	if err := repo.InitSchema(); err != nil {
-		t.Fatalf("Failed to initialize database schema: %v", err)
+	if _, err := repo.Migrator().Up(); err != nil {
+		t.Fatalf("Failed to migrate database schema: %v", err)
 	}
 
 	return repo, func() {
@@ -27,15 +31,6 @@ func newTestRepository(t *testing.T) (*AppealRepository, func()) {
 	}
 }
 
-func TestInitSchema(t *testing.T) {
-	t.Parallel()
-
-	repo, cleanup := newTestRepository(t)
-	defer cleanup()
-
-	_ = repo
-}
-
 func TestClose(t *testing.T) {
 	t.Parallel()
 
@@ -50,361 +45,77 @@ func TestClose(t *testing.T) {
 	}
 }
 
-func TestSelectAppealsByDates(t *testing.T) {
+func TestAppealRepositoryConformance(t *testing.T) {
 	t.Parallel()
 
-	repo, cleanup := newTestRepository(t)
-	defer cleanup()
-
-	startDate := time.Date(2025, 10, 27, 0, 0, 0, 0, time.UTC)  // Начало дня
-	endDate := time.Date(2025, 10, 27, 23, 59, 59, 0, time.UTC) // Конец дня
-
-	appeals := []*models.Appeal{
-		{
-			Theme:        "Test theme",
-			Message:      "Test message",
-			Status:       "open",
-			Solution:     "Test solution",
-			CanselReason: "Test reason",
-			CreatedAt:    time.Date(2025, 10, 27, 12, 0, 0, 0, time.UTC), // Середина дня
-			UpdatedAt:    time.Date(2025, 10, 27, 12, 0, 0, 0, time.UTC),
-		},
-		{
-			Theme:        "Test theme",
-			Message:      "Test message",
-			Status:       "open",
-			Solution:     "Test solution",
-			CanselReason: "Test reason",
-			CreatedAt:    time.Date(2025, 10, 27, 18, 0, 0, 0, time.UTC), // Вечер дня
-			UpdatedAt:    time.Date(2025, 10, 27, 18, 0, 0, 0, time.UTC),
-		},
-	}
-
-	for _, appeal := range appeals {
-		_, err := repo.Save(appeal)
-		if err != nil {
-			t.Errorf("Failed to save appeal: %v", err)
-		}
-	}
-
-	selectedAppeals, err := repo.SelectAppealsByDates(startDate, endDate)
-	if err != nil {
-		t.Errorf("Failed to select appeals by dates: %v", err)
-	}
-
-	if len(selectedAppeals) != len(appeals) {
-		t.Errorf("Expected %d appeals, got %d", len(appeals), len(selectedAppeals))
-	}
-
-	if len(selectedAppeals) == len(appeals) {
-		for i, appeal := range appeals {
-			if selectedAppeals[i].ID != appeal.ID {
-				t.Errorf("Expected appeal ID %s, got %s", appeal.ID, selectedAppeals[i].ID)
-			}
-		}
-	}
+	runAppealStoreConformance(t, func(t *testing.T) Store {
+		repo, cleanup := newTestRepository(t)
+		t.Cleanup(cleanup)
+		return repo
+	})
 }
 
-func TestCancelInProgressAppeals(t *testing.T) {
+func TestSQLiteDSN(t *testing.T) {
 	t.Parallel()
 
-	repo, cleanup := newTestRepository(t)
-	defer cleanup()
-
-	appeals := []*models.Appeal{
-		{
-			Theme:        "Test theme",
-			Message:      "Test message",
-			Status:       models.StatusNew,
-			Solution:     "Test solution",
-			CanselReason: "Test reason",
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
-		},
-		{
-			Theme:        "Test theme",
-			Message:      "Test message",
-			Status:       models.StatusInProgress,
-			Solution:     "Test solution",
-			CanselReason: "Test reason",
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
-		},
-		{
-			Theme:        "Test theme",
-			Message:      "Test message",
-			Status:       models.StatusCompleted,
-			Solution:     "Test solution",
-			CanselReason: "Test reason",
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
-		},
-		{
-			Theme:        "Test theme",
-			Message:      "Test message",
-			Status:       models.StatusCancelled,
-			Solution:     "Test solution",
-			CanselReason: "Test reason",
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
-		},
-	}
-
-	savedAppeals := make([]*models.Appeal, len(appeals))
-	for i, appeal := range appeals {
-		saved, err := repo.Save(appeal)
-		if err != nil {
-			t.Errorf("Failed to save appeal: %v", err)
-		} else {
-			savedAppeals[i] = saved
+	for path, want := range map[string]string{
+		"appeals.db":                   "appeals.db?_txlock=immediate&_busy_timeout=5000",
+		"file:appeals.db?cache=shared": "file:appeals.db?cache=shared&_txlock=immediate&_busy_timeout=5000",
+		"appeals.db?_busy_timeout=100": "appeals.db?_busy_timeout=100&_txlock=immediate",
+		"appeals.db?_txlock=exclusive": "appeals.db?_txlock=exclusive&_busy_timeout=5000",
+	} {
+		if got := sqliteDSN(path); got != want {
+			t.Errorf("%s: expected %s, got %s", path, want, got)
 		}
 	}
-
-	allAppeals, err := repo.GetAll()
-	if err != nil {
-		t.Errorf("Failed to get all appeals: %v", err)
-	}
-
-	initialInProgressCount := 0
-	for _, appeal := range allAppeals {
-		if appeal.Status == models.StatusNew || appeal.Status == models.StatusInProgress {
-			initialInProgressCount++
-		}
-	}
-
-	if initialInProgressCount != 2 {
-		t.Errorf("Expected 2 appeals with StatusNew or StatusInProgress, got %d", initialInProgressCount)
-	}
-
-	if err := repo.CancelInProgressAppeals(); err != nil {
-		t.Errorf("Failed to cancel in-progress appeals: %v", err)
-	}
-
-	allAppealsAfter, err := repo.GetAll()
-	if err != nil {
-		t.Errorf("Failed to get all appeals: %v", err)
-	}
-
-	cancelledCount := 0
-	for _, appeal := range allAppealsAfter {
-		if appeal.Status == models.StatusCancelled {
-			cancelledCount++
-		}
-	}
-
-	if cancelledCount != 3 {
-		t.Errorf("Expected 3 cancelled appeals (2 converted + 1 already cancelled), got %d", cancelledCount)
-	}
-
-	newAppeal := savedAppeals[0]
-	inProgressAppeal := savedAppeals[1]
-
-	updatedNewAppeal, err := repo.FindByID(newAppeal.ID)
-	if err != nil {
-		t.Errorf("Failed to find appeal with original ID %s: %v", newAppeal.ID, err)
-	} else if updatedNewAppeal.Status != models.StatusCancelled {
-		t.Errorf("Expected appeal %s to be cancelled, got %s", newAppeal.ID, updatedNewAppeal.Status)
-	}
-
-	updatedInProgressAppeal, err := repo.FindByID(inProgressAppeal.ID)
-	if err != nil {
-		t.Errorf("Failed to find appeal with original ID %s: %v", inProgressAppeal.ID, err)
-	} else if updatedInProgressAppeal.Status != models.StatusCancelled {
-		t.Errorf("Expected appeal %s to be cancelled, got %s", inProgressAppeal.ID, updatedInProgressAppeal.Status)
-	}
-
-	completedAppeal := savedAppeals[2]
-	alreadyCancelledAppeal := savedAppeals[3]
-
-	updatedCompletedAppeal, err := repo.FindByID(completedAppeal.ID)
-	if err != nil {
-		t.Errorf("Failed to find appeal with original ID %s: %v", completedAppeal.ID, err)
-	} else if updatedCompletedAppeal.Status != models.StatusCompleted {
-		t.Errorf("Expected appeal %s to remain completed, got %s", completedAppeal.ID, updatedCompletedAppeal.Status)
-	}
-
-	updatedAlreadyCancelledAppeal, err := repo.FindByID(alreadyCancelledAppeal.ID)
-	if err != nil {
-		t.Errorf("Failed to find appeal with original ID %s: %v", alreadyCancelledAppeal.ID, err)
-	} else if updatedAlreadyCancelledAppeal.Status != models.StatusCancelled {
-		t.Errorf("Expected appeal %s to remain cancelled, got %s", alreadyCancelledAppeal.ID, updatedAlreadyCancelledAppeal.Status)
-	}
 }
 
-func TestSave(t *testing.T) {
+// Транзакции, которые сначала читают, а потом пишут, не должны падать с
+// "database is locked" на файловой базе.
+func TestConcurrentTransactions(t *testing.T) {
 	t.Parallel()
 
-	repo, cleanup := newTestRepository(t)
-	defer cleanup()
-
-	appeal := &models.Appeal{
-		Theme:        "Test theme",
-		Message:      "Test message",
-		Status:       models.StatusNew,
-		Solution:     "Test solution",
-		CanselReason: "Test reason",
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
-	}
-
-	savedAppeal, err := repo.Save(appeal)
+	repo, err := NewAppealRepository(filepath.Join(t.TempDir(), "appeals.db"))
 	if err != nil {
-		t.Errorf("Failed to save appeal: %v", err)
+		t.Fatalf("Failed to open database: %v", err)
 	}
-
-	if savedAppeal.ID == "" {
-		t.Errorf("Expected saved appeal to have a non-empty ID, got %s", savedAppeal.ID)
+	t.Cleanup(func() { repo.Close() })
+	if _, err := repo.Migrator().Up(); err != nil {
+		t.Fatalf("Failed to migrate database schema: %v", err)
 	}
-}
-
-func TestGetAll(t *testing.T) {
-	t.Parallel()
 
-	repo, cleanup := newTestRepository(t)
-	defer cleanup()
-
-	appeals := []*models.Appeal{
-		{
-			Theme:        "Test theme 1",
-			Message:      "Test message 1",
-			Status:       models.StatusNew,
-			Solution:     "Test solution 1",
-			CanselReason: "Test reason 1",
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
-		},
-		{
-			Theme:        "Test theme 2",
-			Message:      "Test message 2",
-			Status:       models.StatusNew,
-			Solution:     "Test solution 2",
-			CanselReason: "Test reason 2",
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
-		},
-	}
-
-	for _, appeal := range appeals {
-		_, err := repo.Save(appeal)
+	const appeals = 20
+	ids := make([]string, appeals)
+	for i := range ids {
+		appeal, err := repo.Save(&models.Appeal{Theme: "Theme", Message: "Message", Status: models.StatusNew})
 		if err != nil {
-			t.Errorf("Failed to save appeal: %v", err)
+			t.Fatalf("Failed to save appeal: %v", err)
 		}
+		ids[i] = appeal.ID
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.WithTx(func(tx AppealStore) error {
+				appeal, err := tx.FindByID(id)
+				if err != nil {
+					return err
+				}
+				appeal.Status = models.StatusInProgress
+				_, err = tx.Update(appeal)
+				return err
+			})
+			if err != nil {
+				t.Errorf("Failed to update %s: %v", id, err)
+			}
+		}()
 	}
+	wg.Wait()
 
-	savedAppeals, err := repo.GetAll()
-	if err != nil {
-		t.Errorf("Failed to get all appeals: %v", err)
-	}
-
-	if len(savedAppeals) != len(appeals) {
-		t.Errorf("Expected %d appeals, got %d", len(appeals), len(savedAppeals))
-	}
-}
-
-func TestFindByID(t *testing.T) {
-	t.Parallel()
-
-	repo, cleanup := newTestRepository(t)
-	defer cleanup()
-
-	appeal := &models.Appeal{
-		Theme:        "Test theme",
-		Message:      "Test message",
-		Status:       models.StatusNew,
-		Solution:     "Test solution",
-		CanselReason: "Test reason",
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
-	}
-
-	savedAppeal, err := repo.Save(appeal)
-	if err != nil {
-		t.Errorf("Failed to save appeal: %v", err)
-	}
-
-	foundAppeal, err := repo.FindByID(savedAppeal.ID)
-	if err != nil {
-		t.Errorf("Failed to find appeal by ID: %v", err)
-	}
-
-	if foundAppeal.ID != savedAppeal.ID {
-		t.Errorf("Expected appeal with ID %s, got %s", savedAppeal.ID, foundAppeal.ID)
-	}
-}
-
-func TestUpdate(t *testing.T) {
-	t.Parallel()
-
-	repo, cleanup := newTestRepository(t)
-	defer cleanup()
-
-	originalAppeal := &models.Appeal{
-		Theme:        "Original theme",
-		Message:      "Original message",
-		Status:       models.StatusNew,
-		Solution:     "Original solution",
-		CanselReason: "Original reason",
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
-	}
-
-	savedAppeal, err := repo.Save(originalAppeal)
-	if err != nil {
-		t.Fatalf("Failed to save appeal: %v", err)
-	}
-
-	updatedAppeal := &models.Appeal{
-		ID:           savedAppeal.ID,
-		Theme:        "Updated theme",
-		Message:      "Updated message",
-		Status:       models.StatusInProgress,
-		Solution:     "Updated solution",
-		CanselReason: "Updated reason",
-		CreatedAt:    savedAppeal.CreatedAt,
-		UpdatedAt:    time.Now(),
-	}
-
-	returnedAppeal, err := repo.Update(updatedAppeal)
-	if err != nil {
-		t.Errorf("Failed to update appeal: %v", err)
-	}
-
-	if returnedAppeal.Theme != "Updated theme" {
-		t.Errorf("Expected theme %s, got %s", "Updated theme", returnedAppeal.Theme)
-	}
-	if returnedAppeal.Status != models.StatusInProgress {
-		t.Errorf("Expected status %s, got %s", models.StatusInProgress, returnedAppeal.Status)
-	}
-
-	foundAppeal, err := repo.FindByID(savedAppeal.ID)
-	if err != nil {
-		t.Errorf("Failed to find appeal by ID: %v", err)
-	}
-
-	if foundAppeal.Theme != "Updated theme" {
-		t.Errorf("Expected theme %s in database, got %s", "Updated theme", foundAppeal.Theme)
-	}
-	if foundAppeal.Status != models.StatusInProgress {
-		t.Errorf("Expected status %s in database, got %s", models.StatusInProgress, foundAppeal.Status)
-	}
-
-	if foundAppeal.UpdatedAt.Equal(savedAppeal.UpdatedAt) {
-		t.Errorf("Expected UpdatedAt to be different after update")
-	}
-
-	nonExistentAppeal := &models.Appeal{
-		ID:           "non-existent",
-		Theme:        "Test theme",
-		Message:      "Test message",
-		Status:       models.StatusNew,
-		Solution:     "Test solution",
-		CanselReason: "Test reason",
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
-	}
-
-	_, err = repo.Update(nonExistentAppeal)
-	if err == nil {
-		t.Errorf("Expected error when updating non-existent appeal")
+	if started, err := repo.FindByStatus(models.StatusInProgress); err != nil || len(started) != appeals {
+		t.Errorf("Expected every appeal to be started, got %d, %v", len(started), err)
 	}
 }
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_appeals/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const requesterColumns = "id, tenant_id, name, email, phone, address, preferred_channel, subject, created_at, updated_at"
+
+func requesterNotFound(id string) error {
+	return models.NewError(models.ErrNotFound, "requester_not_found", "requester %s not found", id)
+}
+
+func requesterExists() error {
+	return models.NewError(models.ErrConflict, "requester_exists", "a requester with this email or phone already exists")
+}
+
+// isUniqueViolation reports whether err is SQLite's or PostgreSQL's
+// unique constraint error.
+func isUniqueViolation(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "UNIQUE constraint failed") ||
+		strings.Contains(err.Error(), "SQLSTATE 23505"))
+}
+
+func (r *AppealRepository) AddRequester(requester *models.Requester) error {
+	requester.ID = uuid.New().String()
+	now := time.Now()
+	if requester.CreatedAt.IsZero() {
+		requester.CreatedAt = now
+	}
+	if requester.UpdatedAt.IsZero() {
+		requester.UpdatedAt = now
+	}
+	requester.CreatedAt = requester.CreatedAt.UTC()
+	requester.UpdatedAt = requester.UpdatedAt.UTC()
+	requester.TenantID = r.tenantOf(requester.TenantID)
+
+	_, err := r.conn().Exec(r.rebind(
+		"INSERT INTO requesters ("+requesterColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"),
+		requester.ID,
+		requester.TenantID,
+		requester.Name,
+		requester.Email,
+		requester.Phone,
+		requester.Address,
+		requester.PreferredChannel,
+		requester.Subject,
+		requester.CreatedAt,
+		requester.UpdatedAt,
+	)
+	if isUniqueViolation(err) {
+		return requesterExists()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert requester: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AppealRepository) UpdateRequester(requester *models.Requester) error {
+	requester.UpdatedAt = time.Now().UTC()
+
+	where, args := r.scope("tenant_id", []string{"id = ?"}, []any{requester.ID})
+	result, err := r.conn().Exec(r.rebind(
+		"UPDATE requesters SET name = ?, email = ?, phone = ?, address = ?, preferred_channel = ?, updated_at = ?"+whereClause(where)),
+		append([]any{
+			requester.Name,
+			requester.Email,
+			requester.Phone,
+			requester.Address,
+			requester.PreferredChannel,
+			requester.UpdatedAt,
+		}, args...)...)
+	if isUniqueViolation(err) {
+		return requesterExists()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update requester: %w", err)
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		return requesterNotFound(requester.ID)
+	}
+
+	return nil
+}
+
+func (r *AppealRepository) FindRequester(id string) (*models.Requester, error) {
+	where, args := r.scope("tenant_id", []string{"id = ?"}, []any{id})
+	requester, err := scanRequester(r.conn().QueryRow(r.rebind(
+		"SELECT "+requesterColumns+" FROM requesters"+whereClause(where)), args...))
+	if err == sql.ErrNoRows {
+		return nil, requesterNotFound(id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find requester: %w", err)
+	}
+	return requester, nil
+}
+
+func (r *AppealRepository) FindRequesterByContact(email, phone string) (*models.Requester, error) {
+	for _, contact := range []struct{ column, value string }{{"email", email}, {"phone", phone}} {
+		if contact.value == "" {
+			continue
+		}
+		where, args := r.scope("tenant_id", []string{contact.column + " = ?"}, []any{contact.value})
+		requester, err := scanRequester(r.conn().QueryRow(r.rebind(
+			"SELECT "+requesterColumns+" FROM requesters"+whereClause(where)+" ORDER BY created_at LIMIT 1"), args...))
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find requester: %w", err)
+		}
+		return requester, nil
+	}
+	return nil, models.NewError(models.ErrNotFound, "requester_not_found", "no requester with these contact details")
+}
+
+func scanRequester(row scanner) (*models.Requester, error) {
+	requester := &models.Requester{}
+	err := row.Scan(
+		&requester.ID,
+		&requester.TenantID,
+		&requester.Name,
+		&requester.Email,
+		&requester.Phone,
+		&requester.Address,
+		&requester.PreferredChannel,
+		&requester.Subject,
+		&requester.CreatedAt,
+		&requester.UpdatedAt,
+	)
+	return requester, err
+}
@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go_appeals/internal/models"
+	"go_appeals/internal/search"
+)
+
+// appealSearchFTS is created by the migrator on SQLite builds with FTS5
+// (go build -tags sqlite_fts5); without it searches fall back to scanning
+// appeal_search.
+const appealSearchFTS = `
+CREATE VIRTUAL TABLE appeal_search_fts USING fts5(
+	theme, message, solution, words,
+	content = 'appeal_search', content_rowid = 'id',
+	tokenize = 'unicode61 remove_diacritics 0'
+);
+CREATE TRIGGER appeal_search_fts_insert AFTER INSERT ON appeal_search BEGIN
+	INSERT INTO appeal_search_fts (rowid, theme, message, solution, words)
+	VALUES (new.id, new.theme, new.message, new.solution, new.words);
+END;
+CREATE TRIGGER appeal_search_fts_delete AFTER DELETE ON appeal_search BEGIN
+	INSERT INTO appeal_search_fts (appeal_search_fts, rowid, theme, message, solution, words)
+	VALUES ('delete', old.id, old.theme, old.message, old.solution, old.words);
+END;
+CREATE TRIGGER appeal_search_fts_update AFTER UPDATE ON appeal_search BEGIN
+	INSERT INTO appeal_search_fts (appeal_search_fts, rowid, theme, message, solution, words)
+	VALUES ('delete', old.id, old.theme, old.message, old.solution, old.words);
+	INSERT INTO appeal_search_fts (rowid, theme, message, solution, words)
+	VALUES (new.id, new.theme, new.message, new.solution, new.words);
+END;
+INSERT INTO appeal_search_fts (appeal_search_fts) VALUES ('rebuild');
+`
+
+// indexAppeal writes the analyzed form of appeal to appeal_search.
+func (r *AppealRepository) indexAppeal(appeal *models.Appeal) error {
+	_, err := r.conn().Exec(r.rebind(
+		"INSERT INTO appeal_search (appeal_id, theme, message, solution, words) VALUES (?, ?, ?, ?, ?) "+
+			"ON CONFLICT (appeal_id) DO UPDATE SET theme = excluded.theme, message = excluded.message, solution = excluded.solution, words = excluded.words"),
+		appeal.ID,
+		search.Stems(appeal.Theme),
+		search.Stems(appeal.Message),
+		search.Stems(appeal.Solution),
+		search.Words(appeal.Theme+" "+appeal.Message+" "+appeal.Solution),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update search index: %w", err)
+	}
+	return nil
+}
+
+func (r *AppealRepository) Search(q search.Query, limit, offset int) ([]*models.SearchResult, error) {
+	if r.dialect == dialectPostgres {
+		return r.searchPostgres(q, limit, offset)
+	}
+
+	var fts int
+	err := r.conn().QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'appeal_search_fts'").Scan(&fts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up search index: %w", err)
+	}
+	if fts > 0 {
+		return r.searchFTS5(q, limit, offset)
+	}
+	return r.searchScan(q, limit, offset)
+}
+
+func (r *AppealRepository) searchFTS5(q search.Query, limit, offset int) ([]*models.SearchResult, error) {
+	clauses := make([]string, len(q.Clauses))
+	for i, clause := range q.Clauses {
+		if clause.Prefix {
+			clauses[i] = `words : "` + clause.Terms[0] + `" *`
+		} else {
+			clauses[i] = `{theme message solution} : "` + strings.Join(clause.Terms, " ") + `"`
+		}
+	}
+
+	weights := strings.Join([]string{
+		formatWeight(search.ThemeWeight),
+		formatWeight(search.MessageWeight),
+		formatWeight(search.SolutionWeight),
+		formatWeight(search.WordsWeight),
+	}, ", ")
+
+	where, args := r.scope("a.tenant_id", []string{"appeal_search_fts MATCH ?"}, []any{strings.Join(clauses, " AND ")})
+	return r.querySearchResults(
+		"SELECT "+qualifiedAppealColumns("a")+", -bm25(appeal_search_fts, "+weights+") AS rank "+
+			"FROM appeal_search_fts JOIN appeal_search s ON s.id = appeal_search_fts.rowid JOIN appeals a ON a.id = s.appeal_id"+
+			whereClause(where)+" ORDER BY rank DESC, a.created_at DESC, a.id LIMIT ? OFFSET ?",
+		append(args, limit, offset)...)
+}
+
+func (r *AppealRepository) searchPostgres(q search.Query, limit, offset int) ([]*models.SearchResult, error) {
+	clauses := make([]string, len(q.Clauses))
+	for i, clause := range q.Clauses {
+		if clause.Prefix {
+			clauses[i] = "'" + clause.Terms[0] + "':*"
+			continue
+		}
+		terms := make([]string, len(clause.Terms))
+		for j, term := range clause.Terms {
+			terms[j] = "'" + term + "'"
+		}
+		clauses[i] = "(" + strings.Join(terms, " <-> ") + ")"
+	}
+
+	where, args := r.scope("a.tenant_id", []string{"s.document @@ q"}, nil)
+	return r.querySearchResults(
+		"SELECT "+qualifiedAppealColumns("a")+", ts_rank_cd(s.document, q) AS rank "+
+			"FROM appeal_search s JOIN appeals a ON a.id = s.appeal_id, to_tsquery('simple', ?) q"+
+			whereClause(where)+" ORDER BY rank DESC, a.created_at DESC, a.id LIMIT ? OFFSET ?",
+		append(append([]any{strings.Join(clauses, " & ")}, args...), limit, offset)...)
+}
+
+// searchScan narrows candidates with LIKE and ranks them in Go. It is the
+// fallback for SQLite builds without FTS5 and reads every matching row.
+func (r *AppealRepository) searchScan(q search.Query, limit, offset int) ([]*models.SearchResult, error) {
+	// Fields are joined with " | " so a phrase cannot span two of them.
+	where, args := r.scope("a.tenant_id", nil, nil)
+	for _, clause := range q.Clauses {
+		if clause.Prefix {
+			where = append(where, "(' ' || s.words) LIKE ?")
+			args = append(args, "% "+clause.Terms[0]+"%")
+		} else {
+			where = append(where, "(' ' || s.theme || ' | ' || s.message || ' | ' || s.solution || ' ') LIKE ?")
+			args = append(args, "% "+strings.Join(clause.Terms, " ")+" %")
+		}
+	}
+
+	rows, err := r.conn().Query(
+		"SELECT "+qualifiedAppealColumns("a")+", s.theme, s.message, s.solution, s.words "+
+			"FROM appeal_search s JOIN appeals a ON a.id = s.appeal_id"+whereClause(where),
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search appeals: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]*models.SearchResult, 0)
+	for rows.Next() {
+		var theme, message, solution, words string
+		appeal, err := scanAppeal(rows, &theme, &message, &solution, &words)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		if rank, ok := search.Score(search.ParseDocument(theme, message, solution, words), q); ok {
+			results = append(results, &models.SearchResult{Appeal: appeal, Rank: rank})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return pageSearchResults(results, limit, offset), nil
+}
+
+func (r *AppealRepository) querySearchResults(query string, args ...any) ([]*models.SearchResult, error) {
+	rows, err := r.conn().Query(r.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search appeals: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]*models.SearchResult, 0)
+	for rows.Next() {
+		result := &models.SearchResult{}
+		result.Appeal, err = scanAppeal(rows, &result.Rank)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// syncSearchIndex creates the FTS5 index where SQLite supports it and
+// analyzes appeals that have no appeal_search row yet, e.g. ones stored
+// before the search migration. It runs after every migrator Up.
+func (m *Migrator) syncSearchIndex(conn *sql.Conn) error {
+	ctx := context.Background()
+
+	exists, err := m.tableExists(conn, "appeal_search")
+	if err != nil || !exists {
+		return err
+	}
+
+	if m.dialect == dialectSQLite {
+		var fts5 bool
+		if err := conn.QueryRowContext(ctx, "SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5); err != nil {
+			return fmt.Errorf("failed to check for FTS5: %w", err)
+		}
+		indexed, err := m.tableExists(conn, "appeal_search_fts")
+		if err != nil {
+			return err
+		}
+		if fts5 && !indexed {
+			if _, err := conn.ExecContext(ctx, appealSearchFTS); err != nil {
+				return fmt.Errorf("failed to create full-text index: %w", err)
+			}
+		}
+		if !fts5 && !indexed {
+			log.Printf("SQLite driver built without FTS5: search scans every matching appeal; build with -tags sqlite_fts5 to index it.")
+		}
+	}
+
+	rows, err := conn.QueryContext(ctx,
+		"SELECT "+qualifiedAppealColumns("a")+" FROM appeals a LEFT JOIN appeal_search s ON s.appeal_id = a.id WHERE s.id IS NULL")
+	if err != nil {
+		return fmt.Errorf("failed to find unindexed appeals: %w", err)
+	}
+	var missing []*models.Appeal
+	for rows.Next() {
+		appeal, err := scanAppeal(rows)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan appeal row: %w", err)
+		}
+		missing = append(missing, appeal)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin search reindex: %w", err)
+	}
+	defer tx.Rollback()
+
+	repo := &AppealRepository{tx: tx, dialect: m.dialect}
+	for _, appeal := range missing {
+		if err := repo.indexAppeal(appeal); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit search reindex: %w", err)
+	}
+
+	log.Printf("Indexed %d appeals for search.", len(missing))
+	return nil
+}
+
+// pageSearchResults sorts results ranked in Go the same way the SQL
+// backends order theirs and cuts out one page.
+func pageSearchResults(results []*models.SearchResult, limit, offset int) []*models.SearchResult {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Rank != b.Rank {
+			return a.Rank > b.Rank
+		}
+		if !a.Appeal.CreatedAt.Equal(b.Appeal.CreatedAt) {
+			return a.Appeal.CreatedAt.After(b.Appeal.CreatedAt)
+		}
+		return a.Appeal.ID < b.Appeal.ID
+	})
+
+	if offset >= len(results) {
+		return make([]*models.SearchResult, 0)
+	}
+	results = results[offset:]
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+func qualifiedAppealColumns(alias string) string {
+	return alias + "." + strings.ReplaceAll(appealColumns, ", ", ", "+alias+".")
+}
+
+func formatWeight(w float64) string {
+	return strconv.FormatFloat(w, 'f', -1, 64)
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"go_appeals/internal/models"
+	"go_appeals/internal/search"
+)
+
+// AppealStore is the storage contract the service layer depends on.
+// Every backend must pass the shared conformance suite in conformance_test.go.
+type AppealStore interface {
+	Save(appeal *models.Appeal) (*models.Appeal, error)
+	Update(appeal *models.Appeal) (*models.Appeal, error)
+	FindByID(id string) (*models.Appeal, error)
+	FindByStatus(statuses ...models.AppealStatus) ([]*models.Appeal, error)
+	GetAll() ([]*models.Appeal, error)
+	// List returns one page of appeals matching filter, see
+	// models.AppealFilter.
+	List(filter models.AppealFilter) (*models.AppealPage, error)
+	// Search returns appeals matching q, best first. Highlights are left
+	// to the caller.
+	Search(q search.Query, limit, offset int) ([]*models.SearchResult, error)
+	SelectAppealsByDates(start, end time.Time) ([]*models.Appeal, error)
+	// CancelInProgressAppeals cancels every appeal in one of statuses in a
+	// single transaction and returns them as saved. cancel makes the change
+	// to each appeal, so that the target status, the reason and the SLA
+	// fields follow the workflow; if it fails, no appeal is changed.
+	CancelInProgressAppeals(statuses []models.AppealStatus, cancel func(appeal *models.Appeal) error) ([]*models.Appeal, error)
+
+	AddHistory(entry *models.StatusHistoryEntry) error
+	GetHistory(appealID string) ([]*models.StatusHistoryEntry, error)
+
+	// AddReminder stores reminder unless one of the same kind for the same
+	// appeal and due time exists, and reports whether it was added.
+	AddReminder(reminder *models.Reminder) (bool, error)
+	ListReminders(filter models.ReminderFilter) ([]*models.Reminder, error)
+
+	// AddEvent appends event to the outbox and sets its Sequence. Events
+	// become visible to readers in Sequence order, so a reader that has seen
+	// an event has seen every one before it.
+	AddEvent(event *models.Event) error
+	// ListEvents returns up to limit events with a Sequence above after, in
+	// order.
+	ListEvents(after int64, limit int) ([]*models.Event, error)
+	// LastEventSequence returns the Sequence of the latest event, or 0.
+	LastEventSequence() (int64, error)
+
+	// AddComment stores a new comment and sets its ID.
+	AddComment(comment *models.Comment) error
+	// FindComment returns a comment with its earlier versions.
+	FindComment(id int64) (*models.Comment, error)
+	// EditComment stores the new Body and EditedAt of comment and keeps
+	// edit, the version it replaces, setting its ID.
+	EditComment(comment *models.Comment, edit *models.CommentEdit) error
+	// ListComments returns the thread of an appeal, oldest first, with
+	// earlier versions. Internal notes are left out unless internal is set.
+	ListComments(appealID string, internal bool) ([]*models.Comment, error)
+
+	// AddAttachment stores the metadata of an attached file and sets its ID.
+	AddAttachment(attachment *models.Attachment) error
+	FindAttachment(id string) (*models.Attachment, error)
+	// ListAttachments returns the files attached to an appeal, oldest first.
+	ListAttachments(appealID string) ([]*models.Attachment, error)
+	// ContentInUse reports whether an attachment in any tenant refers to
+	// the content with the given hash.
+	ContentInUse(sha256 string) (bool, error)
+
+	// AddRequester stores a new requester and sets its ID. A requester with
+	// the same email or phone in the tenant is a requester_exists conflict.
+	AddRequester(requester *models.Requester) error
+	UpdateRequester(requester *models.Requester) error
+	FindRequester(id string) (*models.Requester, error)
+	// FindRequesterByContact returns the requester with the given normalized
+	// email or, failing that, phone. Empty values match nothing.
+	FindRequesterByContact(email, phone string) (*models.Requester, error)
+
+	// FindByTrackingHash returns the appeal whose tracking token has the
+	// given hash.
+	FindByTrackingHash(hash string) (*models.Appeal, error)
+	// FindByNumber returns the appeal with the given registration number.
+	FindByNumber(number string) (*models.Appeal, error)
+	// NextNumber returns the next registration number in series, starting
+	// from 1. Used inside a transaction, the number is only taken if it
+	// commits, so numbers have no gaps.
+	NextNumber(series string) (int64, error)
+
+	// ForTenant returns a view of the store bound to tenant: every query
+	// reads only that tenant's records and new records are stored under it.
+	// The store itself is not bound and sees every tenant; records saved
+	// through it keep their own tenant, or get models.DefaultTenant.
+	ForTenant(tenant string) AppealStore
+
+	// WithTx runs fn against a store bound to a single transaction, which is
+	// committed if fn returns nil and rolled back otherwise. Calling WithTx on
+	// a store that is already transactional joins the outer transaction.
+	WithTx(fn func(tx AppealStore) error) error
+	Close() error
+}
+
+// JobRunStore keeps the run history of scheduled jobs.
+type JobRunStore interface {
+	// StartJobRun stores a new run and sets its ID.
+	StartJobRun(run *models.JobRun) error
+	// FinishJobRun records the outcome of a started run.
+	FinishJobRun(run *models.JobRun) error
+	// ListJobRuns returns the latest runs of job, newest first.
+	ListJobRuns(job string, limit int) ([]*models.JobRun, error)
+}
+
+// WebhookStore keeps webhook subscriptions and their delivery log.
+type WebhookStore interface {
+	// CreateWebhook stores a new subscription and sets its ID.
+	CreateWebhook(sub *models.WebhookSubscription) error
+	FindWebhook(id string) (*models.WebhookSubscription, error)
+	ListWebhooks() ([]*models.WebhookSubscription, error)
+	// DeleteWebhook removes a subscription together with its deliveries.
+	DeleteWebhook(id string) error
+
+	// AddDelivery stores a new delivery and sets its ID.
+	AddDelivery(delivery *models.WebhookDelivery) error
+	// UpdateDelivery records the outcome of an attempt.
+	UpdateDelivery(delivery *models.WebhookDelivery) error
+	FindDelivery(id int64) (*models.WebhookDelivery, error)
+	// ListDeliveries returns the latest deliveries of a subscription, newest
+	// first.
+	ListDeliveries(subscriptionID string, limit int) ([]*models.WebhookDelivery, error)
+	// PendingDeliveries returns the deliveries still to be attempted, oldest
+	// first.
+	PendingDeliveries() ([]*models.WebhookDelivery, error)
+}
+
+// OutboxStore remembers how far each outbox sink has got.
+type OutboxStore interface {
+	// EventOffset returns the Sequence of the last event sink has handled;
+	// ok is false for a sink that has never handled one.
+	EventOffset(sink string) (seq int64, ok bool, err error)
+	SetEventOffset(sink string, seq int64) error
+}
+
+// APIKeyStore keeps the API keys callers authenticate with.
+type APIKeyStore interface {
+	// CreateAPIKey stores a new key and sets its ID.
+	CreateAPIKey(key *models.APIKey) error
+	FindAPIKey(id string) (*models.APIKey, error)
+	FindAPIKeyByHash(hash string) (*models.APIKey, error)
+	// ListAPIKeys returns every key, revoked ones included, oldest first.
+	ListAPIKeys() ([]*models.APIKey, error)
+	RevokeAPIKey(id string, at time.Time) error
+	// TouchAPIKey records that a key was used at the given time.
+	TouchAPIKey(id string, at time.Time) error
+}
+
+// cancelInProgress implements CancelInProgressAppeals for both backends.
+func cancelInProgress(store AppealStore, statuses []models.AppealStatus, cancel func(appeal *models.Appeal) error) ([]*models.Appeal, error) {
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	var cancelled []*models.Appeal
+	err := store.WithTx(func(tx AppealStore) error {
+		appeals, err := tx.FindByStatus(statuses...)
+		if err != nil {
+			return err
+		}
+		for _, appeal := range appeals {
+			if err := cancel(appeal); err != nil {
+				return err
+			}
+			updated, err := tx.Update(appeal)
+			if err != nil {
+				return err
+			}
+			cancelled = append(cancelled, updated)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cancelled, nil
+}
+
+// Store is everything a backend provides.
+type Store interface {
+	AppealStore
+	JobRunStore
+	WebhookStore
+	OutboxStore
+	APIKeyStore
+}
+
+var (
+	_ Store = (*AppealRepository)(nil)
+	_ Store = (*MemoryAppealRepository)(nil)
+)
+
+// Open picks a backend from the DSN: postgres:// and postgresql:// URLs select
+// PostgreSQL, "memory" selects the in-memory store and anything else is
+// treated as a SQLite file path (an optional sqlite:// prefix is stripped).
+func Open(dsn string) (Store, error) {
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		repo, err := NewPostgresAppealRepository(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	case dsn == "memory":
+		return NewMemoryAppealRepository(), nil
+	default:
+		repo, err := NewAppealRepository(strings.TrimPrefix(dsn, "sqlite://"))
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	}
+}
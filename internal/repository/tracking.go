@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"go_appeals/internal/models"
+)
+
+func trackingNotFound() error {
+	return models.NewError(models.ErrNotFound, "tracking_token_not_found", "no appeal with this tracking token")
+}
+
+func numberTaken(number string) error {
+	return models.NewError(models.ErrConflict, "number_taken", "registration number %s is already taken", number)
+}
+
+func (r *AppealRepository) FindByTrackingHash(hash string) (*models.Appeal, error) {
+	if hash == "" {
+		return nil, trackingNotFound()
+	}
+	where, args := r.scope("tenant_id", []string{"tracking_token_hash = ?"}, []any{hash})
+	appeal, err := scanAppeal(r.conn().QueryRow(r.rebind(
+		"SELECT "+appealColumns+" FROM appeals"+whereClause(where)), args...))
+	if err == sql.ErrNoRows {
+		return nil, trackingNotFound()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find appeal: %w", err)
+	}
+	return appeal, nil
+}
+
+func (r *AppealRepository) FindByNumber(number string) (*models.Appeal, error) {
+	if number == "" {
+		return nil, models.AppealNotFound(number)
+	}
+	where, args := r.scope("tenant_id", []string{"number = ?"}, []any{number})
+	appeal, err := scanAppeal(r.conn().QueryRow(r.rebind(
+		"SELECT "+appealColumns+" FROM appeals"+whereClause(where)), args...))
+	if err == sql.ErrNoRows {
+		return nil, models.AppealNotFound(number)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find appeal: %w", err)
+	}
+	return appeal, nil
+}
+
+// NextNumber counts in a row of appeal_number_sequences. The upsert locks
+// the row until the transaction ends, so concurrent appeals wait for each
+// other instead of sharing or skipping a number.
+func (r *AppealRepository) NextNumber(series string) (int64, error) {
+	var next int64
+	err := r.conn().QueryRow(r.rebind(
+		"INSERT INTO appeal_number_sequences (tenant_id, series, last_number) VALUES (?, ?, 1) "+
+			"ON CONFLICT (tenant_id, series) DO UPDATE SET last_number = appeal_number_sequences.last_number + 1 "+
+			"RETURNING last_number"),
+		r.tenantOf(""), series,
+	).Scan(&next)
+	if err != nil {
+		return 0, fmt.Errorf("failed to take the next number: %w", err)
+	}
+	return next, nil
+}
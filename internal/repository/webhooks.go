@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_appeals/internal/models"
+
+	"github.com/google/uuid"
+)
+
+const deliveryColumns = "id, subscription_id, event_id, event_type, payload, status, attempts, response_status, error, created_at, next_attempt_at, finished_at"
+
+func webhookNotFound(id string) *models.Error {
+	return models.NewError(models.ErrNotFound, "webhook_not_found", "webhook %s not found", id)
+}
+
+func deliveryNotFound(id int64) *models.Error {
+	return models.NewError(models.ErrNotFound, "delivery_not_found", "delivery %d not found", id)
+}
+
+func (r *AppealRepository) CreateWebhook(sub *models.WebhookSubscription) error {
+	sub.ID = uuid.New().String()
+	if sub.CreatedAt.IsZero() {
+		sub.CreatedAt = time.Now()
+	}
+	sub.TenantID = r.tenantOf(sub.TenantID)
+
+	_, err := r.conn().Exec(r.rebind(
+		"INSERT INTO webhook_subscriptions (id, tenant_id, url, secret, events, active, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)"),
+		sub.ID,
+		sub.TenantID,
+		sub.URL,
+		sub.Secret,
+		strings.Join(sub.Events, ","),
+		sub.Active,
+		sub.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AppealRepository) FindWebhook(id string) (*models.WebhookSubscription, error) {
+	sub, err := scanWebhook(r.conn().QueryRow(r.rebind(
+		"SELECT id, tenant_id, url, secret, events, active, created_at FROM webhook_subscriptions WHERE id = ?"), id))
+	if err == sql.ErrNoRows {
+		return nil, webhookNotFound(id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find webhook: %w", err)
+	}
+	return sub, nil
+}
+
+func (r *AppealRepository) ListWebhooks() ([]*models.WebhookSubscription, error) {
+	rows, err := r.conn().Query("SELECT id, tenant_id, url, secret, events, active, created_at FROM webhook_subscriptions ORDER BY created_at, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	subs := make([]*models.WebhookSubscription, 0)
+	for rows.Next() {
+		sub, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook row: %w", err)
+		}
+		subs = append(subs, sub)
+	}
+
+	return subs, rows.Err()
+}
+
+func (r *AppealRepository) DeleteWebhook(id string) error {
+	return r.atomically(func(tx *AppealRepository) error {
+		if _, err := tx.conn().Exec(r.rebind("DELETE FROM webhook_deliveries WHERE subscription_id = ?"), id); err != nil {
+			return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+		}
+		result, err := tx.conn().Exec(r.rebind("DELETE FROM webhook_subscriptions WHERE id = ?"), id)
+		if err != nil {
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+
+		rowsAffected, _ := result.RowsAffected()
+		if rowsAffected == 0 {
+			return webhookNotFound(id)
+		}
+		return nil
+	})
+}
+
+func (r *AppealRepository) AddDelivery(delivery *models.WebhookDelivery) error {
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now()
+	}
+
+	err := r.conn().QueryRow(r.rebind(
+		"INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, status, attempts, response_status, error, created_at, next_attempt_at, finished_at) "+
+			"VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id"),
+		delivery.SubscriptionID,
+		delivery.EventID,
+		delivery.EventType,
+		string(delivery.Payload),
+		delivery.Status,
+		delivery.Attempts,
+		delivery.ResponseStatus,
+		delivery.Error,
+		delivery.CreatedAt.UTC(),
+		nullTime(delivery.NextAttemptAt),
+		nullTime(delivery.FinishedAt),
+	).Scan(&delivery.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert delivery: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AppealRepository) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	result, err := r.conn().Exec(r.rebind(
+		"UPDATE webhook_deliveries SET status = ?, attempts = ?, response_status = ?, error = ?, next_attempt_at = ?, finished_at = ? WHERE id = ?"),
+		delivery.Status,
+		delivery.Attempts,
+		delivery.ResponseStatus,
+		delivery.Error,
+		nullTime(delivery.NextAttemptAt),
+		nullTime(delivery.FinishedAt),
+		delivery.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update delivery: %w", err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return deliveryNotFound(delivery.ID)
+	}
+	return nil
+}
+
+func (r *AppealRepository) FindDelivery(id int64) (*models.WebhookDelivery, error) {
+	delivery, err := scanDelivery(r.conn().QueryRow(r.rebind(
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = ?"), id))
+	if err == sql.ErrNoRows {
+		return nil, deliveryNotFound(id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+func (r *AppealRepository) ListDeliveries(subscriptionID string, limit int) ([]*models.WebhookDelivery, error) {
+	return r.queryDeliveries(
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE subscription_id = ? ORDER BY id DESC LIMIT ?",
+		subscriptionID, limit)
+}
+
+func (r *AppealRepository) PendingDeliveries() ([]*models.WebhookDelivery, error) {
+	return r.queryDeliveries(
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE status = ? ORDER BY id",
+		models.DeliveryPending)
+}
+
+func (r *AppealRepository) queryDeliveries(query string, args ...any) ([]*models.WebhookDelivery, error) {
+	rows, err := r.conn().Query(r.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan delivery row: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func scanWebhook(row scanner) (*models.WebhookSubscription, error) {
+	sub := &models.WebhookSubscription{}
+	var events string
+	err := row.Scan(
+		&sub.ID,
+		&sub.TenantID,
+		&sub.URL,
+		&sub.Secret,
+		&events,
+		&sub.Active,
+		&sub.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if events != "" {
+		sub.Events = strings.Split(events, ",")
+	}
+	return sub, nil
+}
+
+func scanDelivery(row scanner) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	var payload []byte
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.SubscriptionID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.ResponseStatus,
+		&delivery.Error,
+		&delivery.CreatedAt,
+		&delivery.NextAttemptAt,
+		&delivery.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = payload
+	return delivery, nil
+}
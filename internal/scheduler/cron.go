@@ -0,0 +1,165 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a job runs next.
+type Schedule interface {
+	// Next returns the first activation strictly after t.
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule reads a five-field cron expression (minute, hour, day of
+// month, month, day of week) or one of @yearly, @monthly, @weekly, @daily,
+// @hourly and "@every <duration>". Fields accept *, lists, ranges and
+// steps; Sunday is 0 or 7. Times are interpreted in loc.
+func ParseSchedule(spec string, loc *time.Location) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if every, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(every))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("invalid schedule %q, @every needs a duration of at least 1s", spec)
+		}
+		return interval(d), nil
+	}
+
+	switch spec {
+	case "@yearly", "@annually":
+		spec = "0 0 1 1 *"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@hourly":
+		spec = "0 * * * *"
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q, expected 5 fields", spec)
+	}
+
+	c := &cron{loc: loc}
+	bounds := []struct {
+		dest     *uint64
+		min, max int
+	}{
+		{&c.minute, 0, 59},
+		{&c.hour, 0, 23},
+		{&c.dom, 1, 31},
+		{&c.month, 1, 12},
+		{&c.dow, 0, 7},
+	}
+	for i, field := range fields {
+		bits, err := parseField(field, bounds[i].min, bounds[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		*bounds[i].dest = bits
+	}
+	// 7 is another name for Sunday.
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.anyDOM = fields[2] == "*"
+	c.anyDOW = fields[4] == "*"
+
+	return c, nil
+}
+
+type interval time.Duration
+
+func (i interval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
+
+// cron keeps each field as a bit set of the values it matches.
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	anyDOM, anyDOW                bool
+	loc                           *time.Location
+}
+
+func (c *cron) Next(t time.Time) time.Time {
+	t = t.In(c.loc).Truncate(time.Minute).Add(time.Minute)
+
+	// A matching time exists within a few years unless the expression asks
+	// for an impossible date such as February 30th.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches follows cron: when both day fields are restricted, either may
+// match.
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.anyDOM && c.anyDOW:
+		return true
+	case c.anyDOM:
+		return dow
+	case c.anyDOW:
+		return dom
+	}
+	return dom || dow
+}
+
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if before, after, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(after)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng, step = before, n
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return 0, fmt.Errorf("invalid value in %q", part)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return 0, fmt.Errorf("invalid range in %q", part)
+				}
+			} else if step > 1 {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	t.Parallel()
+
+	msk, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatalf("Failed to load location: %v", err)
+	}
+	// Четверг, 15 января 2026 года.
+	from := time.Date(2026, time.January, 15, 10, 20, 30, 0, msk)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, time.January, 15, 10, 30, 0, 0, msk)},
+		{"0 * * * *", time.Date(2026, time.January, 15, 11, 0, 0, 0, msk)},
+		{"30 3 * * *", time.Date(2026, time.January, 16, 3, 30, 0, 0, msk)},
+		{"0 9-18/3 * * 1-5", time.Date(2026, time.January, 15, 12, 0, 0, 0, msk)},
+		{"0 9 * * 0", time.Date(2026, time.January, 18, 9, 0, 0, 0, msk)},
+		{"0 9 * * 7", time.Date(2026, time.January, 18, 9, 0, 0, 0, msk)},
+		{"0 0 1,15 * *", time.Date(2026, time.February, 1, 0, 0, 0, 0, msk)},
+		{"0 0 31 * *", time.Date(2026, time.January, 31, 0, 0, 0, 0, msk)},
+		{"0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, msk)},
+		// День месяца или день недели: либо 13-е, либо пятница.
+		{"0 0 13 * 5", time.Date(2026, time.January, 16, 0, 0, 0, 0, msk)},
+		{"@daily", time.Date(2026, time.January, 16, 0, 0, 0, 0, msk)},
+		{"@monthly", time.Date(2026, time.February, 1, 0, 0, 0, 0, msk)},
+		{"@every 90m", from.Add(90 * time.Minute)},
+	}
+
+	for _, tt := range tests {
+		schedule, err := ParseSchedule(tt.spec, msk)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.spec, err)
+			continue
+		}
+		if got := schedule.Next(from); !got.Equal(tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.spec, tt.want, got)
+		}
+	}
+
+	if got := mustParse(t, "0 0 30 2 *").Next(from); !got.IsZero() {
+		t.Errorf("Expected no activation on February 30, got %v", got)
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "5-1 * * * *", "*/0 * * * *", "@often", "@every 10ms", "@every soon"} {
+		if _, err := ParseSchedule(spec, time.UTC); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func mustParse(t *testing.T, spec string) Schedule {
+	t.Helper()
+	schedule, err := ParseSchedule(spec, time.UTC)
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", spec, err)
+	}
+	return schedule
+}
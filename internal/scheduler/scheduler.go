@@ -0,0 +1,367 @@
+package scheduler
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+)
+
+//go:embed default.json
+var defaultDefinition []byte
+
+// Handler runs one job. The returned summary is stored with the run.
+type Handler func(ctx context.Context, params map[string]string) (string, error)
+
+// JobDefinition binds a registered handler to a schedule. A job without a
+// schedule only runs when triggered by hand.
+type JobDefinition struct {
+	Name     string            `json:"name"`
+	Schedule string            `json:"schedule,omitempty"`
+	Params   map[string]string `json:"params,omitempty"`
+}
+
+// Definition lists the jobs to run. Schedules are read in TimeZone, or in
+// the location given with WithLocation when it is empty.
+type Definition struct {
+	TimeZone string          `json:"time_zone,omitempty"`
+	Jobs     []JobDefinition `json:"jobs"`
+}
+
+// JobStatus is a job as reported by Jobs.
+type JobStatus struct {
+	Name     string            `json:"name"`
+	Schedule string            `json:"schedule,omitempty"`
+	Params   map[string]string `json:"params,omitempty"`
+	NextRun  *time.Time        `json:"next_run,omitempty"`
+	Running  bool              `json:"running"`
+}
+
+type Option func(s *Scheduler)
+
+// WithHandler registers a handler that definitions can refer to by name.
+func WithHandler(name string, handler Handler) Option {
+	return func(s *Scheduler) { s.handlers[name] = handler }
+}
+
+// WithHandlers registers several handlers at once.
+func WithHandlers(handlers map[string]Handler) Option {
+	return func(s *Scheduler) {
+		for name, handler := range handlers {
+			s.handlers[name] = handler
+		}
+	}
+}
+
+// WithLocation sets the time zone of schedules for definitions that do not
+// name one. The default is UTC.
+func WithLocation(loc *time.Location) Option {
+	return func(s *Scheduler) { s.loc = loc }
+}
+
+type job struct {
+	def      JobDefinition
+	schedule Schedule
+	handler  Handler
+	running  bool
+	next     time.Time
+}
+
+// Scheduler runs jobs on their schedules and on demand, one run of each job
+// at a time, and records every run in the store.
+type Scheduler struct {
+	def      Definition
+	store    repository.JobRunStore
+	handlers map[string]Handler
+	loc      *time.Location
+	jobs     []*job
+	byName   map[string]*job
+
+	mu       sync.Mutex
+	started  bool
+	stopped  bool
+	stop     chan struct{}
+	runs     sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopOnce sync.Once
+}
+
+func New(def Definition, store repository.JobRunStore, opts ...Option) (*Scheduler, error) {
+	s := &Scheduler{
+		def:      def,
+		store:    store,
+		handlers: make(map[string]Handler),
+		loc:      time.UTC,
+		byName:   make(map[string]*job, len(def.Jobs)),
+		stop:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	if def.TimeZone != "" {
+		loc, err := time.LoadLocation(def.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("scheduler time zone: %w", err)
+		}
+		s.loc = loc
+	}
+
+	for _, jd := range def.Jobs {
+		if jd.Name == "" {
+			return nil, fmt.Errorf("scheduler job without a name")
+		}
+		if _, ok := s.byName[jd.Name]; ok {
+			return nil, fmt.Errorf("scheduler job %s is defined twice", jd.Name)
+		}
+		handler, ok := s.handlers[jd.Name]
+		if !ok {
+			return nil, fmt.Errorf("scheduler job %s has no handler", jd.Name)
+		}
+
+		j := &job{def: jd, handler: handler}
+		if jd.Schedule != "" {
+			schedule, err := ParseSchedule(jd.Schedule, s.loc)
+			if err != nil {
+				return nil, fmt.Errorf("scheduler job %s: %w", jd.Name, err)
+			}
+			j.schedule = schedule
+		}
+		s.jobs = append(s.jobs, j)
+		s.byName[jd.Name] = j
+	}
+
+	return s, nil
+}
+
+func Parse(data []byte, store repository.JobRunStore, opts ...Option) (*Scheduler, error) {
+	var def Definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("failed to parse scheduler definition: %w", err)
+	}
+	return New(def, store, opts...)
+}
+
+// Load reads a JSON scheduler definition from path.
+func Load(path string, store repository.JobRunStore, opts ...Option) (*Scheduler, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scheduler definition: %w", err)
+	}
+	return Parse(data, store, opts...)
+}
+
+// Default builds the scheduler from the built-in default.json. The handlers
+// it refers to must be registered with opts.
+func Default(store repository.JobRunStore, opts ...Option) (*Scheduler, error) {
+	return Parse(defaultDefinition, store, opts...)
+}
+
+func (s *Scheduler) Definition() Definition {
+	return s.def
+}
+
+// Start begins running jobs on their schedules. Manual triggers work
+// whether or not the scheduler was started.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started || s.stopped {
+		return
+	}
+	s.started = true
+
+	for _, j := range s.jobs {
+		if j.schedule != nil {
+			s.runs.Add(1)
+			go s.loop(j)
+		}
+	}
+}
+
+// Shutdown stops scheduling new runs and waits for the ones in progress.
+// If ctx expires first, the runs' context is cancelled and ctx.Err() is
+// returned.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.stopOnce.Do(func() { close(s.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+
+	defer s.cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Trigger starts a run of the named job in the background and returns it.
+func (s *Scheduler) Trigger(name string) (*models.JobRun, error) {
+	j, err := s.job(name)
+	if err != nil {
+		return nil, err
+	}
+
+	run, err := s.begin(j, models.TriggerManual)
+	if err != nil {
+		return nil, err
+	}
+	started := *run
+	go s.execute(j, run)
+
+	return &started, nil
+}
+
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		status := JobStatus{
+			Name:     j.def.Name,
+			Schedule: j.def.Schedule,
+			Params:   j.def.Params,
+			Running:  j.running,
+		}
+		if !j.next.IsZero() {
+			next := j.next
+			status.NextRun = &next
+		}
+		jobs = append(jobs, status)
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].Name < jobs[k].Name })
+	return jobs
+}
+
+// Runs returns the latest runs of the named job, newest first; a zero
+// limit means the default page size.
+func (s *Scheduler) Runs(name string, limit int) ([]*models.JobRun, error) {
+	if _, err := s.job(name); err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		limit = models.DefaultPageSize
+	}
+	if limit < 0 || limit > models.MaxPageSize {
+		return nil, models.NewError(models.ErrValidation, "invalid_limit", "limit must be between 1 and %d", models.MaxPageSize)
+	}
+	return s.store.ListJobRuns(name, limit)
+}
+
+func (s *Scheduler) job(name string) (*job, error) {
+	j, ok := s.byName[name]
+	if !ok {
+		return nil, models.NewError(models.ErrNotFound, "job_not_found", "job %s not found", name)
+	}
+	return j, nil
+}
+
+func (s *Scheduler) loop(j *job) {
+	defer s.runs.Done()
+
+	for {
+		next := j.schedule.Next(time.Now())
+		s.mu.Lock()
+		j.next = next
+		s.mu.Unlock()
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		run, err := s.begin(j, models.TriggerSchedule)
+		if err != nil {
+			log.Printf("Job %s skipped: %v", j.def.Name, err)
+			continue
+		}
+		s.execute(j, run)
+	}
+}
+
+// begin marks the job as running and records the start of a run. Every
+// successful begin must be followed by execute.
+func (s *Scheduler) begin(j *job, trigger models.JobTrigger) (*models.JobRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return nil, models.NewError(models.ErrConflict, "scheduler_stopped", "the scheduler is shutting down")
+	}
+	if j.running {
+		return nil, models.NewError(models.ErrConflict, "job_running", "job %s is already running", j.def.Name)
+	}
+
+	run := &models.JobRun{
+		Job:         j.def.Name,
+		TriggeredBy: trigger,
+		Status:      models.JobRunning,
+		StartedAt:   time.Now(),
+	}
+	if err := s.store.StartJobRun(run); err != nil {
+		return nil, fmt.Errorf("failed to record job run: %w", err)
+	}
+
+	j.running = true
+	s.runs.Add(1)
+	return run, nil
+}
+
+func (s *Scheduler) execute(j *job, run *models.JobRun) {
+	defer s.runs.Done()
+
+	result, err := s.call(j)
+
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.Result = result
+	run.Status = models.JobSucceeded
+	if err != nil {
+		run.Status = models.JobFailed
+		run.Error = err.Error()
+		log.Printf("Job %s failed: %v", j.def.Name, err)
+	} else {
+		log.Printf("Job %s finished: %s", j.def.Name, result)
+	}
+	if err := s.store.FinishJobRun(run); err != nil {
+		log.Printf("Failed to record the end of job %s: %v", j.def.Name, err)
+	}
+
+	s.mu.Lock()
+	j.running = false
+	s.mu.Unlock()
+}
+
+func (s *Scheduler) call(j *job) (result string, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return j.handler(s.ctx, j.def.Params)
+}
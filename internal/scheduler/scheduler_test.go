@@ -0,0 +1,211 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+)
+
+func noop(ctx context.Context, params map[string]string) (string, error) {
+	return "done", nil
+}
+
+// waitForRun polls the job history until the latest run of name finishes.
+func waitForRun(t *testing.T, s *Scheduler, name string) *models.JobRun {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		runs, err := s.Runs(name, 1)
+		if err != nil {
+			t.Fatalf("Failed to list runs: %v", err)
+		}
+		if len(runs) == 1 && runs[0].Status != models.JobRunning {
+			return runs[0]
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish in time", name)
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	store := repository.NewMemoryAppealRepository()
+	handlers := map[string]Handler{"cancel_stale": noop, "escalate_breaches": noop, "generate_reminders": noop}
+
+	s, err := Default(store, WithHandlers(handlers))
+	if err != nil {
+		t.Fatalf("Failed to build the default scheduler: %v", err)
+	}
+	if jobs := s.Jobs(); len(jobs) != 3 || jobs[0].Name != "cancel_stale" || jobs[0].Params["after"] != "720h" {
+		t.Errorf("Unexpected default jobs %+v", jobs)
+	}
+
+	tests := []struct {
+		name string
+		def  string
+		want string
+	}{
+		{"NoHandler", `{"jobs": [{"name": "missing"}]}`, "has no handler"},
+		{"Duplicate", `{"jobs": [{"name": "cancel_stale"}, {"name": "cancel_stale"}]}`, "defined twice"},
+		{"Unnamed", `{"jobs": [{"schedule": "@daily"}]}`, "without a name"},
+		{"Schedule", `{"jobs": [{"name": "cancel_stale", "schedule": "daily"}]}`, "invalid schedule"},
+		{"TimeZone", `{"time_zone": "Mars/Olympus", "jobs": []}`, "time zone"},
+		{"Malformed", `{"jobs":`, "failed to parse"},
+	}
+	for _, tt := range tests {
+		_, err := Parse([]byte(tt.def), store, WithHandlers(handlers))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	s, err := New(Definition{Jobs: []JobDefinition{
+		{Name: "slow", Params: map[string]string{"greeting": "hello"}},
+		{Name: "broken"},
+	}}, repository.NewMemoryAppealRepository(),
+		WithHandler("slow", func(ctx context.Context, params map[string]string) (string, error) {
+			<-release
+			return params["greeting"], nil
+		}),
+		WithHandler("broken", func(ctx context.Context, params map[string]string) (string, error) {
+			panic("boom")
+		}))
+	if err != nil {
+		t.Fatalf("Failed to create scheduler: %v", err)
+	}
+
+	run, err := s.Trigger("slow")
+	if err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	if run.ID == 0 || run.Status != models.JobRunning || run.TriggeredBy != models.TriggerManual {
+		t.Errorf("Expected a running manual run, got %+v", run)
+	}
+	if jobs := s.Jobs(); !jobs[1].Running {
+		t.Errorf("Expected the job to be reported as running, got %+v", jobs)
+	}
+
+	// Второй запуск, пока первый не закончился, не допускается.
+	if _, err := s.Trigger("slow"); models.ErrorCode(err) != "job_running" || !errors.Is(err, models.ErrConflict) {
+		t.Errorf("Expected job_running conflict, got %v", err)
+	}
+	if _, err := s.Trigger("missing"); models.ErrorCode(err) != "job_not_found" {
+		t.Errorf("Expected job_not_found, got %v", err)
+	}
+	if _, err := s.Runs("missing", 0); models.ErrorCode(err) != "job_not_found" {
+		t.Errorf("Expected job_not_found, got %v", err)
+	}
+	if _, err := s.Runs("slow", 1000); models.ErrorCode(err) != "invalid_limit" {
+		t.Errorf("Expected invalid_limit, got %v", err)
+	}
+
+	close(release)
+	finished := waitForRun(t, s, "slow")
+	if finished.Status != models.JobSucceeded || finished.Result != "hello" || finished.FinishedAt == nil {
+		t.Errorf("Expected a succeeded run with the job's result, got %+v", finished)
+	}
+
+	if _, err := s.Trigger("broken"); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	if failed := waitForRun(t, s, "broken"); failed.Status != models.JobFailed || failed.Error != "panic: boom" {
+		t.Errorf("Expected a panicking job to fail, got %+v", failed)
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("Unexpected shutdown error: %v", err)
+	}
+}
+
+func TestScheduledRuns(t *testing.T) {
+	t.Parallel()
+
+	s, err := New(Definition{Jobs: []JobDefinition{{Name: "tick", Schedule: "@every 1s"}}},
+		repository.NewMemoryAppealRepository(), WithHandler("tick", noop))
+	if err != nil {
+		t.Fatalf("Failed to create scheduler: %v", err)
+	}
+	s.Start()
+
+	run := waitForRun(t, s, "tick")
+	if run.TriggeredBy != models.TriggerSchedule || run.Status != models.JobSucceeded {
+		t.Errorf("Expected a successful scheduled run, got %+v", run)
+	}
+	if jobs := s.Jobs(); jobs[0].NextRun == nil {
+		t.Errorf("Expected the next run to be reported")
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("Unexpected shutdown error: %v", err)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	s, err := New(Definition{Jobs: []JobDefinition{{Name: "drain"}}}, repository.NewMemoryAppealRepository(),
+		WithHandler("drain", func(ctx context.Context, params map[string]string) (string, error) {
+			started <- struct{}{}
+			time.Sleep(50 * time.Millisecond)
+			return "drained", nil
+		}))
+	if err != nil {
+		t.Fatalf("Failed to create scheduler: %v", err)
+	}
+
+	if _, err := s.Trigger("drain"); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	<-started
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+	runs, err := s.Runs("drain", 1)
+	if err != nil {
+		t.Fatalf("Failed to list runs: %v", err)
+	}
+	if runs[0].Status != models.JobSucceeded {
+		t.Errorf("Expected shutdown to wait for the run, got %+v", runs[0])
+	}
+
+	if _, err := s.Trigger("drain"); models.ErrorCode(err) != "scheduler_stopped" {
+		t.Errorf("Expected scheduler_stopped, got %v", err)
+	}
+
+	// Задача, которая не укладывается в таймаут, получает отмену контекста.
+	forced, err := New(Definition{Jobs: []JobDefinition{{Name: "stuck"}}}, repository.NewMemoryAppealRepository(),
+		WithHandler("stuck", func(ctx context.Context, params map[string]string) (string, error) {
+			started <- struct{}{}
+			<-ctx.Done()
+			return "", ctx.Err()
+		}))
+	if err != nil {
+		t.Fatalf("Failed to create scheduler: %v", err)
+	}
+	if _, err := forced.Trigger("stuck"); err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := forced.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the shutdown to time out, got %v", err)
+	}
+	if run := waitForRun(t, forced, "stuck"); run.Status != models.JobFailed {
+		t.Errorf("Expected the cancelled run to fail, got %+v", run)
+	}
+}
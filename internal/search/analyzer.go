@@ -0,0 +1,97 @@
+// Package search turns appeal text into stemmed terms and evaluates search
+// queries against them. Stemming happens here rather than in the database so
+// every storage backend indexes and matches the same terms.
+package search
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/kljensen/snowball/english"
+	"github.com/kljensen/snowball/russian"
+)
+
+// Token is a word of the original text with its byte offsets.
+type Token struct {
+	Word  string
+	Stem  string
+	Start int
+	End   int
+}
+
+// Tokenize splits text into runs of letters and digits, lowercases them and
+// stems Cyrillic words as Russian and Latin ones as English.
+func Tokenize(text string) []Token {
+	var tokens []Token
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, newToken(text[start:i], start, i))
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, newToken(text[start:], start, len(text)))
+	}
+	return tokens
+}
+
+// Stems returns the stems of text joined by spaces, the form stored in the
+// search index.
+func Stems(text string) string {
+	return join(Tokenize(text), func(t Token) string { return t.Stem })
+}
+
+// Words returns the lowercased, unstemmed words of text joined by spaces;
+// prefix queries are matched against these.
+func Words(text string) string {
+	return join(Tokenize(text), func(t Token) string { return t.Word })
+}
+
+// Stem normalizes a single lowercase word.
+func Stem(word string) string {
+	switch {
+	case hasScript(word, unicode.Cyrillic):
+		return russian.Stem(word, true)
+	case hasScript(word, unicode.Latin):
+		return english.Stem(word, true)
+	default:
+		return word
+	}
+}
+
+func newToken(raw string, start, end int) Token {
+	word := normalize(raw)
+	return Token{Word: word, Stem: Stem(word), Start: start, End: end}
+}
+
+func normalize(word string) string {
+	return strings.ReplaceAll(strings.ToLower(word), "ё", "е")
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+func hasScript(word string, script *unicode.RangeTable) bool {
+	for _, r := range word {
+		if unicode.Is(script, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func join(tokens []Token, part func(Token) string) string {
+	parts := make([]string, len(tokens))
+	for i, token := range tokens {
+		parts[i] = part(token)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,94 @@
+package search
+
+import (
+	"html"
+	"strings"
+)
+
+const (
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+
+	// snippetContext is how many words are kept on each side of the first
+	// match when text is too long to return whole.
+	snippetContext = 12
+	maxSnippetLen  = 240
+)
+
+// Highlight returns an HTML-escaped snippet of text with the words matching
+// q wrapped in <mark> tags, or "" if nothing in text matches.
+func Highlight(text string, q Query) string {
+	tokens := Tokenize(text)
+	marked := matchTokens(tokens, q)
+
+	first := -1
+	for i, ok := range marked {
+		if ok {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+
+	from, to := 0, len(text)
+	prefix, suffix := "", ""
+	if len(text) > maxSnippetLen {
+		if lo := first - snippetContext; lo > 0 {
+			from, prefix = tokens[lo].Start, "…"
+		}
+		if hi := first + snippetContext; hi < len(tokens)-1 {
+			to, suffix = tokens[hi].End, "…"
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	pos := from
+	for i, token := range tokens {
+		if !marked[i] || token.Start < from || token.End > to {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:token.Start]))
+		b.WriteString(HighlightStart)
+		b.WriteString(html.EscapeString(text[token.Start:token.End]))
+		b.WriteString(HighlightEnd)
+		pos = token.End
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	b.WriteString(suffix)
+	return b.String()
+}
+
+// matchTokens marks tokens that are part of a term or phrase match, or that
+// start with a prefix clause.
+func matchTokens(tokens []Token, q Query) []bool {
+	marked := make([]bool, len(tokens))
+	for _, clause := range q.Clauses {
+		for i := range tokens {
+			if clause.Prefix {
+				if strings.HasPrefix(tokens[i].Word, clause.Terms[0]) {
+					marked[i] = true
+				}
+				continue
+			}
+			if i+len(clause.Terms) > len(tokens) {
+				break
+			}
+			match := true
+			for j, term := range clause.Terms {
+				if tokens[i+j].Stem != term {
+					match = false
+					break
+				}
+			}
+			if match {
+				for j := range clause.Terms {
+					marked[i+j] = true
+				}
+			}
+		}
+	}
+	return marked
+}
@@ -0,0 +1,168 @@
+package search
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"go_appeals/internal/models"
+)
+
+const (
+	MaxQueryLength  = 256
+	maxQueryClauses = 16
+)
+
+// Clause is one condition of a query. A single-term clause matches the stem
+// anywhere, a multi-term clause is a phrase whose stems must be adjacent in
+// the same field, and a prefix clause matches words starting with Terms[0].
+type Clause struct {
+	Terms  []string
+	Prefix bool
+}
+
+// Query matches appeals that satisfy all of its clauses.
+type Query struct {
+	Clauses []Clause
+}
+
+// Parse reads a query such as `pothole "Lenina street" deliv*`: bare words
+// are stemmed terms, quoted text is a phrase and a trailing * makes a prefix.
+func Parse(text string) (Query, error) {
+	if utf8.RuneCountInString(text) > MaxQueryLength {
+		return Query{}, models.NewError(models.ErrValidation, "query_too_long", "search query must not exceed %d characters", MaxQueryLength)
+	}
+
+	var q Query
+	for len(text) > 0 {
+		if text[0] == '"' {
+			end := strings.IndexByte(text[1:], '"')
+			phrase := text[1:]
+			if end >= 0 {
+				phrase, text = text[1:end+1], text[end+2:]
+			} else {
+				text = ""
+			}
+			if stems := stemsOf(phrase); len(stems) > 0 {
+				q.Clauses = append(q.Clauses, Clause{Terms: stems})
+			}
+			continue
+		}
+
+		end := strings.IndexAny(text, " \t\r\n\"")
+		word := text
+		if end >= 0 {
+			word, text = text[:end], text[end:]
+			if text[0] != '"' {
+				text = text[1:]
+			}
+		} else {
+			text = ""
+		}
+
+		if prefix, ok := strings.CutSuffix(word, "*"); ok {
+			tokens := Tokenize(prefix)
+			if len(tokens) == 1 && tokens[0].Start == 0 && tokens[0].End == len(prefix) {
+				q.Clauses = append(q.Clauses, Clause{Terms: []string{tokens[0].Word}, Prefix: true})
+				continue
+			}
+		}
+		for _, stem := range stemsOf(word) {
+			q.Clauses = append(q.Clauses, Clause{Terms: []string{stem}})
+		}
+	}
+
+	if len(q.Clauses) == 0 {
+		return Query{}, models.NewError(models.ErrValidation, "query_required", "search query must contain at least one word")
+	}
+	if len(q.Clauses) > maxQueryClauses {
+		return Query{}, models.NewError(models.ErrValidation, "query_too_long", "search query must not have more than %d terms", maxQueryClauses)
+	}
+	return q, nil
+}
+
+func stemsOf(text string) []string {
+	tokens := Tokenize(text)
+	stems := make([]string, len(tokens))
+	for i, token := range tokens {
+		stems[i] = token.Stem
+	}
+	return stems
+}
+
+// Document is the analyzed form of an appeal as kept in the search index.
+type Document struct {
+	Theme    []string
+	Message  []string
+	Solution []string
+	Words    []string
+}
+
+// NewDocument analyzes the searchable fields of an appeal.
+func NewDocument(appeal *models.Appeal) Document {
+	return ParseDocument(
+		Stems(appeal.Theme),
+		Stems(appeal.Message),
+		Stems(appeal.Solution),
+		Words(appeal.Theme+" "+appeal.Message+" "+appeal.Solution),
+	)
+}
+
+// ParseDocument rebuilds a Document from its stored, space-separated form.
+func ParseDocument(theme, message, solution, words string) Document {
+	return Document{
+		Theme:    strings.Fields(theme),
+		Message:  strings.Fields(message),
+		Solution: strings.Fields(solution),
+		Words:    strings.Fields(words),
+	}
+}
+
+// Field weights used by Score; the SQL backends use the same ratios.
+const (
+	ThemeWeight    = 3.0
+	MessageWeight  = 1.0
+	SolutionWeight = 1.0
+	WordsWeight    = 0.5
+)
+
+// Score reports whether doc matches every clause of q and, if so, a
+// relevance score: weighted occurrence counts, theme counting most.
+func Score(doc Document, q Query) (float64, bool) {
+	var score float64
+	for _, clause := range q.Clauses {
+		var hits float64
+		if clause.Prefix {
+			for _, word := range doc.Words {
+				if strings.HasPrefix(word, clause.Terms[0]) {
+					hits += WordsWeight
+				}
+			}
+		} else {
+			hits = ThemeWeight*float64(countPhrase(doc.Theme, clause.Terms)) +
+				MessageWeight*float64(countPhrase(doc.Message, clause.Terms)) +
+				SolutionWeight*float64(countPhrase(doc.Solution, clause.Terms))
+		}
+		if hits == 0 {
+			return 0, false
+		}
+		score += hits
+	}
+	return score, true
+}
+
+func countPhrase(stems, phrase []string) int {
+	count := 0
+	for i := 0; i+len(phrase) <= len(stems); i++ {
+		match := true
+		for j, term := range phrase {
+			if stems[i+j] != term {
+				match = false
+				break
+			}
+		}
+		if match {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,151 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"go_appeals/internal/models"
+)
+
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+
+	tokens := Tokenize("Ёлки, deliveries & 42-й дом")
+	expected := []Token{
+		{Word: "елки", Stem: "елк", Start: 0, End: 8},
+		{Word: "deliveries", Stem: "deliveri", Start: 10, End: 20},
+		{Word: "42", Stem: "42", Start: 23, End: 25},
+		{Word: "й", Stem: "й", Start: 26, End: 28},
+		{Word: "дом", Stem: "дом", Start: 29, End: 35},
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %d tokens, got %+v", len(expected), tokens)
+	}
+	for i, want := range expected {
+		if tokens[i] != want {
+			t.Errorf("Token %d: expected %+v, got %+v", i, want, tokens[i])
+		}
+	}
+}
+
+func TestStemming(t *testing.T) {
+	t.Parallel()
+
+	// Разные формы одного слова должны давать одну основу.
+	pairs := [][2]string{
+		{"улица", "улицы"},
+		{"улице", "улицей"},
+		{"delivery", "deliveries"},
+		{"printing", "printed"},
+	}
+	for _, pair := range pairs {
+		if Stem(pair[0]) != Stem(pair[1]) {
+			t.Errorf("Expected %s and %s to share a stem, got %s and %s", pair[0], pair[1], Stem(pair[0]), Stem(pair[1]))
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	q, err := Parse(`Яма  "улица Ленина" deliv* foo-bar`)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	expected := []Clause{
+		{Terms: []string{"ям"}},
+		{Terms: []string{"улиц", "ленин"}},
+		{Terms: []string{"deliv"}, Prefix: true},
+		{Terms: []string{"foo"}},
+		{Terms: []string{"bar"}},
+	}
+	if len(q.Clauses) != len(expected) {
+		t.Fatalf("Expected %d clauses, got %+v", len(expected), q.Clauses)
+	}
+	for i, want := range expected {
+		got := q.Clauses[i]
+		if got.Prefix != want.Prefix || strings.Join(got.Terms, " ") != strings.Join(want.Terms, " ") {
+			t.Errorf("Clause %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	for _, text := range []string{"", `"" * ,`, strings.Repeat("a ", 17), strings.Repeat("я", MaxQueryLength+1)} {
+		if _, err := Parse(text); !errors.Is(err, models.ErrValidation) {
+			t.Errorf("Expected validation error for %q, got %v", text, err)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	t.Parallel()
+
+	doc := NewDocument(&models.Appeal{
+		Theme:    "Яма на улице Ленина",
+		Message:  "На улице Ленина у дома 5 огромная яма, машины ломаются",
+		Solution: "Deliveries of asphalt scheduled",
+	})
+
+	tests := []struct {
+		query   string
+		matches bool
+	}{
+		{"ямы", true},
+		{`"улицы Ленина"`, true},
+		{`"Ленина улица"`, false},
+		{"ломает*", false},
+		{"ломаю*", true},
+		{"delivery asphalt", true},
+		{"яма трамвай", false},
+	}
+	for _, tt := range tests {
+		q, err := Parse(tt.query)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", tt.query, err)
+		}
+		if _, ok := Score(doc, q); ok != tt.matches {
+			t.Errorf("%q: expected match %v, got %v", tt.query, tt.matches, ok)
+		}
+	}
+
+	themeQuery, _ := Parse("яма")
+	solutionQuery, _ := Parse("asphalt")
+	themeScore, _ := Score(doc, themeQuery)
+	solutionScore, _ := Score(doc, solutionQuery)
+	if themeScore <= solutionScore {
+		t.Errorf("Expected theme matches to outrank solution matches, got %v <= %v", themeScore, solutionScore)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	t.Parallel()
+
+	q, _ := Parse(`"улица Ленина" <b>`)
+	got := Highlight("Дом на улице Ленина <b>", q)
+	want := "Дом на <mark>улице</mark> <mark>Ленина</mark> &lt;<mark>b</mark>&gt;"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	if got := Highlight("Ленина улица", mustParse(t, `"улица Ленина"`)); got != "" {
+		t.Errorf("Expected no highlight for a reversed phrase, got %q", got)
+	}
+
+	long := strings.Repeat("слово ", 60) + "яма " + strings.Repeat("слово ", 60)
+	snippet := Highlight(long, mustParse(t, "яма"))
+	if !strings.HasPrefix(snippet, "…") || !strings.HasSuffix(snippet, "…") || !strings.Contains(snippet, "<mark>яма</mark>") {
+		t.Errorf("Expected a trimmed snippet around the match, got %q", snippet)
+	}
+	if len(snippet) > len(long)/2 {
+		t.Errorf("Expected snippet to be shorter than the text, got %d bytes", len(snippet))
+	}
+}
+
+func mustParse(t *testing.T, text string) Query {
+	q, err := Parse(text)
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", text, err)
+	}
+	return q
+}
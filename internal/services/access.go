@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+
+	"go_appeals/internal/access"
+	"go_appeals/internal/models"
+)
+
+// WithAccessPolicy replaces the built-in access policy.
+func WithAccessPolicy(policy *access.Policy) Option {
+	return func(s *AppealService) { s.access = policy }
+}
+
+func (s *AppealService) Access() *access.Policy {
+	return s.access
+}
+
+// Authorize checks that the caller holds perm. Calls without a principal
+// come from inside the service, such as scheduled jobs, and are trusted.
+func (s *AppealService) Authorize(ctx context.Context, perm access.Permission) error {
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		return nil
+	}
+	return s.access.Check(principal, perm)
+}
+
+// scopeListing narrows a listing to the caller's own appeals when they may
+// not read everyone's.
+func (s *AppealService) scopeListing(ctx context.Context, filter *models.AppealFilter) error {
+	principal := PrincipalFromContext(ctx)
+	if principal == nil || s.access.Allows(principal, access.ReadAppeals) {
+		return nil
+	}
+	if err := s.access.Check(principal, access.ReadOwnAppeals); err != nil {
+		return err
+	}
+	filter.CreatedBy = principal.Subject
+	return nil
+}
+
+// authorizeRead lets the caller see appeal if they may read every appeal,
+// or only their own and they created it.
+func (s *AppealService) authorizeRead(ctx context.Context, appeal *models.Appeal) error {
+	principal := PrincipalFromContext(ctx)
+	if principal == nil || s.access.Allows(principal, access.ReadAppeals) {
+		return nil
+	}
+	if err := s.access.Check(principal, access.ReadOwnAppeals); err != nil {
+		return err
+	}
+	if appeal.CreatedBy != principal.Subject {
+		return models.NewError(models.ErrForbidden, "not_your_appeal", "%s may only read their own appeals", s.access.Describe(principal))
+	}
+	return nil
+}
+
+// authorizeComplete lets only the assignee complete an appeal, unless the
+// caller may complete any.
+func (s *AppealService) authorizeComplete(ctx context.Context, appeal *models.Appeal) error {
+	principal := PrincipalFromContext(ctx)
+	if principal == nil || appeal.Assignee == principal.Subject || s.access.Allows(principal, access.CompleteAnyAppeal) {
+		return nil
+	}
+	if appeal.Assignee == "" {
+		return models.NewError(models.ErrForbidden, "not_assignee", "%s may not complete an unassigned appeal, claim it first", s.access.Describe(principal))
+	}
+	return models.NewError(models.ErrForbidden, "not_assignee", "%s may not complete an appeal assigned to %s", s.access.Describe(principal), appeal.Assignee)
+}
@@ -1,128 +1,588 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"go_appeals/internal/access"
+	"go_appeals/internal/blob"
+	"go_appeals/internal/calendar"
 	"go_appeals/internal/models"
 	"go_appeals/internal/repository"
+	"go_appeals/internal/search"
+	"go_appeals/internal/sla"
+	"go_appeals/internal/tenant"
+	"go_appeals/internal/workflow"
+	"strings"
 	"time"
 )
 
 type AppealService struct {
-	repo *repository.AppealRepository
+	repo      repository.AppealStore
+	workflow  *workflow.Machine
+	sla       *sla.Policy
+	calendar  *calendar.Calendar
+	access    *access.Policy
+	tenants   *tenant.Registry
+	blobs     blob.Store
+	limits    AttachmentLimits
+	pending   pendingUploads
+	notifiers []Notifier
 }
 
-func NewAppealService(repo *repository.AppealRepository) *AppealService {
-	return &AppealService{
-		repo: repo,
+type Option func(s *AppealService)
+
+// WithSLAPolicy replaces the built-in SLA policy.
+func WithSLAPolicy(policy *sla.Policy) Option {
+	return func(s *AppealService) { s.sla = policy }
+}
+
+// WithTenants sets the organizations the service serves, replacing the
+// built-in single default tenant.
+func WithTenants(reg *tenant.Registry) Option {
+	return func(s *AppealService) { s.tenants = reg }
+}
+
+// WithCalendar sets the working hours SLA deadlines are counted in,
+// replacing the built-in calendar.
+func WithCalendar(cal *calendar.Calendar) Option {
+	return func(s *AppealService) { s.calendar = cal }
+}
+
+func NewAppealService(repo repository.AppealStore, machine *workflow.Machine, opts ...Option) *AppealService {
+	s := &AppealService{
+		repo:     repo,
+		workflow: machine,
+		sla:      sla.Default(),
+		calendar: calendar.Default(),
+		access:   access.Default(),
+		tenants:  tenant.Default(),
+		blobs:    blob.NewMemory(),
+		limits:   DefaultAttachmentLimits,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
+}
+
+func (s *AppealService) Workflow() *workflow.Machine {
+	return s.workflow
+}
+
+// SLAPolicy is the policy of the request's tenant, which falls back to the
+// service-wide one.
+func (s *AppealService) SLAPolicy(ctx context.Context) *sla.Policy {
+	return s.slaPolicy(ctx)
 }
 
-func (s *AppealService) CreateAppeal(req models.CreateAppealRequest) (*models.Appeal, error) {
+func (s *AppealService) Tenants() *tenant.Registry {
+	return s.tenants
+}
+
+func (s *AppealService) Calendar() *calendar.Calendar {
+	return s.calendar
+}
+
+func (s *AppealService) CreateAppeal(ctx context.Context, req models.CreateAppealRequest) (*models.Appeal, error) {
+	if err := s.Authorize(ctx, access.CreateAppeals); err != nil {
+		return nil, err
+	}
+	priority, err := models.ParsePriority(req.Priority)
+	if err != nil {
+		return nil, err
+	}
+
 	appeal := &models.Appeal{
-		Theme:   req.Theme,
-		Message: req.Message,
-		Status:  models.StatusNew,
+		Theme:     req.Theme,
+		Message:   req.Message,
+		Status:    s.workflow.Initial(),
+		Priority:  priority,
+		CreatedBy: ActorFromContext(ctx),
+		CreatedAt: time.Now(),
 	}
 
 	if appeal.Theme == "" || appeal.Message == "" {
-		return nil, fmt.Errorf("theme and message are required")
+		return nil, models.NewError(models.ErrValidation, "theme_and_message_required", "theme and message are required")
 	}
+	org, err := s.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !org.AllowsTheme(appeal.Theme) {
+		return nil, models.NewError(models.ErrValidation, "unknown_theme", "theme %q is not offered by %s", appeal.Theme, org.Name)
+	}
+	appeal.TenantID = org.ID
+	var details *models.RequesterDetails
+	if req.Requester != nil {
+		normalized := *req.Requester
+		normalized.Normalize()
+		if err := normalized.Validate(); err != nil {
+			return nil, err
+		}
+		details = &normalized
+	}
+	s.slaPolicy(ctx).Plan(appeal, s.calendar)
 
-	savedAppeal, err := s.repo.Save(appeal)
+	attachments, err := s.storeUploads(ctx, appeal, req.Attachments)
 	if err != nil {
-		return nil, fmt.Errorf("failed to save appeal: %w", err)
+		return nil, err
 	}
-	return savedAppeal, nil
-}
+	token, tokenHash, err := newTrackingToken()
+	if err != nil {
+		s.settleUploads(ctx, attachments, false)
+		return nil, err
+	}
+	appeal.TrackingTokenHash = tokenHash
 
-func (s *AppealService) GetStartedAppeals() ([]*models.Appeal, error) {
-	allAppeals, err := s.repo.GetAll()
+	var requester *models.Requester
+	err = s.store(ctx).WithTx(func(tx repository.AppealStore) error {
+		if details != nil {
+			found, err := s.resolveRequester(ctx, tx, appeal.TenantID, *details)
+			if err != nil {
+				return err
+			}
+			requester, appeal.RequesterID = found, found.ID
+		}
+		if err := s.assignNumber(tx, org, appeal); err != nil {
+			return err
+		}
+		if _, err := tx.Save(appeal); err != nil {
+			return fmt.Errorf("failed to save appeal: %w", err)
+		}
+		for _, attachment := range attachments {
+			attachment.AppealID = appeal.ID
+		}
+		if err := saveAttachments(tx, attachments); err != nil {
+			return err
+		}
+		if err := s.recordTransition(ctx, tx, appeal, "", ""); err != nil {
+			return err
+		}
+		return s.recordEvent(ctx, tx, models.EventAppealCreated, appeal, "")
+	})
+	s.settleUploads(ctx, attachments, err == nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get all appeals: %w", err)
+		return nil, err
+	}
+
+	s.notify()
+	appeal.TrackingToken = token
+	if len(attachments) > 0 {
+		appeal.Attachments = attachments
+	}
+	if requester != nil {
+		s.exposeRequester(ctx, appeal, requester)
+	}
+	return appeal, nil
+}
+
+// GetStartedAppeals lists active appeals. A status filter is narrowed to the
+// active states; asking only for inactive ones yields an empty page.
+// Callers who may only read their own appeals see just those.
+func (s *AppealService) GetStartedAppeals(ctx context.Context, filter models.AppealFilter) (*models.AppealPage, error) {
+	if err := s.scopeListing(ctx, &filter); err != nil {
+		return nil, err
+	}
+	s.defaultDueWithin(ctx, &filter)
+	if err := filter.Normalize(); err != nil {
+		return nil, err
 	}
 
-	startedAppeals := make([]*models.Appeal, 0)
-	for _, appeal := range allAppeals {
-		if appeal.IsNew() || appeal.IsInProgress() {
-			startedAppeals = append(startedAppeals, appeal)
+	active := s.workflow.ActiveStates()
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = active
+	} else {
+		var statuses []models.AppealStatus
+		for _, status := range filter.Statuses {
+			if s.workflow.IsActive(status) {
+				statuses = append(statuses, status)
+			}
+		}
+		if len(statuses) == 0 {
+			return &models.AppealPage{Appeals: make([]*models.Appeal, 0)}, nil
 		}
+		filter.Statuses = statuses
+	}
+
+	page, err := s.store(ctx).List(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get started appeals: %w", err)
+	}
+
+	return page, nil
+}
+
+// GetMyAppeals is the caller's queue: active appeals assigned to them.
+func (s *AppealService) GetMyAppeals(ctx context.Context, filter models.AppealFilter) (*models.AppealPage, error) {
+	actor, err := requireActor(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return startedAppeals, nil
+	filter.Assignee = actor
+	return s.GetStartedAppeals(ctx, filter)
 }
 
-func (s *AppealService) GetAllAppeals() ([]*models.Appeal, error) {
-	return s.repo.GetAll()
+func (s *AppealService) GetAllAppeals(ctx context.Context, filter models.AppealFilter) (*models.AppealPage, error) {
+	if err := s.scopeListing(ctx, &filter); err != nil {
+		return nil, err
+	}
+	s.defaultDueWithin(ctx, &filter)
+	return s.store(ctx).List(filter)
 }
 
-func (s *AppealService) GetAppealByID(id string) (*models.Appeal, error) {
-	return s.repo.FindByID(id)
+// defaultDueWithin takes the "due soon" window from the SLA policy unless
+// the caller chose one.
+func (s *AppealService) defaultDueWithin(ctx context.Context, filter *models.AppealFilter) {
+	if filter.SLA == models.SLADueSoon && filter.DueWithin == 0 {
+		filter.DueWithin = s.slaPolicy(ctx).DueSoon()
+	}
 }
 
-func (s *AppealService) StartProcessing(id string) (*models.Appeal, error) {
-	appeal, err := s.repo.FindByID(id)
+func (s *AppealService) SearchAppeals(ctx context.Context, req models.SearchRequest) ([]*models.SearchResult, error) {
+	if err := s.Authorize(ctx, access.ReadAppeals); err != nil {
+		return nil, err
+	}
+	if err := req.Normalize(); err != nil {
+		return nil, err
+	}
+	q, err := search.Parse(req.Query)
 	if err != nil {
 		return nil, err
 	}
 
-	if !appeal.CanStartProcessing() {
-		return nil, fmt.Errorf("cannot start processing appeal with status: %s", appeal.Status)
+	results, err := s.store(ctx).Search(q, req.Limit, req.Offset)
+	if err != nil {
+		return nil, err
 	}
 
-	appeal.Status = models.StatusInProgress
+	for _, result := range results {
+		fields := map[string]string{
+			"theme":    result.Appeal.Theme,
+			"message":  result.Appeal.Message,
+			"solution": result.Appeal.Solution,
+		}
+		for field, text := range fields {
+			if snippet := search.Highlight(text, q); snippet != "" {
+				if result.Highlights == nil {
+					result.Highlights = make(map[string]string)
+				}
+				result.Highlights[field] = snippet
+			}
+		}
+	}
 
-	updatedAppeal, err := s.repo.Update(appeal)
+	return results, nil
+}
+
+func (s *AppealService) GetAppealByID(ctx context.Context, id string) (*models.Appeal, error) {
+	appeal, err := s.readAppeal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := s.store(ctx).ListAttachments(appeal.ID)
 	if err != nil {
 		return nil, err
 	}
+	if len(attachments) > 0 {
+		appeal.Attachments = attachments
+	}
+	if appeal.RequesterID != "" {
+		requester, err := s.store(ctx).FindRequester(appeal.RequesterID)
+		if err != nil {
+			return nil, err
+		}
+		s.exposeRequester(ctx, appeal, requester)
+	}
+	return appeal, nil
+}
 
-	return updatedAppeal, nil
+func (s *AppealService) GetAppealHistory(ctx context.Context, id string) ([]*models.StatusHistoryEntry, error) {
+	appeal, err := s.readAppeal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.store(ctx).GetHistory(appeal.ID)
 }
 
-func (s *AppealService) CancelAppeal(id string) error {
-	appeal, err := s.repo.FindByID(id)
+// GetAppealSLA reports the deadlines of an appeal and how much working time
+// it has taken so far.
+func (s *AppealService) GetAppealSLA(ctx context.Context, id string) (*models.AppealSLA, error) {
+	appeal, err := s.readAppeal(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return sla.Report(appeal, s.calendar, time.Now()), nil
+}
 
-	if !appeal.CanCancel() {
-		return fmt.Errorf("cannot cancel appeal with status: %s", appeal.Status)
+// readAppeal returns the appeal with the given ID or registration number if
+// the caller may read it.
+func (s *AppealService) readAppeal(ctx context.Context, id string) (*models.Appeal, error) {
+	appeal, err := findAppeal(s.store(ctx), id)
+	if err != nil {
+		return nil, err
 	}
+	if err := s.authorizeRead(ctx, appeal); err != nil {
+		return nil, err
+	}
+	return appeal, nil
+}
 
-	appeal.Status = models.StatusCancelled
+func (s *AppealService) StartProcessing(ctx context.Context, id string) (*models.Appeal, error) {
+	return s.fire(ctx, id, "start", workflow.Input{})
+}
 
-	_, err = s.repo.Update(appeal)
+func (s *AppealService) CancelAppeal(ctx context.Context, id string, req models.UpdateAppealCancelRequest) (*models.Appeal, error) {
+	reason, err := validateCancelReason(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return s.fire(ctx, id, "cancel", workflow.Input{Reason: reason})
+}
+
+// CancelAllInProgress fires the workflow's cancel transition on every appeal
+// it can leave, all in one transaction, and returns how many it cancelled.
+func (s *AppealService) CancelAllInProgress(ctx context.Context, req models.UpdateAppealCancelRequest) (int, error) {
+	if err := s.Authorize(ctx, access.CancelAllAppeals); err != nil {
+		return 0, err
+	}
+	reason, err := validateCancelReason(req)
+	if err != nil {
+		return 0, err
+	}
+
+	in := workflow.Input{Reason: reason, Actor: ActorFromContext(ctx)}
+
+	var cancelled int
+	err = s.store(ctx).WithTx(func(tx repository.AppealStore) error {
+		from := make(map[string]models.AppealStatus)
+		affected, err := tx.CancelInProgressAppeals(s.workflow.Sources("cancel"), func(appeal *models.Appeal) error {
+			from[appeal.ID] = appeal.Status
+			return s.change(ctx, appeal, "cancel", in)
+		})
+		if err != nil {
+			return err
+		}
+		for _, appeal := range affected {
+			if err := s.record(ctx, tx, appeal, "cancel", from[appeal.ID], reason); err != nil {
+				return err
+			}
+		}
+		cancelled = len(affected)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if cancelled > 0 {
+		s.notify()
+	}
+	return cancelled, nil
+}
+
+func (s *AppealService) GetAppealsByDates(ctx context.Context, start, end time.Time) ([]*models.Appeal, error) {
+	if err := s.Authorize(ctx, access.ReadAppeals); err != nil {
+		return nil, err
+	}
+	return s.store(ctx).SelectAppealsByDates(start, end)
+}
+
+// CompleteAppeal resolves an appeal. Only its assignee may complete it,
+// unless the caller may complete any appeal.
+func (s *AppealService) CompleteAppeal(ctx context.Context, id string, req models.UpdateAppealSolutionRequest) (*models.Appeal, error) {
+	return s.fire(ctx, id, "complete", workflow.Input{Solution: req.Solution})
 }
 
-func (s *AppealService) CancelAllInProgress() error {
-	return s.repo.CancelInProgressAppeals()
+// HoldAppeal parks an appeal that cannot be worked on for internal reasons.
+func (s *AppealService) HoldAppeal(ctx context.Context, id string, req models.UpdateAppealReasonRequest) (*models.Appeal, error) {
+	return s.fireWithReason(ctx, id, "hold", req)
 }
 
-func (s *AppealService) GetAppealsByDates(start, end time.Time) ([]*models.Appeal, error) {
-	return s.repo.SelectAppealsByDates(start, end)
+// WaitForRequester parks an appeal until the requester supplies the
+// information described in the reason.
+func (s *AppealService) WaitForRequester(ctx context.Context, id string, req models.UpdateAppealReasonRequest) (*models.Appeal, error) {
+	return s.fireWithReason(ctx, id, "wait", req)
 }
 
-func (s *AppealService) CompleteAppeal(id string, req models.UpdateAppealSolutionRequest) (*models.Appeal, error) {
-	appeal, err := s.repo.FindByID(id)
+func (s *AppealService) ResumeAppeal(ctx context.Context, id string) (*models.Appeal, error) {
+	return s.fire(ctx, id, "resume", workflow.Input{})
+}
+
+func (s *AppealService) RejectAppeal(ctx context.Context, id string, req models.UpdateAppealReasonRequest) (*models.Appeal, error) {
+	return s.fireWithReason(ctx, id, "reject", req)
+}
+
+// ReopenAppeal brings a completed appeal back when the requester disputes
+// the solution.
+func (s *AppealService) ReopenAppeal(ctx context.Context, id string, req models.UpdateAppealReasonRequest) (*models.Appeal, error) {
+	return s.fireWithReason(ctx, id, "reopen", req)
+}
+
+func (s *AppealService) fireWithReason(ctx context.Context, id, transition string, req models.UpdateAppealReasonRequest) (*models.Appeal, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return s.fire(ctx, id, transition, workflow.Input{Reason: strings.TrimSpace(req.Reason)})
+}
+
+// AssignAppeal gives an unassigned appeal to an operator.
+func (s *AppealService) AssignAppeal(ctx context.Context, id string, req models.AssignAppealRequest) (*models.Appeal, error) {
+	if err := s.Authorize(ctx, access.AssignAppeals); err != nil {
+		return nil, err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return s.assign(ctx, id, strings.TrimSpace(req.Assignee), false)
+}
+
+// ReassignAppeal hands an appeal over to another operator, whoever holds it.
+func (s *AppealService) ReassignAppeal(ctx context.Context, id string, req models.AssignAppealRequest) (*models.Appeal, error) {
+	if err := s.Authorize(ctx, access.AssignAppeals); err != nil {
+		return nil, err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	return s.assign(ctx, id, strings.TrimSpace(req.Assignee), true)
+}
+
+// ClaimAppeal assigns an unassigned appeal to the caller.
+func (s *AppealService) ClaimAppeal(ctx context.Context, id string) (*models.Appeal, error) {
+	if err := s.Authorize(ctx, access.ProcessAppeals); err != nil {
+		return nil, err
+	}
+	actor, err := requireActor(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return s.assign(ctx, id, actor, false)
+}
 
-	if !appeal.CanComplete() {
-		return nil, fmt.Errorf("cannot complete appeal with status: %s", appeal.Status)
+func (s *AppealService) assign(ctx context.Context, id, assignee string, reassign bool) (*models.Appeal, error) {
+	var updatedAppeal *models.Appeal
+	var changed bool
+	err := s.store(ctx).WithTx(func(tx repository.AppealStore) error {
+		appeal, err := findAppeal(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if !s.workflow.IsActive(appeal.Status) {
+			return models.NewError(models.ErrInvalidTransition, "invalid_transition", "cannot assign appeal with status: %s", appeal.Status)
+		}
+		if appeal.Assignee == assignee {
+			updatedAppeal = appeal
+			return nil
+		}
+		if appeal.Assignee != "" && !reassign {
+			return models.NewError(models.ErrConflict, "already_assigned", "appeal is already assigned to %s", appeal.Assignee)
+		}
+
+		appeal.Assignee = assignee
+		updatedAppeal, err = tx.Update(appeal)
+		if err != nil {
+			return err
+		}
+		changed = true
+		return s.recordEvent(ctx, tx, models.EventAppealAssigned, updatedAppeal, "")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		s.notify()
 	}
+	return updatedAppeal, nil
+}
 
-	appeal.Status = models.StatusCompleted
-	appeal.Solution = req.Solution
-	updatedAppeal, err := s.repo.Update(appeal)
+// fire loads the appeal, runs the named workflow transition on it and stores
+// the result together with its status history entry in a single transaction.
+func (s *AppealService) fire(ctx context.Context, id, transition string, in workflow.Input) (*models.Appeal, error) {
+	if err := s.Authorize(ctx, access.ProcessAppeals); err != nil {
+		return nil, err
+	}
+	in.Actor = ActorFromContext(ctx)
+
+	var updatedAppeal *models.Appeal
+	err := s.store(ctx).WithTx(func(tx repository.AppealStore) error {
+		appeal, err := findAppeal(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if transition == "complete" {
+			if err := s.authorizeComplete(ctx, appeal); err != nil {
+				return err
+			}
+		}
+
+		updatedAppeal, err = s.apply(ctx, tx, appeal, transition, in)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	s.notify()
 	return updatedAppeal, nil
 }
+
+// apply runs the named workflow transition on appeal and stores the result
+// with its status history entry and event as part of tx.
+func (s *AppealService) apply(ctx context.Context, tx repository.AppealStore, appeal *models.Appeal, transition string, in workflow.Input) (*models.Appeal, error) {
+	from := appeal.Status
+	if err := s.change(ctx, appeal, transition, in); err != nil {
+		return nil, err
+	}
+	updated, err := tx.Update(appeal)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.record(ctx, tx, updated, transition, from, in.Reason); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// change runs the named workflow transition on appeal and updates its SLA
+// clock, without storing anything.
+func (s *AppealService) change(ctx context.Context, appeal *models.Appeal, transition string, in workflow.Input) error {
+	if err := s.workflow.Fire(appeal, transition, in); err != nil {
+		return err
+	}
+	s.slaPolicy(ctx).Track(appeal, s.calendar, s.workflow.IsActive(appeal.Status), s.workflow.StopsClock(appeal.Status), time.Now())
+	return nil
+}
+
+// record adds the status history entry and event of a stored transition.
+func (s *AppealService) record(ctx context.Context, tx repository.AppealStore, appeal *models.Appeal, transition string, from models.AppealStatus, reason string) error {
+	if err := s.recordTransition(ctx, tx, appeal, from, reason); err != nil {
+		return err
+	}
+	return s.recordEvent(ctx, tx, s.workflow.Event(transition), appeal, from)
+}
+
+func (s *AppealService) recordTransition(ctx context.Context, tx repository.AppealStore, appeal *models.Appeal, from models.AppealStatus, reason string) error {
+	err := tx.AddHistory(&models.StatusHistoryEntry{
+		AppealID:   appeal.ID,
+		FromStatus: from,
+		ToStatus:   appeal.Status,
+		Actor:      ActorFromContext(ctx),
+		Reason:     reason,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record status history: %w", err)
+	}
+	return nil
+}
+
+func validateCancelReason(req models.UpdateAppealCancelRequest) (string, error) {
+	if err := req.Validate(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(req.Reason), nil
+}
@@ -0,0 +1,935 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go_appeals/internal/calendar"
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+	"go_appeals/internal/sla"
+	"go_appeals/internal/tenant"
+	"go_appeals/internal/workflow"
+)
+
+var ctx = WithActor(context.Background(), "operator@example.com")
+
+func newTestService(t *testing.T) *AppealService {
+	return NewAppealService(repository.NewMemoryAppealRepository(), workflow.Default())
+}
+
+func createTestAppeal(t *testing.T, service *AppealService) *models.Appeal {
+	appeal, err := service.CreateAppeal(ctx, models.CreateAppealRequest{
+		Theme:   "Test theme",
+		Message: "Test message",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+	return appeal
+}
+
+func startedAppeals(t *testing.T, service *AppealService, filter models.AppealFilter) []*models.Appeal {
+	page, err := service.GetStartedAppeals(ctx, filter)
+	if err != nil {
+		t.Fatalf("Failed to get started appeals: %v", err)
+	}
+	return page.Appeals
+}
+
+func TestCreateAppeal(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+
+	appeal := createTestAppeal(t, service)
+	if appeal.ID == "" {
+		t.Errorf("Expected created appeal to have an ID")
+	}
+	if appeal.Status != models.StatusNew {
+		t.Errorf("Expected status %s, got %s", models.StatusNew, appeal.Status)
+	}
+
+	if _, err := service.CreateAppeal(ctx, models.CreateAppealRequest{Theme: "Only theme"}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected validation error when message is missing, got %v", err)
+	}
+}
+
+func TestAppealLifecycle(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	appeal := createTestAppeal(t, service)
+
+	if _, err := service.CompleteAppeal(ctx, appeal.ID, models.UpdateAppealSolutionRequest{Solution: "Done"}); !errors.Is(err, models.ErrInvalidTransition) {
+		t.Errorf("Expected invalid transition when completing a new appeal, got %v", err)
+	}
+
+	started, err := service.StartProcessing(ctx, appeal.ID)
+	if err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if started.Status != models.StatusInProgress {
+		t.Errorf("Expected status %s, got %s", models.StatusInProgress, started.Status)
+	}
+
+	if _, err := service.StartProcessing(ctx, appeal.ID); !errors.Is(err, models.ErrInvalidTransition) {
+		t.Errorf("Expected invalid transition when starting an appeal twice, got %v", err)
+	}
+
+	completed, err := service.CompleteAppeal(ctx, appeal.ID, models.UpdateAppealSolutionRequest{Solution: "Done"})
+	if err != nil {
+		t.Fatalf("Failed to complete appeal: %v", err)
+	}
+	if completed.Status != models.StatusCompleted || completed.Solution != "Done" {
+		t.Errorf("Expected completed appeal with solution, got %+v", completed)
+	}
+
+	if _, err := service.CancelAppeal(ctx, appeal.ID, models.UpdateAppealCancelRequest{Reason: "Duplicate"}); err == nil {
+		t.Errorf("Expected error when cancelling a completed appeal")
+	}
+}
+
+func TestCancelAppeal(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	appeal := createTestAppeal(t, service)
+
+	if _, err := service.CancelAppeal(ctx, appeal.ID, models.UpdateAppealCancelRequest{Reason: "   "}); models.ErrorCode(err) != "reason_required" {
+		t.Errorf("Expected reason_required when cancelling without a reason, got %v", err)
+	}
+
+	cancelled, err := service.CancelAppeal(ctx, appeal.ID, models.UpdateAppealCancelRequest{Reason: "  Duplicate of another appeal "})
+	if err != nil {
+		t.Fatalf("Failed to cancel appeal: %v", err)
+	}
+	if cancelled.CancelReason != "Duplicate of another appeal" {
+		t.Errorf("Expected trimmed reason in response, got %q", cancelled.CancelReason)
+	}
+
+	found, err := service.GetAppealByID(ctx, appeal.ID)
+	if err != nil {
+		t.Fatalf("Failed to get appeal: %v", err)
+	}
+	if found.Status != models.StatusCancelled {
+		t.Errorf("Expected status %s, got %s", models.StatusCancelled, found.Status)
+	}
+	if found.CancelReason != "Duplicate of another appeal" {
+		t.Errorf("Expected reason to be stored, got %q", found.CancelReason)
+	}
+
+	restarted, err := service.StartProcessing(ctx, appeal.ID)
+	if err != nil {
+		t.Fatalf("Failed to restart cancelled appeal: %v", err)
+	}
+	if restarted.CancelReason != "" {
+		t.Errorf("Expected reason to be cleared on restart, got %q", restarted.CancelReason)
+	}
+
+	if _, err := service.CancelAppeal(ctx, "non-existent", models.UpdateAppealCancelRequest{Reason: "Duplicate"}); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when cancelling a non-existent appeal, got %v", err)
+	}
+}
+
+func TestGetStartedAppealsAndCancelAll(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	fresh := createTestAppeal(t, service)
+	started := createTestAppeal(t, service)
+	completed := createTestAppeal(t, service)
+
+	if _, err := service.StartProcessing(ctx, started.ID); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if _, err := service.StartProcessing(ctx, completed.ID); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if _, err := service.CompleteAppeal(ctx, completed.ID, models.UpdateAppealSolutionRequest{Solution: "Done"}); err != nil {
+		t.Fatalf("Failed to complete appeal: %v", err)
+	}
+
+	active := startedAppeals(t, service, models.AppealFilter{})
+	if len(active) != 2 {
+		t.Errorf("Expected 2 started appeals, got %d", len(active))
+	}
+
+	if _, err := service.CancelAllInProgress(ctx, models.UpdateAppealCancelRequest{}); err == nil {
+		t.Errorf("Expected error when cancelling all without a reason")
+	}
+
+	cancelled, err := service.CancelAllInProgress(ctx, models.UpdateAppealCancelRequest{Reason: "Office closed"})
+	if err != nil {
+		t.Fatalf("Failed to cancel all in progress: %v", err)
+	}
+	if cancelled != 2 {
+		t.Errorf("Expected 2 appeals cancelled, got %d", cancelled)
+	}
+
+	for _, id := range []string{fresh.ID, started.ID} {
+		appeal, err := service.GetAppealByID(ctx, id)
+		if err != nil {
+			t.Fatalf("Failed to get appeal: %v", err)
+		}
+		if appeal.Status != models.StatusCancelled {
+			t.Errorf("Expected appeal %s to be cancelled, got %s", id, appeal.Status)
+		}
+		if appeal.CancelReason != "Office closed" {
+			t.Errorf("Expected appeal %s to carry the bulk reason, got %q", id, appeal.CancelReason)
+		}
+	}
+
+	active = startedAppeals(t, service, models.AppealFilter{})
+	if len(active) != 0 {
+		t.Errorf("Expected no started appeals after cancel-all, got %d", len(active))
+	}
+}
+
+func TestCancelAllRestartsStoppedClocks(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	appeal := createTestAppeal(t, service)
+	if _, err := service.StartProcessing(ctx, appeal.ID); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	waiting, err := service.WaitForRequester(ctx, appeal.ID, models.UpdateAppealReasonRequest{Reason: "Need a photo"})
+	if err != nil || waiting.SLAPausedAt == nil {
+		t.Fatalf("Expected the SLA clock to stop, got %+v, %v", waiting, err)
+	}
+
+	if _, err := service.CancelAllInProgress(ctx, models.UpdateAppealCancelRequest{Reason: "Office closed"}); err != nil {
+		t.Fatalf("Failed to cancel all: %v", err)
+	}
+	report, err := service.GetAppealSLA(ctx, appeal.ID)
+	if err != nil {
+		t.Fatalf("Failed to get SLA: %v", err)
+	}
+	// Отменённое пачкой обращение не должно остаться на паузе.
+	if report.ClockStopped || report.ResolvedAt == nil {
+		t.Errorf("Expected the pause to end with the cancellation, got %+v", report)
+	}
+}
+
+func TestCancelAllFollowsWorkflow(t *testing.T) {
+	t.Parallel()
+
+	// Отмена в этом процессе ведёт в Rejected и сама проставляет решение.
+	def := workflow.Default().Definition()
+	def.Transitions = append([]workflow.Transition(nil), def.Transitions...)
+	for i, transition := range def.Transitions {
+		if transition.Name == "cancel" {
+			def.Transitions[i].To = models.StatusRejected
+			def.Transitions[i].Event = "appeal.closed"
+			def.Transitions[i].Effects = append(transition.Effects, "close")
+		}
+	}
+	machine, err := workflow.New(def, workflow.WithEffect("close", func(appeal *models.Appeal, in workflow.Input) {
+		appeal.Solution = "Closed by " + in.Actor
+	}))
+	if err != nil {
+		t.Fatalf("Failed to build workflow: %v", err)
+	}
+	repo := repository.NewMemoryAppealRepository()
+	service := NewAppealService(repo, machine)
+	appeal := createTestAppeal(t, service)
+
+	if cancelled, err := service.CancelAllInProgress(ctx, models.UpdateAppealCancelRequest{Reason: "Office closed"}); err != nil || cancelled != 1 {
+		t.Fatalf("Expected one appeal cancelled, got %d, %v", cancelled, err)
+	}
+	found, err := service.GetAppealByID(ctx, appeal.ID)
+	if err != nil {
+		t.Fatalf("Failed to get appeal: %v", err)
+	}
+	if found.Status != models.StatusRejected || found.Solution != "Closed by operator@example.com" || found.CancelReason != "Office closed" {
+		t.Errorf("Expected the workflow's target and effects, got %+v", found)
+	}
+
+	events, err := repo.ListEvents(0, 100)
+	if err != nil || len(events) != 2 {
+		t.Fatalf("Expected two events, got %+v, %v", events, err)
+	}
+	// Событие несёт обращение уже после отмены, а не снимок до неё.
+	closed := events[1]
+	if closed.Type != "appeal.closed" || closed.Appeal.Status != models.StatusRejected || closed.Appeal.ResolvedAt == nil ||
+		!closed.Appeal.UpdatedAt.Equal(found.UpdatedAt) {
+		t.Errorf("Expected the event to carry the cancelled appeal, got %+v", closed.Appeal)
+	}
+}
+
+func TestAppealHistory(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	appeal := createTestAppeal(t, service)
+	other := createTestAppeal(t, service)
+
+	if _, err := service.StartProcessing(ctx, appeal.ID); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if _, err := service.CompleteAppeal(ctx, appeal.ID, models.UpdateAppealSolutionRequest{Solution: "Done"}); err != nil {
+		t.Fatalf("Failed to complete appeal: %v", err)
+	}
+	// A rejected transition must not leave a history entry behind.
+	if _, err := service.CompleteAppeal(ctx, appeal.ID, models.UpdateAppealSolutionRequest{Solution: "Again"}); err == nil {
+		t.Errorf("Expected error when completing twice")
+	}
+	supervisor := WithActor(context.Background(), "supervisor@example.com")
+	if _, err := service.CancelAllInProgress(supervisor, models.UpdateAppealCancelRequest{Reason: "Office closed"}); err != nil {
+		t.Fatalf("Failed to cancel all: %v", err)
+	}
+
+	history, err := service.GetAppealHistory(ctx, appeal.ID)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+
+	expected := []models.StatusHistoryEntry{
+		{FromStatus: "", ToStatus: models.StatusNew, Actor: "operator@example.com"},
+		{FromStatus: models.StatusNew, ToStatus: models.StatusInProgress, Actor: "operator@example.com"},
+		{FromStatus: models.StatusInProgress, ToStatus: models.StatusCompleted, Actor: "operator@example.com"},
+	}
+	if len(history) != len(expected) {
+		t.Fatalf("Expected %d history entries, got %d", len(expected), len(history))
+	}
+	for i, want := range expected {
+		got := history[i]
+		if got.FromStatus != want.FromStatus || got.ToStatus != want.ToStatus || got.Actor != want.Actor {
+			t.Errorf("Entry %d: expected %s -> %s by %s, got %s -> %s by %s",
+				i, want.FromStatus, want.ToStatus, want.Actor, got.FromStatus, got.ToStatus, got.Actor)
+		}
+		if got.AppealID != appeal.ID || got.CreatedAt.IsZero() {
+			t.Errorf("Entry %d: expected appeal ID and timestamp, got %+v", i, got)
+		}
+	}
+
+	otherHistory, err := service.GetAppealHistory(ctx, other.ID)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	last := otherHistory[len(otherHistory)-1]
+	if last.FromStatus != models.StatusNew || last.ToStatus != models.StatusCancelled ||
+		last.Actor != "supervisor@example.com" || last.Reason != "Office closed" {
+		t.Errorf("Expected bulk cancellation entry, got %+v", last)
+	}
+
+	if _, err := service.GetAppealHistory(ctx, "non-existent"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected error for history of a non-existent appeal")
+	}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	appeal := createTestAppeal(t, service)
+
+	if _, err := service.HoldAppeal(ctx, appeal.ID, models.UpdateAppealReasonRequest{Reason: "Blocked"}); err == nil {
+		t.Errorf("Expected error when holding a new appeal")
+	}
+	if _, err := service.StartProcessing(ctx, appeal.ID); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if _, err := service.WaitForRequester(ctx, appeal.ID, models.UpdateAppealReasonRequest{}); err == nil {
+		t.Errorf("Expected error when waiting without a reason")
+	}
+
+	waiting, err := service.WaitForRequester(ctx, appeal.ID, models.UpdateAppealReasonRequest{Reason: "  Need a copy of the contract "})
+	if err != nil {
+		t.Fatalf("Failed to wait for requester: %v", err)
+	}
+	if !waiting.IsWaitingForRequester() {
+		t.Errorf("Expected appeal to wait for requester, got %s", waiting.Status)
+	}
+
+	held, err := service.HoldAppeal(ctx, appeal.ID, models.UpdateAppealReasonRequest{Reason: "Legal review"})
+	if err != nil {
+		t.Fatalf("Failed to hold appeal: %v", err)
+	}
+	if !held.IsOnHold() {
+		t.Errorf("Expected appeal to be on hold, got %s", held.Status)
+	}
+
+	// Приостановленные обращения остаются в списке активных.
+	active := startedAppeals(t, service, models.AppealFilter{})
+	if len(active) != 1 || active[0].ID != appeal.ID {
+		t.Errorf("Expected the held appeal among started appeals, got %d", len(active))
+	}
+
+	resumed, err := service.ResumeAppeal(ctx, appeal.ID)
+	if err != nil {
+		t.Fatalf("Failed to resume appeal: %v", err)
+	}
+	if !resumed.IsInProgress() {
+		t.Errorf("Expected appeal to be in progress, got %s", resumed.Status)
+	}
+	if _, err := service.ResumeAppeal(ctx, appeal.ID); err == nil {
+		t.Errorf("Expected error when resuming an appeal that is not paused")
+	}
+
+	history, err := service.GetAppealHistory(ctx, appeal.ID)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if len(history) != 5 {
+		t.Fatalf("Expected 5 history entries, got %d", len(history))
+	}
+	if history[2].Reason != "Need a copy of the contract" || history[2].ToStatus != models.StatusWaitingForRequester {
+		t.Errorf("Expected trimmed wait reason in history, got %+v", history[2])
+	}
+}
+
+func TestRejectAndReopen(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	rejected := createTestAppeal(t, service)
+	disputed := createTestAppeal(t, service)
+
+	appeal, err := service.RejectAppeal(ctx, rejected.ID, models.UpdateAppealReasonRequest{Reason: "Out of scope"})
+	if err != nil {
+		t.Fatalf("Failed to reject appeal: %v", err)
+	}
+	if !appeal.IsRejected() {
+		t.Errorf("Expected appeal to be rejected, got %s", appeal.Status)
+	}
+	if _, err := service.StartProcessing(ctx, rejected.ID); err == nil {
+		t.Errorf("Expected error when starting a rejected appeal")
+	}
+	if _, err := service.ReopenAppeal(ctx, rejected.ID, models.UpdateAppealReasonRequest{Reason: "Please reconsider"}); err == nil {
+		t.Errorf("Expected error when reopening a rejected appeal")
+	}
+
+	if _, err := service.ReopenAppeal(ctx, disputed.ID, models.UpdateAppealReasonRequest{Reason: "Not done"}); err == nil {
+		t.Errorf("Expected error when reopening a new appeal")
+	}
+	if _, err := service.StartProcessing(ctx, disputed.ID); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if _, err := service.CompleteAppeal(ctx, disputed.ID, models.UpdateAppealSolutionRequest{Solution: "Refunded"}); err != nil {
+		t.Fatalf("Failed to complete appeal: %v", err)
+	}
+
+	reopened, err := service.ReopenAppeal(ctx, disputed.ID, models.UpdateAppealReasonRequest{Reason: "Refund never arrived"})
+	if err != nil {
+		t.Fatalf("Failed to reopen appeal: %v", err)
+	}
+	if !reopened.IsReopened() || reopened.Solution != "Refunded" {
+		t.Errorf("Expected reopened appeal to keep its solution, got %+v", reopened)
+	}
+
+	active := startedAppeals(t, service, models.AppealFilter{})
+	if len(active) != 1 || active[0].ID != disputed.ID {
+		t.Errorf("Expected only the reopened appeal to be active, got %d", len(active))
+	}
+
+	if _, err := service.StartProcessing(ctx, disputed.ID); err != nil {
+		t.Fatalf("Failed to restart reopened appeal: %v", err)
+	}
+	if _, err := service.RejectAppeal(ctx, "missing", models.UpdateAppealReasonRequest{Reason: "Spam"}); err == nil {
+		t.Errorf("Expected error when rejecting a non-existent appeal")
+	}
+}
+
+func TestGetStartedAppealsFilter(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	fresh := createTestAppeal(t, service)
+	started := createTestAppeal(t, service)
+	completed := createTestAppeal(t, service)
+
+	for _, id := range []string{started.ID, completed.ID} {
+		if _, err := service.StartProcessing(ctx, id); err != nil {
+			t.Fatalf("Failed to start processing: %v", err)
+		}
+	}
+	if _, err := service.CompleteAppeal(ctx, completed.ID, models.UpdateAppealSolutionRequest{Solution: "Done"}); err != nil {
+		t.Fatalf("Failed to complete appeal: %v", err)
+	}
+
+	inProgress := startedAppeals(t, service, models.AppealFilter{
+		Statuses: []models.AppealStatus{models.StatusInProgress, models.StatusCompleted},
+	})
+	if len(inProgress) != 1 || inProgress[0].ID != started.ID {
+		t.Errorf("Expected only the in-progress appeal, got %d", len(inProgress))
+	}
+
+	if inactive := startedAppeals(t, service, models.AppealFilter{
+		Statuses: []models.AppealStatus{models.StatusCompleted},
+	}); len(inactive) != 0 {
+		t.Errorf("Expected no started appeals among completed ones, got %d", len(inactive))
+	}
+
+	page, err := service.GetStartedAppeals(ctx, models.AppealFilter{Limit: 1, Descending: true})
+	if err != nil {
+		t.Fatalf("Failed to get started appeals: %v", err)
+	}
+	if len(page.Appeals) != 1 || page.NextCursor == "" {
+		t.Fatalf("Expected a one-appeal page with a cursor, got %+v", page)
+	}
+	next, err := service.GetStartedAppeals(ctx, models.AppealFilter{Limit: 1, Descending: true, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("Failed to get next page: %v", err)
+	}
+	if len(next.Appeals) != 1 || next.NextCursor != "" {
+		t.Errorf("Expected a final one-appeal page, got %+v", next)
+	}
+	seen := map[string]bool{page.Appeals[0].ID: true, next.Appeals[0].ID: true}
+	if !seen[fresh.ID] || !seen[started.ID] {
+		t.Errorf("Expected pages to cover both active appeals")
+	}
+
+	if _, err := service.GetStartedAppeals(ctx, models.AppealFilter{Statuses: []models.AppealStatus{models.StatusCompleted}, Limit: -1}); !errors.Is(err, models.ErrValidation) {
+		t.Errorf("Expected validation error for a negative limit, got %v", err)
+	}
+}
+
+func TestSearchAppeals(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	pothole, err := service.CreateAppeal(ctx, models.CreateAppealRequest{Theme: "Яма на дороге", Message: "Глубокая яма на улице Ленина"})
+	if err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+	if _, err := service.CreateAppeal(ctx, models.CreateAppealRequest{Theme: "Шум", Message: "Шумные соседи"}); err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+	if _, err := service.StartProcessing(ctx, pothole.ID); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if _, err := service.CompleteAppeal(ctx, pothole.ID, models.UpdateAppealSolutionRequest{Solution: "Яму заделали"}); err != nil {
+		t.Fatalf("Failed to complete appeal: %v", err)
+	}
+
+	results, err := service.SearchAppeals(ctx, models.SearchRequest{Query: "ямы"})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].Appeal.ID != pothole.ID {
+		t.Fatalf("Expected the pothole appeal, got %d results", len(results))
+	}
+
+	expected := map[string]string{
+		"theme":    "<mark>Яма</mark> на дороге",
+		"message":  "Глубокая <mark>яма</mark> на улице Ленина",
+		"solution": "<mark>Яму</mark> заделали",
+	}
+	for field, want := range expected {
+		if got := results[0].Highlights[field]; got != want {
+			t.Errorf("Expected %s highlight %q, got %q", field, want, got)
+		}
+	}
+
+	results, err = service.SearchAppeals(ctx, models.SearchRequest{Query: "шум*"})
+	if err != nil {
+		t.Fatalf("Failed to search: %v", err)
+	}
+	if len(results) != 1 || results[0].Highlights["solution"] != "" {
+		t.Errorf("Expected one match without a solution highlight, got %+v", results)
+	}
+
+	for _, req := range []models.SearchRequest{
+		{Query: "  "},
+		{Query: "яма", Limit: models.MaxSearchLimit + 1},
+		{Query: "яма", Offset: -1},
+	} {
+		if _, err := service.SearchAppeals(ctx, req); !errors.Is(err, models.ErrValidation) {
+			t.Errorf("Expected validation error for %+v, got %v", req, err)
+		}
+	}
+}
+
+func TestAssignment(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	anna := WithActor(context.Background(), "anna")
+	boris := WithActor(context.Background(), "boris")
+	claimed := createTestAppeal(t, service)
+	assigned := createTestAppeal(t, service)
+	started := createTestAppeal(t, service)
+
+	if _, err := service.ClaimAppeal(context.Background(), claimed.ID); models.ErrorCode(err) != "actor_required" {
+		t.Errorf("Expected actor_required for an anonymous claim, got %v", err)
+	}
+	appeal, err := service.ClaimAppeal(anna, claimed.ID)
+	if err != nil {
+		t.Fatalf("Failed to claim appeal: %v", err)
+	}
+	if appeal.Assignee != "anna" {
+		t.Errorf("Expected anna to hold the appeal, got %q", appeal.Assignee)
+	}
+	if _, err := service.ClaimAppeal(anna, claimed.ID); err != nil {
+		t.Errorf("Expected claiming twice to be a no-op, got %v", err)
+	}
+	if _, err := service.ClaimAppeal(boris, claimed.ID); !errors.Is(err, models.ErrConflict) {
+		t.Errorf("Expected conflict when claiming someone else's appeal, got %v", err)
+	}
+
+	if _, err := service.AssignAppeal(ctx, assigned.ID, models.AssignAppealRequest{Assignee: " boris "}); err != nil {
+		t.Fatalf("Failed to assign appeal: %v", err)
+	}
+	if _, err := service.AssignAppeal(ctx, assigned.ID, models.AssignAppealRequest{Assignee: "anna"}); !errors.Is(err, models.ErrConflict) {
+		t.Errorf("Expected conflict when assigning an assigned appeal, got %v", err)
+	}
+	if _, err := service.StartProcessing(anna, assigned.ID); !errors.Is(err, models.ErrConflict) {
+		t.Errorf("Expected conflict when starting boris's appeal, got %v", err)
+	}
+	appeal, err = service.ReassignAppeal(ctx, assigned.ID, models.AssignAppealRequest{Assignee: "anna"})
+	if err != nil {
+		t.Fatalf("Failed to reassign appeal: %v", err)
+	}
+	if appeal.Assignee != "anna" {
+		t.Errorf("Expected appeal to move to anna, got %q", appeal.Assignee)
+	}
+
+	// StartProcessing закрепляет обращение за тем, кто его взял.
+	appeal, err = service.StartProcessing(boris, started.ID)
+	if err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if appeal.Assignee != "boris" {
+		t.Errorf("Expected starting operator to become the assignee, got %q", appeal.Assignee)
+	}
+
+	page, err := service.GetMyAppeals(anna, models.AppealFilter{})
+	if err != nil {
+		t.Fatalf("Failed to get anna's queue: %v", err)
+	}
+	if len(page.Appeals) != 2 {
+		t.Errorf("Expected 2 appeals in anna's queue, got %d", len(page.Appeals))
+	}
+	if _, err := service.GetMyAppeals(context.Background(), models.AppealFilter{}); models.ErrorCode(err) != "actor_required" {
+		t.Errorf("Expected actor_required for an anonymous queue, got %v", err)
+	}
+
+	if _, err := service.CompleteAppeal(boris, started.ID, models.UpdateAppealSolutionRequest{Solution: "Done"}); err != nil {
+		t.Fatalf("Failed to complete appeal: %v", err)
+	}
+	if _, err := service.ReassignAppeal(ctx, started.ID, models.AssignAppealRequest{Assignee: "anna"}); !errors.Is(err, models.ErrInvalidTransition) {
+		t.Errorf("Expected completed appeals to be closed for assignment, got %v", err)
+	}
+}
+
+func TestSLA(t *testing.T) {
+	t.Parallel()
+
+	policy, err := sla.Parse([]byte(`{
+		"due_soon": "2h",
+		"targets": [
+			{"response": "1d", "resolution": "3d"},
+			{"priority": "urgent", "response": "1h", "resolution": "4h"},
+			{"theme": "Outage", "resolution": "2h"}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to parse SLA policy: %v", err)
+	}
+	service := NewAppealService(repository.NewMemoryAppealRepository(), workflow.Default(),
+		WithSLAPolicy(policy), WithCalendar(calendar.AlwaysOpen()))
+
+	if _, err := service.CreateAppeal(ctx, models.CreateAppealRequest{Theme: "Theme", Message: "Message", Priority: "critical"}); models.ErrorCode(err) != "invalid_priority" {
+		t.Errorf("Expected invalid_priority error, got %v", err)
+	}
+
+	normal := createTestAppeal(t, service)
+	if normal.Priority != models.PriorityNormal {
+		t.Errorf("Expected default priority %s, got %s", models.PriorityNormal, normal.Priority)
+	}
+	if normal.ResolutionDueAt == nil || normal.ResolutionDueAt.Sub(normal.CreatedAt) != 72*time.Hour {
+		t.Errorf("Expected resolution due in 3 days, got %v", normal.ResolutionDueAt)
+	}
+
+	urgent, err := service.CreateAppeal(ctx, models.CreateAppealRequest{Theme: "Theme", Message: "Message", Priority: models.PriorityUrgent})
+	if err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+	if urgent.ResponseDueAt == nil || urgent.ResponseDueAt.Sub(urgent.CreatedAt) != time.Hour {
+		t.Errorf("Expected response due in an hour, got %v", urgent.ResponseDueAt)
+	}
+
+	// Тема важнее приоритета, а цели без срока ответа нет и дедлайна.
+	outage, err := service.CreateAppeal(ctx, models.CreateAppealRequest{Theme: "outage", Message: "Message", Priority: models.PriorityUrgent})
+	if err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+	if outage.ResponseDueAt != nil || outage.ResolutionDueAt == nil || outage.ResolutionDueAt.Sub(outage.CreatedAt) != 2*time.Hour {
+		t.Errorf("Expected only a 2h resolution deadline, got %v / %v", outage.ResponseDueAt, outage.ResolutionDueAt)
+	}
+
+	page, err := service.GetStartedAppeals(ctx, models.AppealFilter{SLA: models.SLADueSoon})
+	if err != nil {
+		t.Fatalf("Failed to list appeals due soon: %v", err)
+	}
+	if len(page.Appeals) != 2 {
+		t.Errorf("Expected the urgent and outage appeals to be due soon, got %d", len(page.Appeals))
+	}
+
+	started, err := service.StartProcessing(ctx, urgent.ID)
+	if err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if started.RespondedAt == nil || started.ResolvedAt != nil {
+		t.Errorf("Expected start to count as the response, got %v / %v", started.RespondedAt, started.ResolvedAt)
+	}
+
+	cancelled, err := service.CancelAppeal(ctx, urgent.ID, models.UpdateAppealCancelRequest{Reason: "Duplicate"})
+	if err != nil {
+		t.Fatalf("Failed to cancel appeal: %v", err)
+	}
+	if cancelled.ResolvedAt == nil || !cancelled.RespondedAt.Equal(*started.RespondedAt) {
+		t.Errorf("Expected cancellation to resolve the appeal and keep the response time")
+	}
+
+	restarted, err := service.StartProcessing(ctx, urgent.ID)
+	if err != nil {
+		t.Fatalf("Failed to restart processing: %v", err)
+	}
+	if restarted.ResolvedAt != nil || !restarted.ResolutionDueAt.After(*urgent.ResolutionDueAt) {
+		t.Errorf("Expected a restarted appeal to get a fresh resolution deadline, got %v", restarted.ResolutionDueAt)
+	}
+	if restarted.SLABreached(time.Now()) {
+		t.Errorf("Expected restarted appeal to be within SLA")
+	}
+	if !restarted.SLABreached(time.Now().Add(5 * time.Hour)) {
+		t.Errorf("Expected restarted appeal to breach once the deadline passes")
+	}
+}
+
+func TestSLAClockStopsWhileWaiting(t *testing.T) {
+	t.Parallel()
+
+	service := NewAppealService(repository.NewMemoryAppealRepository(), workflow.Default(), WithCalendar(calendar.AlwaysOpen()))
+	appeal := createTestAppeal(t, service)
+	if _, err := service.StartProcessing(ctx, appeal.ID); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+
+	waiting, err := service.WaitForRequester(ctx, appeal.ID, models.UpdateAppealReasonRequest{Reason: "Need the contract number"})
+	if err != nil {
+		t.Fatalf("Failed to wait for requester: %v", err)
+	}
+	if waiting.SLAPausedAt == nil {
+		t.Fatalf("Expected waiting on the requester to stop the SLA clock")
+	}
+	report, err := service.GetAppealSLA(ctx, appeal.ID)
+	if err != nil {
+		t.Fatalf("Failed to get SLA report: %v", err)
+	}
+	if !report.ClockStopped {
+		t.Errorf("Expected the report to show the stopped clock")
+	}
+
+	// Отложенное обращение останавливает часы только в состоянии ожидания заявителя.
+	resumed, err := service.ResumeAppeal(ctx, appeal.ID)
+	if err != nil {
+		t.Fatalf("Failed to resume appeal: %v", err)
+	}
+	if resumed.SLAPausedAt != nil || resumed.SLAPausedFor <= 0 {
+		t.Fatalf("Expected the clock to restart and count the pause, got %v", resumed.SLAPausedFor)
+	}
+	if !resumed.ResolutionDueAt.Equal(appeal.ResolutionDueAt.Add(resumed.SLAPausedFor)) {
+		t.Errorf("Expected the resolution deadline to move by the pause, got %v", resumed.ResolutionDueAt)
+	}
+
+	held, err := service.HoldAppeal(ctx, appeal.ID, models.UpdateAppealReasonRequest{Reason: "Waiting for the vendor"})
+	if err != nil {
+		t.Fatalf("Failed to hold appeal: %v", err)
+	}
+	if held.SLAPausedAt != nil {
+		t.Errorf("Expected the clock to keep running while on hold")
+	}
+
+	if _, err := service.GetAppealSLA(ctx, "missing"); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+type countingNotifier struct {
+	calls int
+}
+
+func (n *countingNotifier) Notify() {
+	n.calls++
+}
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	repo := repository.NewMemoryAppealRepository()
+	notifier := &countingNotifier{}
+	service := NewAppealService(repo, workflow.Default(), WithNotifier(notifier))
+
+	appeal := createTestAppeal(t, service)
+	if _, err := service.StartProcessing(ctx, appeal.ID); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if _, err := service.ReassignAppeal(ctx, appeal.ID, models.AssignAppealRequest{Assignee: "jane"}); err != nil {
+		t.Fatalf("Failed to reassign appeal: %v", err)
+	}
+	if _, err := service.CompleteAppeal(ctx, appeal.ID, models.UpdateAppealSolutionRequest{Solution: ""}); err == nil {
+		t.Fatalf("Expected completion without a solution to fail")
+	}
+	if _, err := service.CompleteAppeal(ctx, appeal.ID, models.UpdateAppealSolutionRequest{Solution: "Refilled"}); err != nil {
+		t.Fatalf("Failed to complete appeal: %v", err)
+	}
+	other := createTestAppeal(t, service)
+	if _, err := service.CancelAllInProgress(ctx, models.UpdateAppealCancelRequest{Reason: "Office closed"}); err != nil {
+		t.Fatalf("Failed to cancel all: %v", err)
+	}
+
+	// Неудачные переходы событий не порождают.
+	expected := []string{"appeal.created", "appeal.started", "appeal.assigned", "appeal.completed", "appeal.created", "appeal.cancelled"}
+	events, err := repo.ListEvents(0, 100)
+	if err != nil {
+		t.Fatalf("Failed to list events: %v", err)
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected events %v, got %+v", expected, events)
+	}
+	if notifier.calls != len(expected) {
+		t.Errorf("Expected a notification per committed change, got %d", notifier.calls)
+	}
+	for i, event := range events {
+		if event.Sequence != int64(i+1) {
+			t.Errorf("Event %d: expected sequence %d, got %d", i, i+1, event.Sequence)
+		}
+		if event.Type != expected[i] {
+			t.Errorf("Event %d: expected %s, got %s", i, expected[i], event.Type)
+		}
+		if event.ID == "" || event.Actor != "operator@example.com" || event.Appeal == nil || event.Appeal.ID != event.AppealID {
+			t.Errorf("Event %d is incomplete: %+v", i, event)
+		}
+	}
+
+	completed := events[3]
+	if completed.FromStatus != models.StatusInProgress || completed.Appeal.Status != models.StatusCompleted || completed.Appeal.Solution != "Refilled" {
+		t.Errorf("Expected the completion to carry the completed appeal, got %+v", completed)
+	}
+	cancelled := events[5]
+	if cancelled.AppealID != other.ID || cancelled.FromStatus != models.StatusNew || cancelled.Appeal.CancelReason != "Office closed" {
+		t.Errorf("Expected the bulk cancellation to carry the cancelled appeal, got %+v", cancelled)
+	}
+}
+
+func TestAccessControl(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	as := func(subject string, role models.Role) context.Context {
+		return WithPrincipal(context.Background(), &models.Principal{Subject: subject, Roles: []models.Role{role}})
+	}
+	jane := as("jane", models.RoleRequester)
+	olga := as("olga", models.RoleOperator)
+	sam := as("sam", models.RoleSupervisor)
+
+	appeal, err := service.CreateAppeal(jane, models.CreateAppealRequest{Theme: "Printer", Message: "Out of toner"})
+	if err != nil || appeal.CreatedBy != "jane" {
+		t.Fatalf("Expected jane's appeal, got %+v, %v", appeal, err)
+	}
+	createTestAppeal(t, service)
+
+	page, err := service.GetAllAppeals(jane, models.AppealFilter{})
+	if err != nil || len(page.Appeals) != 1 || page.Appeals[0].ID != appeal.ID {
+		t.Errorf("Expected only jane's appeal, got %v, %v", page, err)
+	}
+	if _, err := service.GetAppealHistory(as("john", models.RoleRequester), appeal.ID); models.ErrorCode(err) != "not_your_appeal" {
+		t.Errorf("Expected not_your_appeal, got %v", err)
+	}
+	if _, err := service.GetMyReminders(jane, models.ReminderFilter{}); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("Expected a requester to have no reminders, got %v", err)
+	}
+	if _, err := service.AssignAppeal(olga, appeal.ID, models.AssignAppealRequest{Assignee: "olga"}); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("Expected an operator to be refused assigning, got %v", err)
+	}
+	if _, err := service.AssignAppeal(sam, appeal.ID, models.AssignAppealRequest{Assignee: "olga"}); err != nil {
+		t.Fatalf("Failed to assign appeal: %v", err)
+	}
+	if _, err := service.StartProcessing(olga, appeal.ID); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if _, err := service.CompleteAppeal(as("oleg", models.RoleOperator), appeal.ID, models.UpdateAppealSolutionRequest{Solution: "Refilled"}); models.ErrorCode(err) != "not_assignee" {
+		t.Errorf("Expected not_assignee, got %v", err)
+	}
+	if _, err := service.CompleteAppeal(olga, appeal.ID, models.UpdateAppealSolutionRequest{Solution: "Refilled"}); err != nil {
+		t.Errorf("Expected the assignee to complete, got %v", err)
+	}
+
+	if _, err := service.CancelAllInProgress(olga, models.UpdateAppealCancelRequest{Reason: "Outage"}); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("Expected an operator to be refused cancelling everything, got %v", err)
+	}
+	// Задания планировщика идут без принципала и проверок.
+	if _, err := service.CancelAllInProgress(WithActor(context.Background(), SchedulerActor), models.UpdateAppealCancelRequest{Reason: "Outage"}); err != nil {
+		t.Errorf("Expected jobs to be trusted, got %v", err)
+	}
+}
+
+func TestTenants(t *testing.T) {
+	t.Parallel()
+
+	tenants, err := tenant.Parse([]byte(`{"tenants": [
+		{"id": "acme", "name": "Acme", "themes": ["Printer"],
+		 "sla": {"due_soon": "1h", "targets": [{"response": "1h", "resolution": "2h"}]}},
+		{"id": "globex", "name": "Globex"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse tenants: %v", err)
+	}
+	service := NewAppealService(repository.NewMemoryAppealRepository(), workflow.Default(),
+		WithTenants(tenants), WithCalendar(calendar.AlwaysOpen()))
+	acmeCtx, globexCtx := WithTenant(ctx, "acme"), WithTenant(ctx, "globex")
+
+	if _, err := service.CreateAppeal(acmeCtx, models.CreateAppealRequest{Theme: "Scanner", Message: "Jammed"}); models.ErrorCode(err) != "unknown_theme" {
+		t.Errorf("Expected unknown_theme, got %v", err)
+	}
+	if _, err := service.CreateAppeal(WithTenant(ctx, "initech"), models.CreateAppealRequest{Theme: "Printer", Message: "Jammed"}); models.ErrorCode(err) != "tenant_not_found" {
+		t.Errorf("Expected tenant_not_found, got %v", err)
+	}
+
+	acme, err := service.CreateAppeal(acmeCtx, models.CreateAppealRequest{Theme: "printer", Message: "Out of toner"})
+	if err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+	if acme.TenantID != "acme" || acme.ResolutionDueAt == nil || acme.ResolutionDueAt.Sub(acme.CreatedAt) != 2*time.Hour {
+		t.Errorf("Expected an acme appeal under acme's SLA, got %+v", acme)
+	}
+	globex, err := service.CreateAppeal(globexCtx, models.CreateAppealRequest{Theme: "Scanner", Message: "Jammed"})
+	if err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+	// У globex своей политики нет, действует общая.
+	if globex.TenantID != "globex" || globex.ResolutionDueAt.Sub(globex.CreatedAt) == 2*time.Hour {
+		t.Errorf("Expected a globex appeal under the service SLA, got %+v", globex)
+	}
+
+	if _, err := service.GetAppealByID(globexCtx, acme.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected another tenant's appeal to be invisible, got %v", err)
+	}
+	if _, err := service.StartProcessing(globexCtx, acme.ID); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected another tenant's appeal to be untouchable, got %v", err)
+	}
+	for _, appeal := range []*models.Appeal{acme, globex} {
+		if _, err := service.StartProcessing(WithTenant(ctx, appeal.TenantID), appeal.ID); err != nil {
+			t.Fatalf("Failed to start processing: %v", err)
+		}
+	}
+
+	cancelled, err := service.CancelAllInProgress(acmeCtx, models.UpdateAppealCancelRequest{Reason: "Closing"})
+	if err != nil || cancelled != 1 {
+		t.Fatalf("Expected only acme's appeal to be cancelled, got %d, %v", cancelled, err)
+	}
+	if found, err := service.GetAppealByID(globexCtx, globex.ID); err != nil || found.Status != models.StatusInProgress {
+		t.Errorf("Expected globex's appeal to stay in progress, got %+v, %v", found, err)
+	}
+
+	// Задание планировщика обходит все организации.
+	result, err := service.Jobs()["cancel_all_in_progress"](ctx, nil)
+	if err != nil || result != "cancelled 1 appeals" {
+		t.Errorf("Unexpected result %q, %v", result, err)
+	}
+}
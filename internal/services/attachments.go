@@ -0,0 +1,287 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go_appeals/internal/blob"
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+)
+
+// AttachmentLimits bound what may be attached to an appeal. ContentTypes
+// are matched against the type sniffed from the content, not the one the
+// client claims.
+type AttachmentLimits struct {
+	MaxSize      int64
+	MaxFiles     int
+	ContentTypes []string
+}
+
+var DefaultAttachmentLimits = AttachmentLimits{
+	MaxSize:  10 << 20,
+	MaxFiles: 10,
+	ContentTypes: []string{
+		"image/jpeg",
+		"image/png",
+		"image/gif",
+		"image/webp",
+		"application/pdf",
+	},
+}
+
+// WithAttachments keeps attached files in store, replacing the built-in
+// in-memory store, and enforces limits on them.
+func WithAttachments(store blob.Store, limits AttachmentLimits) Option {
+	return func(s *AppealService) { s.blobs, s.limits = store, limits }
+}
+
+func (s *AppealService) AttachmentLimits() AttachmentLimits {
+	return s.limits
+}
+
+// GetAttachments lists the files attached to an appeal, oldest first.
+func (s *AppealService) GetAttachments(ctx context.Context, appealID string) ([]*models.Attachment, error) {
+	appeal, err := s.readAppeal(ctx, appealID)
+	if err != nil {
+		return nil, err
+	}
+	return s.store(ctx).ListAttachments(appeal.ID)
+}
+
+// AddAttachments attaches files to an existing appeal. Anyone who may read
+// the appeal may attach to it, up to the limit of files per appeal.
+func (s *AppealService) AddAttachments(ctx context.Context, appealID string, uploads []models.AttachmentUpload) ([]*models.Attachment, error) {
+	if len(uploads) == 0 {
+		return nil, models.NewError(models.ErrValidation, "attachment_required", "at least one file is required")
+	}
+	appeal, err := s.readAppeal(ctx, appealID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkAttachmentCount(s.store(ctx), appeal.ID, len(uploads)); err != nil {
+		return nil, err
+	}
+
+	attachments, err := s.storeUploads(ctx, appeal, uploads)
+	if err != nil {
+		return nil, err
+	}
+	err = s.store(ctx).WithTx(func(tx repository.AppealStore) error {
+		// Counted again: another upload may have been saved since.
+		if err := s.checkAttachmentCount(tx, appeal.ID, len(uploads)); err != nil {
+			return err
+		}
+		return saveAttachments(tx, attachments)
+	})
+	s.settleUploads(ctx, attachments, err == nil)
+	if err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// OpenAttachment returns an attached file and its content, which the caller
+// must close.
+func (s *AppealService) OpenAttachment(ctx context.Context, appealID, id string) (*models.Attachment, io.ReadSeekCloser, error) {
+	appeal, err := s.readAppeal(ctx, appealID)
+	if err != nil {
+		return nil, nil, err
+	}
+	attachment, err := s.store(ctx).FindAttachment(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attachment.AppealID != appeal.ID {
+		return nil, nil, models.NewError(models.ErrNotFound, "attachment_not_found", "attachment %s not found", id)
+	}
+
+	content, err := s.blobs.Open(attachment.SHA256)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+// storeUploads checks the files and puts their content into blob storage.
+// The returned attachments still have to be saved, after which the caller
+// must hand them to settleUploads whether saving worked or not.
+func (s *AppealService) storeUploads(ctx context.Context, appeal *models.Appeal, uploads []models.AttachmentUpload) ([]*models.Attachment, error) {
+	if len(uploads) > s.limits.MaxFiles {
+		return nil, tooManyAttachments(s.limits.MaxFiles)
+	}
+
+	attachments := make([]*models.Attachment, 0, len(uploads))
+	for _, upload := range uploads {
+		attachment, err := s.storeUpload(ctx, appeal, upload)
+		if err != nil {
+			s.settleUploads(ctx, attachments, false)
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+func (s *AppealService) storeUpload(ctx context.Context, appeal *models.Appeal, upload models.AttachmentUpload) (*models.Attachment, error) {
+	name := cleanFileName(upload.FileName)
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(upload.Content, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if n == 0 {
+		return nil, models.NewError(models.ErrValidation, "empty_attachment", "%s is empty", name)
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(head[:n]), ";")
+	if !slices.Contains(s.limits.ContentTypes, contentType) {
+		return nil, models.NewError(models.ErrValidation, "unsupported_attachment_type",
+			"%s is %s; allowed types are %s", name, contentType, strings.Join(s.limits.ContentTypes, ", "))
+	}
+
+	tooLarge := models.NewError(models.ErrTooLarge, "attachment_too_large", "%s exceeds %d bytes", name, s.limits.MaxSize)
+	content := &cappedReader{
+		r:   io.LimitReader(io.MultiReader(bytes.NewReader(head[:n]), upload.Content), s.limits.MaxSize+1),
+		max: s.limits.MaxSize,
+		err: tooLarge,
+	}
+	key, size, err := s.blobs.Put(content)
+	if errors.Is(err, tooLarge) {
+		return nil, tooLarge
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store %s: %w", name, err)
+	}
+	if err := s.pending.hold(s.blobs, key); err != nil {
+		return nil, fmt.Errorf("failed to store %s: %w", name, err)
+	}
+
+	return &models.Attachment{
+		TenantID:    appeal.TenantID,
+		AppealID:    appeal.ID,
+		FileName:    name,
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      key,
+		UploadedBy:  ActorFromContext(ctx),
+		CreatedAt:   time.Now(),
+	}, nil
+}
+
+// settleUploads is called once attachments from storeUploads are saved or
+// given up on. Content that was given up on is deleted unless an attachment
+// refers to it or another request is still saving it. Deleting is best
+// effort: content left behind is only wasted space.
+func (s *AppealService) settleUploads(ctx context.Context, attachments []*models.Attachment, saved bool) {
+	for _, attachment := range attachments {
+		s.pending.release(attachment.SHA256, func() {
+			if saved {
+				return
+			}
+			if inUse, err := s.store(ctx).ContentInUse(attachment.SHA256); err == nil && !inUse {
+				_ = s.blobs.Delete(attachment.SHA256)
+			}
+		})
+	}
+}
+
+// pendingUploads counts, per key, the requests that stored content and
+// have not saved their attachments yet.
+type pendingUploads struct {
+	mu   sync.Mutex
+	keys map[string]int
+}
+
+// hold marks the content as about to be saved. It fails if the content
+// was deleted between being put and being held.
+func (p *pendingUploads) hold(blobs blob.Store, key string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	content, err := blobs.Open(key)
+	if err != nil {
+		return err
+	}
+	content.Close()
+	if p.keys == nil {
+		p.keys = make(map[string]int)
+	}
+	p.keys[key]++
+	return nil
+}
+
+// release undoes hold and calls last if nothing holds the content any
+// more, before anything can hold it again.
+func (p *pendingUploads) release(key string, last func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.keys[key]--
+	if p.keys[key] > 0 {
+		return
+	}
+	delete(p.keys, key)
+	last()
+}
+
+func saveAttachments(tx repository.AppealStore, attachments []*models.Attachment) error {
+	for _, attachment := range attachments {
+		if err := tx.AddAttachment(attachment); err != nil {
+			return fmt.Errorf("failed to save attachment: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkAttachmentCount fails when adding more files would take an appeal
+// past the limit.
+func (s *AppealService) checkAttachmentCount(store repository.AppealStore, appealID string, more int) error {
+	existing, err := store.ListAttachments(appealID)
+	if err != nil {
+		return err
+	}
+	if len(existing)+more > s.limits.MaxFiles {
+		return tooManyAttachments(s.limits.MaxFiles)
+	}
+	return nil
+}
+
+func tooManyAttachments(max int) error {
+	return models.NewError(models.ErrValidation, "too_many_attachments", "an appeal may have at most %d attachments", max)
+}
+
+// cleanFileName keeps only the last element of a client-supplied path.
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(strings.TrimSpace(name), `\`, "/"))
+	if name == "." || name == "/" || name == "" {
+		return "attachment"
+	}
+	return name
+}
+
+// cappedReader fails with err once more than max bytes have been read.
+type cappedReader struct {
+	r    io.Reader
+	read int64
+	max  int64
+	err  error
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += int64(n)
+	if c.read > c.max {
+		return 0, c.err
+	}
+	return n, err
+}
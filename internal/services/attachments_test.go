@@ -0,0 +1,198 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"go_appeals/internal/blob"
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+	"go_appeals/internal/workflow"
+)
+
+// pngHeader is enough of a PNG for the content type to be sniffed.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func upload(name string, content []byte) models.AttachmentUpload {
+	return models.AttachmentUpload{FileName: name, Content: bytes.NewReader(content)}
+}
+
+func TestAttachments(t *testing.T) {
+	t.Parallel()
+
+	blobs := blob.NewMemory()
+	limits := AttachmentLimits{MaxSize: 64, MaxFiles: 2, ContentTypes: []string{"image/png", "application/pdf"}}
+	service := NewAppealService(repository.NewMemoryAppealRepository(), workflow.Default(), WithAttachments(blobs, limits))
+	as := func(subject string, role models.Role) context.Context {
+		return WithPrincipal(context.Background(), &models.Principal{Subject: subject, Roles: []models.Role{role}})
+	}
+	jane, john := as("jane", models.RoleRequester), as("john", models.RoleRequester)
+
+	photo := append(append([]byte{}, pngHeader...), "pixels"...)
+	appeal, err := service.CreateAppeal(jane, models.CreateAppealRequest{
+		Theme:       "Printer",
+		Message:     "Paper jam",
+		Attachments: []models.AttachmentUpload{upload(`C:\Users\jane\jam.png`, photo)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+	if len(appeal.Attachments) != 1 {
+		t.Fatalf("Expected the attachment in the created appeal, got %+v", appeal.Attachments)
+	}
+	attached := appeal.Attachments[0]
+	if attached.FileName != "jam.png" || attached.ContentType != "image/png" || attached.Size != int64(len(photo)) ||
+		attached.UploadedBy != "jane" || attached.AppealID != appeal.ID || len(attached.SHA256) != 64 {
+		t.Errorf("Unexpected attachment %+v", attached)
+	}
+
+	fetched, err := service.GetAppealByID(jane, appeal.ID)
+	if err != nil || len(fetched.Attachments) != 1 || fetched.Attachments[0].ID != attached.ID {
+		t.Errorf("Expected the attachment with the appeal, got %+v, %v", fetched, err)
+	}
+
+	tests := []struct {
+		name    string
+		uploads []models.AttachmentUpload
+		code    string
+	}{
+		{"None", nil, "attachment_required"},
+		{"Empty", []models.AttachmentUpload{upload("empty.png", nil)}, "empty_attachment"},
+		// Тип определяется по содержимому, а не по расширению.
+		{"Disguised", []models.AttachmentUpload{upload("invoice.pdf", []byte("MZ\x90\x00 not a pdf"))}, "unsupported_attachment_type"},
+		{"TooLarge", []models.AttachmentUpload{upload("huge.png", append(append([]byte{}, pngHeader...), make([]byte, 64)...))}, "attachment_too_large"},
+		{"TooMany", []models.AttachmentUpload{upload("a.png", photo), upload("b.png", photo)}, "too_many_attachments"},
+	}
+	for _, tt := range tests {
+		if _, err := service.AddAttachments(jane, appeal.ID, tt.uploads); models.ErrorCode(err) != tt.code {
+			t.Errorf("%s: expected %s, got %v", tt.name, tt.code, err)
+		}
+	}
+	if _, err := service.AddAttachments(john, appeal.ID, []models.AttachmentUpload{upload("mine.png", photo)}); models.ErrorCode(err) != "not_your_appeal" {
+		t.Errorf("Expected not_your_appeal, got %v", err)
+	}
+
+	added, err := service.AddAttachments(jane, appeal.ID, []models.AttachmentUpload{upload("again.png", photo)})
+	if err != nil {
+		t.Fatalf("Failed to add attachment: %v", err)
+	}
+	if added[0].SHA256 != attached.SHA256 {
+		t.Errorf("Expected the same content to have the same hash, got %s and %s", added[0].SHA256, attached.SHA256)
+	}
+	list, err := service.GetAttachments(jane, appeal.ID)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("Expected both attachments, got %+v, %v", list, err)
+	}
+
+	found, content, err := service.OpenAttachment(jane, appeal.ID, attached.ID)
+	if err != nil {
+		t.Fatalf("Failed to open attachment: %v", err)
+	}
+	data, _ := io.ReadAll(content)
+	content.Close()
+	if found.ID != attached.ID || !bytes.Equal(data, photo) {
+		t.Errorf("Expected the uploaded content, got %q", data)
+	}
+
+	other, err := service.CreateAppeal(jane, models.CreateAppealRequest{Theme: "Printer", Message: "No toner"})
+	if err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+	if _, _, err := service.OpenAttachment(jane, other.ID, attached.ID); models.ErrorCode(err) != "attachment_not_found" {
+		t.Errorf("Expected attachment_not_found through another appeal, got %v", err)
+	}
+	if _, _, err := service.OpenAttachment(john, appeal.ID, attached.ID); models.ErrorCode(err) != "not_your_appeal" {
+		t.Errorf("Expected not_your_appeal, got %v", err)
+	}
+
+	_, err = service.CreateAppeal(jane, models.CreateAppealRequest{
+		Theme:       "Printer",
+		Message:     "Smudges",
+		Attachments: []models.AttachmentUpload{upload("notes.txt", []byte(strings.Repeat("smudge ", 3)))},
+	})
+	if models.ErrorCode(err) != "unsupported_attachment_type" {
+		t.Errorf("Expected unsupported_attachment_type, got %v", err)
+	}
+	if page, _ := service.GetAllAppeals(jane, models.AppealFilter{}); len(page.Appeals) != 2 {
+		t.Errorf("Expected the refused appeal not to be created, got %d appeals", len(page.Appeals))
+	}
+
+	// Файлы отказанного запроса удаляются, кроме тех, на которые уже ссылаются вложения.
+	scan := append(append([]byte{}, pngHeader...), "scan"...)
+	for _, content := range [][]byte{scan, photo} {
+		_, err = service.CreateAppeal(jane, models.CreateAppealRequest{
+			Theme:       "Printer",
+			Message:     "Streaks",
+			Attachments: []models.AttachmentUpload{upload("scan.png", content), upload("notes.txt", []byte("streaks"))},
+		})
+		if models.ErrorCode(err) != "unsupported_attachment_type" {
+			t.Errorf("Expected unsupported_attachment_type, got %v", err)
+		}
+	}
+	if _, err := blobs.Open(contentKey(scan)); models.ErrorCode(err) != "blob_not_found" {
+		t.Errorf("Expected the refused content to be deleted, got %v", err)
+	}
+	if _, err := blobs.Open(attached.SHA256); err != nil {
+		t.Errorf("Expected attached content to stay, got %v", err)
+	}
+}
+
+func contentKey(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestConcurrentAttachmentsStayWithinLimit(t *testing.T) {
+	t.Parallel()
+
+	blobs := blob.NewMemory()
+	limits := AttachmentLimits{MaxSize: 64, MaxFiles: 3, ContentTypes: []string{"image/png"}}
+	service := NewAppealService(repository.NewMemoryAppealRepository(), workflow.Default(), WithAttachments(blobs, limits))
+	ctx := WithActor(context.Background(), "operator@example.com")
+	appeal, err := service.CreateAppeal(ctx, models.CreateAppealRequest{Theme: "Printer", Message: "Paper jam"})
+	if err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+
+	// Все загрузки проходят первую проверку, лишние отсекаются в транзакции.
+	const uploads = 8
+	photos := make([][]byte, uploads)
+	errs := make([]error, uploads)
+	var wg sync.WaitGroup
+	for i := range uploads {
+		photos[i] = append(append([]byte{}, pngHeader...), fmt.Sprintf("pixels %d", i)...)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = service.AddAttachments(ctx, appeal.ID, []models.AttachmentUpload{upload("photo.png", photos[i])})
+		}()
+	}
+	wg.Wait()
+
+	refused := 0
+	for i, err := range errs {
+		_, stored := blobs.Open(contentKey(photos[i]))
+		switch {
+		case models.ErrorCode(err) == "too_many_attachments":
+			refused++
+			if models.ErrorCode(stored) != "blob_not_found" {
+				t.Errorf("Expected refused content to be deleted, got %v", stored)
+			}
+		case err != nil:
+			t.Errorf("Unexpected error: %v", err)
+		case stored != nil:
+			t.Errorf("Expected saved content to stay, got %v", stored)
+		}
+	}
+	list, err := service.GetAttachments(ctx, appeal.ID)
+	if err != nil || len(list) != limits.MaxFiles || refused != uploads-limits.MaxFiles {
+		t.Errorf("Expected %d attachments and %d refusals, got %d, %d, %v", limits.MaxFiles, uploads-limits.MaxFiles, len(list), refused, err)
+	}
+}
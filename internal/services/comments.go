@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go_appeals/internal/access"
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+)
+
+// GetComments returns the thread of an appeal, oldest first. Internal notes
+// are left out for callers who may not see them.
+func (s *AppealService) GetComments(ctx context.Context, appealID string) ([]*models.Comment, error) {
+	appeal, err := s.readAppeal(ctx, appealID)
+	if err != nil {
+		return nil, err
+	}
+	return s.store(ctx).ListComments(appeal.ID, s.Authorize(ctx, access.InternalNotes) == nil)
+}
+
+// AddComment adds the caller's reply or internal note to the thread of an
+// appeal. Anyone who may read the appeal may reply.
+func (s *AppealService) AddComment(ctx context.Context, appealID string, req models.CreateCommentRequest) (*models.Comment, error) {
+	author, err := requireActor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	comment := &models.Comment{
+		Visibility: req.Visibility,
+		Author:     author,
+		Body:       strings.TrimSpace(req.Body),
+		CreatedAt:  time.Now(),
+	}
+	if comment.Visibility == "" {
+		comment.Visibility = models.CommentPublic
+	}
+	if comment.Visibility == models.CommentInternal {
+		if err := s.Authorize(ctx, access.InternalNotes); err != nil {
+			return nil, err
+		}
+	}
+
+	appeal, err := s.readAppeal(ctx, appealID)
+	if err != nil {
+		return nil, err
+	}
+	comment.AppealID, comment.TenantID = appeal.ID, appeal.TenantID
+	if err := s.store(ctx).AddComment(comment); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// EditComment replaces the text of one of the caller's own comments. The
+// earlier text stays in the comment's edits.
+func (s *AppealService) EditComment(ctx context.Context, appealID string, commentID int64, req models.UpdateCommentRequest) (*models.Comment, error) {
+	editor, err := requireActor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	appeal, err := s.readAppeal(ctx, appealID)
+	if err != nil {
+		return nil, err
+	}
+
+	var edited *models.Comment
+	err = s.store(ctx).WithTx(func(tx repository.AppealStore) error {
+		comment, err := tx.FindComment(commentID)
+		if err != nil {
+			return err
+		}
+		// Comments of other appeals, and ones hidden from the caller, look like
+		// they do not exist.
+		if comment.AppealID != appeal.ID ||
+			(comment.Visibility == models.CommentInternal && s.Authorize(ctx, access.InternalNotes) != nil) {
+			return models.NewError(models.ErrNotFound, "comment_not_found", "comment %d not found", commentID)
+		}
+		if comment.Author != editor {
+			return models.NewError(models.ErrForbidden, "not_comment_author", "%s may only edit their own comments", editor)
+		}
+
+		body := strings.TrimSpace(req.Body)
+		if body == comment.Body {
+			edited = comment
+			return nil
+		}
+		now := time.Now()
+		edit := &models.CommentEdit{Body: comment.Body, EditedBy: editor, EditedAt: now}
+		comment.Body, comment.EditedAt = body, &now
+		if err := tx.EditComment(comment, edit); err != nil {
+			return err
+		}
+		comment.Edits = append(comment.Edits, edit)
+		edited = comment
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return edited, nil
+}
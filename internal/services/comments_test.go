@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go_appeals/internal/models"
+)
+
+func TestComments(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	as := func(subject string, role models.Role) context.Context {
+		return WithPrincipal(context.Background(), &models.Principal{Subject: subject, Roles: []models.Role{role}})
+	}
+	jane, john := as("jane", models.RoleRequester), as("john", models.RoleRequester)
+	olga := as("olga", models.RoleOperator)
+
+	appeal, err := service.CreateAppeal(jane, models.CreateAppealRequest{Theme: "Printer", Message: "Out of toner"})
+	if err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+
+	if _, err := service.AddComment(jane, appeal.ID, models.CreateCommentRequest{Body: "  "}); models.ErrorCode(err) != "body_required" {
+		t.Errorf("Expected body_required, got %v", err)
+	}
+	if _, err := service.AddComment(jane, appeal.ID, models.CreateCommentRequest{Body: "Hi", Visibility: "secret"}); models.ErrorCode(err) != "invalid_visibility" {
+		t.Errorf("Expected invalid_visibility, got %v", err)
+	}
+	if _, err := service.AddComment(jane, appeal.ID, models.CreateCommentRequest{Body: "Note to self", Visibility: models.CommentInternal}); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("Expected a requester to be refused internal notes, got %v", err)
+	}
+	if _, err := service.AddComment(john, appeal.ID, models.CreateCommentRequest{Body: "Me too"}); models.ErrorCode(err) != "not_your_appeal" {
+		t.Errorf("Expected not_your_appeal, got %v", err)
+	}
+	if _, err := service.AddComment(olga, "missing", models.CreateCommentRequest{Body: "Hello"}); !errors.Is(err, models.ErrNotFound) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+
+	reply, err := service.AddComment(olga, appeal.ID, models.CreateCommentRequest{Body: " Ordered a cartridge "})
+	if err != nil {
+		t.Fatalf("Failed to reply: %v", err)
+	}
+	if reply.Visibility != models.CommentPublic || reply.Author != "olga" || reply.Body != "Ordered a cartridge" {
+		t.Errorf("Unexpected reply %+v", reply)
+	}
+	note, err := service.AddComment(olga, appeal.ID, models.CreateCommentRequest{Body: "Vendor is slow", Visibility: models.CommentInternal})
+	if err != nil {
+		t.Fatalf("Failed to add note: %v", err)
+	}
+
+	// Заявитель не видит внутренних заметок.
+	if thread, err := service.GetComments(jane, appeal.ID); err != nil || len(thread) != 1 || thread[0].ID != reply.ID {
+		t.Errorf("Expected only the public reply, got %+v, %v", thread, err)
+	}
+	if thread, err := service.GetComments(olga, appeal.ID); err != nil || len(thread) != 2 {
+		t.Errorf("Expected the whole thread, got %+v, %v", thread, err)
+	}
+
+	if _, err := service.EditComment(jane, appeal.ID, reply.ID, models.UpdateCommentRequest{Body: "Fixed it"}); models.ErrorCode(err) != "not_comment_author" {
+		t.Errorf("Expected not_comment_author, got %v", err)
+	}
+	if _, err := service.EditComment(jane, appeal.ID, note.ID, models.UpdateCommentRequest{Body: "Peek"}); models.ErrorCode(err) != "comment_not_found" {
+		t.Errorf("Expected a hidden note to be not found, got %v", err)
+	}
+	edited, err := service.EditComment(olga, appeal.ID, reply.ID, models.UpdateCommentRequest{Body: "Ordered two cartridges"})
+	if err != nil {
+		t.Fatalf("Failed to edit: %v", err)
+	}
+	if edited.Body != "Ordered two cartridges" || edited.EditedAt == nil || len(edited.Edits) != 1 ||
+		edited.Edits[0].Body != "Ordered a cartridge" || edited.Edits[0].EditedBy != "olga" {
+		t.Errorf("Expected the edit to be recorded, got %+v", edited)
+	}
+	// Та же правка ещё раз ничего не меняет.
+	if again, err := service.EditComment(olga, appeal.ID, reply.ID, models.UpdateCommentRequest{Body: "Ordered two cartridges"}); err != nil || len(again.Edits) != 1 {
+		t.Errorf("Expected an unchanged body not to be recorded, got %+v, %v", again, err)
+	}
+
+	other := createTestAppeal(t, service)
+	if _, err := service.EditComment(olga, other.ID, reply.ID, models.UpdateCommentRequest{Body: "Moved"}); models.ErrorCode(err) != "comment_not_found" {
+		t.Errorf("Expected a comment of another appeal to be not found, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+
+	"go_appeals/internal/models"
+)
+
+type actorKey struct{}
+
+type principalKey struct{}
+
+// WithActor attaches the identity of whoever performs the request, so that
+// transitions can record it in the status history.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// WithPrincipal attaches the authenticated caller. Its subject becomes the
+// actor.
+func WithPrincipal(ctx context.Context, p *models.Principal) context.Context {
+	return WithActor(context.WithValue(ctx, principalKey{}, p), p.Subject)
+}
+
+// PrincipalFromContext returns the authenticated caller, or nil when the
+// request was not authenticated.
+func PrincipalFromContext(ctx context.Context) *models.Principal {
+	p, _ := ctx.Value(principalKey{}).(*models.Principal)
+	return p
+}
+
+// requireActor is ActorFromContext for operations that make no sense
+// anonymously.
+func requireActor(ctx context.Context) (string, error) {
+	actor := ActorFromContext(ctx)
+	if actor == "" {
+		return "", models.NewError(models.ErrValidation, "actor_required", "the caller must identify themselves")
+	}
+	return actor, nil
+}
+
+type tenantKey struct{}
+
+// WithTenant sets the organization a request acts for. Every read and write
+// of the service is confined to it.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant of the request, or the default one
+// when none was set.
+func TenantFromContext(ctx context.Context) string {
+	if tenant, _ := ctx.Value(tenantKey{}).(string); tenant != "" {
+		return tenant
+	}
+	return models.DefaultTenant
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go_appeals/internal/access"
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Notifier is told that new events have been committed to the outbox, so
+// whatever relays them does not have to wait for its next poll. Notify must
+// not block.
+type Notifier interface {
+	Notify()
+}
+
+// WithNotifier calls n after every committed change that recorded events.
+func WithNotifier(n Notifier) Option {
+	return func(s *AppealService) { s.notifiers = append(s.notifiers, n) }
+}
+
+// recordEvent adds an event about appeal to the outbox as part of tx, so the
+// event exists exactly when the change does.
+func (s *AppealService) recordEvent(ctx context.Context, tx repository.AppealStore, eventType string, appeal *models.Appeal, from models.AppealStatus) error {
+	snapshot := *appeal
+	err := tx.AddEvent(&models.Event{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		AppealID:   appeal.ID,
+		FromStatus: from,
+		Actor:      ActorFromContext(ctx),
+		Appeal:     &snapshot,
+		OccurredAt: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record event: %w", err)
+	}
+	return nil
+}
+
+func (s *AppealService) notify() {
+	for _, n := range s.notifiers {
+		n.Notify()
+	}
+}
+
+// GetEvents returns up to limit events committed after the given sequence,
+// oldest first; a zero limit means the default page size.
+func (s *AppealService) GetEvents(ctx context.Context, after int64, limit int) ([]*models.Event, error) {
+	if err := s.Authorize(ctx, access.ReadEvents); err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		limit = models.DefaultPageSize
+	}
+	if limit < 0 || limit > models.MaxPageSize {
+		return nil, models.NewError(models.ErrValidation, "invalid_limit", "limit must be between 1 and %d", models.MaxPageSize)
+	}
+	return s.store(ctx).ListEvents(after, limit)
+}
+
+// LastEventSequence returns the sequence of the latest event, or 0.
+func (s *AppealService) LastEventSequence(ctx context.Context) (int64, error) {
+	if err := s.Authorize(ctx, access.ReadEvents); err != nil {
+		return 0, err
+	}
+	return s.store(ctx).LastEventSequence()
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go_appeals/internal/access"
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+	"go_appeals/internal/scheduler"
+	"go_appeals/internal/sla"
+	"go_appeals/internal/workflow"
+)
+
+// SchedulerActor is recorded in the status history of changes made by jobs.
+const SchedulerActor = "scheduler"
+
+// Jobs returns the scheduler handlers backed by the service, by job name.
+// Each run goes through every tenant in turn.
+//
+//	cancel_stale            after=720h statuses=WaitingForRequester,OnHold
+//	cancel_all_in_progress  reason=...
+//	escalate_breaches
+//	generate_reminders      within=4h requester_silent_after=72h
+func (s *AppealService) Jobs() map[string]scheduler.Handler {
+	return map[string]scheduler.Handler{
+		"cancel_stale": func(ctx context.Context, params map[string]string) (string, error) {
+			after, err := durationParam(params, "after", 30*24*time.Hour)
+			if err != nil {
+				return "", err
+			}
+			var statuses []models.AppealStatus
+			for _, status := range splitParam(params["statuses"]) {
+				statuses = append(statuses, models.AppealStatus(status))
+			}
+			n, err := s.eachTenant(WithActor(ctx, SchedulerActor), func(ctx context.Context) (int, error) {
+				return s.CancelStaleAppeals(ctx, statuses, after)
+			})
+			return fmt.Sprintf("cancelled %d appeals", n), err
+		},
+		"cancel_all_in_progress": func(ctx context.Context, params map[string]string) (string, error) {
+			reason := params["reason"]
+			if reason == "" {
+				reason = "Cancelled on schedule"
+			}
+			n, err := s.eachTenant(WithActor(ctx, SchedulerActor), func(ctx context.Context) (int, error) {
+				return s.CancelAllInProgress(ctx, models.UpdateAppealCancelRequest{Reason: reason})
+			})
+			return fmt.Sprintf("cancelled %d appeals", n), err
+		},
+		"escalate_breaches": func(ctx context.Context, params map[string]string) (string, error) {
+			n, err := s.eachTenant(WithActor(ctx, SchedulerActor), s.EscalateBreaches)
+			return fmt.Sprintf("escalated %d appeals", n), err
+		},
+		"generate_reminders": func(ctx context.Context, params map[string]string) (string, error) {
+			within, err := durationParam(params, "within", 0)
+			if err != nil {
+				return "", err
+			}
+			silentAfter, err := durationParam(params, "requester_silent_after", 72*time.Hour)
+			if err != nil {
+				return "", err
+			}
+			n, err := s.eachTenant(ctx, func(ctx context.Context) (int, error) {
+				return s.GenerateReminders(ctx, within, silentAfter)
+			})
+			return fmt.Sprintf("created %d reminders", n), err
+		},
+	}
+}
+
+// CancelStaleAppeals cancels appeals in one of statuses that have not
+// changed for idle. Without statuses it looks at the paused states. It
+// returns the number of appeals cancelled.
+func (s *AppealService) CancelStaleAppeals(ctx context.Context, statuses []models.AppealStatus, idle time.Duration) (int, error) {
+	if len(statuses) == 0 {
+		statuses = s.workflow.PausedStates()
+	}
+	var cancellable []models.AppealStatus
+	for _, status := range statuses {
+		for _, source := range s.workflow.Sources("cancel") {
+			if status == source {
+				cancellable = append(cancellable, status)
+			}
+		}
+	}
+	if len(cancellable) == 0 {
+		return 0, nil
+	}
+
+	stale, err := s.collect(ctx, models.AppealFilter{
+		Statuses:  cancellable,
+		UpdatedTo: time.Now().Add(-idle),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	reason := fmt.Sprintf("No activity for %s", idle)
+	var cancelled int
+	for _, appeal := range stale {
+		if err := ctx.Err(); err != nil {
+			return cancelled, err
+		}
+		_, err := s.fire(ctx, appeal.ID, "cancel", workflow.Input{Reason: reason})
+		if errors.Is(err, models.ErrInvalidTransition) || errors.Is(err, models.ErrNotFound) {
+			// Changed while we were walking the list.
+			continue
+		}
+		if err != nil {
+			return cancelled, err
+		}
+		cancelled++
+	}
+	return cancelled, nil
+}
+
+// EscalateBreaches raises the priority of active appeals that missed a
+// deadline and leaves the team a reminder about each. Every appeal is
+// escalated once.
+func (s *AppealService) EscalateBreaches(ctx context.Context) (int, error) {
+	now := time.Now()
+	breached, err := s.collect(ctx, models.AppealFilter{
+		Statuses: s.workflow.ActiveStates(),
+		SLA:      models.SLABreached,
+		AsOf:     now,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var escalated int
+	for _, appeal := range breached {
+		if appeal.EscalatedAt != nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return escalated, err
+		}
+
+		changed := false
+		err := s.store(ctx).WithTx(func(tx repository.AppealStore) error {
+			current, err := tx.FindByID(appeal.ID)
+			if err != nil {
+				return err
+			}
+			if current.EscalatedAt != nil || !s.workflow.IsActive(current.Status) || !current.SLABreached(now) {
+				return nil
+			}
+
+			from := current.Priority
+			current.Priority = current.Priority.Escalated()
+			current.EscalatedAt = &now
+			updated, err := tx.Update(current)
+			if err != nil {
+				return err
+			}
+
+			due := current.ResolutionDueAt
+			if current.ResponseBreached(now) {
+				due = current.ResponseDueAt
+			}
+			if _, err := tx.AddReminder(&models.Reminder{
+				AppealID: current.ID,
+				Kind:     models.ReminderEscalated,
+				Message:  fmt.Sprintf("Appeal %q missed its SLA deadline, priority raised from %s to %s", current.Theme, from, current.Priority),
+				DueAt:    *due,
+			}); err != nil {
+				return err
+			}
+			if err := s.recordEvent(ctx, tx, models.EventAppealEscalated, updated, ""); err != nil {
+				return err
+			}
+			changed = true
+			return nil
+		})
+		if err != nil {
+			return escalated, fmt.Errorf("failed to escalate appeal %s: %w", appeal.ID, err)
+		}
+		// Counted only once the transaction has committed.
+		if changed {
+			escalated++
+		}
+	}
+	if escalated > 0 {
+		s.notify()
+	}
+	return escalated, nil
+}
+
+// GenerateReminders reminds assignees of deadlines due within the window
+// and of appeals that have been waiting for the requester for longer than
+// silentAfter. Reminders that already exist are not repeated; the count of
+// new ones is returned. A zero window uses the "due soon" window of the
+// tenant's SLA policy.
+func (s *AppealService) GenerateReminders(ctx context.Context, within, silentAfter time.Duration) (int, error) {
+	now := time.Now()
+	if within == 0 {
+		within = s.slaPolicy(ctx).DueSoon()
+	}
+	var reminders []*models.Reminder
+
+	dueSoon, err := s.collect(ctx, models.AppealFilter{
+		Statuses:  s.workflow.ActiveStates(),
+		SLA:       models.SLADueSoon,
+		DueWithin: within,
+		AsOf:      now,
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, appeal := range dueSoon {
+		if dueWithin(appeal.ResponseDueAt, appeal.RespondedAt, now, within) {
+			reminders = append(reminders, &models.Reminder{
+				AppealID:  appeal.ID,
+				Kind:      models.ReminderResponseDue,
+				Recipient: appeal.Assignee,
+				Message:   fmt.Sprintf("Appeal %q needs a response by %s", appeal.Theme, appeal.ResponseDueAt.Format(time.RFC3339)),
+				DueAt:     *appeal.ResponseDueAt,
+			})
+		}
+		if dueWithin(appeal.ResolutionDueAt, appeal.ResolvedAt, now, within) {
+			reminders = append(reminders, &models.Reminder{
+				AppealID:  appeal.ID,
+				Kind:      models.ReminderResolutionDue,
+				Recipient: appeal.Assignee,
+				Message:   fmt.Sprintf("Appeal %q must be resolved by %s", appeal.Theme, appeal.ResolutionDueAt.Format(time.RFC3339)),
+				DueAt:     *appeal.ResolutionDueAt,
+			})
+		}
+	}
+
+	var waiting []models.AppealStatus
+	for _, status := range s.workflow.ActiveStates() {
+		if s.workflow.StopsClock(status) {
+			waiting = append(waiting, status)
+		}
+	}
+	if len(waiting) > 0 {
+		silent, err := s.collect(ctx, models.AppealFilter{
+			Statuses:  waiting,
+			UpdatedTo: now.Add(-silentAfter),
+		})
+		if err != nil {
+			return 0, err
+		}
+		for _, appeal := range silent {
+			since := appeal.UpdatedAt
+			if appeal.SLAPausedAt != nil {
+				since = *appeal.SLAPausedAt
+			}
+			reminders = append(reminders, &models.Reminder{
+				AppealID:  appeal.ID,
+				Kind:      models.ReminderRequesterSilent,
+				Recipient: appeal.Assignee,
+				Message:   fmt.Sprintf("The requester of appeal %q has not answered since %s", appeal.Theme, since.Format(time.RFC3339)),
+				DueAt:     since.Add(silentAfter),
+			})
+		}
+	}
+
+	var created int
+	for _, reminder := range reminders {
+		if err := ctx.Err(); err != nil {
+			return created, err
+		}
+		added, err := s.store(ctx).AddReminder(reminder)
+		if err != nil {
+			return created, fmt.Errorf("failed to add reminder: %w", err)
+		}
+		if added {
+			created++
+		}
+	}
+	return created, nil
+}
+
+// GetReminders lists reminders, newest first.
+func (s *AppealService) GetReminders(ctx context.Context, filter models.ReminderFilter) ([]*models.Reminder, error) {
+	if err := s.Authorize(ctx, access.ReadReminders); err != nil {
+		return nil, err
+	}
+	return s.listReminders(ctx, filter)
+}
+
+// GetMyReminders lists the reminders addressed to the caller.
+func (s *AppealService) GetMyReminders(ctx context.Context, filter models.ReminderFilter) ([]*models.Reminder, error) {
+	if err := s.Authorize(ctx, access.ProcessAppeals); err != nil {
+		return nil, err
+	}
+	actor, err := requireActor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filter.Recipient = actor
+	return s.listReminders(ctx, filter)
+}
+
+func (s *AppealService) listReminders(ctx context.Context, filter models.ReminderFilter) ([]*models.Reminder, error) {
+	if err := filter.Normalize(); err != nil {
+		return nil, err
+	}
+	return s.store(ctx).ListReminders(filter)
+}
+
+// collect reads every page of a listing.
+func (s *AppealService) collect(ctx context.Context, filter models.AppealFilter) ([]*models.Appeal, error) {
+	filter.Limit = models.MaxPageSize
+	var appeals []*models.Appeal
+	for {
+		page, err := s.store(ctx).List(filter)
+		if err != nil {
+			return nil, err
+		}
+		appeals = append(appeals, page.Appeals...)
+		if page.NextCursor == "" {
+			return appeals, nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+func dueWithin(due, met *time.Time, now time.Time, window time.Duration) bool {
+	return due != nil && met == nil && !due.Before(now) && !due.After(now.Add(window))
+}
+
+func durationParam(params map[string]string, key string, def time.Duration) (time.Duration, error) {
+	value, ok := params[key]
+	if !ok || value == "" {
+		return def, nil
+	}
+	d, err := sla.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q, use a duration such as 30m, 4h or 2d", key, value)
+	}
+	return d, nil
+}
+
+func splitParam(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
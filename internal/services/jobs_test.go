@@ -0,0 +1,155 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go_appeals/internal/calendar"
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+	"go_appeals/internal/workflow"
+)
+
+func TestCancelStaleAppeals(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	fresh := createTestAppeal(t, service)
+	waiting := createTestAppeal(t, service)
+	if _, err := service.StartProcessing(ctx, waiting.ID); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if _, err := service.WaitForRequester(ctx, waiting.ID, models.UpdateAppealReasonRequest{Reason: "Need a photo"}); err != nil {
+		t.Fatalf("Failed to wait for requester: %v", err)
+	}
+
+	if n, err := service.CancelStaleAppeals(ctx, nil, time.Hour); err != nil || n != 0 {
+		t.Errorf("Expected nothing to be stale yet, got %d, %v", n, err)
+	}
+
+	n, err := service.CancelStaleAppeals(WithActor(ctx, SchedulerActor), nil, time.Nanosecond)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected the waiting appeal to be cancelled, got %d, %v", n, err)
+	}
+	cancelled, err := service.GetAppealByID(ctx, waiting.ID)
+	if err != nil {
+		t.Fatalf("Failed to get appeal: %v", err)
+	}
+	if cancelled.Status != models.StatusCancelled || !strings.HasPrefix(cancelled.CancelReason, "No activity") {
+		t.Errorf("Expected a cancelled appeal with a reason, got %s %q", cancelled.Status, cancelled.CancelReason)
+	}
+	history, err := service.GetAppealHistory(ctx, waiting.ID)
+	if err != nil {
+		t.Fatalf("Failed to get history: %v", err)
+	}
+	if last := history[len(history)-1]; last.Actor != SchedulerActor {
+		t.Errorf("Expected the scheduler to be recorded as the actor, got %q", last.Actor)
+	}
+
+	// Новые обращения не входят в статусы по умолчанию.
+	if appeal, _ := service.GetAppealByID(ctx, fresh.ID); appeal.Status != models.StatusNew {
+		t.Errorf("Expected the new appeal to be left alone, got %s", appeal.Status)
+	}
+}
+
+func TestEscalateBreaches(t *testing.T) {
+	t.Parallel()
+
+	repo := repository.NewMemoryAppealRepository()
+	service := NewAppealService(repo, workflow.Default(), WithCalendar(calendar.AlwaysOpen()))
+	appeal := createTestAppeal(t, service)
+	createTestAppeal(t, service)
+
+	overdue := time.Now().Add(-time.Hour)
+	appeal.ResponseDueAt = &overdue
+	if _, err := repo.Update(appeal); err != nil {
+		t.Fatalf("Failed to update appeal: %v", err)
+	}
+
+	n, err := service.EscalateBreaches(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("Expected one escalation, got %d, %v", n, err)
+	}
+	escalated, err := service.GetAppealByID(ctx, appeal.ID)
+	if err != nil {
+		t.Fatalf("Failed to get appeal: %v", err)
+	}
+	if escalated.EscalatedAt == nil || escalated.Priority != models.PriorityHigh {
+		t.Errorf("Expected the appeal to be escalated to high, got %v %s", escalated.EscalatedAt, escalated.Priority)
+	}
+
+	reminders, err := service.GetReminders(ctx, models.ReminderFilter{AppealID: appeal.ID})
+	if err != nil {
+		t.Fatalf("Failed to list reminders: %v", err)
+	}
+	if len(reminders) != 1 || reminders[0].Kind != models.ReminderEscalated || !reminders[0].DueAt.Equal(overdue) {
+		t.Errorf("Expected an escalation reminder, got %+v", reminders)
+	}
+
+	if n, err := service.EscalateBreaches(ctx); err != nil || n != 0 {
+		t.Errorf("Expected an appeal to be escalated only once, got %d, %v", n, err)
+	}
+}
+
+func TestGenerateReminders(t *testing.T) {
+	t.Parallel()
+
+	service := NewAppealService(repository.NewMemoryAppealRepository(), workflow.Default(), WithCalendar(calendar.AlwaysOpen()))
+	appeal := createTestAppeal(t, service)
+	if _, err := service.ClaimAppeal(ctx, appeal.ID); err != nil {
+		t.Fatalf("Failed to claim appeal: %v", err)
+	}
+	waiting := createTestAppeal(t, service)
+	if _, err := service.StartProcessing(ctx, waiting.ID); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if _, err := service.WaitForRequester(ctx, waiting.ID, models.UpdateAppealReasonRequest{Reason: "Need a photo"}); err != nil {
+		t.Fatalf("Failed to wait for requester: %v", err)
+	}
+
+	// Ответ по умолчанию нужен через 9 часов, решение через 27.
+	n, err := service.GenerateReminders(ctx, 10*time.Hour, time.Nanosecond)
+	if err != nil || n != 2 {
+		t.Fatalf("Expected a response and a silent requester reminder, got %d, %v", n, err)
+	}
+	if n, err := service.GenerateReminders(ctx, 10*time.Hour, time.Nanosecond); err != nil || n != 0 {
+		t.Errorf("Expected reminders not to repeat, got %d, %v", n, err)
+	}
+
+	mine, err := service.GetMyReminders(ctx, models.ReminderFilter{})
+	if err != nil {
+		t.Fatalf("Failed to list reminders: %v", err)
+	}
+	kinds := map[models.ReminderKind]string{}
+	for _, reminder := range mine {
+		kinds[reminder.Kind] = reminder.AppealID
+	}
+	if len(mine) != 2 || kinds[models.ReminderResponseDue] != appeal.ID || kinds[models.ReminderRequesterSilent] != waiting.ID {
+		t.Errorf("Expected both reminders to go to the assignee, got %+v", mine)
+	}
+
+	if _, err := service.GetMyReminders(WithActor(ctx, ""), models.ReminderFilter{}); models.ErrorCode(err) != "actor_required" {
+		t.Errorf("Expected actor_required error, got %v", err)
+	}
+}
+
+func TestJobsHandlers(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	jobs := service.Jobs()
+	for _, name := range []string{"cancel_stale", "cancel_all_in_progress", "escalate_breaches", "generate_reminders"} {
+		if jobs[name] == nil {
+			t.Errorf("Expected a handler for %s", name)
+		}
+	}
+
+	if _, err := jobs["cancel_stale"](ctx, map[string]string{"after": "soon"}); err == nil {
+		t.Errorf("Expected an invalid duration to fail the job")
+	}
+	result, err := jobs["cancel_stale"](ctx, map[string]string{"after": "30d", "statuses": "OnHold, WaitingForRequester"})
+	if err != nil || result != "cancelled 0 appeals" {
+		t.Errorf("Unexpected result %q, %v", result, err)
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"go_appeals/internal/access"
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+)
+
+// GetRequester returns a requester with their contact details.
+func (s *AppealService) GetRequester(ctx context.Context, id string) (*models.Requester, error) {
+	if err := s.Authorize(ctx, access.ReadRequesters); err != nil {
+		return nil, err
+	}
+	return s.store(ctx).FindRequester(id)
+}
+
+// GetRequesterAppeals lists the appeals a requester has filed.
+func (s *AppealService) GetRequesterAppeals(ctx context.Context, id string, filter models.AppealFilter) (*models.AppealPage, error) {
+	if _, err := s.GetRequester(ctx, id); err != nil {
+		return nil, err
+	}
+	if err := s.scopeListing(ctx, &filter); err != nil {
+		return nil, err
+	}
+	filter.RequesterID = id
+	s.defaultDueWithin(ctx, &filter)
+	return s.store(ctx).List(filter)
+}
+
+// resolveRequester returns the requester with the email or phone of
+// details or adds a new one. Given details replace stored ones, except that
+// a known email or phone is never changed: they are what requesters are
+// recognized by. Only callers who know the requester may change them;
+// anyone else giving the same email or phone is linked to the requester
+// as it is.
+func (s *AppealService) resolveRequester(ctx context.Context, tx repository.AppealStore, tenantID string, details models.RequesterDetails) (*models.Requester, error) {
+	requester, err := tx.FindRequesterByContact(details.Email, details.Phone)
+	if errors.Is(err, models.ErrNotFound) {
+		requester = &models.Requester{
+			TenantID:         tenantID,
+			Name:             details.Name,
+			Email:            details.Email,
+			Phone:            details.Phone,
+			Address:          details.Address,
+			PreferredChannel: details.PreferredChannel,
+		}
+		if requester.PreferredChannel == "" {
+			requester.PreferredChannel = models.ChannelEmail
+			if requester.Email == "" {
+				requester.PreferredChannel = models.ChannelPhone
+			}
+		}
+		if principal := PrincipalFromContext(ctx); principal != nil && !s.access.Allows(principal, access.ReadRequesters) {
+			requester.Subject = principal.Subject
+		}
+		if err := tx.AddRequester(requester); err != nil {
+			return nil, err
+		}
+		return requester, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !s.knowsRequester(ctx, requester) {
+		return requester, nil
+	}
+
+	before := *requester
+	if details.Name != "" {
+		requester.Name = details.Name
+	}
+	if details.Address != "" {
+		requester.Address = details.Address
+	}
+	if details.PreferredChannel != "" {
+		requester.PreferredChannel = details.PreferredChannel
+	}
+	// Matching prefers the email, so a requester found without one has an
+	// email nobody has; a phone may belong to someone else.
+	if requester.Email == "" {
+		requester.Email = details.Email
+	}
+	if requester.Phone == "" && details.Phone != "" {
+		if _, err := tx.FindRequesterByContact("", details.Phone); errors.Is(err, models.ErrNotFound) {
+			requester.Phone = details.Phone
+		}
+	}
+	if *requester == before {
+		return requester, nil
+	}
+	if err := tx.UpdateRequester(requester); err != nil {
+		return nil, err
+	}
+	return requester, nil
+}
+
+// knowsRequester reports whether the caller may see and change the contact
+// details of requester: those who may read contact details and the
+// requester themselves.
+func (s *AppealService) knowsRequester(ctx context.Context, requester *models.Requester) bool {
+	principal := PrincipalFromContext(ctx)
+	return principal == nil || s.access.Allows(principal, access.ReadRequesters) ||
+		(requester.Subject != "" && requester.Subject == principal.Subject)
+}
+
+// exposeRequester attaches the requester of appeal as the caller may see
+// them: in full to those who know the requester, otherwise redacted. Filing
+// the appeal is not enough, as anyone may give someone else's email.
+func (s *AppealService) exposeRequester(ctx context.Context, appeal *models.Appeal, requester *models.Requester) {
+	if s.knowsRequester(ctx, requester) {
+		appeal.Requester = requester
+		return
+	}
+	appeal.Requester = requester.Redacted()
+}
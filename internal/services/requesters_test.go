@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go_appeals/internal/access"
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+	"go_appeals/internal/workflow"
+)
+
+func TestRequesters(t *testing.T) {
+	t.Parallel()
+
+	// Аудитор читает обращения, но не видит контактов заявителей.
+	policy, err := access.New(access.Definition{Roles: map[models.Role][]access.Permission{
+		models.RoleRequester: {access.CreateAppeals, access.ReadOwnAppeals},
+		models.RoleOperator:  {access.CreateAppeals, access.ReadAppeals, access.ReadRequesters},
+		"auditor":            {access.ReadAppeals},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	service := NewAppealService(repository.NewMemoryAppealRepository(), workflow.Default(), WithAccessPolicy(policy))
+	as := func(subject string, role models.Role) context.Context {
+		return WithPrincipal(context.Background(), &models.Principal{Subject: subject, Roles: []models.Role{role}})
+	}
+	jane, olga, audit := as("jane", models.RoleRequester), as("olga", models.RoleOperator), as("ann", "auditor")
+
+	invalid := []struct {
+		name    string
+		details models.RequesterDetails
+		code    string
+	}{
+		{"NoContact", models.RequesterDetails{Name: "Jane Doe"}, "contact_required"},
+		{"BadEmail", models.RequesterDetails{Email: "jane at example.com"}, "invalid_email"},
+		{"ShortPhone", models.RequesterDetails{Phone: "12-34"}, "invalid_phone"},
+		{"UnknownChannel", models.RequesterDetails{Email: "jane@example.com", PreferredChannel: "pigeon"}, "invalid_channel"},
+		{"NoAddress", models.RequesterDetails{Email: "jane@example.com", PreferredChannel: models.ChannelPost}, "channel_unreachable"},
+	}
+	for _, tt := range invalid {
+		details := tt.details
+		_, err := service.CreateAppeal(jane, models.CreateAppealRequest{Theme: "Printer", Message: "Out of toner", Requester: &details})
+		if models.ErrorCode(err) != tt.code {
+			t.Errorf("%s: expected %s, got %v", tt.name, tt.code, err)
+		}
+	}
+
+	first, err := service.CreateAppeal(jane, models.CreateAppealRequest{Theme: "Printer", Message: "Out of toner", Requester: &models.RequesterDetails{
+		Name:  " Jane   Doe ",
+		Email: "Jane.Doe@Example.com",
+	}})
+	if err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+	requester := first.Requester
+	if requester == nil || first.RequesterID != requester.ID || requester.Name != "Jane Doe" ||
+		requester.Email != "jane.doe@example.com" || requester.PreferredChannel != models.ChannelEmail {
+		t.Fatalf("Expected a normalized requester, got %+v", requester)
+	}
+
+	// Тот же адрес в другом написании — тот же заявитель, новые данные дописываются.
+	second, err := service.CreateAppeal(jane, models.CreateAppealRequest{Theme: "Printer", Message: "Paper jam", Requester: &models.RequesterDetails{
+		Email:            " JANE.DOE@example.com",
+		Phone:            "+7 (912) 345-67-89",
+		Address:          "1 Main St, Springfield",
+		PreferredChannel: models.ChannelPhone,
+	}})
+	if err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+	if second.RequesterID != requester.ID || second.Requester.Phone != "+79123456789" ||
+		second.Requester.Name != "Jane Doe" || second.Requester.PreferredChannel != models.ChannelPhone {
+		t.Errorf("Expected the same requester with the phone added, got %+v", second.Requester)
+	}
+	third, err := service.CreateAppeal(olga, models.CreateAppealRequest{Theme: "Printer", Message: "Called about toner", Requester: &models.RequesterDetails{
+		Phone: "+79123456789",
+	}})
+	if err != nil || third.RequesterID != requester.ID {
+		t.Errorf("Expected the requester to be recognized by phone, got %+v, %v", third, err)
+	}
+
+	// Чужой адрес не даёт ни увидеть, ни переписать данные заявителя.
+	mallory := as("mallory", models.RoleRequester)
+	spoofed, err := service.CreateAppeal(mallory, models.CreateAppealRequest{Theme: "Printer", Message: "Send it to me", Requester: &models.RequesterDetails{
+		Name:             "Mallory",
+		Email:            "jane.doe@example.com",
+		Address:          "2 Elm St",
+		PreferredChannel: models.ChannelPost,
+	}})
+	if err != nil {
+		t.Fatalf("Failed to create appeal: %v", err)
+	}
+	if got := spoofed.Requester; got.Phone != "**********89" || got.Address != "" || got.Email != "j***@example.com" {
+		t.Errorf("Expected someone else's details to be redacted, got %+v", got)
+	}
+	if fetched, _ := service.GetAppealByID(mallory, spoofed.ID); fetched.Requester.Address != "" || fetched.Requester.Phone != "**********89" {
+		t.Errorf("Expected someone else's details to stay redacted, got %+v", fetched.Requester)
+	}
+	if stored, err := service.GetRequester(olga, requester.ID); err != nil || stored.Name != "Jane Doe" ||
+		stored.Address != "1 Main St, Springfield" || stored.PreferredChannel != models.ChannelPhone {
+		t.Errorf("Expected the requester's details to be left alone, got %+v, %v", stored, err)
+	}
+
+	other, err := service.CreateAppeal(olga, models.CreateAppealRequest{Theme: "Printer", Message: "No paper", Requester: &models.RequesterDetails{Phone: "8 800 555 35 35"}})
+	if err != nil || other.RequesterID == requester.ID {
+		t.Errorf("Expected a new requester for another phone, got %+v, %v", other, err)
+	}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		redacted bool
+	}{
+		{"Filer", jane, false},
+		{"Operator", olga, false},
+		{"Auditor", audit, true},
+	}
+	for _, tt := range tests {
+		appeal, err := service.GetAppealByID(tt.ctx, second.ID)
+		if err != nil {
+			t.Fatalf("%s: failed to get appeal: %v", tt.name, err)
+		}
+		got := appeal.Requester
+		if !tt.redacted && (got.Email != "jane.doe@example.com" || got.Address == "") {
+			t.Errorf("%s: expected the contact details, got %+v", tt.name, got)
+		}
+		if tt.redacted && (got.Name != "Jane D." || got.Email != "j***@example.com" || got.Phone != "**********89" || got.Address != "") {
+			t.Errorf("%s: expected redacted contact details, got %+v", tt.name, got)
+		}
+	}
+
+	page, err := service.GetRequesterAppeals(olga, requester.ID, models.AppealFilter{})
+	if err != nil || len(page.Appeals) != 4 {
+		t.Errorf("Expected the requester's four appeals, got %+v, %v", page, err)
+	}
+	if _, err := service.GetRequesterAppeals(audit, requester.ID, models.AppealFilter{}); !errors.Is(err, models.ErrForbidden) {
+		t.Errorf("Expected the auditor to be refused, got %v", err)
+	}
+	if _, err := service.GetRequesterAppeals(olga, "missing", models.AppealFilter{}); models.ErrorCode(err) != "requester_not_found" {
+		t.Errorf("Expected requester_not_found, got %v", err)
+	}
+	found, err := service.GetRequester(olga, requester.ID)
+	if err != nil || found.Address != "1 Main St, Springfield" {
+		t.Errorf("Expected the full requester, got %+v, %v", found, err)
+	}
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"go_appeals/internal/repository"
+	"go_appeals/internal/sla"
+	"go_appeals/internal/tenant"
+)
+
+// store is the repository confined to the request's tenant. Nothing in the
+// service reaches the unscoped one.
+func (s *AppealService) store(ctx context.Context) repository.AppealStore {
+	return s.repo.ForTenant(TenantFromContext(ctx))
+}
+
+func (s *AppealService) tenant(ctx context.Context) (*tenant.Tenant, error) {
+	return s.tenants.Get(TenantFromContext(ctx))
+}
+
+func (s *AppealService) slaPolicy(ctx context.Context) *sla.Policy {
+	if org, err := s.tenant(ctx); err == nil {
+		if policy := org.SLAPolicy(); policy != nil {
+			return policy
+		}
+	}
+	return s.sla
+}
+
+// eachTenant runs a job once for every tenant and adds up what it did.
+func (s *AppealService) eachTenant(ctx context.Context, job func(ctx context.Context) (int, error)) (int, error) {
+	total := 0
+	for _, org := range s.tenants.Tenants() {
+		n, err := job(WithTenant(ctx, org.ID))
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("tenant %s: %w", org.ID, err)
+		}
+	}
+	return total, nil
+}
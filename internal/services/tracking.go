@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+	"go_appeals/internal/tenant"
+)
+
+// newTrackingToken returns a random token to give the requester and the
+// hash it is stored under.
+func newTrackingToken() (token, hash string, err error) {
+	random := make([]byte, 18)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", fmt.Errorf("failed to generate tracking token: %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(random)
+	return token, hashTrackingToken(token), nil
+}
+
+func hashTrackingToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// assignNumber gives appeal the next registration number of its series as
+// part of tx. The series is the tenant's prefix for the appeal's theme and,
+// unless the tenant never resets, the year the appeal was filed in, counted
+// in the service's time zone.
+func (s *AppealService) assignNumber(tx repository.AppealStore, org *tenant.Tenant, appeal *models.Appeal) error {
+	var parts []string
+	if prefix := org.NumberPrefix(appeal.Theme); prefix != "" {
+		parts = append(parts, prefix)
+	}
+	if org.ResetsYearly() {
+		parts = append(parts, strconv.Itoa(appeal.CreatedAt.In(s.calendar.Location()).Year()))
+	}
+	series := strings.Join(parts, "-")
+
+	n, err := tx.NextNumber(series)
+	if err != nil {
+		return err
+	}
+	appeal.Number = strings.Join(append(parts, fmt.Sprintf("%06d", n)), "-")
+	return nil
+}
+
+// findAppeal looks an appeal up by its ID or, failing that, by its
+// registration number.
+func findAppeal(store repository.AppealStore, ref string) (*models.Appeal, error) {
+	appeal, err := store.FindByID(ref)
+	if errors.Is(err, models.ErrNotFound) {
+		return store.FindByNumber(strings.ToUpper(strings.TrimSpace(ref)))
+	}
+	return appeal, err
+}
+
+// TrackAppeal returns what the holder of a tracking token may see of its
+// appeal. Holding the token is all it takes, whatever the tenant.
+func (s *AppealService) TrackAppeal(ctx context.Context, token string) (*models.TrackedAppeal, error) {
+	appeal, err := s.repo.FindByTrackingHash(hashTrackingToken(token))
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.repo.ForTenant(appeal.TenantID).ListComments(appeal.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	return models.Track(appeal, comments), nil
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+	"go_appeals/internal/tenant"
+	"go_appeals/internal/workflow"
+)
+
+func TestTracking(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	year := time.Now().In(service.Calendar().Location()).Year()
+
+	appeal := createTestAppeal(t, service)
+	if appeal.Number != fmt.Sprintf("%d-000001", year) {
+		t.Errorf("Expected the first number of the year, got %q", appeal.Number)
+	}
+	if len(appeal.TrackingToken) < 20 {
+		t.Fatalf("Expected a tracking token, got %q", appeal.TrackingToken)
+	}
+	if fetched, _ := service.GetAppealByID(ctx, appeal.ID); fetched.TrackingToken != "" || fetched.Number != appeal.Number {
+		t.Errorf("Expected the number but not the token later, got %+v", fetched)
+	}
+
+	if _, err := service.AddComment(ctx, appeal.ID, models.CreateCommentRequest{Body: "We are on it"}); err != nil {
+		t.Fatalf("Failed to reply: %v", err)
+	}
+	if _, err := service.AddComment(ctx, appeal.ID, models.CreateCommentRequest{Body: "Vendor is slow", Visibility: models.CommentInternal}); err != nil {
+		t.Fatalf("Failed to add a note: %v", err)
+	}
+	if _, err := service.StartProcessing(ctx, appeal.ID); err != nil {
+		t.Fatalf("Failed to start processing: %v", err)
+	}
+	if _, err := service.CompleteAppeal(ctx, appeal.ID, models.UpdateAppealSolutionRequest{Solution: "Toner replaced"}); err != nil {
+		t.Fatalf("Failed to complete: %v", err)
+	}
+
+	tracked, err := service.TrackAppeal(ctx, appeal.TrackingToken)
+	if err != nil {
+		t.Fatalf("Failed to track appeal: %v", err)
+	}
+	if tracked.Number != appeal.Number || tracked.Status != models.StatusCompleted || tracked.Solution != "Toner replaced" || tracked.ResolvedAt == nil {
+		t.Errorf("Unexpected tracked appeal %+v", tracked)
+	}
+	// Внутренние заметки по токену не видны.
+	if len(tracked.Replies) != 1 || tracked.Replies[0].Body != "We are on it" {
+		t.Errorf("Expected only the public reply, got %+v", tracked.Replies)
+	}
+
+	for _, token := range []string{"", "guess", appeal.TrackingTokenHash} {
+		if _, err := service.TrackAppeal(ctx, token); models.ErrorCode(err) != "tracking_token_not_found" {
+			t.Errorf("Expected tracking_token_not_found for %q, got %v", token, err)
+		}
+	}
+}
+
+func TestNumbersHaveNoGaps(t *testing.T) {
+	t.Parallel()
+
+	// На файловой SQLite параллельные транзакции упираются в блокировку базы,
+	// в памяти или с одним соединением этого не видно.
+	sqlite, err := repository.NewAppealRepository(filepath.Join(t.TempDir(), "appeals.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+	if _, err := sqlite.Migrator().Up(); err != nil {
+		t.Fatalf("Failed to migrate database schema: %v", err)
+	}
+
+	for name, store := range map[string]repository.AppealStore{"Memory": repository.NewMemoryAppealRepository(), "SQLite": sqlite} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			service := NewAppealService(store, workflow.Default())
+			year := time.Now().In(service.Calendar().Location()).Year()
+
+			// Неудачное создание номер не расходует.
+			if _, err := service.CreateAppeal(ctx, models.CreateAppealRequest{Theme: "Printer", Message: "Jam", Priority: "whenever"}); err == nil {
+				t.Fatal("Expected an invalid priority to be refused")
+			}
+
+			var (
+				wg      sync.WaitGroup
+				mu      sync.Mutex
+				numbers []string
+			)
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					appeal, err := service.CreateAppeal(ctx, models.CreateAppealRequest{
+						Theme:     "Printer",
+						Message:   "Out of toner",
+						Requester: &models.RequesterDetails{Name: "Jane Doe", Email: fmt.Sprintf("jane%d@example.com", i%5)},
+					})
+					if err != nil {
+						t.Errorf("Failed to create appeal: %v", err)
+						return
+					}
+					mu.Lock()
+					numbers = append(numbers, appeal.Number)
+					mu.Unlock()
+				}()
+			}
+			wg.Wait()
+
+			sort.Strings(numbers)
+			for i, number := range numbers {
+				if want := fmt.Sprintf("%d-%06d", year, i+1); number != want {
+					t.Fatalf("Expected %s at %d, got %v", want, i, numbers)
+				}
+			}
+		})
+	}
+}
+
+func TestNumberPrefixes(t *testing.T) {
+	t.Parallel()
+
+	tenants, err := tenant.Parse([]byte(`{"tenants": [
+		{"id": "acme", "name": "Acme", "numbering": {"prefix": "ACME", "themes": {"Billing": "BIL"}}},
+		{"id": "globex", "name": "Globex", "numbering": {"prefix": "GX", "reset": "never"}}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse tenants: %v", err)
+	}
+	service := NewAppealService(repository.NewMemoryAppealRepository(), workflow.Default(), WithTenants(tenants))
+	year := time.Now().In(service.Calendar().Location()).Year()
+	acmeCtx, globexCtx := WithTenant(ctx, "acme"), WithTenant(ctx, "globex")
+
+	// У каждого отдела свой счётчик.
+	for _, tt := range []struct {
+		ctx   context.Context
+		theme string
+		want  string
+	}{
+		{acmeCtx, "Printer", fmt.Sprintf("ACME-%d-000001", year)},
+		{acmeCtx, "billing", fmt.Sprintf("BIL-%d-000001", year)},
+		{acmeCtx, "Network", fmt.Sprintf("ACME-%d-000002", year)},
+		{globexCtx, "Printer", "GX-000001"},
+	} {
+		appeal, err := service.CreateAppeal(tt.ctx, models.CreateAppealRequest{Theme: tt.theme, Message: "Help"})
+		if err != nil {
+			t.Fatalf("Failed to create appeal: %v", err)
+		}
+		if appeal.Number != tt.want {
+			t.Errorf("Expected %s for %s, got %q", tt.want, tt.theme, appeal.Number)
+		}
+	}
+}
+
+func TestAppealsByNumber(t *testing.T) {
+	t.Parallel()
+
+	service := newTestService(t)
+	appeal := createTestAppeal(t, service)
+	number := strings.ToLower(" " + appeal.Number)
+
+	if found, err := service.GetAppealByID(ctx, number); err != nil || found.ID != appeal.ID {
+		t.Fatalf("Expected the appeal by its number, got %+v, %v", found, err)
+	}
+	if _, err := service.AddComment(ctx, appeal.Number, models.CreateCommentRequest{Body: "On it"}); err != nil {
+		t.Fatalf("Failed to comment by number: %v", err)
+	}
+	if comments, err := service.GetComments(ctx, appeal.ID); err != nil || len(comments) != 1 || comments[0].AppealID != appeal.ID {
+		t.Errorf("Expected the comment on the appeal, got %+v, %v", comments, err)
+	}
+	if started, err := service.StartProcessing(ctx, appeal.Number); err != nil || started.Status != models.StatusInProgress {
+		t.Fatalf("Failed to start by number: %+v, %v", started, err)
+	}
+	if history, err := service.GetAppealHistory(ctx, appeal.Number); err != nil || len(history) != 2 {
+		t.Errorf("Expected the history by number, got %+v, %v", history, err)
+	}
+
+	if _, err := service.GetAppealByID(ctx, "1999-000001"); models.ErrorCode(err) != "appeal_not_found" {
+		t.Errorf("Expected appeal_not_found, got %v", err)
+	}
+}
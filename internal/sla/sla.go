@@ -0,0 +1,270 @@
+package sla
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_appeals/internal/calendar"
+	"go_appeals/internal/models"
+)
+
+//go:embed default.json
+var defaultDefinition []byte
+
+// Duration is a time.Duration written in JSON as a string such as "30m",
+// "4h" or "3d".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	v := time.Duration(d)
+	if v != 0 && v%(24*time.Hour) == 0 {
+		return json.Marshal(strconv.FormatInt(int64(v/(24*time.Hour)), 10) + "d")
+	}
+	return json.Marshal(v.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string: %w", err)
+	}
+	v, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// ParseDuration is time.ParseDuration that also accepts whole days, "2d".
+func ParseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// Target sets how much working time appeals matching Priority and Theme
+// may wait for a response and for a resolution. Empty Priority or Theme match anything; a
+// zero duration means there is no deadline.
+type Target struct {
+	Priority   models.AppealPriority `json:"priority,omitempty"`
+	Theme      string                `json:"theme,omitempty"`
+	Response   Duration              `json:"response,omitempty"`
+	Resolution Duration              `json:"resolution,omitempty"`
+}
+
+// Definition is the SLA configuration. DueSoon is the default look-ahead of
+// the "due soon" listing filter.
+type Definition struct {
+	DueSoon Duration `json:"due_soon"`
+	Targets []Target `json:"targets"`
+}
+
+type Policy struct {
+	def Definition
+}
+
+func New(def Definition) (*Policy, error) {
+	if def.DueSoon <= 0 {
+		return nil, fmt.Errorf("sla due_soon must be positive")
+	}
+
+	seen := make(map[Target]bool, len(def.Targets))
+	for _, target := range def.Targets {
+		if target.Priority != "" && !target.Priority.Valid() {
+			return nil, fmt.Errorf("sla target uses unknown priority %s", target.Priority)
+		}
+		if target.Response < 0 || target.Resolution < 0 {
+			return nil, fmt.Errorf("sla target for %s must not have negative durations", target.describe())
+		}
+		key := Target{Priority: target.Priority, Theme: strings.ToLower(target.Theme)}
+		if seen[key] {
+			return nil, fmt.Errorf("sla target for %s is defined twice", target.describe())
+		}
+		seen[key] = true
+	}
+
+	return &Policy{def: def}, nil
+}
+
+func Parse(data []byte) (*Policy, error) {
+	var def Definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("failed to parse sla definition: %w", err)
+	}
+	return New(def)
+}
+
+// Load reads a JSON SLA definition from path.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sla definition: %w", err)
+	}
+	return Parse(data)
+}
+
+// Default returns the built-in policy from default.json.
+func Default() *Policy {
+	p, err := Parse(defaultDefinition)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in sla policy: %v", err))
+	}
+	return p
+}
+
+func (p *Policy) Definition() Definition {
+	return p.def
+}
+
+func (p *Policy) DueSoon() time.Duration {
+	return time.Duration(p.def.DueSoon)
+}
+
+// Target returns the most specific target for an appeal: one naming both
+// the theme and the priority wins over one naming only the theme, which wins
+// over one naming only the priority, which wins over a catch-all.
+func (p *Policy) Target(priority models.AppealPriority, theme string) (Target, bool) {
+	best, bestScore := Target{}, -1
+	for _, target := range p.def.Targets {
+		if target.Priority != "" && target.Priority != priority {
+			continue
+		}
+		if target.Theme != "" && !strings.EqualFold(target.Theme, theme) {
+			continue
+		}
+		score := 0
+		if target.Theme != "" {
+			score += 2
+		}
+		if target.Priority != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = target, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// Plan sets the deadlines of a new appeal, counted in working time from
+// its creation.
+func (p *Policy) Plan(appeal *models.Appeal, cal *calendar.Calendar) {
+	target, _ := p.Target(appeal.Priority, appeal.Theme)
+	appeal.ResponseDueAt = deadline(cal, appeal.CreatedAt, target.Response)
+	appeal.ResolutionDueAt = deadline(cal, appeal.CreatedAt, target.Resolution)
+}
+
+// Track updates the SLA timestamps after the appeal changed status at now.
+// Any change counts as the response. open tells whether the new status is
+// still active and stopped whether it stops the SLA clock. Restarting the
+// clock moves the unmet deadlines by the working time it stood still; an
+// appeal that becomes active again after being closed gets a fresh
+// resolution deadline.
+func (p *Policy) Track(appeal *models.Appeal, cal *calendar.Calendar, open, stopped bool, now time.Time) {
+	if appeal.SLAPausedAt != nil && !stopped {
+		paused := cal.Elapsed(*appeal.SLAPausedAt, now)
+		appeal.SLAPausedFor += paused
+		appeal.SLAPausedAt = nil
+		if appeal.RespondedAt == nil {
+			appeal.ResponseDueAt = postpone(cal, appeal.ResponseDueAt, paused)
+		}
+		if appeal.ResolvedAt == nil {
+			appeal.ResolutionDueAt = postpone(cal, appeal.ResolutionDueAt, paused)
+		}
+	}
+	if stopped && appeal.SLAPausedAt == nil {
+		appeal.SLAPausedAt = &now
+	}
+
+	if appeal.RespondedAt == nil {
+		appeal.RespondedAt = &now
+	}
+
+	switch {
+	case !open && appeal.ResolvedAt == nil:
+		appeal.ResolvedAt = &now
+	case open && appeal.ResolvedAt != nil:
+		target, _ := p.Target(appeal.Priority, appeal.Theme)
+		appeal.ResolvedAt = nil
+		appeal.ResolutionDueAt = deadline(cal, now, target.Resolution)
+	}
+}
+
+// Report describes the SLA state of an appeal at now.
+func Report(appeal *models.Appeal, cal *calendar.Calendar, now time.Time) *models.AppealSLA {
+	return &models.AppealSLA{
+		AppealID:           appeal.ID,
+		Priority:           appeal.Priority,
+		ResponseDueAt:      appeal.ResponseDueAt,
+		ResolutionDueAt:    appeal.ResolutionDueAt,
+		RespondedAt:        appeal.RespondedAt,
+		ResolvedAt:         appeal.ResolvedAt,
+		ClockStopped:       appeal.SLAPausedAt != nil,
+		PausedSeconds:      int64(pausedFor(appeal, cal, now).Seconds()),
+		HandlingSeconds:    int64(HandlingTime(appeal, cal, now).Seconds()),
+		ResponseBreached:   appeal.ResponseBreached(now),
+		ResolutionBreached: appeal.ResolutionBreached(now),
+	}
+}
+
+// HandlingTime is the working time from the creation of the appeal until
+// its resolution, or now if it is still open, while the SLA clock ran.
+func HandlingTime(appeal *models.Appeal, cal *calendar.Calendar, now time.Time) time.Duration {
+	end := now
+	if appeal.ResolvedAt != nil {
+		end = *appeal.ResolvedAt
+	}
+	handling := cal.Elapsed(appeal.CreatedAt, end) - pausedFor(appeal, cal, end)
+	if handling < 0 {
+		return 0
+	}
+	return handling
+}
+
+// pausedFor is the working time the SLA clock has stood still until now,
+// including a stop still in progress.
+func pausedFor(appeal *models.Appeal, cal *calendar.Calendar, now time.Time) time.Duration {
+	paused := appeal.SLAPausedFor
+	if appeal.SLAPausedAt != nil && now.After(*appeal.SLAPausedAt) {
+		paused += cal.Elapsed(*appeal.SLAPausedAt, now)
+	}
+	return paused
+}
+
+func deadline(cal *calendar.Calendar, from time.Time, d Duration) *time.Time {
+	if d <= 0 {
+		return nil
+	}
+	due := cal.Add(from, time.Duration(d))
+	return &due
+}
+
+func postpone(cal *calendar.Calendar, due *time.Time, d time.Duration) *time.Time {
+	if due == nil || d <= 0 {
+		return due
+	}
+	moved := cal.Add(*due, d)
+	return &moved
+}
+
+func (t Target) describe() string {
+	priority, theme := string(t.Priority), t.Theme
+	if priority == "" {
+		priority = "any priority"
+	}
+	if theme == "" {
+		theme = "any theme"
+	}
+	return priority + "/" + theme
+}
@@ -0,0 +1,166 @@
+package sla
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go_appeals/internal/calendar"
+	"go_appeals/internal/models"
+)
+
+func TestDuration(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]time.Duration{
+		`"90m"`: 90 * time.Minute,
+		`"4h"`:  4 * time.Hour,
+		`"3d"`:  72 * time.Hour,
+	}
+	for input, expected := range tests {
+		var d Duration
+		if err := json.Unmarshal([]byte(input), &d); err != nil {
+			t.Fatalf("Failed to parse %s: %v", input, err)
+		}
+		if time.Duration(d) != expected {
+			t.Errorf("%s: expected %v, got %v", input, expected, time.Duration(d))
+		}
+
+		data, _ := json.Marshal(d)
+		var back Duration
+		if err := json.Unmarshal(data, &back); err != nil || back != d {
+			t.Errorf("%s: expected %s to round-trip, got %v (%v)", input, data, back, err)
+		}
+	}
+
+	for _, input := range []string{`"soon"`, `"xd"`, `14400`} {
+		var d Duration
+		if err := json.Unmarshal([]byte(input), &d); err == nil {
+			t.Errorf("Expected error for %s", input)
+		}
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	t.Parallel()
+
+	invalid := map[string]Definition{
+		"no due soon":      {Targets: []Target{{Response: Duration(time.Hour)}}},
+		"unknown priority": {DueSoon: Duration(time.Hour), Targets: []Target{{Priority: "critical"}}},
+		"negative":         {DueSoon: Duration(time.Hour), Targets: []Target{{Response: Duration(-time.Hour)}}},
+		"duplicate": {DueSoon: Duration(time.Hour), Targets: []Target{
+			{Theme: "Billing", Response: Duration(time.Hour)},
+			{Theme: "billing", Response: Duration(2 * time.Hour)},
+		}},
+	}
+	for name, def := range invalid {
+		if _, err := New(def); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestTarget(t *testing.T) {
+	t.Parallel()
+
+	policy, err := New(Definition{
+		DueSoon: Duration(time.Hour),
+		Targets: []Target{
+			{Resolution: Duration(1)},
+			{Priority: models.PriorityHigh, Resolution: Duration(2)},
+			{Theme: "Billing", Resolution: Duration(3)},
+			{Theme: "Billing", Priority: models.PriorityHigh, Resolution: Duration(4)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+
+	tests := []struct {
+		priority models.AppealPriority
+		theme    string
+		expected Duration
+	}{
+		{models.PriorityLow, "Delivery", 1},
+		{models.PriorityHigh, "Delivery", 2},
+		{models.PriorityLow, "billing", 3},
+		{models.PriorityHigh, "Billing", 4},
+	}
+	for _, tt := range tests {
+		target, ok := policy.Target(tt.priority, tt.theme)
+		if !ok || target.Resolution != tt.expected {
+			t.Errorf("%s/%s: expected target %d, got %d", tt.priority, tt.theme, tt.expected, target.Resolution)
+		}
+	}
+
+	empty, _ := New(Definition{DueSoon: Duration(time.Hour)})
+	if _, ok := empty.Target(models.PriorityNormal, "Billing"); ok {
+		t.Errorf("Expected no target in an empty policy")
+	}
+	if Default().DueSoon() <= 0 {
+		t.Errorf("Expected the built-in policy to define due_soon")
+	}
+}
+
+func TestTrackInWorkingTime(t *testing.T) {
+	t.Parallel()
+
+	cal, err := calendar.New(calendar.Definition{
+		TimeZone: "UTC",
+		Hours: map[string][]string{
+			"monday": {"09:00-18:00"}, "tuesday": {"09:00-18:00"}, "wednesday": {"09:00-18:00"},
+			"thursday": {"09:00-18:00"}, "friday": {"09:00-18:00"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create calendar: %v", err)
+	}
+	policy, err := New(Definition{
+		DueSoon: Duration(time.Hour),
+		Targets: []Target{{Response: Duration(2 * time.Hour), Resolution: Duration(20 * time.Hour)}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	at := func(day, hour int) time.Time {
+		return time.Date(2026, 3, day, hour, 0, 0, 0, time.UTC)
+	}
+
+	// Пятница, 17:00: час до конца дня, остальное переносится на понедельник.
+	appeal := &models.Appeal{Priority: models.PriorityNormal, CreatedAt: at(6, 17)}
+	policy.Plan(appeal, cal)
+	if !appeal.ResponseDueAt.Equal(at(9, 10)) || !appeal.ResolutionDueAt.Equal(at(11, 10)) {
+		t.Fatalf("Expected deadlines Monday 10:00 and Wednesday 10:00, got %v and %v", appeal.ResponseDueAt, appeal.ResolutionDueAt)
+	}
+
+	policy.Track(appeal, cal, true, false, at(9, 9))
+	if !appeal.RespondedAt.Equal(at(9, 9)) || appeal.ResponseBreached(at(20, 0)) {
+		t.Errorf("Expected an in-time response on Monday 9:00, got %v", appeal.RespondedAt)
+	}
+
+	// Ожидание ответа заявителя со вторника 12:00 до среды 12:00 — 9 рабочих часов.
+	policy.Track(appeal, cal, true, true, at(10, 12))
+	if appeal.SLAPausedAt == nil || appeal.DueWithin(at(11, 9), time.Hour) {
+		t.Errorf("Expected the clock to stop and nothing to be due while waiting")
+	}
+	if appeal.SLABreached(at(11, 11)) {
+		t.Errorf("Expected no breach while the clock is stopped")
+	}
+	policy.Track(appeal, cal, true, false, at(11, 12))
+	if appeal.SLAPausedAt != nil || appeal.SLAPausedFor != 9*time.Hour {
+		t.Errorf("Expected 9h of stopped clock, got %v", appeal.SLAPausedFor)
+	}
+	if !appeal.ResolutionDueAt.Equal(at(12, 10)) {
+		t.Errorf("Expected the resolution deadline to move to Thursday 10:00, got %v", appeal.ResolutionDueAt)
+	}
+
+	policy.Track(appeal, cal, false, false, at(12, 9))
+	report := Report(appeal, cal, at(20, 0))
+	if report.ResolutionBreached || report.ClockStopped {
+		t.Errorf("Expected an in-time resolution, got %+v", report)
+	}
+	// С пятницы 17:00 до четверга 9:00 — 1+9+9+9 = 28 рабочих часов, из них 9 на паузе.
+	if report.HandlingSeconds != int64((19*time.Hour).Seconds()) || report.PausedSeconds != int64((9*time.Hour).Seconds()) {
+		t.Errorf("Expected 19h handling and 9h paused, got %ds and %ds", report.HandlingSeconds, report.PausedSeconds)
+	}
+}
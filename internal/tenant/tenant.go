@@ -0,0 +1,213 @@
+package tenant
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"go_appeals/internal/models"
+	"go_appeals/internal/sla"
+)
+
+//go:embed default.json
+var defaultDefinition []byte
+
+// Tenant is an organisation whose appeals are kept apart from everyone
+// else's. Themes, when set, are the only themes its appeals may have; SLA,
+// when set, replaces the service-wide SLA policy for its appeals.
+type Tenant struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Themes    []string        `json:"themes,omitempty"`
+	SLA       *sla.Definition `json:"sla,omitempty"`
+	Numbering *Numbering      `json:"numbering,omitempty"`
+
+	policy *sla.Policy
+}
+
+// Numbering shapes the registration numbers of a tenant's appeals, such as
+// ACME-2026-000123. Prefix is put in front of every number unless Themes
+// has one for the appeal's theme, which lets departments count apart.
+// Reset is "yearly", the default, or "never".
+type Numbering struct {
+	Prefix string            `json:"prefix,omitempty"`
+	Themes map[string]string `json:"themes,omitempty"`
+	Reset  string            `json:"reset,omitempty"`
+}
+
+const (
+	ResetYearly = "yearly"
+	ResetNever  = "never"
+)
+
+// Prefixes start with a letter so that they are never mistaken for a year.
+var validPrefix = regexp.MustCompile(`^[A-Z][A-Z0-9]{0,9}$`)
+
+// NumberPrefix returns the prefix of registration numbers of appeals with
+// theme, or "" when they have none.
+func (t *Tenant) NumberPrefix(theme string) string {
+	if t.Numbering == nil {
+		return ""
+	}
+	for name, prefix := range t.Numbering.Themes {
+		if strings.EqualFold(strings.TrimSpace(theme), strings.TrimSpace(name)) {
+			return prefix
+		}
+	}
+	return t.Numbering.Prefix
+}
+
+// ResetsYearly reports whether registration numbers start again from 1
+// every year.
+func (t *Tenant) ResetsYearly() bool {
+	return t.Numbering == nil || t.Numbering.Reset != ResetNever
+}
+
+// AllowsTheme reports whether an appeal of the tenant may have theme.
+// Themes are compared case-insensitively, ignoring surrounding spaces.
+func (t *Tenant) AllowsTheme(theme string) bool {
+	if len(t.Themes) == 0 {
+		return true
+	}
+	for _, allowed := range t.Themes {
+		if strings.EqualFold(strings.TrimSpace(theme), strings.TrimSpace(allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SLAPolicy returns the tenant's own SLA policy, or nil when it uses the
+// service-wide one.
+func (t *Tenant) SLAPolicy() *sla.Policy {
+	return t.policy
+}
+
+// Definition is the JSON form of the tenant list.
+type Definition struct {
+	Tenants []Tenant `json:"tenants"`
+}
+
+// Registry holds the configured tenants. Requests naming any other tenant
+// are refused.
+type Registry struct {
+	def     Definition
+	tenants map[string]*Tenant
+}
+
+func New(def Definition) (*Registry, error) {
+	if len(def.Tenants) == 0 {
+		return nil, fmt.Errorf("tenants: at least one tenant is required")
+	}
+
+	r := &Registry{def: def, tenants: make(map[string]*Tenant, len(def.Tenants))}
+	for i := range def.Tenants {
+		t := def.Tenants[i]
+		if t.ID == "" || strings.TrimSpace(t.ID) != t.ID || strings.Contains(t.ID, ",") {
+			return nil, fmt.Errorf("tenants: invalid tenant id %q", t.ID)
+		}
+		if r.tenants[t.ID] != nil {
+			return nil, fmt.Errorf("tenants: tenant %s is defined twice", t.ID)
+		}
+		for _, theme := range t.Themes {
+			if strings.TrimSpace(theme) == "" {
+				return nil, fmt.Errorf("tenants: tenant %s has an empty theme", t.ID)
+			}
+		}
+		if err := t.Numbering.validate(&t); err != nil {
+			return nil, fmt.Errorf("tenants: tenant %s: %w", t.ID, err)
+		}
+		if t.SLA != nil {
+			policy, err := sla.New(*t.SLA)
+			if err != nil {
+				return nil, fmt.Errorf("tenants: tenant %s: %w", t.ID, err)
+			}
+			t.policy = policy
+		}
+		r.tenants[t.ID] = &t
+	}
+	return r, nil
+}
+
+func (n *Numbering) validate(t *Tenant) error {
+	if n == nil {
+		return nil
+	}
+	switch n.Reset {
+	case "", ResetYearly, ResetNever:
+	default:
+		return fmt.Errorf("unknown numbering reset %q", n.Reset)
+	}
+	if n.Prefix != "" && !validPrefix.MatchString(n.Prefix) {
+		return fmt.Errorf("invalid number prefix %q", n.Prefix)
+	}
+	// Themes are matched case-insensitively, so two keys that differ only
+	// in case would leave the prefix up to map order.
+	seen := make(map[string]string, len(n.Themes))
+	for theme, prefix := range n.Themes {
+		if !t.AllowsTheme(theme) {
+			return fmt.Errorf("number prefix for unknown theme %q", theme)
+		}
+		if !validPrefix.MatchString(prefix) {
+			return fmt.Errorf("invalid number prefix %q", prefix)
+		}
+		key := strings.ToLower(strings.TrimSpace(theme))
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("number prefixes for themes %q and %q differ only in case", other, theme)
+		}
+		seen[key] = theme
+	}
+	return nil
+}
+
+func Parse(data []byte) (*Registry, error) {
+	var def Definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("failed to parse tenants: %w", err)
+	}
+	return New(def)
+}
+
+// Load reads a JSON tenant list from path.
+func Load(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenants: %w", err)
+	}
+	return Parse(data)
+}
+
+// Default returns the built-in registry from default.json, which has only
+// the default tenant.
+func Default() *Registry {
+	r, err := Parse(defaultDefinition)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in tenants: %v", err))
+	}
+	return r
+}
+
+func (r *Registry) Definition() Definition {
+	return r.def
+}
+
+// Get returns the tenant with id.
+func (r *Registry) Get(id string) (*Tenant, error) {
+	t, ok := r.tenants[id]
+	if !ok {
+		return nil, models.NewError(models.ErrNotFound, "tenant_not_found", "tenant %s not found", id)
+	}
+	return t, nil
+}
+
+// Tenants returns every tenant in the order they were defined.
+func (r *Registry) Tenants() []*Tenant {
+	tenants := make([]*Tenant, 0, len(r.def.Tenants))
+	for _, t := range r.def.Tenants {
+		tenants = append(tenants, r.tenants[t.ID])
+	}
+	return tenants
+}
@@ -0,0 +1,91 @@
+package tenant
+
+import (
+	"testing"
+	"time"
+
+	"go_appeals/internal/models"
+)
+
+func TestDefault(t *testing.T) {
+	t.Parallel()
+
+	reg := Default()
+	tenant, err := reg.Get(models.DefaultTenant)
+	if err != nil {
+		t.Fatalf("Expected the default tenant, got %v", err)
+	}
+	if !tenant.AllowsTheme("Anything") || tenant.SLAPolicy() != nil {
+		t.Errorf("Expected the default tenant to allow every theme and use the service SLA, got %+v", tenant)
+	}
+	if _, err := reg.Get("acme"); models.ErrorCode(err) != "tenant_not_found" {
+		t.Errorf("Expected tenant_not_found, got %v", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for _, data := range []string{
+		`{"tenants": []}`,
+		`{"tenants": [{"id": ""}]}`,
+		`{"tenants": [{"id": "acme"}, {"id": "acme"}]}`,
+		`{"tenants": [{"id": "acme", "themes": [" "]}]}`,
+		`{"tenants": [{"id": "acme", "sla": {"targets": [{"priority": "urgent", "response": "soon"}]}}]}`,
+		`{"tenants": [{"id": "acme", "numbering": {"prefix": "acme"}}]}`,
+		`{"tenants": [{"id": "acme", "numbering": {"prefix": "2026"}}]}`,
+		`{"tenants": [{"id": "acme", "numbering": {"reset": "monthly"}}]}`,
+		`{"tenants": [{"id": "acme", "themes": ["Printer"], "numbering": {"themes": {"Scanner": "SC"}}}]}`,
+		`{"tenants": [{"id": "acme", "numbering": {"themes": {"Billing": "BIL", "billing ": "ACC"}}}]}`,
+		`{"tenants": [`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Expected error for %s", data)
+		}
+	}
+
+	reg, err := Parse([]byte(`{"tenants": [
+		{"id": "acme", "name": "Acme", "themes": ["Printer", " Network "],
+		 "sla": {"due_soon": "2h", "targets": [{"priority": "normal", "response": "1h", "resolution": "8h"}]}},
+		{"id": "globex", "name": "Globex"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse tenants: %v", err)
+	}
+	tenants := reg.Tenants()
+	if len(tenants) != 2 || tenants[0].ID != "acme" || tenants[1].ID != "globex" {
+		t.Fatalf("Expected the tenants in order, got %+v", tenants)
+	}
+
+	acme := tenants[0]
+	// Темы сравниваются без учёта регистра и пробелов по краям.
+	if !acme.AllowsTheme(" printer") || !acme.AllowsTheme("network") || acme.AllowsTheme("Scanner") {
+		t.Errorf("Unexpected themes for %+v", acme)
+	}
+	if policy := acme.SLAPolicy(); policy == nil || policy.DueSoon() != 2*time.Hour {
+		t.Errorf("Expected acme's own SLA policy, got %+v", policy)
+	}
+	if tenants[1].SLAPolicy() != nil {
+		t.Errorf("Expected globex to use the service SLA")
+	}
+}
+
+func TestNumbering(t *testing.T) {
+	t.Parallel()
+
+	reg, err := Parse([]byte(`{"tenants": [
+		{"id": "acme", "numbering": {"prefix": "ACME", "themes": {"Billing": "BIL"}, "reset": "never"}},
+		{"id": "globex"}
+	]}`))
+	if err != nil {
+		t.Fatalf("Failed to parse tenants: %v", err)
+	}
+	acme, _ := reg.Get("acme")
+	if acme.NumberPrefix("Printer") != "ACME" || acme.NumberPrefix(" billing") != "BIL" || acme.ResetsYearly() {
+		t.Errorf("Unexpected numbering for %+v", acme.Numbering)
+	}
+	globex, _ := reg.Get("globex")
+	if globex.NumberPrefix("Printer") != "" || !globex.ResetsYearly() {
+		t.Errorf("Expected plain yearly numbers for globex")
+	}
+}
@@ -0,0 +1,498 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+)
+
+// Headers sent with every delivery. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)). An event can
+// arrive more than once, in separate deliveries; HeaderEventID tells the
+// repeats apart.
+const (
+	HeaderEvent     = "X-Appeals-Event"
+	HeaderEventID   = "X-Appeals-Event-Id"
+	HeaderDelivery  = "X-Appeals-Delivery"
+	HeaderTimestamp = "X-Appeals-Timestamp"
+	HeaderSignature = "X-Appeals-Signature"
+)
+
+const (
+	DefaultMaxAttempts = 6
+	DefaultBackoff     = 10 * time.Second
+	maxBackoff         = time.Hour
+)
+
+type Option func(d *Dispatcher)
+
+// WithClient replaces the HTTP client, which by default gives up on a
+// receiver after 10 seconds and refuses to connect to private addresses.
+// A client given here is used as is.
+func WithClient(client *http.Client) Option {
+	return func(d *Dispatcher) { d.client = client }
+}
+
+// AllowPrivateNetworks lets subscriptions point at loopback, private and
+// link-local addresses, which are refused by default so that a webhook
+// cannot be used to reach services on the server's own network.
+func AllowPrivateNetworks() Option {
+	return func(d *Dispatcher) { d.allowPrivate = true }
+}
+
+// WithRetries sets how many times a delivery is attempted and the delay
+// before the first retry. Each further retry waits twice as long, up to an
+// hour.
+func WithRetries(maxAttempts int, backoff time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = maxAttempts
+		d.backoff = backoff
+	}
+}
+
+// Dispatcher sends events to the subscriptions that want them and retries
+// failed deliveries in the background. Deliveries are stored before they are
+// attempted, so those still pending at shutdown are resumed by Start.
+type Dispatcher struct {
+	store        repository.WebhookStore
+	client       *http.Client
+	allowPrivate bool
+	maxAttempts  int
+	backoff      time.Duration
+
+	mu       sync.Mutex
+	started  bool
+	stopped  bool
+	stop     chan struct{}
+	inFlight sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopOnce sync.Once
+}
+
+func New(store repository.WebhookStore, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		store:       store,
+		maxAttempts: DefaultMaxAttempts,
+		backoff:     DefaultBackoff,
+		stop:        make(chan struct{}),
+	}
+	// The address is checked again when connecting, after the receiver's
+	// name has been resolved, so that changing what it resolves to after
+	// subscribing does not get around the check. Proxies are not used: the
+	// check would only see the proxy.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{Timeout: 10 * time.Second, Control: d.checkDial}).DialContext
+	d.client = &http.Client{Timeout: 10 * time.Second, Transport: transport}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	return d
+}
+
+// Start begins sending deliveries, picking up the ones left pending by a
+// previous run.
+func (d *Dispatcher) Start() error {
+	d.mu.Lock()
+	if d.started || d.stopped {
+		d.mu.Unlock()
+		return nil
+	}
+	d.started = true
+	d.mu.Unlock()
+
+	pending, err := d.store.PendingDeliveries()
+	if err != nil {
+		return fmt.Errorf("failed to load pending deliveries: %w", err)
+	}
+	for _, delivery := range pending {
+		d.send(delivery)
+	}
+	return nil
+}
+
+// Shutdown stops retrying and waits for the attempts in progress. Deliveries
+// waiting for a retry stay pending. If ctx expires first, the requests in
+// progress are cancelled and ctx.Err() is returned.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	d.stopped = true
+	d.mu.Unlock()
+	d.stopOnce.Do(func() { close(d.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		d.inFlight.Wait()
+		close(done)
+	}()
+
+	defer d.cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Name makes the dispatcher an outbox sink.
+func (d *Dispatcher) Name() string {
+	return "webhooks"
+}
+
+// Publish queues event for every active subscription of the event's tenant
+// that wants it.
+func (d *Dispatcher) Publish(ctx context.Context, event models.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	subs, err := d.store.ListWebhooks()
+	if err != nil {
+		return fmt.Errorf("failed to load webhooks: %w", err)
+	}
+	for _, sub := range subs {
+		if sub.TenantID != event.TenantID || !sub.Matches(event.Type) {
+			continue
+		}
+		delivery := &models.WebhookDelivery{
+			SubscriptionID: sub.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         models.DeliveryPending,
+		}
+		if err := d.store.AddDelivery(delivery); err != nil {
+			return fmt.Errorf("failed to queue delivery for webhook %s: %w", sub.ID, err)
+		}
+		d.send(delivery)
+	}
+	return nil
+}
+
+// The administration methods below act for one tenant; subscriptions of
+// other tenants look as if they did not exist.
+
+func (d *Dispatcher) CreateSubscription(tenant string, req models.CreateWebhookRequest) (*models.WebhookSubscription, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if err := d.checkURL(strings.TrimSpace(req.URL)); err != nil {
+		return nil, err
+	}
+
+	sub := &models.WebhookSubscription{
+		TenantID: tenant,
+		URL:      strings.TrimSpace(req.URL),
+		Secret:   req.Secret,
+		Active:   true,
+	}
+	for _, event := range req.Events {
+		sub.Events = append(sub.Events, strings.TrimSpace(event))
+	}
+	if sub.Secret == "" {
+		secret := make([]byte, 24)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		sub.Secret = hex.EncodeToString(secret)
+	}
+
+	if err := d.store.CreateWebhook(sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// Subscriptions lists the subscriptions without their secrets.
+func (d *Dispatcher) Subscriptions(tenant string) ([]*models.WebhookSubscription, error) {
+	subs, err := d.store.ListWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	own := make([]*models.WebhookSubscription, 0, len(subs))
+	for _, sub := range subs {
+		if sub.TenantID == tenant {
+			sub.Secret = ""
+			own = append(own, sub)
+		}
+	}
+	return own, nil
+}
+
+// Subscription returns a subscription without its secret.
+func (d *Dispatcher) Subscription(tenant, id string) (*models.WebhookSubscription, error) {
+	sub, err := d.find(tenant, id)
+	if err != nil {
+		return nil, err
+	}
+	sub.Secret = ""
+	return sub, nil
+}
+
+func (d *Dispatcher) DeleteSubscription(tenant, id string) error {
+	if _, err := d.find(tenant, id); err != nil {
+		return err
+	}
+	return d.store.DeleteWebhook(id)
+}
+
+func (d *Dispatcher) find(tenant, id string) (*models.WebhookSubscription, error) {
+	sub, err := d.store.FindWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	if sub.TenantID != tenant {
+		return nil, models.NewError(models.ErrNotFound, "webhook_not_found", "webhook %s not found", id)
+	}
+	return sub, nil
+}
+
+// Deliveries returns the delivery log of a subscription, newest first; a
+// zero limit means the default page size.
+func (d *Dispatcher) Deliveries(tenant, subscriptionID string, limit int) ([]*models.WebhookDelivery, error) {
+	if _, err := d.find(tenant, subscriptionID); err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		limit = models.DefaultPageSize
+	}
+	if limit < 0 || limit > models.MaxPageSize {
+		return nil, models.NewError(models.ErrValidation, "invalid_limit", "limit must be between 1 and %d", models.MaxPageSize)
+	}
+	return d.store.ListDeliveries(subscriptionID, limit)
+}
+
+// Redeliver sends the payload of a finished delivery again, as a new
+// delivery with the same event ID.
+func (d *Dispatcher) Redeliver(tenant, subscriptionID string, deliveryID int64) (*models.WebhookDelivery, error) {
+	if _, err := d.find(tenant, subscriptionID); err != nil {
+		return nil, err
+	}
+	original, err := d.store.FindDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if original.SubscriptionID != subscriptionID {
+		return nil, models.NewError(models.ErrNotFound, "delivery_not_found", "delivery %d not found", deliveryID)
+	}
+	if original.Status == models.DeliveryPending {
+		return nil, models.NewError(models.ErrConflict, "delivery_pending", "delivery %d is still being attempted", deliveryID)
+	}
+
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: original.SubscriptionID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         models.DeliveryPending,
+	}
+	if err := d.store.AddDelivery(delivery); err != nil {
+		return nil, err
+	}
+	queued := *delivery
+	d.send(delivery)
+
+	return &queued, nil
+}
+
+// send attempts delivery in the background until it succeeds, runs out of
+// attempts or the dispatcher stops.
+func (d *Dispatcher) send(delivery *models.WebhookDelivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.started || d.stopped {
+		return
+	}
+
+	d.inFlight.Add(1)
+	go func() {
+		defer d.inFlight.Done()
+
+		for delivery.Status == models.DeliveryPending {
+			if delivery.NextAttemptAt != nil {
+				timer := time.NewTimer(time.Until(*delivery.NextAttemptAt))
+				select {
+				case <-d.stop:
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+			if !d.attempt(delivery) {
+				return
+			}
+		}
+	}()
+}
+
+// attempt makes one delivery attempt and records its outcome. It reports
+// whether the delivery should go on.
+func (d *Dispatcher) attempt(delivery *models.WebhookDelivery) bool {
+	sub, err := d.store.FindWebhook(delivery.SubscriptionID)
+	if errors.Is(err, models.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		log.Printf("Failed to load webhook %s: %v", delivery.SubscriptionID, err)
+		return false
+	}
+
+	delivery.Attempts++
+	delivery.ResponseStatus, err = d.post(sub, delivery)
+	now := time.Now()
+
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliverySucceeded
+		delivery.Error = ""
+		delivery.NextAttemptAt = nil
+		delivery.FinishedAt = &now
+	case delivery.Attempts >= d.maxAttempts || !sub.Active:
+		delivery.Status = models.DeliveryFailed
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = nil
+		delivery.FinishedAt = &now
+	default:
+		delivery.Error = err.Error()
+		next := now.Add(d.delay(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+
+	if err := d.store.UpdateDelivery(delivery); err != nil {
+		log.Printf("Failed to record delivery %d: %v", delivery.ID, err)
+		return false
+	}
+	return true
+}
+
+// delay is the wait after the given number of failed attempts.
+func (d *Dispatcher) delay(attempts int) time.Duration {
+	delay := d.backoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+func (d *Dispatcher) post(sub *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, sub.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-appeals-webhooks")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// checkURL refuses a receiver whose host is or resolves to a private
+// address. A host that does not resolve yet is accepted; connecting to it
+// is checked again.
+func (d *Dispatcher) checkURL(raw string) error {
+	if d.allowPrivate {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(d.ctx, 5*time.Second)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if private(addr) {
+			return models.NewError(models.ErrValidation, "invalid_url",
+				"url must not point to a loopback, private or link-local address, %s resolves to %s", u.Hostname(), addr.Unmap())
+		}
+	}
+	return nil
+}
+
+// checkDial is the dialer's last look at the address it is connecting to.
+func (d *Dispatcher) checkDial(network, address string, _ syscall.RawConn) error {
+	if d.allowPrivate {
+		return nil
+	}
+	addr, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if private(addr.Addr()) {
+		return fmt.Errorf("refusing to connect to %s: it is a loopback, private or link-local address", addr.Addr().Unmap())
+	}
+	return nil
+}
+
+func private(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() || addr.IsUnspecified()
+}
+
+// Sign computes the signature header value for a payload.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a delivery's signature and rejects timestamps further than
+// tolerance from now, which stops replays of old deliveries.
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, ts, body)))
+}
@@ -0,0 +1,343 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go_appeals/internal/models"
+	"go_appeals/internal/repository"
+)
+
+// receiver is a local webhook endpoint that answers with the queued status
+// codes, then 204, and keeps the requests it got.
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		status := http.StatusNoContent
+		if len(r.statuses) > 0 {
+			status, r.statuses = r.statuses[0], r.statuses[1:]
+		}
+		r.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+// newTestDispatcher lets subscriptions reach receivers on the loopback
+// interface.
+func newTestDispatcher(t *testing.T, store repository.WebhookStore, opts ...Option) *Dispatcher {
+	d := New(store, append([]Option{WithRetries(3, 10*time.Millisecond), AllowPrivateNetworks()}, opts...)...)
+	t.Cleanup(func() { d.Shutdown(context.Background()) })
+	return d
+}
+
+func subscribe(t *testing.T, d *Dispatcher, url string, events ...string) *models.WebhookSubscription {
+	sub, err := d.CreateSubscription(models.DefaultTenant, models.CreateWebhookRequest{URL: url, Events: events})
+	if err != nil {
+		t.Fatalf("Failed to create subscription: %v", err)
+	}
+	return sub
+}
+
+func testEvent(eventType string) models.Event {
+	return models.Event{
+		ID:         "event-" + eventType,
+		Type:       eventType,
+		TenantID:   models.DefaultTenant,
+		AppealID:   "appeal-1",
+		Appeal:     &models.Appeal{ID: "appeal-1", Theme: "Printer", Status: models.StatusNew},
+		OccurredAt: time.Now(),
+	}
+}
+
+// waitForDelivery polls the log until the delivery is no longer pending.
+func waitForDelivery(t *testing.T, store repository.WebhookStore, id int64) *models.WebhookDelivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		delivery, err := store.FindDelivery(id)
+		if err != nil {
+			t.Fatalf("Failed to find delivery: %v", err)
+		}
+		if delivery.Status != models.DeliveryPending {
+			return delivery
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Delivery %d is still pending", id)
+	return nil
+}
+
+func TestPublish(t *testing.T) {
+	t.Parallel()
+
+	store := repository.NewMemoryAppealRepository()
+	d := newTestDispatcher(t, store)
+	if err := d.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	r := newReceiver(t)
+	sub := subscribe(t, d, r.URL, models.EventAppealCreated)
+	if len(sub.Secret) != 48 {
+		t.Errorf("Expected a generated secret, got %q", sub.Secret)
+	}
+	// Подписка другой организации событий default не получает.
+	other, err := d.CreateSubscription("acme", models.CreateWebhookRequest{URL: r.URL})
+	if err != nil {
+		t.Fatalf("Failed to create subscription: %v", err)
+	}
+
+	for _, eventType := range []string{"appeal.started", models.EventAppealCreated} {
+		if err := d.Publish(context.Background(), testEvent(eventType)); err != nil {
+			t.Fatalf("Failed to publish: %v", err)
+		}
+	}
+
+	if deliveries, err := d.Deliveries("acme", other.ID, 0); err != nil || len(deliveries) != 0 {
+		t.Errorf("Expected nothing for another tenant, got %d, %v", len(deliveries), err)
+	}
+	deliveries, err := d.Deliveries(models.DefaultTenant, sub.ID, 0)
+	if err != nil {
+		t.Fatalf("Failed to list deliveries: %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("Expected only the subscribed event to be delivered, got %d", len(deliveries))
+	}
+	delivered := waitForDelivery(t, store, deliveries[0].ID)
+	if delivered.Status != models.DeliverySucceeded || delivered.Attempts != 1 || delivered.ResponseStatus != http.StatusNoContent {
+		t.Errorf("Expected a successful first attempt, got %+v", delivered)
+	}
+
+	req, body := r.requests[0], r.bodies[0]
+	if req.Header.Get(HeaderEvent) != models.EventAppealCreated || req.Header.Get(HeaderEventID) != "event-appeal.created" || req.Header.Get(HeaderDelivery) != strconv.FormatInt(delivered.ID, 10) {
+		t.Errorf("Unexpected headers %v", req.Header)
+	}
+	if !Verify(sub.Secret, req.Header.Get(HeaderTimestamp), req.Header.Get(HeaderSignature), body, time.Minute) {
+		t.Errorf("Expected a valid signature")
+	}
+	if Verify("wrong", req.Header.Get(HeaderTimestamp), req.Header.Get(HeaderSignature), body, time.Minute) {
+		t.Errorf("Expected the signature to depend on the secret")
+	}
+	var event models.Event
+	if err := json.Unmarshal(body, &event); err != nil || event.ID != "event-appeal.created" || event.Appeal.Theme != "Printer" {
+		t.Errorf("Expected the event as the body, got %s", body)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	t.Parallel()
+
+	store := repository.NewMemoryAppealRepository()
+	d := newTestDispatcher(t, store)
+	if err := d.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+
+	flaky := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway)
+	flakySub := subscribe(t, d, flaky.URL)
+	down := newReceiver(t, 500, 500, 500, 500)
+	downSub := subscribe(t, d, down.URL)
+
+	if err := d.Publish(context.Background(), testEvent(models.EventAppealCreated)); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	recovered := waitForDelivery(t, store, 1)
+	if recovered.SubscriptionID != flakySub.ID || recovered.Status != models.DeliverySucceeded || recovered.Attempts != 3 || recovered.Error != "" {
+		t.Errorf("Expected the third attempt to succeed, got %+v", recovered)
+	}
+	failed := waitForDelivery(t, store, 2)
+	if failed.SubscriptionID != downSub.ID || failed.Status != models.DeliveryFailed || failed.Attempts != 3 || failed.ResponseStatus != 500 {
+		t.Errorf("Expected the delivery to fail after three attempts, got %+v", failed)
+	}
+
+	if d.delay(1) != 10*time.Millisecond || d.delay(3) != 40*time.Millisecond || d.delay(100) != time.Hour {
+		t.Errorf("Expected exponential backoff capped at an hour")
+	}
+
+	// Повторная отправка создаёт новую доставку с тем же событием.
+	again, err := d.Redeliver(models.DefaultTenant, downSub.ID, failed.ID)
+	if err != nil {
+		t.Fatalf("Failed to redeliver: %v", err)
+	}
+	redelivered := waitForDelivery(t, store, again.ID)
+	if redelivered.Status != models.DeliverySucceeded || redelivered.EventID != failed.EventID || string(redelivered.Payload) != string(failed.Payload) {
+		t.Errorf("Expected the redelivery to succeed with the same event, got %+v", redelivered)
+	}
+
+	if _, err := d.Redeliver(models.DefaultTenant, flakySub.ID, failed.ID); models.ErrorCode(err) != "delivery_not_found" {
+		t.Errorf("Expected delivery_not_found for another subscription's delivery, got %v", err)
+	}
+	if _, err := d.Redeliver(models.DefaultTenant, downSub.ID, 999); models.ErrorCode(err) != "delivery_not_found" {
+		t.Errorf("Expected delivery_not_found, got %v", err)
+	}
+}
+
+func TestPendingDeliveriesResume(t *testing.T) {
+	t.Parallel()
+
+	store := repository.NewMemoryAppealRepository()
+	r := newReceiver(t)
+
+	stopped := newTestDispatcher(t, store)
+	subscribe(t, stopped, r.URL)
+	if err := stopped.Publish(context.Background(), testEvent(models.EventAppealCreated)); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	if err := stopped.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+	if r.received() != 0 {
+		t.Fatalf("Expected nothing to be sent before Start")
+	}
+
+	pending, err := store.PendingDeliveries()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("Expected one pending delivery, got %d, %v", len(pending), err)
+	}
+	if _, err := stopped.Redeliver(models.DefaultTenant, pending[0].SubscriptionID, pending[0].ID); models.ErrorCode(err) != "delivery_pending" {
+		t.Errorf("Expected delivery_pending, got %v", err)
+	}
+
+	d := newTestDispatcher(t, store)
+	if err := d.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	if delivered := waitForDelivery(t, store, pending[0].ID); delivered.Status != models.DeliverySucceeded {
+		t.Errorf("Expected the pending delivery to be sent on start, got %+v", delivered)
+	}
+}
+
+func TestSubscriptions(t *testing.T) {
+	t.Parallel()
+
+	d := newTestDispatcher(t, repository.NewMemoryAppealRepository())
+
+	for _, url := range []string{"", "ftp://example.com", "/relative", "https://"} {
+		if _, err := d.CreateSubscription(models.DefaultTenant, models.CreateWebhookRequest{URL: url}); models.ErrorCode(err) != "invalid_url" {
+			t.Errorf("%q: expected invalid_url, got %v", url, err)
+		}
+	}
+
+	sub, err := d.CreateSubscription(models.DefaultTenant, models.CreateWebhookRequest{URL: " https://crm.example.com/hook ", Secret: "chosen", Events: []string{" appeal.created "}})
+	if err != nil {
+		t.Fatalf("Failed to create subscription: %v", err)
+	}
+	if sub.URL != "https://crm.example.com/hook" || sub.Secret != "chosen" || sub.Events[0] != "appeal.created" || !sub.Active {
+		t.Errorf("Unexpected subscription %+v", sub)
+	}
+
+	subs, err := d.Subscriptions(models.DefaultTenant)
+	if err != nil || len(subs) != 1 || subs[0].Secret != "" {
+		t.Errorf("Expected the subscription without its secret, got %+v, %v", subs, err)
+	}
+	if found, err := d.Subscription(models.DefaultTenant, sub.ID); err != nil || found.Secret != "" {
+		t.Errorf("Expected the subscription without its secret, got %+v, %v", found, err)
+	}
+
+	// Чужие подписки не видны и не удаляются.
+	if subs, err := d.Subscriptions("acme"); err != nil || len(subs) != 0 {
+		t.Errorf("Expected no subscriptions for another tenant, got %+v, %v", subs, err)
+	}
+	if _, err := d.Subscription("acme", sub.ID); models.ErrorCode(err) != "webhook_not_found" {
+		t.Errorf("Expected webhook_not_found, got %v", err)
+	}
+	if err := d.DeleteSubscription("acme", sub.ID); models.ErrorCode(err) != "webhook_not_found" {
+		t.Errorf("Expected webhook_not_found, got %v", err)
+	}
+
+	if err := d.DeleteSubscription(models.DefaultTenant, sub.ID); err != nil {
+		t.Fatalf("Failed to delete subscription: %v", err)
+	}
+	if _, err := d.Deliveries(models.DefaultTenant, sub.ID, 0); models.ErrorCode(err) != "webhook_not_found" {
+		t.Errorf("Expected webhook_not_found, got %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"id":"1"}`)
+	now := time.Now().Unix()
+	signature := Sign("secret", now, body)
+
+	if !Verify("secret", strconv.FormatInt(now, 10), signature, body, time.Minute) {
+		t.Errorf("Expected a fresh signature to verify")
+	}
+	if Verify("secret", strconv.FormatInt(now, 10), signature, []byte(`{"id":"2"}`), time.Minute) {
+		t.Errorf("Expected a changed body to fail")
+	}
+	old := now - 3600
+	if Verify("secret", strconv.FormatInt(old, 10), Sign("secret", old, body), body, time.Minute) {
+		t.Errorf("Expected an old timestamp to fail")
+	}
+	if Verify("secret", "yesterday", signature, body, time.Minute) {
+		t.Errorf("Expected a malformed timestamp to fail")
+	}
+}
+
+func TestPrivateNetworks(t *testing.T) {
+	t.Parallel()
+
+	store := repository.NewMemoryAppealRepository()
+	d := New(store, WithRetries(1, time.Millisecond))
+	t.Cleanup(func() { d.Shutdown(context.Background()) })
+	if err := d.Start(); err != nil {
+		t.Fatalf("Failed to start: %v", err)
+	}
+	r := newReceiver(t)
+	// Через прокси проверка адреса видела бы только сам прокси.
+	if d.client.Transport.(*http.Transport).Proxy != nil {
+		t.Error("Expected deliveries not to go through a proxy")
+	}
+
+	for _, url := range []string{r.URL, "http://localhost:8080/hook", "http://[::1]/hook", "http://169.254.169.254/latest/meta-data", "https://10.0.0.7/hook", "http://0.0.0.0/"} {
+		if _, err := d.CreateSubscription(models.DefaultTenant, models.CreateWebhookRequest{URL: url}); models.ErrorCode(err) != "invalid_url" {
+			t.Errorf("%q: expected invalid_url, got %v", url, err)
+		}
+	}
+
+	// Подписка, которая прошла проверку раньше, всё равно не доходит до внутреннего адреса.
+	sub := &models.WebhookSubscription{TenantID: models.DefaultTenant, URL: r.URL, Secret: "secret", Active: true}
+	if err := store.CreateWebhook(sub); err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	if err := d.Publish(context.Background(), testEvent(models.EventAppealCreated)); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	deliveries, err := store.ListDeliveries(sub.ID, 10)
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("Expected one delivery, got %+v, %v", deliveries, err)
+	}
+	delivered := waitForDelivery(t, store, deliveries[0].ID)
+	if delivered.Status != models.DeliveryFailed || !strings.Contains(delivered.Error, "refusing to connect") || r.received() != 0 {
+		t.Errorf("Expected the delivery to be refused, got %+v and %d requests", delivered, r.received())
+	}
+}
@@ -0,0 +1,51 @@
+package workflow
+
+import (
+	"strings"
+
+	"go_appeals/internal/models"
+)
+
+func builtinGuards() map[string]Guard {
+	return map[string]Guard{
+		"reason_required": func(_ *models.Appeal, in Input) error {
+			if strings.TrimSpace(in.Reason) == "" {
+				return models.NewError(models.ErrValidation, "reason_required", "reason is required")
+			}
+			return nil
+		},
+		// not_assigned_to_other lets only the assignee, or anyone if the
+		// appeal is unassigned, fire the transition.
+		"not_assigned_to_other": func(appeal *models.Appeal, in Input) error {
+			if in.Actor != "" && appeal.Assignee != "" && appeal.Assignee != in.Actor {
+				return models.NewError(models.ErrConflict, "assigned_to_other", "appeal is assigned to %s", appeal.Assignee)
+			}
+			return nil
+		},
+		"solution_required": func(_ *models.Appeal, in Input) error {
+			if strings.TrimSpace(in.Solution) == "" {
+				return models.NewError(models.ErrValidation, "solution_required", "solution is required")
+			}
+			return nil
+		},
+	}
+}
+
+func builtinEffects() map[string]Effect {
+	return map[string]Effect{
+		"set_solution": func(appeal *models.Appeal, in Input) {
+			appeal.Solution = in.Solution
+		},
+		"set_cancel_reason": func(appeal *models.Appeal, in Input) {
+			appeal.CancelReason = in.Reason
+		},
+		"clear_cancel_reason": func(appeal *models.Appeal, _ Input) {
+			appeal.CancelReason = ""
+		},
+		"claim": func(appeal *models.Appeal, in Input) {
+			if appeal.Assignee == "" {
+				appeal.Assignee = in.Actor
+			}
+		},
+	}
+}
@@ -0,0 +1,312 @@
+package workflow
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"go_appeals/internal/models"
+)
+
+//go:embed default.json
+var defaultDefinition []byte
+
+// State describes one appeal status. Active states count as open work,
+// paused states are active ones where the appeal is parked and nobody is
+// working on it, final states have no way out. ClockStopped states stop the
+// SLA clock because the delay is not ours, e.g. waiting on the requester.
+type State struct {
+	Name         models.AppealStatus `json:"name"`
+	Active       bool                `json:"active,omitempty"`
+	Paused       bool                `json:"paused,omitempty"`
+	Final        bool                `json:"final,omitempty"`
+	ClockStopped bool                `json:"clock_stopped,omitempty"`
+}
+
+// Transition moves an appeal from any of the From states to To. Guards are
+// checked in order before the move, effects are applied in order after it.
+// Event names the event raised by the move, "appeal.<name>" by default.
+type Transition struct {
+	Name    string                `json:"name"`
+	From    []models.AppealStatus `json:"from"`
+	To      models.AppealStatus   `json:"to"`
+	Guards  []string              `json:"guards,omitempty"`
+	Effects []string              `json:"effects,omitempty"`
+	Event   string                `json:"event,omitempty"`
+}
+
+type Definition struct {
+	Initial     models.AppealStatus `json:"initial"`
+	States      []State             `json:"states"`
+	Transitions []Transition        `json:"transitions"`
+}
+
+// Input is the data supplied with a transition request.
+type Input struct {
+	Actor    string
+	Reason   string
+	Solution string
+}
+
+// Guard rejects a transition by returning an error. Errors that are not a
+// *models.Error are reported as validation errors coded with the guard name.
+type Guard func(appeal *models.Appeal, in Input) error
+
+type Effect func(appeal *models.Appeal, in Input)
+
+type Option func(m *Machine)
+
+// WithGuard registers a guard that definitions can refer to by name.
+func WithGuard(name string, guard Guard) Option {
+	return func(m *Machine) { m.guards[name] = guard }
+}
+
+// WithEffect registers a side effect that definitions can refer to by name.
+func WithEffect(name string, effect Effect) Option {
+	return func(m *Machine) { m.effects[name] = effect }
+}
+
+type Machine struct {
+	def         Definition
+	states      map[models.AppealStatus]State
+	transitions map[string]Transition
+	guards      map[string]Guard
+	effects     map[string]Effect
+}
+
+func New(def Definition, opts ...Option) (*Machine, error) {
+	m := &Machine{
+		def:         def,
+		states:      make(map[models.AppealStatus]State, len(def.States)),
+		transitions: make(map[string]Transition, len(def.Transitions)),
+		guards:      builtinGuards(),
+		effects:     builtinEffects(),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	for _, state := range def.States {
+		if state.Name == "" {
+			return nil, fmt.Errorf("workflow state without a name")
+		}
+		if _, ok := m.states[state.Name]; ok {
+			return nil, fmt.Errorf("workflow state %s is defined twice", state.Name)
+		}
+		if state.Active && state.Final {
+			return nil, fmt.Errorf("workflow state %s cannot be both active and final", state.Name)
+		}
+		if state.Paused && !state.Active {
+			return nil, fmt.Errorf("workflow state %s is paused but not active", state.Name)
+		}
+		if state.ClockStopped && !state.Active {
+			return nil, fmt.Errorf("workflow state %s stops the SLA clock but is not active", state.Name)
+		}
+		m.states[state.Name] = state
+	}
+	if _, ok := m.states[def.Initial]; !ok {
+		return nil, fmt.Errorf("workflow initial state %q is not defined", def.Initial)
+	}
+
+	for _, transition := range def.Transitions {
+		if transition.Name == "" {
+			return nil, fmt.Errorf("workflow transition without a name")
+		}
+		if _, ok := m.transitions[transition.Name]; ok {
+			return nil, fmt.Errorf("workflow transition %s is defined twice", transition.Name)
+		}
+		if len(transition.From) == 0 {
+			return nil, fmt.Errorf("workflow transition %s has no source states", transition.Name)
+		}
+		for _, from := range transition.From {
+			state, ok := m.states[from]
+			if !ok {
+				return nil, fmt.Errorf("workflow transition %s starts from unknown state %s", transition.Name, from)
+			}
+			if state.Final {
+				return nil, fmt.Errorf("workflow transition %s leaves final state %s", transition.Name, from)
+			}
+		}
+		if _, ok := m.states[transition.To]; !ok {
+			return nil, fmt.Errorf("workflow transition %s leads to unknown state %s", transition.Name, transition.To)
+		}
+		for _, guard := range transition.Guards {
+			if _, ok := m.guards[guard]; !ok {
+				return nil, fmt.Errorf("workflow transition %s uses unknown guard %s", transition.Name, guard)
+			}
+		}
+		for _, effect := range transition.Effects {
+			if _, ok := m.effects[effect]; !ok {
+				return nil, fmt.Errorf("workflow transition %s uses unknown effect %s", transition.Name, effect)
+			}
+		}
+		m.transitions[transition.Name] = transition
+	}
+
+	return m, nil
+}
+
+func Parse(data []byte, opts ...Option) (*Machine, error) {
+	var def Definition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow definition: %w", err)
+	}
+	return New(def, opts...)
+}
+
+// Load reads a JSON workflow definition from path.
+func Load(path string, opts ...Option) (*Machine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow definition: %w", err)
+	}
+	return Parse(data, opts...)
+}
+
+// Default returns the built-in workflow from default.json.
+func Default() *Machine {
+	m, err := Parse(defaultDefinition)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in workflow: %v", err))
+	}
+	return m
+}
+
+func (m *Machine) Definition() Definition {
+	return m.def
+}
+
+func (m *Machine) Initial() models.AppealStatus {
+	return m.def.Initial
+}
+
+// Can reports whether the named transition is allowed from the appeal's
+// current status, ignoring guards.
+func (m *Machine) Can(appeal *models.Appeal, name string) bool {
+	transition, ok := m.transitions[name]
+	return ok && containsStatus(transition.From, appeal.Status)
+}
+
+// Fire checks the named transition against the appeal, then moves it to the
+// target status and applies the transition's effects.
+func (m *Machine) Fire(appeal *models.Appeal, name string, in Input) error {
+	transition, ok := m.transitions[name]
+	if !ok {
+		return models.NewError(models.ErrInvalidTransition, "unknown_transition", "unknown transition %s", name)
+	}
+	if !containsStatus(transition.From, appeal.Status) {
+		return models.NewError(models.ErrInvalidTransition, "invalid_transition", "cannot %s appeal with status: %s", name, appeal.Status)
+	}
+
+	for _, guard := range transition.Guards {
+		if err := m.guards[guard](appeal, in); err != nil {
+			if models.ErrorCode(err) != "" {
+				return err
+			}
+			return &models.Error{Kind: models.ErrValidation, Code: guard, Message: err.Error()}
+		}
+	}
+
+	appeal.Status = transition.To
+	for _, effect := range transition.Effects {
+		m.effects[effect](appeal, in)
+	}
+
+	return nil
+}
+
+// Event returns the type of the event the named transition raises.
+func (m *Machine) Event(name string) string {
+	if event := m.transitions[name].Event; event != "" {
+		return event
+	}
+	return "appeal." + name
+}
+
+// Sources returns the statuses the named transition may start from.
+func (m *Machine) Sources(name string) []models.AppealStatus {
+	return append([]models.AppealStatus(nil), m.transitions[name].From...)
+}
+
+func (m *Machine) ActiveStates() []models.AppealStatus {
+	var active []models.AppealStatus
+	for _, state := range m.def.States {
+		if state.Active {
+			active = append(active, state.Name)
+		}
+	}
+	return active
+}
+
+func (m *Machine) IsActive(status models.AppealStatus) bool {
+	return m.states[status].Active
+}
+
+// StopsClock reports whether the SLA clock stands still in status.
+func (m *Machine) StopsClock(status models.AppealStatus) bool {
+	return m.states[status].ClockStopped
+}
+
+// PausedStates returns the active states in which the appeal is parked.
+func (m *Machine) PausedStates() []models.AppealStatus {
+	var paused []models.AppealStatus
+	for _, state := range m.def.States {
+		if state.Paused {
+			paused = append(paused, state.Name)
+		}
+	}
+	return paused
+}
+
+// Mermaid renders the workflow as a Mermaid state diagram.
+func (m *Machine) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("stateDiagram-v2\n")
+	fmt.Fprintf(&b, "    [*] --> %s\n", m.def.Initial)
+	for _, transition := range m.def.Transitions {
+		for _, from := range transition.From {
+			fmt.Fprintf(&b, "    %s --> %s : %s\n", from, transition.To, transition.Name)
+		}
+	}
+	for _, state := range m.def.States {
+		if state.Final {
+			fmt.Fprintf(&b, "    %s --> [*]\n", state.Name)
+		}
+	}
+	return b.String()
+}
+
+// Graphviz renders the workflow in DOT format.
+func (m *Machine) Graphviz() string {
+	var b strings.Builder
+	b.WriteString("digraph appeal_workflow {\n")
+	b.WriteString("    rankdir=LR;\n")
+	b.WriteString("    node [shape=box, style=rounded];\n")
+	b.WriteString("    __start [shape=point];\n")
+	for _, state := range m.def.States {
+		attrs := ""
+		if state.Final {
+			attrs = " [peripheries=2]"
+		}
+		fmt.Fprintf(&b, "    %q%s;\n", state.Name, attrs)
+	}
+	fmt.Fprintf(&b, "    __start -> %q;\n", m.def.Initial)
+	for _, transition := range m.def.Transitions {
+		for _, from := range transition.From {
+			fmt.Fprintf(&b, "    %q -> %q [label=%q];\n", from, transition.To, transition.Name)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func containsStatus(statuses []models.AppealStatus, status models.AppealStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,303 @@
+package workflow
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go_appeals/internal/models"
+)
+
+func TestDefaultTransitions(t *testing.T) {
+	t.Parallel()
+
+	machine := Default()
+
+	tests := []struct {
+		transition string
+		from       models.AppealStatus
+		allowed    bool
+	}{
+		{"start", models.StatusNew, true},
+		{"start", models.StatusCancelled, true},
+		{"start", models.StatusInProgress, false},
+		{"start", models.StatusCompleted, false},
+		{"complete", models.StatusInProgress, true},
+		{"complete", models.StatusNew, false},
+		{"complete", models.StatusCompleted, false},
+		{"complete", models.StatusCancelled, false},
+		{"cancel", models.StatusNew, true},
+		{"cancel", models.StatusInProgress, true},
+		{"cancel", models.StatusCompleted, false},
+		{"cancel", models.StatusCancelled, false},
+		{"cancel", models.StatusOnHold, true},
+		{"cancel", models.StatusRejected, false},
+		{"hold", models.StatusInProgress, true},
+		{"hold", models.StatusNew, false},
+		{"wait", models.StatusInProgress, true},
+		{"wait", models.StatusOnHold, true},
+		{"wait", models.StatusCompleted, false},
+		{"resume", models.StatusOnHold, true},
+		{"resume", models.StatusWaitingForRequester, true},
+		{"resume", models.StatusInProgress, false},
+		{"reject", models.StatusNew, true},
+		{"reject", models.StatusWaitingForRequester, true},
+		{"reject", models.StatusCompleted, false},
+		{"reopen", models.StatusCompleted, true},
+		{"reopen", models.StatusCancelled, false},
+		{"reopen", models.StatusRejected, false},
+		{"start", models.StatusReopened, true},
+		{"start", models.StatusRejected, false},
+	}
+
+	for _, tt := range tests {
+		appeal := &models.Appeal{Status: tt.from}
+		if got := machine.Can(appeal, tt.transition); got != tt.allowed {
+			t.Errorf("Can(%s) from %s: expected %v, got %v", tt.transition, tt.from, tt.allowed, got)
+		}
+	}
+}
+
+func TestFire(t *testing.T) {
+	t.Parallel()
+
+	machine := Default()
+
+	t.Run("GuardsAndEffects", func(t *testing.T) {
+		appeal := &models.Appeal{Status: models.StatusInProgress}
+
+		if err := machine.Fire(appeal, "complete", Input{}); err == nil {
+			t.Errorf("Expected solution_required guard to reject an empty solution")
+		}
+		if appeal.Status != models.StatusInProgress {
+			t.Errorf("Expected rejected transition to leave status unchanged, got %s", appeal.Status)
+		}
+
+		if err := machine.Fire(appeal, "complete", Input{Solution: "Fixed"}); err != nil {
+			t.Fatalf("Failed to complete: %v", err)
+		}
+		if appeal.Status != models.StatusCompleted || appeal.Solution != "Fixed" {
+			t.Errorf("Expected completed appeal with solution, got %+v", appeal)
+		}
+	})
+
+	t.Run("CancelAndRestart", func(t *testing.T) {
+		appeal := &models.Appeal{Status: models.StatusNew}
+
+		if err := machine.Fire(appeal, "cancel", Input{Reason: "Duplicate"}); err != nil {
+			t.Fatalf("Failed to cancel: %v", err)
+		}
+		if appeal.CancelReason != "Duplicate" {
+			t.Errorf("Expected cancel reason to be set, got %q", appeal.CancelReason)
+		}
+
+		if err := machine.Fire(appeal, "start", Input{}); err != nil {
+			t.Fatalf("Failed to restart: %v", err)
+		}
+		if appeal.Status != models.StatusInProgress || appeal.CancelReason != "" {
+			t.Errorf("Expected restarted appeal without cancel reason, got %+v", appeal)
+		}
+	})
+
+	t.Run("InvalidTransition", func(t *testing.T) {
+		appeal := &models.Appeal{Status: models.StatusCompleted}
+
+		if err := machine.Fire(appeal, "cancel", Input{Reason: "Too late"}); err == nil {
+			t.Errorf("Expected error when cancelling a completed appeal")
+		}
+		if err := machine.Fire(appeal, "teleport", Input{}); err == nil {
+			t.Errorf("Expected error for an unknown transition")
+		}
+	})
+}
+
+func TestActiveStatesAndSources(t *testing.T) {
+	t.Parallel()
+
+	machine := Default()
+
+	active := machine.ActiveStates()
+	expectedActive := []models.AppealStatus{
+		models.StatusNew,
+		models.StatusInProgress,
+		models.StatusOnHold,
+		models.StatusWaitingForRequester,
+		models.StatusReopened,
+	}
+	if !equalStatuses(active, expectedActive) {
+		t.Errorf("Expected active states %v, got %v", expectedActive, active)
+	}
+
+	paused := machine.PausedStates()
+	if !equalStatuses(paused, []models.AppealStatus{models.StatusOnHold, models.StatusWaitingForRequester}) {
+		t.Errorf("Expected OnHold and WaitingForRequester to be paused, got %v", paused)
+	}
+	if !machine.StopsClock(models.StatusWaitingForRequester) || machine.StopsClock(models.StatusOnHold) {
+		t.Errorf("Expected only WaitingForRequester to stop the SLA clock")
+	}
+
+	sources := machine.Sources("resume")
+	if !equalStatuses(sources, []models.AppealStatus{models.StatusOnHold, models.StatusWaitingForRequester}) {
+		t.Errorf("Expected resume to start from paused states, got %v", sources)
+	}
+	if machine.Initial() != models.StatusNew {
+		t.Errorf("Expected initial state New, got %s", machine.Initial())
+	}
+
+	if event := machine.Event("cancel"); event != "appeal.cancelled" {
+		t.Errorf("Expected cancel to raise appeal.cancelled, got %s", event)
+	}
+	if event := machine.Event("escalate"); event != "appeal.escalate" {
+		t.Errorf("Expected a transition without an event to raise appeal.<name>, got %s", event)
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	t.Parallel()
+
+	valid := func() Definition {
+		return Definition{
+			Initial: "Open",
+			States:  []State{{Name: "Open", Active: true}, {Name: "Closed", Final: true}},
+			Transitions: []Transition{
+				{Name: "close", From: []models.AppealStatus{"Open"}, To: "Closed"},
+			},
+		}
+	}
+
+	if _, err := New(valid()); err != nil {
+		t.Fatalf("Expected valid definition, got %v", err)
+	}
+
+	tests := map[string]func(d *Definition){
+		"unknown initial":      func(d *Definition) { d.Initial = "Missing" },
+		"duplicate state":      func(d *Definition) { d.States = append(d.States, State{Name: "Open"}) },
+		"active and final":     func(d *Definition) { d.States[1].Active = true },
+		"paused not active":    func(d *Definition) { d.States[0].Active = false; d.States[0].Paused = true },
+		"clock stopped closed": func(d *Definition) { d.States[1].ClockStopped = true },
+		"duplicate name":       func(d *Definition) { d.Transitions = append(d.Transitions, d.Transitions[0]) },
+		"unknown source":       func(d *Definition) { d.Transitions[0].From = []models.AppealStatus{"Missing"} },
+		"leaves final state":   func(d *Definition) { d.Transitions[0].From = []models.AppealStatus{"Closed"} },
+		"unknown target":       func(d *Definition) { d.Transitions[0].To = "Missing" },
+		"unknown guard":        func(d *Definition) { d.Transitions[0].Guards = []string{"missing"} },
+		"unknown effect":       func(d *Definition) { d.Transitions[0].Effects = []string{"missing"} },
+	}
+
+	for name, mutate := range tests {
+		def := valid()
+		mutate(&def)
+		if _, err := New(def); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestCustomGuardsAndEffects(t *testing.T) {
+	t.Parallel()
+
+	def := Definition{
+		Initial: "Open",
+		States:  []State{{Name: "Open", Active: true}, {Name: "Closed", Final: true}},
+		Transitions: []Transition{
+			{Name: "close", From: []models.AppealStatus{"Open"}, To: "Closed", Guards: []string{"supervisor_only"}, Effects: []string{"stamp"}},
+		},
+	}
+
+	machine, err := New(def,
+		WithGuard("supervisor_only", func(_ *models.Appeal, in Input) error {
+			if in.Actor != "supervisor" {
+				return os.ErrPermission
+			}
+			return nil
+		}),
+		WithEffect("stamp", func(appeal *models.Appeal, in Input) {
+			appeal.Solution = "closed by " + in.Actor
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to build machine: %v", err)
+	}
+
+	appeal := &models.Appeal{Status: "Open"}
+	err = machine.Fire(appeal, "close", Input{Actor: "operator"})
+	if !errors.Is(err, models.ErrValidation) || models.ErrorCode(err) != "supervisor_only" {
+		t.Errorf("Expected custom guard failure coded supervisor_only, got %v", err)
+	}
+	if err := machine.Fire(appeal, "close", Input{Actor: "supervisor"}); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if appeal.Solution != "closed by supervisor" {
+		t.Errorf("Expected custom effect to run, got %q", appeal.Solution)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "workflow.json")
+	if err := os.WriteFile(path, defaultDefinition, 0o600); err != nil {
+		t.Fatalf("Failed to write workflow file: %v", err)
+	}
+
+	machine, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load workflow: %v", err)
+	}
+	if len(machine.Definition().Transitions) != 8 {
+		t.Errorf("Expected 8 transitions, got %d", len(machine.Definition().Transitions))
+	}
+
+	if _, err := Parse([]byte("{not json")); err == nil {
+		t.Errorf("Expected error for malformed definition")
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("Expected error for missing file")
+	}
+}
+
+func TestDiagrams(t *testing.T) {
+	t.Parallel()
+
+	machine := Default()
+
+	mermaid := machine.Mermaid()
+	for _, want := range []string{
+		"stateDiagram-v2",
+		"[*] --> New",
+		"New --> InProgress : start",
+		"Cancelled --> InProgress : start",
+		"InProgress --> Completed : complete",
+		"Completed --> Reopened : reopen",
+		"Rejected --> [*]",
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Expected Mermaid output to contain %q, got:\n%s", want, mermaid)
+		}
+	}
+
+	dot := machine.Graphviz()
+	for _, want := range []string{
+		"digraph appeal_workflow {",
+		`__start -> "New";`,
+		`"InProgress" -> "Cancelled" [label="cancel"];`,
+		`"Rejected" [peripheries=2];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("Expected DOT output to contain %q, got:\n%s", want, dot)
+		}
+	}
+}
+
+func equalStatuses(a, b []models.AppealStatus) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}